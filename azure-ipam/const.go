@@ -8,6 +8,8 @@ const (
 	pluginName    = "azure-ipam"
 	cnsBaseURL    = "" // fallback to default http://localhost:10090
 	cnsReqTimeout = 15 * time.Second
+	// cnsGRPCSocket is where CNS serves its gRPC API, if it is enabled.
+	cnsGRPCSocket = "/var/run/azure-cns/grpc.sock"
 )
 
 // plugin specific error codes
@@ -2,7 +2,9 @@ package main
 
 import (
 	"log"
+	"net"
 	"os"
+	"time"
 
 	"github.com/Azure/azure-container-networking/azure-ipam/internal/buildinfo"
 	"github.com/Azure/azure-container-networking/azure-ipam/logger"
@@ -11,6 +13,7 @@ import (
 	"github.com/containernetworking/cni/pkg/version"
 	bv "github.com/containernetworking/plugins/pkg/utils/buildversion"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 )
 
 func main() {
@@ -36,8 +39,14 @@ func executePlugin() error {
 	pluginLogger.Debug("logger construction succeeded")
 	defer cleanup()
 
-	// Create CNS client
-	client, err := cnsclient.New(cnsBaseURL, cnsReqTimeout)
+	// Create CNS client, of the gRPC API if CNS serves it
+	var client cnsClient
+	if cnsGRPCServed(cnsGRPCSocket) {
+		pluginLogger.Debug("using the CNS gRPC API", zap.String("socket", cnsGRPCSocket))
+		client, err = cnsclient.NewGRPC(cnsGRPCSocket, cnsReqTimeout)
+	} else {
+		client, err = cnsclient.New(cnsBaseURL, cnsReqTimeout)
+	}
 	if err != nil {
 		return errors.Wrapf(err, "failed to initialize CNS client")
 	}
@@ -60,3 +69,13 @@ func executePlugin() error {
 
 	return nil
 }
+
+// cnsGRPCServed reports whether CNS accepts connections on the gRPC API socket.
+func cnsGRPCServed(socketPath string) bool {
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
@@ -27,6 +27,10 @@ const (
 	// CNI errors.
 	ErrRuntime = 100
 
+	// StoreTypeEnv selects the backend of the plugin's state store. Container runtimes
+	// don't pass arguments to CNI plugins, so it's read from the environment.
+	StoreTypeEnv = "AZURE_CNI_STORE_TYPE"
+
 	// DefaultVersion is the CNI version used when no version is specified in a network config file.
 	defaultVersion = "0.2.0"
 )
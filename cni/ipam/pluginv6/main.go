@@ -21,6 +21,7 @@ var version string
 func main() {
 	var config common.PluginConfig
 	config.Version = version
+	config.StoreType = cni.StoreTypeFromEnv()
 
 	ipamPlugin, err := ipam.NewPlugin(name, &config)
 	if err != nil {
@@ -73,6 +73,7 @@ type NetworkConfig struct {
 	DisableHairpinOnHostInterface bool            `json:"disableHairpinOnHostInterface,omitempty"`
 	DisableIPTableLock            bool            `json:"disableIPTableLock,omitempty"`
 	CNSUrl                        string          `json:"cnsurl,omitempty"`
+	CNSGRPCSocket                 string          `json:"cnsGrpcSocket,omitempty"`
 	ExecutionMode                 string          `json:"executionMode,omitempty"`
 	IPAM                          IPAM            `json:"ipam,omitempty"`
 	DNS                           cniTypes.DNS    `json:"dns,omitempty"`
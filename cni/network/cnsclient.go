@@ -2,8 +2,12 @@ package network
 
 import (
 	"context"
+	"time"
 
+	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cns"
+	cnscli "github.com/Azure/azure-container-networking/cns/client"
+	"github.com/pkg/errors"
 )
 
 type cnsclient interface {
@@ -14,3 +18,16 @@ type cnsclient interface {
 	GetNetworkContainer(ctx context.Context, orchestratorContext []byte) (*cns.GetNetworkContainerResponse, error)
 	GetAllNetworkContainers(ctx context.Context, orchestratorContext []byte) ([]cns.GetNetworkContainerResponse, error)
 }
+
+// cnsIPAMClient returns the client the CNSIPAMInvoker uses, which is a client of the CNS gRPC API if its
+// socket is configured, or else the REST client.
+func cnsIPAMClient(nwCfg *cni.NetworkConfig, restClient cnsclient, timeout time.Duration) (cnsclient, error) {
+	if nwCfg.CNSGRPCSocket == "" {
+		return restClient, nil
+	}
+	grpcClient, err := cnscli.NewGRPC(nwCfg.CNSGRPCSocket, timeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cns gRPC client")
+	}
+	return grpcClient, nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"net"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cni/util"
@@ -22,6 +24,10 @@ import (
 
 const (
 	expectedNumInterfacesWithDefaultRoutes = 1
+	// defaultThrottledRetryAfter is how long to wait before retrying an IP request CNS throttled without a hint.
+	defaultThrottledRetryAfter = time.Second
+	// maxThrottledRetryWait bounds how long IP requests CNS throttles are retried for.
+	maxThrottledRetryWait = time.Minute
 )
 
 var (
@@ -107,7 +113,7 @@ func (invoker *CNSIPAMInvoker) Add(addConfig IPAMAddConfig) (IPAMAddResult, erro
 	logger.Info("Requesting IP for pod using ipconfig",
 		zap.Any("pod", podInfo),
 		zap.Any("ipconfig", ipconfigs))
-	response, err := invoker.cnsClient.RequestIPs(context.TODO(), ipconfigs)
+	response, err := invoker.requestIPs(context.TODO(), ipconfigs)
 	if err != nil {
 		if cnscli.IsUnsupportedAPI(err) {
 			// If RequestIPs is not supported by CNS, use RequestIPAddress API
@@ -119,7 +125,7 @@ func (invoker *CNSIPAMInvoker) Add(addConfig IPAMAddConfig) (IPAMAddResult, erro
 				InfraContainerID:    addConfig.args.ContainerID,
 			}
 
-			res, errRequestIP := invoker.cnsClient.RequestIPAddress(context.TODO(), ipconfig)
+			res, errRequestIP := invoker.requestIPAddress(context.TODO(), ipconfig)
 			if errRequestIP != nil {
 				// if the old API fails as well then we just return the error
 				logger.Error("Failed to request IP address from CNS using RequestIPAddress",
@@ -198,6 +204,54 @@ func (invoker *CNSIPAMInvoker) Add(addConfig IPAMAddConfig) (IPAMAddResult, erro
 	return addResult, nil
 }
 
+// requestIPs requests the IPs from CNS, retrying the request while CNS throttles it.
+func (invoker *CNSIPAMInvoker) requestIPs(ctx context.Context, ipconfigs cns.IPConfigsRequest) (*cns.IPConfigsResponse, error) {
+	var response *cns.IPConfigsResponse
+	err := retryThrottled(ctx, ipconfigs.InfraContainerID, func() (err error) {
+		response, err = invoker.cnsClient.RequestIPs(ctx, ipconfigs)
+		return err
+	})
+	return response, err
+}
+
+// requestIPAddress requests the IP from CNS with the legacy API, retrying the request while CNS throttles it.
+func (invoker *CNSIPAMInvoker) requestIPAddress(ctx context.Context, ipconfig cns.IPConfigRequest) (*cns.IPConfigResponse, error) {
+	var response *cns.IPConfigResponse
+	err := retryThrottled(ctx, ipconfig.InfraContainerID, func() (err error) {
+		response, err = invoker.cnsClient.RequestIPAddress(ctx, ipconfig)
+		return err
+	})
+	return response, err
+}
+
+// retryThrottled makes the IP request, waiting as long as CNS asks before retrying it while CNS throttles it,
+// for up to maxThrottledRetryWait. The waits are jittered so that the throttled callers do not retry all at once.
+func retryThrottled(ctx context.Context, infraContainerID string, request func() error) error {
+	deadline := time.Now().Add(maxThrottledRetryWait)
+	for {
+		err := request()
+		retryAfter, throttled := cnscli.RetryAfter(err)
+		if !throttled {
+			return err
+		}
+		if retryAfter <= 0 {
+			retryAfter = defaultThrottledRetryAfter
+		}
+		retryAfter += time.Duration(rand.Int63n(int64(retryAfter)/2 + 1)) //nolint:gosec // jitter does not need crypto rand
+		if time.Now().Add(retryAfter).After(deadline) {
+			return err
+		}
+		logger.Info("CNS throttled the IP request, retrying",
+			zap.Any("infracontainerid", infraContainerID),
+			zap.Duration("retryAfter", retryAfter))
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "cancelled while waiting to retry the throttled IP request")
+		case <-time.After(retryAfter):
+		}
+	}
+}
+
 func setHostOptions(ncSubnetPrefix *net.IPNet, options map[string]interface{}, info *IPResultInfo) error {
 	// get the host ip
 	hostIP := net.ParseIP(info.hostPrimaryIP)
@@ -1,15 +1,18 @@
 package network
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cni/util"
 	"github.com/Azure/azure-container-networking/cns"
+	cnscli "github.com/Azure/azure-container-networking/cns/client"
 	"github.com/Azure/azure-container-networking/iptables"
 	"github.com/Azure/azure-container-networking/network"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
@@ -1337,3 +1340,67 @@ func Test_setHostOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestCNSIPAMInvoker_RequestIPsThrottled(t *testing.T) {
+	require := require.New(t) //nolint further usage of require without passing t
+	result := &cns.IPConfigsResponse{
+		PodIPInfo: []cns.PodIpInfo{{PodIPConfig: cns.IPSubnet{IPAddress: "10.0.1.10", PrefixLength: 24}}},
+	}
+	newInvoker := func(throttled int, retryAfter time.Duration) (*CNSIPAMInvoker, *MockCNSClient) {
+		cnsClient := &MockCNSClient{
+			require: require,
+			requestIPs: requestIPsHandler{
+				ipconfigArgument: getTestIPConfigsRequest(),
+				result:           result,
+				throttled:        throttled,
+				retryAfter:       retryAfter,
+			},
+		}
+		return &CNSIPAMInvoker{podName: testPodInfo.PodName, podNamespace: testPodInfo.PodNamespace, cnsClient: cnsClient}, cnsClient
+	}
+
+	// the request is retried as CNS asks until it is not throttled.
+	invoker, cnsClient := newInvoker(2, time.Millisecond)
+	got, err := invoker.requestIPs(context.Background(), getTestIPConfigsRequest())
+	require.NoError(err)
+	require.Equal(result, got)
+	require.Zero(cnsClient.requestIPs.throttled)
+
+	// the request is not retried if CNS asks to wait for longer than the retries are bounded to.
+	invoker, cnsClient = newInvoker(1, 2*maxThrottledRetryWait)
+	_, err = invoker.requestIPs(context.Background(), getTestIPConfigsRequest())
+	retryAfter, throttled := cnscli.RetryAfter(err)
+	require.True(throttled)
+	require.Equal(2*maxThrottledRetryWait, retryAfter)
+	require.Zero(cnsClient.requestIPs.throttled)
+
+	// the wait to retry the request is cancelled with the context.
+	invoker, _ = newInvoker(1, time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = invoker.requestIPs(ctx, getTestIPConfigsRequest())
+	require.ErrorIs(err, context.Canceled)
+}
+
+func TestCNSIPAMInvoker_RequestIPAddressThrottled(t *testing.T) {
+	require := require.New(t) //nolint further usage of require without passing t
+	result := &cns.IPConfigResponse{
+		PodIpInfo: cns.PodIpInfo{PodIPConfig: cns.IPSubnet{IPAddress: "10.0.1.10", PrefixLength: 24}},
+	}
+	cnsClient := &MockCNSClient{
+		require: require,
+		requestIP: requestIPAddressHandler{
+			ipconfigArgument: getTestIPConfigRequest(),
+			result:           result,
+			throttled:        2,
+			retryAfter:       time.Millisecond,
+		},
+	}
+	invoker := &CNSIPAMInvoker{podName: testPodInfo.PodName, podNamespace: testPodInfo.PodNamespace, cnsClient: cnsClient}
+
+	// the legacy request is retried as CNS asks until it is not throttled too.
+	got, err := invoker.requestIPAddress(context.Background(), getTestIPConfigRequest())
+	require.NoError(err)
+	require.Equal(result, got)
+	require.Zero(cnsClient.requestIP.throttled)
+}
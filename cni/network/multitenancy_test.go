@@ -6,6 +6,7 @@ import (
 	"errors"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cns"
@@ -26,6 +27,10 @@ type requestIPAddressHandler struct {
 	// results
 	result *cns.IPConfigResponse
 	err    error
+
+	// throttled requests are answered with RequestThrottled and the retryAfter hint before the results
+	throttled  int
+	retryAfter time.Duration
 }
 
 type requestIPsHandler struct {
@@ -35,6 +40,10 @@ type requestIPsHandler struct {
 	// results
 	result *cns.IPConfigsResponse // this will return the IPConfigsResponse which contains a slice of IPs as opposed to one IP
 	err    error
+
+	// throttled requests are answered with RequestThrottled and the retryAfter hint before the results
+	throttled  int
+	retryAfter time.Duration
 }
 
 type releaseIPHandler struct {
@@ -76,6 +85,7 @@ const (
 
 var (
 	errUnsupportedAPI             = errors.New("Unsupported API")
+	errThrottled                  = errors.New("request was queued for too long")
 	errNoRequestIPFound           = errors.New("No Request IP Found")
 	errNoReleaseIPFound           = errors.New("No Release IP Found")
 	errNoOrchestratorContextFound = errors.New("No CNI OrchestratorContext Found")
@@ -96,6 +106,10 @@ func (c *MockCNSClient) RequestIPAddress(_ context.Context, ipconfig cns.IPConfi
 	if !cmp.Equal(c.requestIP.ipconfigArgument, ipconfig) {
 		return nil, errNoRequestIPFound
 	}
+	if c.requestIP.throttled > 0 {
+		c.requestIP.throttled--
+		return nil, &client.CNSClientError{Code: types.RequestThrottled, Err: errThrottled, RetryAfter: c.requestIP.retryAfter}
+	}
 	return c.requestIP.result, c.requestIP.err
 }
 
@@ -110,6 +124,10 @@ func (c *MockCNSClient) RequestIPs(_ context.Context, ipconfig cns.IPConfigsRequ
 	if !cmp.Equal(c.requestIPs.ipconfigArgument, ipconfig) {
 		return nil, errNoRequestIPFound
 	}
+	if c.requestIPs.throttled > 0 {
+		c.requestIPs.throttled--
+		return nil, &client.CNSClientError{Code: types.RequestThrottled, Err: errThrottled, RetryAfter: c.requestIPs.retryAfter}
+	}
 	return c.requestIPs.result, c.requestIPs.err
 }
 
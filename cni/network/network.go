@@ -509,7 +509,11 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 		if plugin.ipamInvoker == nil {
 			switch nwCfg.IPAM.Type {
 			case network.AzureCNS:
-				plugin.ipamInvoker = NewCNSInvoker(k8sPodName, k8sNamespace, cnsClient, util.ExecutionMode(nwCfg.ExecutionMode), util.IpamMode(nwCfg.IPAM.Mode))
+				ipamClient, cnsErr := cnsIPAMClient(nwCfg, cnsClient, defaultRequestTimeout)
+				if cnsErr != nil {
+					return cnsErr
+				}
+				plugin.ipamInvoker = NewCNSInvoker(k8sPodName, k8sNamespace, ipamClient, util.ExecutionMode(nwCfg.ExecutionMode), util.IpamMode(nwCfg.IPAM.Mode))
 
 			default:
 				plugin.ipamInvoker = NewAzureIpamInvoker(plugin, &nwInfo)
@@ -999,7 +1003,12 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 				logger.Error("failed to create cns client", zap.Error(cnsErr))
 				return errors.Wrap(cnsErr, "failed to create cns client")
 			}
-			plugin.ipamInvoker = NewCNSInvoker(k8sPodName, k8sNamespace, cnsClient, util.ExecutionMode(nwCfg.ExecutionMode), util.IpamMode(nwCfg.IPAM.Mode))
+			ipamClient, cnsErr := cnsIPAMClient(nwCfg, cnsClient, defaultRequestTimeout)
+			if cnsErr != nil {
+				logger.Error("failed to create cns client", zap.Error(cnsErr))
+				return cnsErr
+			}
+			plugin.ipamInvoker = NewCNSInvoker(k8sPodName, k8sNamespace, ipamClient, util.ExecutionMode(nwCfg.ExecutionMode), util.IpamMode(nwCfg.IPAM.Mode))
 
 		default:
 			plugin.ipamInvoker = NewAzureIpamInvoker(plugin, &nwInfo)
@@ -147,6 +147,23 @@ func rootExecute() error {
 	)
 
 	config.Version = version
+	config.StoreType = cni.StoreTypeFromEnv()
+	// the store is read after the telemetry buffer is connected below.
+	config.StoreRecoveryHandler = func(e store.RecoveryEvent) {
+		cniMetric := telemetry.AIMetric{
+			Metric: aitelemetry.Metric{
+				Name:  telemetry.CNIStateRecoveredStr,
+				Value: 1.0,
+				CustomDimensions: map[string]string{
+					telemetry.StoreFileStr: e.FileName,
+					telemetry.SnapshotStr:  e.Snapshot,
+				},
+			},
+		}
+		if err := telemetry.SendCNIMetric(&cniMetric, tb); err != nil {
+			logger.Error("Couldn't send cnistaterecovered metric", zap.Error(err))
+		}
+	}
 	reportManager := &telemetry.ReportManager{
 		HostNetAgentURL: hostNetAgentURL,
 		ContentType:     telemetry.ContentType,
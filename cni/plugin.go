@@ -181,6 +181,11 @@ func (plugin *Plugin) RetriableError(err error) *cniTypes.Error {
 	return tryAgainErr
 }
 
+// StoreTypeFromEnv returns the store type selected by StoreTypeEnv. It's empty, which selects the JSON file store, if unset.
+func StoreTypeFromEnv() store.Type {
+	return store.Type(os.Getenv(StoreTypeEnv))
+}
+
 // Initialize key-value store
 func (plugin *Plugin) InitializeKeyValueStore(config *common.PluginConfig) error {
 	// Create the key value store.
@@ -191,7 +196,8 @@ func (plugin *Plugin) InitializeKeyValueStore(config *common.PluginConfig) error
 			return errors.Wrap(err, "error creating new filelock")
 		}
 
-		plugin.Store, err = store.NewJsonFileStore(platform.CNIRuntimePath+plugin.Name+".json", lockclient, storeLogger)
+		plugin.Store, err = store.New(config.StoreType, platform.CNIRuntimePath+plugin.Name+".json", lockclient, storeLogger,
+			store.WithSnapshots(store.DefaultSnapshotCount), store.WithRecoveryHandler(config.StoreRecoveryHandler))
 		if err != nil {
 			logger.Error("Failed to create store", zap.Error(err))
 			return err
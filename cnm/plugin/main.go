@@ -105,6 +105,17 @@ var args = common.ArgumentList{
 		Type:         "string",
 		DefaultValue: platform.CNMRuntimePath,
 	},
+	{
+		Name:         common.OptStoreType,
+		Shorthand:    common.OptStoreTypeAlias,
+		Description:  "Set the store backend",
+		Type:         "string",
+		DefaultValue: string(store.TypeJSON),
+		ValueMap: map[string]interface{}{
+			string(store.TypeJSON): store.TypeJSON,
+			string(store.TypeBolt): store.TypeBolt,
+		},
+	},
 }
 
 // Prints description and version information.
@@ -126,6 +137,7 @@ func main() {
 	ipamQueryInterval, _ := common.GetArg(common.OptIpamQueryInterval).(int)
 	vers := common.GetArg(common.OptVersion).(bool)
 	storeFileLocation := common.GetArg(common.OptStoreFileLocation).(string)
+	storeType := store.Type(common.GetArg(common.OptStoreType).(string))
 
 	if vers {
 		printVersion()
@@ -135,6 +147,7 @@ func main() {
 	// Initialize plugin common configuration.
 	var config common.PluginConfig
 	config.Version = version
+	config.StoreType = storeType
 
 	// Create a channel to receive unhandled errors from the plugins.
 	config.ErrChan = make(chan error, 1)
@@ -167,7 +180,7 @@ func main() {
 
 	// Create the key value store.
 	storeFileName := storeFileLocation + name + ".json"
-	config.Store, err = store.NewJsonFileStore(storeFileName, lockclient, nil)
+	config.Store, err = store.New(config.StoreType, storeFileName, lockclient, nil)
 	if err != nil {
 		log.Errorf("Failed to create store file: %s, due to error %v\n", storeFileName, err)
 		return
@@ -6,6 +6,7 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
@@ -32,13 +33,22 @@ const (
 	RequestIPConfigs                         = "/network/requestipconfigs"
 	ReleaseIPConfig                          = "/network/releaseipconfig"
 	ReleaseIPConfigs                         = "/network/releaseipconfigs"
+	ReserveIPConfigs                         = "/network/reserveipconfigs"
+	UnreserveIPConfigs                       = "/network/unreserveipconfigs"
+	Watch                                    = "/network/watch"
 	PathDebugIPAddresses                     = "/debug/ipaddresses"
 	PathDebugPodContext                      = "/debug/podcontext"
 	PathDebugRestData                        = "/debug/restdata"
+	PathDebugIPHistory                       = "/debug/iphistory"
+	PathDebugIPAMState                       = "/debug/ipamstate"
+	PathDebugCNIConflist                     = "/debug/cniconflist"
 	NumberOfCPUCores                         = NumberOfCPUCoresPath
 	NMAgentSupportedAPIs                     = NmAgentSupportedApisPath
 )
 
+// DefaultIPReservationTTL is how long IPs stay reserved for a Pod if the ReserveIPConfigsRequest has no TTL.
+const DefaultIPReservationTTL = 5 * time.Minute
+
 // NetworkContainer Prefixes
 const (
 	SwiftPrefix = "Swift_"
@@ -503,12 +513,121 @@ type IPConfigsResponse struct {
 	Response  Response    `json:"response"`
 }
 
+// ReserveIPConfigsRequest is used in CNS IPAM mode to hold IPs for a Pod ahead of its CNI ADD, so that
+// they are not assigned to any other Pod. If DesiredIPAddresses is empty, the IPs currently assigned to
+// the Pod are reserved, or else one available IP from each NC.
+// The reservation expires after TTLSeconds, or DefaultIPReservationTTL if unset. Reserving again for the
+// same Pod extends the reservation. Reservations survive a restart of CNS.
+type ReserveIPConfigsRequest struct {
+	PodName            string   `json:"podName"`
+	PodNamespace       string   `json:"podNamespace"`
+	DesiredIPAddresses []string `json:"desiredIPAddresses"`
+	TTLSeconds         int      `json:"ttlSeconds"`
+}
+
+// ReserveIPConfigsResponse is used in CNS IPAM mode as a response to a ReserveIPConfigsRequest.
+type ReserveIPConfigsResponse struct {
+	IPAddresses []string  `json:"ipAddresses"`
+	Expiry      time.Time `json:"expiry"`
+	Response    Response  `json:"response"`
+}
+
+// UnreserveIPConfigsRequest is used in CNS IPAM mode to drop the reservation of a Pod.
+// IPs that are reserved but not assigned become available again.
+type UnreserveIPConfigsRequest struct {
+	PodName      string `json:"podName"`
+	PodNamespace string `json:"podNamespace"`
+}
+
 // GetIPAddressesRequest is used in CNS IPAM mode to get the states of IPConfigs
 // The IPConfigStateFilter is a slice of IPs to fetch from CNS that match those states
 type GetIPAddressesRequest struct {
 	IPConfigStateFilter []types.IPState
 }
 
+// IPHistoryEvent is a change of the state of an IP in the CNS IPAM pool.
+// The Pod is the one the IP is assigned or reserved for, or the one it was released by.
+type IPHistoryEvent struct {
+	Timestamp        time.Time     `json:"timestamp"`
+	IPAddress        string        `json:"ipAddress"`
+	ID               string        `json:"id"`
+	NCID             string        `json:"ncID"`
+	PreviousState    types.IPState `json:"previousState"`
+	State            types.IPState `json:"state"`
+	PodKey           string        `json:"podKey,omitempty"`
+	PodName          string        `json:"podName,omitempty"`
+	PodNamespace     string        `json:"podNamespace,omitempty"`
+	InfraContainerID string        `json:"infraContainerID,omitempty"`
+	// Caller is the CNS operation that changed the state.
+	Caller string `json:"caller"`
+}
+
+// GetIPHistoryRequest is used in CNS IPAM mode to query the IP state change history.
+// Every set field must match. Since and Until bound the event timestamps, inclusively.
+type GetIPHistoryRequest struct {
+	IPAddress    string    `json:"ipAddress,omitempty"`
+	PodName      string    `json:"podName,omitempty"`
+	PodNamespace string    `json:"podNamespace,omitempty"`
+	Since        time.Time `json:"since,omitempty"`
+	Until        time.Time `json:"until,omitempty"`
+}
+
+// GetIPHistoryResponse is used in CNS IPAM mode as a response to a GetIPHistoryRequest, oldest event first.
+type GetIPHistoryResponse struct {
+	Events   []IPHistoryEvent `json:"events"`
+	Response Response         `json:"response"`
+}
+
+// CNIConflistDiff compares the CNI conflist CNS renders for its current config to the conflist on disk.
+type CNIConflistDiff struct {
+	Path string `json:"path"`
+	// Exists is false if there is no conflist on disk yet.
+	Exists   bool   `json:"exists"`
+	Changed  bool   `json:"changed"`
+	Rendered string `json:"rendered"`
+	OnDisk   string `json:"onDisk"`
+	// Diff has the lines of both conflists prefixed with "-" if they are only on disk, "+" if they are
+	// only rendered, and " " if they are in both. It is empty if the conflists are the same.
+	Diff string `json:"diff,omitempty"`
+}
+
+// GetCNIConflistDiffResponse is the response to a CNI conflist diff.
+type GetCNIConflistDiffResponse struct {
+	Diff     *CNIConflistDiff `json:"diff"`
+	Response Response         `json:"response"`
+}
+
+// WatchEventType is the kind of change of a WatchEvent.
+type WatchEventType string
+
+const (
+	// WatchEventIPStateChanged is sent when an IP is added to the CNS IPAM pool or changes state.
+	WatchEventIPStateChanged WatchEventType = "IPStateChanged"
+	// WatchEventIPDeleted is sent when an IP is removed from the CNS IPAM pool.
+	WatchEventIPDeleted WatchEventType = "IPDeleted"
+	// WatchEventNCCreatedOrUpdated is sent when an NC is created or updated.
+	WatchEventNCCreatedOrUpdated WatchEventType = "NCCreatedOrUpdated"
+	// WatchEventNCDeleted is sent when an NC is deleted.
+	WatchEventNCDeleted WatchEventType = "NCDeleted"
+	// WatchEventExpired is sent, and the watch ends, when CNS no longer has the changes since the requested
+	// resource version. The watcher has to list the current state again and watch from the version of this event.
+	WatchEventExpired WatchEventType = "Expired"
+)
+
+// WatchEvent is a change of CNS state streamed to watchers of the Watch path.
+// ResourceVersion increases with every change, and a watch can be resumed after the last version it saw.
+type WatchEvent struct {
+	ResourceVersion uint64         `json:"resourceVersion"`
+	Type            WatchEventType `json:"type"`
+	Timestamp       time.Time      `json:"timestamp"`
+	// IPConfig is the IP as of the change, for IP events.
+	IPConfig *IPConfigurationStatus `json:"ipConfig,omitempty"`
+	// PreviousState is the state of the IP before the change, empty if the IP was added.
+	PreviousState types.IPState `json:"previousState,omitempty"`
+	// NetworkContainerID is the NC of the change, for NC events.
+	NetworkContainerID string `json:"networkContainerID,omitempty"`
+}
+
 // GetIPAddressStateResponse is used in CNS IPAM mode as a response to get IP address state
 type GetIPAddressStateResponse struct {
 	IPAddresses []IPAddressState
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -8,12 +9,15 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/restserver"
 	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 const (
@@ -33,9 +37,15 @@ var clientPaths = []string{
 	cns.RequestIPConfigs,
 	cns.ReleaseIPConfig,
 	cns.ReleaseIPConfigs,
+	cns.ReserveIPConfigs,
+	cns.UnreserveIPConfigs,
+	cns.Watch,
 	cns.PathDebugIPAddresses,
 	cns.PathDebugPodContext,
 	cns.PathDebugRestData,
+	cns.PathDebugIPHistory,
+	cns.PathDebugIPAMState,
+	cns.PathDebugCNIConflist,
 	cns.UnpublishNetworkContainer,
 	cns.PublishNetworkContainer,
 	cns.CreateOrUpdateNetworkContainer,
@@ -51,10 +61,22 @@ type do interface {
 	Do(*http.Request) (*http.Response, error)
 }
 
+// traceContextDoer propagates the W3C trace context of each request's context to CNS.
+type traceContextDoer struct {
+	do
+}
+
+func (t traceContextDoer) Do(req *http.Request) (*http.Response, error) {
+	propagation.TraceContext{}.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	return t.do.Do(req) //nolint:wrapcheck // passthrough
+}
+
 // Client specifies a client to connect to Ipam Plugin.
 type Client struct {
 	client do
-	routes map[string]url.URL
+	// streamClient has no timeout, for long-lived streams.
+	streamClient do
+	routes       map[string]url.URL
 }
 
 type ConnectionFailureErr struct {
@@ -77,8 +99,13 @@ func New(baseURL string, requestTimeout time.Duration) (*Client, error) {
 	}
 
 	return &Client{
-		client: &http.Client{
-			Timeout: requestTimeout,
+		client: traceContextDoer{
+			do: &http.Client{
+				Timeout: requestTimeout,
+			},
+		},
+		streamClient: traceContextDoer{
+			do: &http.Client{},
 		},
 		routes: routes,
 	}, nil
@@ -339,6 +366,10 @@ func (c *Client) RequestIPAddress(ctx context.Context, ipconfig cns.IPConfigRequ
 		return nil, errors.Wrap(err, "failed to decode IPConfigResponse")
 	}
 
+	if response.Response.ReturnCode == types.RequestThrottled {
+		return nil, throttledError(res.Header, response.Response.Message)
+	}
+
 	if response.Response.ReturnCode != 0 {
 		return nil, errors.New(response.Response.Message)
 	}
@@ -432,6 +463,10 @@ func (c *Client) RequestIPs(ctx context.Context, ipconfig cns.IPConfigsRequest)
 		return nil, errors.Wrap(err, "failed to decode IPConfigsResponse")
 	}
 
+	if response.Response.ReturnCode == types.RequestThrottled {
+		return nil, throttledError(res.Header, response.Response.Message)
+	}
+
 	if response.Response.ReturnCode != 0 {
 		return nil, errors.New(response.Response.Message)
 	}
@@ -439,6 +474,19 @@ func (c *Client) RequestIPs(ctx context.Context, ipconfig cns.IPConfigsRequest)
 	return &response, nil
 }
 
+// throttledError returns the error of a request CNS throttled, with the Retry-After hint of its response.
+func throttledError(header http.Header, message string) error {
+	var retryAfter time.Duration
+	if secs, err := strconv.Atoi(header.Get(restserver.RetryAfterHeader)); err == nil && secs > 0 {
+		retryAfter = time.Duration(secs) * time.Second
+	}
+	return &CNSClientError{
+		Code:       types.RequestThrottled,
+		Err:        errors.New(message),
+		RetryAfter: retryAfter,
+	}
+}
+
 // ReleaseIPs calls releaseIPs on which releases the IPs on the pod
 func (c *Client) ReleaseIPs(ctx context.Context, ipconfig cns.IPConfigsRequest) error {
 	var body bytes.Buffer
@@ -487,6 +535,98 @@ func (c *Client) ReleaseIPs(ctx context.Context, ipconfig cns.IPConfigsRequest)
 	return nil
 }
 
+// ReserveIPs reserves IPs in CNS for a Pod ahead of its CNI ADD, or extends its existing reservation.
+func (c *Client) ReserveIPs(ctx context.Context, reservation cns.ReserveIPConfigsRequest) (*cns.ReserveIPConfigsResponse, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(reservation); err != nil {
+		return nil, errors.Wrap(err, "failed to encode ReserveIPConfigsRequest")
+	}
+
+	u := c.routes[cns.ReserveIPConfigs]
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), &body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build request")
+	}
+	req.Header.Set(headerContentType, contentTypeJSON)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "http request failed")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, &CNSClientError{
+			Code: types.UnsupportedAPI,
+			Err:  errors.Errorf("Unsupported API"),
+		}
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("http response %d", res.StatusCode)
+	}
+
+	var response cns.ReserveIPConfigsResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, errors.Wrap(err, "failed to decode ReserveIPConfigsResponse")
+	}
+
+	if response.Response.ReturnCode != 0 {
+		return nil, &CNSClientError{
+			Code: response.Response.ReturnCode,
+			Err:  errors.New(response.Response.Message),
+		}
+	}
+
+	return &response, nil
+}
+
+// UnreserveIPs drops the reservation of a Pod in CNS.
+func (c *Client) UnreserveIPs(ctx context.Context, reservation cns.UnreserveIPConfigsRequest) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(reservation); err != nil {
+		return errors.Wrap(err, "failed to encode UnreserveIPConfigsRequest")
+	}
+
+	u := c.routes[cns.UnreserveIPConfigs]
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), &body)
+	if err != nil {
+		return errors.Wrap(err, "failed to build request")
+	}
+	req.Header.Set(headerContentType, contentTypeJSON)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return &ConnectionFailureErr{
+			cause: err,
+		}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return &CNSClientError{
+			Code: types.UnsupportedAPI,
+			Err:  errors.Errorf("Unsupported API"),
+		}
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("http response %d", res.StatusCode)
+	}
+
+	var resp cns.Response
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return errors.Wrap(err, "failed to decode Response")
+	}
+
+	if resp.ReturnCode != 0 {
+		return &CNSClientError{
+			Code: resp.ReturnCode,
+			Err:  errors.New(resp.Message),
+		}
+	}
+
+	return nil
+}
+
 // GetIPAddressesMatchingStates takes a variadic number of string parameters, to get all IP Addresses matching a number of states
 // usage GetIPAddressesWithStates(ctx, types.Available...)
 func (c *Client) GetIPAddressesMatchingStates(ctx context.Context, stateFilter ...types.IPState) ([]cns.IPConfigurationStatus, error) {
@@ -532,6 +672,107 @@ func (c *Client) GetIPAddressesMatchingStates(ctx context.Context, stateFilter .
 	return resp.IPConfigurationStatus, nil
 }
 
+// Watch streams the changes of IPs and NCs in CNS after the resource version, or only new changes if it is 0.
+// The channel is closed when the stream ends or the context is canceled. To resume, Watch again after the
+// ResourceVersion of the last event received. After a cns.WatchEventExpired event the changes since the
+// resource version are lost, and the current state has to be listed again.
+func (c *Client) Watch(ctx context.Context, resourceVersion uint64) (<-chan cns.WatchEvent, error) {
+	u := c.routes[cns.Watch]
+	if resourceVersion != 0 {
+		u.RawQuery = url.Values{"resourceVersion": []string{strconv.FormatUint(resourceVersion, 10)}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build request")
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	client := c.streamClient
+	if client == nil {
+		client = c.client
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "http request failed")
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, errors.Errorf("http response %d", res.StatusCode)
+	}
+
+	events := make(chan cns.WatchEvent)
+	go func() {
+		defer close(events)
+		defer res.Body.Close()
+		readWatchEvents(ctx, res.Body, events)
+	}()
+	return events, nil
+}
+
+// readWatchEvents sends the Server-Sent Events read from r until it ends or the context is canceled.
+// Only the data of the events is used, the id and event fields are also in the data.
+func readWatchEvents(ctx context.Context, r io.Reader, events chan<- cns.WatchEvent) {
+	scanner := bufio.NewScanner(r)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			if v, found := strings.CutPrefix(line, "data:"); found {
+				data.WriteString(strings.TrimPrefix(v, " "))
+			}
+			continue
+		}
+		if data.Len() == 0 {
+			continue
+		}
+		var event cns.WatchEvent
+		err := json.Unmarshal([]byte(data.String()), &event)
+		data.Reset()
+		if err != nil {
+			return
+		}
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// GetIPHistory returns the IP state changes recorded by CNS that match the request, oldest first.
+func (c *Client) GetIPHistory(ctx context.Context, payload cns.GetIPHistoryRequest) ([]cns.IPHistoryEvent, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		return nil, errors.Wrap(err, "failed to encode GetIPHistoryRequest")
+	}
+
+	u := c.routes[cns.PathDebugIPHistory]
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), &body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build request")
+	}
+	req.Header.Set(headerContentType, contentTypeJSON)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "http request failed")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("http response %d", res.StatusCode)
+	}
+
+	var resp cns.GetIPHistoryResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, errors.Wrap(err, "failed to decode GetIPHistoryResponse")
+	}
+
+	if resp.Response.ReturnCode != 0 {
+		return nil, errors.New(resp.Response.Message)
+	}
+
+	return resp.Events, nil
+}
+
 // GetPodOrchestratorContext calls GetPodIpOrchestratorContext API on CNS
 func (c *Client) GetPodOrchestratorContext(ctx context.Context) (map[string][]string, error) {
 	u := c.routes[cns.PathDebugPodContext]
@@ -595,6 +836,65 @@ func (c *Client) GetHTTPServiceData(ctx context.Context) (*restserver.GetHTTPSer
 	return &resp, nil
 }
 
+// ExportIPAMState returns the IPAM state of CNS as a signed archive.
+func (c *Client) ExportIPAMState(ctx context.Context) (*restserver.IPAMStateArchive, error) {
+	u := c.routes[cns.PathDebugIPAMState]
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build request")
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "http request failed")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("http response %d", res.StatusCode)
+	}
+
+	var resp restserver.ExportIPAMStateResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, errors.Wrap(err, "failed to decode ExportIPAMStateResponse")
+	}
+
+	if resp.Response.ReturnCode != 0 {
+		return nil, errors.New(resp.Response.Message)
+	}
+
+	return resp.Archive, nil
+}
+
+// GetCNIConflistDiff returns the diff of the CNI conflist CNS renders for its current config against the
+// conflist on disk.
+func (c *Client) GetCNIConflistDiff(ctx context.Context) (*cns.CNIConflistDiff, error) {
+	u := c.routes[cns.PathDebugCNIConflist]
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build request")
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "http request failed")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("http response %d", res.StatusCode)
+	}
+
+	var resp cns.GetCNIConflistDiffResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, errors.Wrap(err, "failed to decode GetCNIConflistDiffResponse")
+	}
+
+	if resp.Response.ReturnCode != 0 {
+		return nil, errors.New(resp.Response.Message)
+	}
+
+	return resp.Diff, nil
+}
+
 // NumOfCPUCores returns the number of CPU cores available on the host that
 // CNS is running on.
 func (c *Client) NumOfCPUCores(ctx context.Context) (*cns.NumOfCPUCoresResponse, error) {
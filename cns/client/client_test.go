@@ -9,6 +9,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"sort"
@@ -52,6 +53,7 @@ type mockdo struct {
 	errToReturn            error
 	objToReturn            interface{}
 	httpStatusCodeToReturn int
+	headerToReturn         http.Header
 }
 
 func (m *mockdo) Do(req *http.Request) (*http.Response, error) {
@@ -60,6 +62,7 @@ func (m *mockdo) Do(req *http.Request) (*http.Response, error) {
 
 	return &http.Response{
 		StatusCode: m.httpStatusCodeToReturn,
+		Header:     m.headerToReturn,
 		Body:       body,
 	}, m.errToReturn
 }
@@ -408,8 +411,13 @@ func TestNew(t *testing.T) {
 			url:  "",
 			want: &Client{
 				routes: emptyRoutes,
-				client: &http.Client{
-					Timeout: 0,
+				client: traceContextDoer{
+					do: &http.Client{
+						Timeout: 0,
+					},
+				},
+				streamClient: traceContextDoer{
+					do: &http.Client{},
 				},
 			},
 			wantErr: false,
@@ -419,8 +427,13 @@ func TestNew(t *testing.T) {
 			url:  fqdnBaseURL,
 			want: &Client{
 				routes: fqdnRoutes,
-				client: &http.Client{
-					Timeout: 0,
+				client: traceContextDoer{
+					do: &http.Client{
+						Timeout: 0,
+					},
+				},
+				streamClient: traceContextDoer{
+					do: &http.Client{},
 				},
 			},
 			wantErr: false,
@@ -430,8 +443,13 @@ func TestNew(t *testing.T) {
 			url:  fqdnWithPortBaseURL,
 			want: &Client{
 				routes: fqdnWithPortRoutes,
-				client: &http.Client{
-					Timeout: 0,
+				client: traceContextDoer{
+					do: &http.Client{
+						Timeout: 0,
+					},
+				},
+				streamClient: traceContextDoer{
+					do: &http.Client{},
 				},
 			},
 			wantErr: false,
@@ -526,6 +544,32 @@ func TestBuildRoutes(t *testing.T) {
 	}
 }
 
+func TestRequestIPsThrottled(t *testing.T) {
+	emptyRoutes, _ := buildRoutes(defaultBaseURL, clientPaths)
+	client := Client{
+		client: &mockdo{
+			objToReturn: &cns.IPConfigsResponse{
+				Response: cns.Response{ReturnCode: types.RequestThrottled, Message: "too many requests are queued"},
+			},
+			httpStatusCodeToReturn: http.StatusOK,
+			headerToReturn:         http.Header{restserver.RetryAfterHeader: []string{"2"}},
+		},
+		routes: emptyRoutes,
+	}
+
+	_, err := client.RequestIPs(context.TODO(), cns.IPConfigsRequest{})
+	retryAfter, throttled := RetryAfter(err)
+	require.True(t, throttled)
+	assert.Equal(t, 2*time.Second, retryAfter)
+
+	_, err = client.RequestIPAddress(context.TODO(), cns.IPConfigRequest{})
+	_, throttled = RetryAfter(err)
+	require.True(t, throttled)
+
+	_, throttled = RetryAfter(errors.New("error"))
+	assert.False(t, throttled)
+}
+
 func TestGetAllNetworkContainers(t *testing.T) {
 	emptyRoutes, _ := buildRoutes(defaultBaseURL, clientPaths)
 	tests := []struct {
@@ -2765,3 +2809,43 @@ func TestGetHomeAz(t *testing.T) {
 		})
 	}
 }
+
+func TestWatch(t *testing.T) {
+	events := []cns.WatchEvent{
+		{ResourceVersion: 11, Type: cns.WatchEventNCCreatedOrUpdated, NetworkContainerID: "nc"},
+		{ResourceVersion: 12, Type: cns.WatchEventNCDeleted, NetworkContainerID: "nc"},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "10", r.URL.Query().Get("resourceVersion"))
+		fmt.Fprint(w, ": heartbeat\n\n")
+		for i := range events {
+			b, err := json.Marshal(events[i])
+			assert.NoError(t, err)
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", events[i].ResourceVersion, events[i].Type, b)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := New(srv.URL, time.Second)
+	require.NoError(t, err)
+	ch, err := client.Watch(context.Background(), 10)
+	require.NoError(t, err)
+	got := []cns.WatchEvent{}
+	for event := range ch {
+		got = append(got, event)
+	}
+	assert.Equal(t, events, got)
+}
+
+func TestWatchExpired(t *testing.T) {
+	client, err := New("", time.Second)
+	require.NoError(t, err)
+	ch, err := client.Watch(context.Background(), 1)
+	require.NoError(t, err)
+	event, ok := <-ch
+	require.True(t, ok)
+	assert.Equal(t, cns.WatchEventExpired, event.Type)
+	_, ok = <-ch
+	assert.False(t, ok)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns/types"
 )
@@ -22,6 +23,8 @@ func (f *FailedHTTPRequest) Error() string {
 type CNSClientError struct {
 	Code types.ResponseCode
 	Err  error
+	// RetryAfter is how long CNS asked to wait before retrying a RequestThrottled request, if it did.
+	RetryAfter time.Duration
 }
 
 func (e *CNSClientError) Error() string {
@@ -41,3 +44,14 @@ func IsUnsupportedAPI(err error) bool {
 	e := &CNSClientError{}
 	return errors.As(err, &e) && (e.Code == types.UnsupportedAPI)
 }
+
+// RetryAfter tests if the provided error is of type CNSClientError and then
+// further tests if the error code is of type RequestThrottled. It returns how
+// long CNS asked to wait before retrying the request, which may be 0.
+func RetryAfter(err error) (time.Duration, bool) {
+	e := &CNSClientError{}
+	if errors.As(err, &e) && (e.Code == types.RequestThrottled) {
+		return e.RetryAfter, true
+	}
+	return 0, false
+}
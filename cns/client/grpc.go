@@ -0,0 +1,243 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/grpc/v1alpha"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCClient is a client of the CNS gRPC API, which CNS serves on a unix socket.
+// It offers the IPAM and NC operations of the Client, and fails them with the same errors.
+type GRPCClient struct {
+	conn    *grpc.ClientConn
+	cns     v1alpha.CNSClient
+	timeout time.Duration
+}
+
+// NewGRPC returns a new client of the CNS gRPC API served on the unix socket at the path.
+// CNS is connected to when the first request is made, and each request times out after the
+// requestTimeout, if it is set.
+func NewGRPC(socketPath string, requestTimeout time.Duration) (*GRPCClient, error) {
+	conn, err := grpc.Dial("unix://"+socketPath, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create gRPC connection to %s", socketPath)
+	}
+	return &GRPCClient{
+		conn:    conn,
+		cns:     v1alpha.NewCNSClient(conn),
+		timeout: requestTimeout,
+	}, nil
+}
+
+// Close closes the connection to CNS.
+func (c *GRPCClient) Close() error {
+	return errors.Wrap(c.conn.Close(), "failed to close gRPC connection")
+}
+
+func (c *GRPCClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// rpcError converts the error of a call that did not get a response from CNS.
+func rpcError(err error) error {
+	switch status.Code(err) {
+	case codes.Unimplemented:
+		return &CNSClientError{
+			Code: types.UnsupportedAPI,
+			Err:  errors.Errorf("Unsupported API"),
+		}
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return &CNSClientError{
+			Code: types.StatusUnauthorized,
+			Err:  errors.New(status.Convert(err).Message()),
+		}
+	}
+	return &ConnectionFailureErr{
+		cause: err,
+	}
+}
+
+// responseError converts the Response of a failed operation to an error, or nil if it succeeded.
+func responseError(r *v1alpha.Response) error {
+	if r.GetReturnCode() == 0 {
+		return nil
+	}
+	return &CNSClientError{
+		Code: types.ResponseCode(r.GetReturnCode()),
+		Err:  errors.New(r.GetMessage()),
+	}
+}
+
+// RequestIPAddress requests an IP with the RequestIPConfigs API, which it expects to assign exactly one IP.
+func (c *GRPCClient) RequestIPAddress(ctx context.Context, ipconfig cns.IPConfigRequest) (*cns.IPConfigResponse, error) {
+	ipconfigs := cns.IPConfigsRequest{
+		PodInterfaceID:      ipconfig.PodInterfaceID,
+		InfraContainerID:    ipconfig.InfraContainerID,
+		OrchestratorContext: ipconfig.OrchestratorContext,
+		Ifname:              ipconfig.Ifname,
+	}
+	if ipconfig.DesiredIPAddress != "" {
+		ipconfigs.DesiredIPAddresses = []string{ipconfig.DesiredIPAddress}
+	}
+	resp, err := c.RequestIPs(ctx, ipconfigs)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.PodIPInfo) != 1 {
+		err = errors.Errorf("request returned incorrect number of IPs. Expected 1 and returned %d", len(resp.PodIPInfo))
+		if e := c.ReleaseIPs(ctx, ipconfigs); e != nil {
+			err = errors.Wrap(e, err.Error())
+		}
+		return nil, err
+	}
+	return &cns.IPConfigResponse{
+		PodIpInfo: resp.PodIPInfo[0],
+		Response:  resp.Response,
+	}, nil
+}
+
+// ReleaseIPAddress releases the IP of the request with the ReleaseIPConfigs API.
+func (c *GRPCClient) ReleaseIPAddress(ctx context.Context, ipconfig cns.IPConfigRequest) error {
+	ipconfigs := cns.IPConfigsRequest{
+		PodInterfaceID:      ipconfig.PodInterfaceID,
+		InfraContainerID:    ipconfig.InfraContainerID,
+		OrchestratorContext: ipconfig.OrchestratorContext,
+		Ifname:              ipconfig.Ifname,
+	}
+	if ipconfig.DesiredIPAddress != "" {
+		ipconfigs.DesiredIPAddresses = []string{ipconfig.DesiredIPAddress}
+	}
+	return c.ReleaseIPs(ctx, ipconfigs)
+}
+
+// RequestIPs calls RequestIPConfigs on CNS, releasing any IPs assigned to the Pod if it fails.
+func (c *GRPCClient) RequestIPs(ctx context.Context, ipconfig cns.IPConfigsRequest) (*cns.IPConfigsResponse, error) {
+	var err error
+	defer func() {
+		if err != nil {
+			if e := c.ReleaseIPs(ctx, ipconfig); e != nil {
+				err = errors.Wrap(e, err.Error())
+			}
+		}
+	}()
+
+	rctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	var header metadata.MD
+	resp, err := c.cns.RequestIPConfigs(rctx, v1alpha.IPConfigsRequestFromCNS(ipconfig), grpc.Header(&header))
+	if err != nil {
+		err = rpcError(err)
+		return nil, err
+	}
+	if types.ResponseCode(resp.GetResponse().GetReturnCode()) == types.RequestThrottled {
+		// nothing was assigned to release.
+		return nil, throttledError(http.Header{restserver.RetryAfterHeader: header.Get(restserver.RetryAfterHeader)}, resp.GetResponse().GetMessage())
+	}
+	if err = responseError(resp.GetResponse()); err != nil {
+		return nil, err
+	}
+	return resp.ToCNS(), nil
+}
+
+// ReleaseIPs calls ReleaseIPConfigs on CNS, which releases the IPs of the Pod.
+func (c *GRPCClient) ReleaseIPs(ctx context.Context, ipconfig cns.IPConfigsRequest) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.cns.ReleaseIPConfigs(ctx, v1alpha.IPConfigsRequestFromCNS(ipconfig))
+	if err != nil {
+		return rpcError(err)
+	}
+	return responseError(resp.GetResponse())
+}
+
+// GetNetworkContainer gets the network container of the orchestrator context.
+func (c *GRPCClient) GetNetworkContainer(ctx context.Context, orchestratorContext []byte) (*cns.GetNetworkContainerResponse, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.cns.GetNetworkContainer(ctx, &v1alpha.GetNetworkContainerRequest{OrchestratorContext: orchestratorContext})
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	if err := responseError(resp.GetResponse()); err != nil {
+		return nil, err
+	}
+	nc := resp.ToCNS()
+	return &nc, nil
+}
+
+// GetAllNetworkContainers gets all network containers of the orchestrator context.
+func (c *GRPCClient) GetAllNetworkContainers(ctx context.Context, orchestratorContext []byte) ([]cns.GetNetworkContainerResponse, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.cns.GetAllNetworkContainers(ctx, &v1alpha.GetNetworkContainerRequest{OrchestratorContext: orchestratorContext})
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	if err := responseError(resp.GetResponse()); err != nil {
+		return nil, err
+	}
+	return resp.ToCNS().NetworkContainers, nil
+}
+
+// CreateNetworkContainer creates the network container, or updates it if it exists.
+func (c *GRPCClient) CreateNetworkContainer(ctx context.Context, cncr cns.CreateNetworkContainerRequest) error {
+	if cncr.NetworkContainerid == "" {
+		return errors.New("empty request provided")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.cns.CreateOrUpdateNetworkContainer(ctx, v1alpha.CreateNetworkContainerRequestFromCNS(&cncr))
+	if err != nil {
+		return rpcError(err)
+	}
+	return responseError(resp.GetResponse())
+}
+
+// DeleteNetworkContainer deletes the network container of the ID.
+func (c *GRPCClient) DeleteNetworkContainer(ctx context.Context, ncID string) error {
+	if ncID == "" {
+		return errors.New("no network container ID provided")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.cns.DeleteNetworkContainer(ctx, &v1alpha.DeleteNetworkContainerRequest{NetworkContainerId: ncID})
+	if err != nil {
+		return rpcError(err)
+	}
+	return responseError(resp.GetResponse())
+}
+
+// GetIPAddressesMatchingStates gets the IPs of the IPAM pool in any of the states.
+func (c *GRPCClient) GetIPAddressesMatchingStates(ctx context.Context, stateFilter ...types.IPState) ([]cns.IPConfigurationStatus, error) {
+	if len(stateFilter) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.cns.GetIPAddresses(ctx, v1alpha.GetIPAddressesRequestFromCNS(cns.GetIPAddressesRequest{IPConfigStateFilter: stateFilter}))
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	if err := responseError(resp.GetResponse()); err != nil {
+		return nil, err
+	}
+	ipConfigs := make([]cns.IPConfigurationStatus, 0, len(resp.GetIpConfigurationStatus()))
+	for _, status := range resp.GetIpConfigurationStatus() {
+		ipConfigs = append(ipConfigs, status.ToCNS())
+	}
+	return ipConfigs, nil
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/configuration"
+	"github.com/Azure/azure-container-networking/cns/types"
+	acn "github.com/Azure/azure-container-networking/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGRPCClient(t *testing.T) *GRPCClient {
+	t.Helper()
+	return newTestGRPCClientAt(t, filepath.Join(t.TempDir(), "cns.sock"))
+}
+
+func newTestGRPCClientAt(t *testing.T, socketPath string) *GRPCClient {
+	t.Helper()
+	if !acn.PeerCredentialsSupported {
+		t.Skip("the gRPC API requires peer credentials")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- svc.ServeGRPC(ctx, socketPath)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-served)
+	})
+
+	client, err := NewGRPC(socketPath, time.Minute)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestGRPCClientRequestAndRelease(t *testing.T) {
+	client := newTestGRPCClient(t)
+	desiredIPAddress := "10.0.0.5"
+
+	orchestratorContext, err := json.Marshal(cns.KubernetesPodInfo{PodName: "testpodname", PodNamespace: "testpodnamespace"})
+	require.NoError(t, err)
+	req := cns.IPConfigsRequest{
+		PodInterfaceID:      "abc-eth0",
+		InfraContainerID:    "some-guid-1",
+		OrchestratorContext: orchestratorContext,
+	}
+
+	// release the IP of the Pod if a previous test left it assigned, once the server is listening.
+	require.Eventually(t, func() bool {
+		return client.ReleaseIPs(context.Background(), req) == nil
+	}, 5*time.Second, 10*time.Millisecond, "idempotent release failed")
+	addTestStateToRestServer(t, []string{desiredIPAddress})
+
+	resp, err := client.RequestIPs(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, resp.PodIPInfo, 1)
+	podIPInfo := resp.PodIPInfo[0]
+	assert.Equal(t, desiredIPAddress, podIPInfo.PodIPConfig.IPAddress)
+	assert.Equal(t, primaryIp, podIPInfo.NetworkContainerPrimaryIPConfig.IPSubnet.IPAddress)
+	assert.EqualValues(t, subnetPrfixLength, podIPInfo.NetworkContainerPrimaryIPConfig.IPSubnet.PrefixLength)
+	assert.Equal(t, dnsServers, podIPInfo.NetworkContainerPrimaryIPConfig.DNSServers)
+	assert.Equal(t, gatewayIp, podIPInfo.NetworkContainerPrimaryIPConfig.GatewayIPAddress)
+
+	ipConfigs, err := client.GetIPAddressesMatchingStates(context.Background(), types.Assigned)
+	require.NoError(t, err)
+	require.Len(t, ipConfigs, 1)
+	assert.Equal(t, desiredIPAddress, ipConfigs[0].IPAddress)
+	assert.Equal(t, types.Assigned, ipConfigs[0].GetState())
+	assert.Equal(t, "testpodname", ipConfigs[0].PodInfo.Name())
+	assert.False(t, ipConfigs[0].LastStateTransition.IsZero())
+
+	require.NoError(t, client.ReleaseIPs(context.Background(), req))
+	ipConfigs, err = client.GetIPAddressesMatchingStates(context.Background(), types.Assigned)
+	require.NoError(t, err)
+	assert.Empty(t, ipConfigs)
+}
+
+func TestGRPCClientNetworkContainerFailures(t *testing.T) {
+	client := newTestGRPCClient(t)
+
+	orchestratorContext, err := json.Marshal(cns.KubernetesPodInfo{PodName: "unknownpod", PodNamespace: "unknownpodnamespace"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, err = client.GetNetworkContainer(context.Background(), orchestratorContext)
+		return !errors.As(err, new(*ConnectionFailureErr))
+	}, 5*time.Second, 10*time.Millisecond)
+	// the NCs of Pods are not served with the KubernetesCRD orchestrator.
+	var cnsErr *CNSClientError
+	require.ErrorAs(t, err, &cnsErr)
+	assert.Equal(t, types.UnsupportedOrchestratorType, cnsErr.Code)
+
+	_, err = client.GetAllNetworkContainers(context.Background(), orchestratorContext)
+	require.ErrorAs(t, err, &cnsErr)
+	assert.Equal(t, types.UnexpectedError, cnsErr.Code)
+
+	assert.Error(t, client.DeleteNetworkContainer(context.Background(), ""))
+	assert.Error(t, client.CreateNetworkContainer(context.Background(), cns.CreateNetworkContainerRequest{}))
+	// the NC ID must be a UUID.
+	err = client.CreateNetworkContainer(context.Background(), cns.CreateNetworkContainerRequest{NetworkContainerid: "invalid"})
+	require.ErrorAs(t, err, &cnsErr)
+	assert.Equal(t, types.InvalidRequest, cnsErr.Code)
+}
+
+func TestGRPCClientPeerAuthorization(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "cns.sock")
+	client := newTestGRPCClientAt(t, socketPath)
+	// only the caller's UID is allowed to get the IPs.
+	svc.PeerAuthorizationRules = []configuration.UnixSocketAuthorizationRule{
+		{Paths: []string{"/cns.v1alpha.CNS/GetIPAddresses"}, UIDs: []uint32{uint32(os.Getuid())}},
+	}
+	t.Cleanup(func() { svc.PeerAuthorizationRules = nil })
+
+	var err error
+	require.Eventually(t, func() bool {
+		_, err = client.GetIPAddressesMatchingStates(context.Background(), types.Assigned)
+		return !errors.As(err, new(*ConnectionFailureErr))
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, err)
+
+	err = client.DeleteNetworkContainer(context.Background(), "denied")
+	var cnsErr *CNSClientError
+	require.ErrorAs(t, err, &cnsErr)
+	assert.Equal(t, types.StatusUnauthorized, cnsErr.Code)
+
+	// only the owner may connect to the socket.
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
@@ -2,10 +2,12 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/client"
@@ -18,6 +20,9 @@ const (
 	getCmdArg       = "get"
 	getInMemoryData = "getInMemory"
 	getPodCmdArg    = "getPodContexts"
+	getIPHistoryArg = "getIPHistory"
+	exportIPAMArg   = "exportIPAMState"
+	diffConflistArg = "diffCNIConflist"
 )
 
 func HandleCNSClientCommands(ctx context.Context, cmd string, arg string) error {
@@ -36,8 +41,14 @@ func HandleCNSClientCommands(ctx context.Context, cmd string, arg string) error
 		return getPodCmd(ctx, cnsClient)
 	case strings.EqualFold(getInMemoryData, cmd):
 		return getInMemory(ctx, cnsClient)
+	case strings.EqualFold(getIPHistoryArg, cmd):
+		return getIPHistory(ctx, cnsClient, arg)
+	case strings.EqualFold(exportIPAMArg, cmd):
+		return exportIPAMState(ctx, cnsClient, arg)
+	case strings.EqualFold(diffConflistArg, cmd):
+		return diffCNIConflist(ctx, cnsClient)
 	default:
-		return fmt.Errorf("No debug cmd supplied, options are: %v", getCmdArg)
+		return fmt.Errorf("No debug cmd supplied, options are: %v", []string{getCmdArg, getPodCmdArg, getInMemoryData, getIPHistoryArg, exportIPAMArg, diffConflistArg})
 	}
 }
 
@@ -99,3 +110,94 @@ func getInMemory(ctx context.Context, client *client.Client) error {
 		data.HTTPRestServiceData.PodIPIDByPodInterfaceKey, data.HTTPRestServiceData.PodIPConfigState, data.HTTPRestServiceData.IPAMPoolMonitor)
 	return nil
 }
+
+// getIPHistory prints the IP state changes matching the filter arg, a comma separated list of
+// ip=<address>, pod=<namespace>/<name>, since=<RFC3339 time>, and until=<RFC3339 time>.
+func getIPHistory(ctx context.Context, client *client.Client, arg string) error {
+	req, err := parseIPHistoryFilter(arg)
+	if err != nil {
+		return err
+	}
+	events, err := client.GetIPHistory(ctx, req)
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		e := &events[i]
+		fmt.Printf("%s %s %s -> %s pod: %s/%s container: %s nc: %s by: %s\n", e.Timestamp.Format(time.RFC3339), e.IPAddress,
+			e.PreviousState, e.State, e.PodNamespace, e.PodName, e.InfraContainerID, e.NCID, e.Caller)
+	}
+	return nil
+}
+
+func parseIPHistoryFilter(arg string) (cns.GetIPHistoryRequest, error) {
+	var req cns.GetIPHistoryRequest
+	if arg == "" {
+		return req, nil
+	}
+	for _, kv := range strings.Split(arg, ",") {
+		key, value, found := strings.Cut(kv, "=")
+		if !found {
+			return req, fmt.Errorf("invalid IP history filter %q, expected key=value", kv)
+		}
+		var err error
+		switch key {
+		case "ip":
+			req.IPAddress = value
+		case "pod":
+			namespace, name, found := strings.Cut(value, "/")
+			if !found {
+				return req, fmt.Errorf("invalid pod %q, expected namespace/name", value)
+			}
+			req.PodNamespace, req.PodName = namespace, name
+		case "since":
+			req.Since, err = time.Parse(time.RFC3339, value)
+		case "until":
+			req.Until, err = time.Parse(time.RFC3339, value)
+		default:
+			return req, fmt.Errorf("unknown IP history filter %q, options are: ip, pod, since, until", key)
+		}
+		if err != nil {
+			return req, fmt.Errorf("invalid %s time %q: %w", key, value, err)
+		}
+	}
+	return req, nil
+}
+
+// exportIPAMState writes the signed IPAM state archive to the file arg, or to stdout if it is empty.
+// The archive can be imported at startup with the IPAMStateSettings.ImportFile CNS config.
+func exportIPAMState(ctx context.Context, client *client.Client, arg string) error {
+	archive, err := client.ExportIPAMState(ctx)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(archive)
+	if err != nil {
+		return fmt.Errorf("failed to marshal IPAM state archive: %w", err)
+	}
+	if arg == "" {
+		fmt.Println(string(b))
+		return nil
+	}
+	if err := os.WriteFile(arg, b, 0o600); err != nil { //nolint:gomnd // only readable by the owner
+		return fmt.Errorf("failed to write IPAM state archive: %w", err)
+	}
+	return nil
+}
+
+// diffCNIConflist prints the diff of the CNI conflist CNS renders for its current config against the conflist on disk.
+func diffCNIConflist(ctx context.Context, client *client.Client) error {
+	diff, err := client.GetCNIConflistDiff(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !diff.Exists:
+		fmt.Printf("%s does not exist, CNS would write:\n%s", diff.Path, diff.Rendered)
+	case !diff.Changed:
+		fmt.Printf("%s is up to date\n", diff.Path)
+	default:
+		fmt.Printf("--- %s\n+++ rendered\n%s", diff.Path, diff.Diff)
+	}
+	return nil
+}
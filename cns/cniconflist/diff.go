@@ -0,0 +1,126 @@
+package cniconflist
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/pkg/errors"
+)
+
+// Generator generates a CNI conflist to its output stream.
+type Generator interface {
+	Generate() error
+	Close() error
+}
+
+type nopWriteCloser struct {
+	*bytes.Buffer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// Render returns the conflist that the Generator created by newGenerator writes, without writing it anywhere.
+func Render(newGenerator func(io.WriteCloser) Generator) ([]byte, error) {
+	var buf bytes.Buffer
+	g := newGenerator(nopWriteCloser{&buf})
+	if err := g.Generate(); err != nil {
+		return nil, errors.Wrap(err, "error rendering conflist")
+	}
+	if err := g.Close(); err != nil {
+		return nil, errors.Wrap(err, "error closing generator")
+	}
+	return buf.Bytes(), nil
+}
+
+// DiffFile compares the rendered conflist to the conflist in the file at path. A missing file is
+// treated as empty, so that the diff adds the whole rendered conflist.
+func DiffFile(path string, rendered []byte) (*cns.CNIConflistDiff, error) {
+	onDisk, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "error reading conflist %s", path)
+	}
+	diff := &cns.CNIConflistDiff{
+		Path:     path,
+		Exists:   err == nil,
+		Rendered: string(rendered),
+		OnDisk:   string(onDisk),
+	}
+	diff.Changed = !bytes.Equal(onDisk, rendered)
+	if diff.Changed {
+		diff.Diff = diffLines(splitLines(diff.OnDisk), splitLines(diff.Rendered))
+	}
+	return diff, nil
+}
+
+// DryRunWriter buffers the conflist written by a Generator instead of writing it to Path. When it is
+// closed it diffs the buffered conflist against the file at Path and passes the diff to Report.
+type DryRunWriter struct {
+	Path   string
+	Report func(*cns.CNIConflistDiff)
+	buf    bytes.Buffer
+}
+
+func (d *DryRunWriter) Write(p []byte) (int, error) {
+	return d.buf.Write(p) //nolint:wrapcheck // bytes.Buffer writes only fail with ErrTooLarge panics
+}
+
+func (d *DryRunWriter) Close() error {
+	diff, err := DiffFile(d.Path, d.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	if d.Report != nil {
+		d.Report(diff)
+	}
+	return nil
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	// conflists may have been written on Windows.
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n"), "\n")
+}
+
+// diffLines returns the lines of a and b in order, prefixed with "-" if they were only in a, "+" if they
+// were only in b, and " " if they were in both, using the longest common subsequence of the lines.
+// Conflists are small, so the quadratic table is fine.
+func diffLines(a, b []string) string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			sb.WriteString(" " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("-" + a[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+" + b[j] + "\n")
+			j++
+		}
+	}
+	return sb.String()
+}
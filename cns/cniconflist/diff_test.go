@@ -0,0 +1,79 @@
+package cniconflist_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/cniconflist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticGenerator struct {
+	w        io.WriteCloser
+	conflist string
+}
+
+func (g *staticGenerator) Generate() error {
+	_, err := io.WriteString(g.w, g.conflist)
+	return err
+}
+
+func (g *staticGenerator) Close() error {
+	return g.w.Close()
+}
+
+func newStaticGenerator(conflist string) func(io.WriteCloser) cniconflist.Generator {
+	return func(w io.WriteCloser) cniconflist.Generator {
+		return &staticGenerator{w: w, conflist: conflist}
+	}
+}
+
+func TestRenderAndDiffFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "10-azure.conflist")
+	rendered, err := cniconflist.Render(newStaticGenerator("{\n\t\"name\": \"azure\",\n\t\"plugins\": []\n}\n"))
+	require.NoError(t, err)
+
+	// a missing conflist is all added.
+	diff, err := cniconflist.DiffFile(path, rendered)
+	require.NoError(t, err)
+	assert.False(t, diff.Exists)
+	assert.True(t, diff.Changed)
+	assert.Equal(t, "+{\n+\t\"name\": \"azure\",\n+\t\"plugins\": []\n+}\n", diff.Diff)
+
+	require.NoError(t, os.WriteFile(path, []byte("{\r\n\t\"name\": \"cilium\",\r\n\t\"plugins\": []\r\n}\r\n"), 0o600))
+	diff, err = cniconflist.DiffFile(path, rendered)
+	require.NoError(t, err)
+	assert.True(t, diff.Exists)
+	assert.True(t, diff.Changed)
+	assert.Equal(t, " {\n-\t\"name\": \"cilium\",\n+\t\"name\": \"azure\",\n \t\"plugins\": []\n }\n", diff.Diff)
+
+	require.NoError(t, os.WriteFile(path, rendered, 0o600))
+	diff, err = cniconflist.DiffFile(path, rendered)
+	require.NoError(t, err)
+	assert.False(t, diff.Changed)
+	assert.Empty(t, diff.Diff)
+}
+
+func TestDryRunWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "10-azure.conflist")
+	require.NoError(t, os.WriteFile(path, []byte("{}\n"), 0o600))
+
+	var reported *cns.CNIConflistDiff
+	g := newStaticGenerator("{\"name\": \"azure\"}\n")(&cniconflist.DryRunWriter{
+		Path:   path,
+		Report: func(d *cns.CNIConflistDiff) { reported = d },
+	})
+	require.NoError(t, g.Generate())
+	require.NoError(t, g.Close())
+
+	require.NotNil(t, reported)
+	assert.Equal(t, "-{}\n+{\"name\": \"azure\"}\n", reported.Diff)
+	// the conflist on disk is not changed.
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{}\n", string(b))
+}
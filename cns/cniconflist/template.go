@@ -0,0 +1,144 @@
+package cniconflist
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/netip"
+	"os"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+var errInvalidConflist = errors.New("rendered conflist is invalid")
+
+// NodeFacts are the facts about the Node that conflist templates are rendered with.
+type NodeFacts struct {
+	// Subnets are the CIDRs of the NC subnets on the Node, IPv4 first.
+	Subnets []string
+	// IPv4Subnet and IPv6Subnet are the first IPv4 and IPv6 NC subnets, or empty if there are none.
+	IPv4Subnet string
+	IPv6Subnet string
+	// MTU is the MTU for pod interfaces, or 0 if it is not configured.
+	MTU int
+	// DNSIP is the IP of the node local DNS cache.
+	DNSIP       string
+	IPv6Enabled bool
+}
+
+// NewNodeFacts returns the NodeFacts for the NC subnets on the Node. The DNS IP defaults to the node local DNS IP.
+func NewNodeFacts(subnets []netip.Prefix, mtu int, dnsIP string) NodeFacts {
+	if dnsIP == "" {
+		dnsIP = nodeLocalDNSIP
+	}
+	facts := NodeFacts{
+		MTU:   mtu,
+		DNSIP: dnsIP,
+	}
+	for _, is4 := range []bool{true, false} {
+		for _, subnet := range subnets {
+			if subnet.Addr().Is4() != is4 {
+				continue
+			}
+			facts.Subnets = append(facts.Subnets, subnet.String())
+			if is4 && facts.IPv4Subnet == "" {
+				facts.IPv4Subnet = subnet.String()
+			}
+			if !is4 && facts.IPv6Subnet == "" {
+				facts.IPv6Subnet = subnet.String()
+				facts.IPv6Enabled = true
+			}
+		}
+	}
+	return facts
+}
+
+// ParseTemplateFile parses the conflist template at path. Templates are text/templates rendered with
+// NodeFacts, and can use the toJSON func to quote values.
+func ParseTemplateFile(path string) (*template.Template, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading conflist template %s", path)
+	}
+	tmpl, err := template.New(path).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"toJSON": toJSON}).
+		Parse(string(b))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing conflist template %s", path)
+	}
+	return tmpl, nil
+}
+
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", errors.Wrap(err, "error encoding value to json")
+	}
+	return string(b), nil
+}
+
+// ValidateTemplate renders the template with the NodeFacts of sample IPv4 and dual-stack Nodes, so that a template
+// which does not render a valid conflist is found when it is loaded rather than when the conflist is generated.
+func ValidateTemplate(tmpl *template.Template, mtu int, dnsIP string) error {
+	for _, subnets := range [][]netip.Prefix{
+		{netip.MustParsePrefix("10.244.0.0/24")},
+		{netip.MustParsePrefix("10.244.0.0/24"), netip.MustParsePrefix("fd00:10:244::/64")},
+	} {
+		if _, err := renderTemplate(tmpl, NewNodeFacts(subnets, mtu, dnsIP)); err != nil {
+			return errors.Wrapf(err, "conflist template %s is invalid for subnets %v", tmpl.Name(), subnets)
+		}
+	}
+	return nil
+}
+
+// renderTemplate renders the conflist template with the facts, refusing to render a conflist the container
+// runtime could not load.
+func renderTemplate(tmpl *template.Template, facts NodeFacts) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, facts); err != nil {
+		return nil, errors.Wrap(err, "error rendering conflist template")
+	}
+
+	var conflist cniConflist
+	if err := json.Unmarshal(buf.Bytes(), &conflist); err != nil {
+		return nil, errors.Wrap(errInvalidConflist, err.Error())
+	}
+	if conflist.Name == "" || len(conflist.Plugins) == 0 {
+		return nil, errors.Wrap(errInvalidConflist, "conflist has no name or no plugins")
+	}
+	return buf.Bytes(), nil
+}
+
+// TemplateGenerator generates the CNI conflist by rendering a user supplied template with the NodeFacts, so that
+// chained plugins can be added to the conflist without CNS changes.
+// The conflist is generated once, so it has the facts of the NCs on the Node at that time. The facts of NCs
+// which are added later are only rendered once CNS is restarted.
+type TemplateGenerator struct {
+	Writer   io.WriteCloser
+	Template *template.Template
+	Facts    func() NodeFacts
+}
+
+// Generate writes the CNI conflist to the Generator's output stream
+func (v *TemplateGenerator) Generate() error {
+	conflist, err := renderTemplate(v.Template, v.Facts())
+	if err != nil {
+		return err
+	}
+
+	if _, err := v.Writer.Write(conflist); err != nil {
+		return errors.Wrap(err, "error writing conflist")
+	}
+
+	return nil
+}
+
+func (v *TemplateGenerator) Close() error {
+	if err := v.Writer.Close(); err != nil {
+		return errors.Wrap(err, "error closing generator")
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package cniconflist_test
+
+import (
+	"bytes"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns/cniconflist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewNodeFacts(t *testing.T) {
+	facts := cniconflist.NewNodeFacts([]netip.Prefix{
+		netip.MustParsePrefix("fd00:10:244::/64"),
+		netip.MustParsePrefix("10.244.0.0/24"),
+		netip.MustParsePrefix("10.245.0.0/24"),
+	}, 0, "")
+	assert.Equal(t, cniconflist.NodeFacts{
+		Subnets:     []string{"10.244.0.0/24", "10.245.0.0/24", "fd00:10:244::/64"},
+		IPv4Subnet:  "10.244.0.0/24",
+		IPv6Subnet:  "fd00:10:244::/64",
+		DNSIP:       "169.254.20.10",
+		IPv6Enabled: true,
+	}, facts)
+
+	facts = cniconflist.NewNodeFacts([]netip.Prefix{netip.MustParsePrefix("10.244.0.0/24")}, 1450, "10.0.0.10")
+	assert.False(t, facts.IPv6Enabled)
+	assert.Empty(t, facts.IPv6Subnet)
+	assert.Equal(t, "10.0.0.10", facts.DNSIP)
+	assert.Equal(t, 1450, facts.MTU)
+}
+
+func TestGenerateTemplateConflist(t *testing.T) {
+	fixture := "testdata/fixtures/azure-chained-dualstack.conflist"
+
+	tmpl, err := cniconflist.ParseTemplateFile("testdata/templates/azure-chained.conflist.tmpl")
+	require.NoError(t, err)
+	facts := func() cniconflist.NodeFacts {
+		return cniconflist.NewNodeFacts([]netip.Prefix{
+			netip.MustParsePrefix("10.244.0.0/24"),
+			netip.MustParsePrefix("fd00:10:244::/64"),
+		}, 1450, "")
+	}
+	rendered, err := cniconflist.Render(func(w io.WriteCloser) cniconflist.Generator {
+		return &cniconflist.TemplateGenerator{Writer: w, Template: tmpl, Facts: facts}
+	})
+	require.NoError(t, err)
+
+	fixtureBytes, err := os.ReadFile(fixture)
+	require.NoError(t, err)
+
+	// the fixture may have been checked out with carriage returns on Windows
+	assert.Equal(t, string(bytes.ReplaceAll(fixtureBytes, []byte("\r\n"), []byte("\n"))), string(rendered))
+}
+
+func TestGenerateTemplateConflistInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"not json":   `{"name": {{ .DNSIP }}}`,
+		"no plugins": `{"name": "azure", "plugins": []}`,
+		"bad field":  `{"name": "azure", "plugins": [{"type": "{{ .Missing }}"}]}`,
+	} {
+		path := filepath.Join(dir, "conflist.tmpl")
+		require.NoError(t, os.WriteFile(path, []byte(text), 0o600))
+		tmpl, err := cniconflist.ParseTemplateFile(path)
+		require.NoError(t, err, name)
+
+		rendered, err := cniconflist.Render(func(w io.WriteCloser) cniconflist.Generator {
+			return &cniconflist.TemplateGenerator{
+				Writer:   w,
+				Template: tmpl,
+				Facts:    func() cniconflist.NodeFacts { return cniconflist.NewNodeFacts(nil, 0, "") },
+			}
+		})
+		require.Error(t, err, name)
+		assert.Nil(t, rendered, name)
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	tmpl, err := cniconflist.ParseTemplateFile("testdata/templates/azure-chained.conflist.tmpl")
+	require.NoError(t, err)
+	require.NoError(t, cniconflist.ValidateTemplate(tmpl, 1450, ""))
+
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"bad field": `{"name": "azure", "plugins": [{"type": "{{ .Missing }}"}]}`,
+		// only fails to render for dual-stack Nodes.
+		"bad ipv6": `{"name": "azure", "plugins": [{"type": "azure"}]{{ if .IPv6Enabled }},{{ end }}}`,
+	} {
+		path := filepath.Join(dir, "conflist.tmpl")
+		require.NoError(t, os.WriteFile(path, []byte(text), 0o600))
+		tmpl, err := cniconflist.ParseTemplateFile(path)
+		require.NoError(t, err, name)
+		assert.Error(t, cniconflist.ValidateTemplate(tmpl, 0, ""), name)
+	}
+}
@@ -40,6 +40,8 @@ type ServiceConfig struct {
 	Store       store.KeyValueStore
 	ChannelMode string
 	TlsSettings tls.TlsSettings
+	// UnixSocketPath is the unix socket the Listener serves on too, if set.
+	UnixSocketPath string
 }
 
 // NewService creates a new Service object.
@@ -10,6 +10,7 @@ import (
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/store"
 	"github.com/pkg/errors"
 )
 
@@ -43,11 +44,34 @@ type CNSConfig struct {
 	CNIConflistScenario         string
 	EnableCNIConflistGeneration bool
 	CNIConflistFilepath         string
+	CNIConflistDryRun           bool
 	MellanoxMonitorIntervalSecs int
 	AZRSettings                 AZRSettings
 	WatchPods                   bool
 	EnableAsyncPodDelete        bool
 	AsyncPodDeletePath          string
+	// StoreType selects the backend used to persist CNS and endpoint state. Defaults to the JSON file store.
+	StoreType           store.Type
+	PoolScalingSettings PoolScalingSettings
+	IPHistorySettings   IPHistorySettings
+	// IPCooldownSecs is how long released IPs are quarantined as Cooling before they can be assigned again.
+	// 0 disables the quarantine.
+	IPCooldownSecs    int
+	IPAMStateSettings IPAMStateSettings
+	NMAgentSettings   NMAgentSettings
+	// GRPCSocketPath is the unix socket the gRPC API is served on, alongside the REST API. Empty disables it.
+	// azure-ipam uses the gRPC API if it is served on /var/run/azure-cns/grpc.sock, and the REST API otherwise.
+	// Only the owner of CNS may connect to it, and the UnixSocketSettings rules authorize its callers by the
+	// full names of the methods, like /cns.v1alpha.CNS/RequestIPConfigs.
+	GRPCSocketPath string
+	// UnixSocketSettings serve the REST API on a unix socket too, authorizing its callers by their peer credentials.
+	UnixSocketSettings UnixSocketSettings
+	// MTLSSettings require and authorize client certificates on the HTTPS endpoint.
+	MTLSSettings MTLSSettings
+	// AdmissionSettings bound the IP requests served at once, shedding bursts of them with a retriable response.
+	AdmissionSettings AdmissionSettings
+	// CNIConflistTemplateSettings render the CNI conflist from a template instead of the CNIConflistScenario.
+	CNIConflistTemplateSettings CNIConflistTemplateSettings
 }
 
 type TelemetrySettings struct {
@@ -89,6 +113,166 @@ type AZRSettings struct {
 	PopulateHomeAzCacheRetryIntervalSecs int
 }
 
+type PoolScalingSettings struct {
+	// Grow the IP pool ahead of the IP demand predicted from the recent rate of IP assignments.
+	EnablePredictiveScaling bool
+	// How far ahead IP demand is predicted, about the DNC round trip latency. Defaults to 30 seconds.
+	PredictionWindowSecs int
+	// Policy that decides when and by how much to scale the IP pool. Defaults to threshold.
+	Policy PoolScalingPolicy
+	// Number of free IPs kept by the warmpool policy.
+	WarmPoolSize int
+	// Time of day windows of the schedule policy. The threshold policy applies outside of them.
+	Schedule []PoolScalingScheduleEntry
+	// How long the hysteresis policy waits after scaling the pool before decreasing it.
+	CooldownSecs int
+}
+
+type PoolScalingPolicy string
+
+const (
+	// PoolScalingPolicyThreshold keeps the free IPs between the NNC Scaler request and release thresholds.
+	PoolScalingPolicyThreshold PoolScalingPolicy = "threshold"
+	// PoolScalingPolicyWarmPool keeps WarmPoolSize free IPs.
+	PoolScalingPolicyWarmPool PoolScalingPolicy = "warmpool"
+	// PoolScalingPolicySchedule keeps a different number of free IPs by time of day.
+	PoolScalingPolicySchedule PoolScalingPolicy = "schedule"
+	// PoolScalingPolicyHysteresis is the threshold policy with a wider band and a cooldown before decreases.
+	PoolScalingPolicyHysteresis PoolScalingPolicy = "hysteresis"
+)
+
+type PoolScalingScheduleEntry struct {
+	// Start and End times of day, in UTC, formatted as "15:04".
+	Start string
+	End   string
+	// Number of free IPs kept during the window.
+	WarmPoolSize int
+}
+
+type IPHistorySettings struct {
+	// Number of state changes kept for each IP. Defaults to 16.
+	EventsPerIP int
+	// Number of IPs that state changes are kept for. Defaults to 1024.
+	MaxIPs int
+	// How often the history is persisted. Defaults to 30 seconds.
+	FlushIntervalSecs int
+}
+
+type IPAMStateSettings struct {
+	// File with the key that IPAM state archives are signed and verified with.
+	// Export and import are disabled without it.
+	SigningKeyFile string
+	// Signed IPAM state archive to restore the Pod IP assignments from at startup, instead of the
+	// endpoint state, CNI, or Kubernetes. Only the Pod IP assignments and the endpoint state are restored,
+	// and the NCs are reconciled from the NNC, so it is only imported in CRD mode. The file is renamed once
+	// it is imported, or rejected because it was exported by another node or is inconsistent with the NNC.
+	ImportFile string
+}
+
+// CNIConflistTemplateSettings configure the CNI conflist template. Empty TemplateFile uses the CNIConflistScenario.
+type CNIConflistTemplateSettings struct {
+	// Path of the text/template file rendered with the node facts to produce the conflist. CNS fails to start
+	// if the template does not render a valid conflist. The conflist is rendered once, with the NCs on the
+	// node when the first of them is programmed, so NCs added later are only rendered once CNS restarts.
+	TemplateFile string
+	// MTU of pod interfaces that templates are rendered with. 0 leaves it to the template.
+	MTU int
+	// IP of the node local DNS cache that templates are rendered with. Defaults to 169.254.20.10.
+	DNSIP string
+}
+
+// NMAgentSettings protects wireserver from the requests of all CNS subsystems to NMAgent.
+// The zero values disable each protection.
+type NMAgentSettings struct {
+	// Number of consecutive failed requests that stop all requests to NMAgent.
+	CircuitBreakerFailureThreshold int
+	// How long requests stay stopped before NMAgent is probed again. Defaults to 30 seconds.
+	CircuitBreakerOpenTimeoutSecs int
+	// Sustained rate of requests to NMAgent, in bursts of up to RequestBurst requests.
+	RequestsPerSecond float64
+	RequestBurst      int
+	// Maximum requests in flight to each NMAgent API.
+	MaxConcurrentRequests int
+	// How long the responses of NMAgent calls that CNS makes often are reused. 0 disables caching of each call.
+	SupportedAPIsCacheTTLSecs int
+	NCVersionListCacheTTLSecs int
+	HomeAzCacheTTLSecs        int
+	// Fail the readiness of CNS while NMAgent is unreachable.
+	ReadinessCheck bool
+}
+
+// UnixSocketSettings configure the unix socket the REST API is served on alongside its TCP endpoint.
+type UnixSocketSettings struct {
+	// Path of the socket. Empty disables it.
+	Path string
+	// Rules authorize the callers of the API on the socket. A request is allowed if any rule which applies
+	// to its path allows its caller. Without rules, only root may call the API on the socket.
+	Rules []UnixSocketAuthorizationRule
+}
+
+// UnixSocketAuthorizationRule allows the callers with any of the UIDs, GIDs, or executables to call the paths.
+type UnixSocketAuthorizationRule struct {
+	// API paths the rule applies to. A path ending with "/" applies to all paths under it. Empty applies to all paths.
+	Paths       []string
+	UIDs        []uint32
+	GIDs        []uint32
+	Executables []string
+}
+
+// MTLSSettings configure mutual TLS on the HTTPS endpoint, so that the APIs can be locked down to the
+// clients which need them, such as DNC for the NC APIs.
+type MTLSSettings struct {
+	// ClientCAFile is a PEM bundle of the CAs client certificates are verified against. Empty disables mTLS.
+	// The bundle is reloaded when it changes. It requires Rules.
+	ClientCAFile string
+	// Rules authorize the clients of the API. A request is allowed if any rule which applies to its path
+	// allows its client. The paths the rules apply to can not be called on the HTTP endpoint, which has no
+	// client certificates, while the paths they do not apply to are still served on it to any caller.
+	Rules []ClientAuthorizationRule
+}
+
+var (
+	ErrMTLSNoRules     = errors.New("mTLS client CAs are configured without rules, so the HTTP endpoint serves every path without a client certificate")
+	ErrMTLSRuleNoPaths = errors.New("mTLS rule has no paths")
+)
+
+// Validate checks that the paths protected by mTLS are explicit, since they are the paths that are
+// not served on the HTTP endpoint.
+func (s *MTLSSettings) Validate() error {
+	if s.ClientCAFile == "" {
+		return nil
+	}
+	if len(s.Rules) == 0 {
+		return ErrMTLSNoRules
+	}
+	for i := range s.Rules {
+		if len(s.Rules[i].Paths) == 0 {
+			return errors.Wrapf(ErrMTLSRuleNoPaths, "rule %d for %v", i, s.Rules[i].Identities)
+		}
+	}
+	return nil
+}
+
+// ClientAuthorizationRule allows the clients with any of the identities to call the paths. The identities of a
+// client are the subject common name and the DNS and URI SANs of its certificate.
+type ClientAuthorizationRule struct {
+	Identities []string
+	// API paths the rule applies to. A path ending with "/" applies to all paths under it. It must not be empty.
+	Paths []string
+}
+
+// AdmissionSettings configure the admission control of IP requests. Zero MaxConcurrentRequestsPerCaller disables it.
+type AdmissionSettings struct {
+	// Number of IP requests of each caller served at once.
+	MaxConcurrentRequestsPerCaller int
+	// Number of IP requests of all callers which wait to be served. Requests beyond it are shed.
+	MaxQueuedRequests int
+	// How long IP requests wait to be served before they are shed. 0 waits until the caller gives up.
+	QueueTimeoutMs int
+	// How long callers of shed requests are asked to wait before retrying them.
+	RetryAfterSecs int
+}
+
 type MSISettings struct {
 	ResourceID string
 }
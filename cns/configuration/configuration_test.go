@@ -277,3 +277,27 @@ func TestSetCNSConfigDefaults(t *testing.T) {
 		})
 	}
 }
+
+func TestMTLSSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings MTLSSettings
+		wantErr  error
+	}{
+		{"mtls disabled", MTLSSettings{}, nil},
+		{"rules with paths", MTLSSettings{ClientCAFile: "ca.pem", Rules: []ClientAuthorizationRule{{Identities: []string{"dnc"}, Paths: []string{"/network/"}}}}, nil},
+		{"no rules", MTLSSettings{ClientCAFile: "ca.pem"}, ErrMTLSNoRules},
+		{"rule without paths", MTLSSettings{ClientCAFile: "ca.pem", Rules: []ClientAuthorizationRule{{Identities: []string{"dnc"}}}}, ErrMTLSRuleNoPaths},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.settings.Validate()
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
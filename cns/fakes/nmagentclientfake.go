@@ -17,6 +17,8 @@ type NMAgentClientFake struct {
 	SupportedAPIsF    func(context.Context) ([]string, error)
 	GetNCVersionListF func(context.Context) (nmagent.NCVersionList, error)
 	GetHomeAzF        func(context.Context) (nmagent.AzResponse, error)
+
+	InvalidateNCVersionListF func()
 }
 
 func (n *NMAgentClientFake) SupportedAPIs(ctx context.Context) ([]string, error) {
@@ -30,3 +32,9 @@ func (n *NMAgentClientFake) GetNCVersionList(ctx context.Context) (nmagent.NCVer
 func (n *NMAgentClientFake) GetHomeAz(ctx context.Context) (nmagent.AzResponse, error) {
 	return n.GetHomeAzF(ctx)
 }
+
+func (n *NMAgentClientFake) InvalidateNCVersionList() {
+	if n.InvalidateNCVersionListF != nil {
+		n.InvalidateNCVersionListF()
+	}
+}
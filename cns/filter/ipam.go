@@ -16,6 +16,10 @@ var (
 	StatePendingProgramming = ipConfigStatePredicate(types.PendingProgramming)
 	// StatePendingRelease is a preset filter for types.PendingRelease.
 	StatePendingRelease = ipConfigStatePredicate(types.PendingRelease)
+	// StateReserved is a preset filter for types.Reserved.
+	StateReserved = ipConfigStatePredicate(types.Reserved)
+	// StateCooling is a preset filter for types.Cooling.
+	StateCooling = ipConfigStatePredicate(types.Cooling)
 )
 
 var filters = map[types.IPState]IPConfigStatePredicate{
@@ -23,6 +27,8 @@ var filters = map[types.IPState]IPConfigStatePredicate{
 	types.Available:          StateAvailable,
 	types.PendingProgramming: StatePendingProgramming,
 	types.PendingRelease:     StatePendingRelease,
+	types.Reserved:           StateReserved,
+	types.Cooling:            StateCooling,
 }
 
 // ipConfigStatePredicate returns a predicate function that compares an IPConfigurationStatus.State to
@@ -0,0 +1,331 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package v1alpha
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// CNSClient is the client API for CNS service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CNSClient interface {
+	// RequestIPConfigs assigns IPs to a Pod interface.
+	RequestIPConfigs(ctx context.Context, in *IPConfigsRequest, opts ...grpc.CallOption) (*IPConfigsResponse, error)
+	// ReleaseIPConfigs releases the IPs of a Pod interface.
+	ReleaseIPConfigs(ctx context.Context, in *IPConfigsRequest, opts ...grpc.CallOption) (*ReleaseIPConfigsResponse, error)
+	// CreateOrUpdateNetworkContainer creates a network container, or updates it if it exists.
+	CreateOrUpdateNetworkContainer(ctx context.Context, in *CreateNetworkContainerRequest, opts ...grpc.CallOption) (*CreateNetworkContainerResponse, error)
+	// GetNetworkContainer gets the network container of an orchestrator context.
+	GetNetworkContainer(ctx context.Context, in *GetNetworkContainerRequest, opts ...grpc.CallOption) (*GetNetworkContainerResponse, error)
+	// GetAllNetworkContainers gets all network containers of an orchestrator context.
+	GetAllNetworkContainers(ctx context.Context, in *GetNetworkContainerRequest, opts ...grpc.CallOption) (*GetAllNetworkContainersResponse, error)
+	// DeleteNetworkContainer deletes a network container.
+	DeleteNetworkContainer(ctx context.Context, in *DeleteNetworkContainerRequest, opts ...grpc.CallOption) (*DeleteNetworkContainerResponse, error)
+	// GetIPAddresses gets the IPs of the IPAM pool in any of the requested states.
+	GetIPAddresses(ctx context.Context, in *GetIPAddressesRequest, opts ...grpc.CallOption) (*GetIPAddressesResponse, error)
+}
+
+type cNSClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCNSClient(cc grpc.ClientConnInterface) CNSClient {
+	return &cNSClient{cc}
+}
+
+func (c *cNSClient) RequestIPConfigs(ctx context.Context, in *IPConfigsRequest, opts ...grpc.CallOption) (*IPConfigsResponse, error) {
+	out := new(IPConfigsResponse)
+	err := c.cc.Invoke(ctx, "/cns.v1alpha.CNS/RequestIPConfigs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSClient) ReleaseIPConfigs(ctx context.Context, in *IPConfigsRequest, opts ...grpc.CallOption) (*ReleaseIPConfigsResponse, error) {
+	out := new(ReleaseIPConfigsResponse)
+	err := c.cc.Invoke(ctx, "/cns.v1alpha.CNS/ReleaseIPConfigs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSClient) CreateOrUpdateNetworkContainer(ctx context.Context, in *CreateNetworkContainerRequest, opts ...grpc.CallOption) (*CreateNetworkContainerResponse, error) {
+	out := new(CreateNetworkContainerResponse)
+	err := c.cc.Invoke(ctx, "/cns.v1alpha.CNS/CreateOrUpdateNetworkContainer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSClient) GetNetworkContainer(ctx context.Context, in *GetNetworkContainerRequest, opts ...grpc.CallOption) (*GetNetworkContainerResponse, error) {
+	out := new(GetNetworkContainerResponse)
+	err := c.cc.Invoke(ctx, "/cns.v1alpha.CNS/GetNetworkContainer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSClient) GetAllNetworkContainers(ctx context.Context, in *GetNetworkContainerRequest, opts ...grpc.CallOption) (*GetAllNetworkContainersResponse, error) {
+	out := new(GetAllNetworkContainersResponse)
+	err := c.cc.Invoke(ctx, "/cns.v1alpha.CNS/GetAllNetworkContainers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSClient) DeleteNetworkContainer(ctx context.Context, in *DeleteNetworkContainerRequest, opts ...grpc.CallOption) (*DeleteNetworkContainerResponse, error) {
+	out := new(DeleteNetworkContainerResponse)
+	err := c.cc.Invoke(ctx, "/cns.v1alpha.CNS/DeleteNetworkContainer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSClient) GetIPAddresses(ctx context.Context, in *GetIPAddressesRequest, opts ...grpc.CallOption) (*GetIPAddressesResponse, error) {
+	out := new(GetIPAddressesResponse)
+	err := c.cc.Invoke(ctx, "/cns.v1alpha.CNS/GetIPAddresses", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CNSServer is the server API for CNS service.
+// All implementations must embed UnimplementedCNSServer
+// for forward compatibility
+type CNSServer interface {
+	// RequestIPConfigs assigns IPs to a Pod interface.
+	RequestIPConfigs(context.Context, *IPConfigsRequest) (*IPConfigsResponse, error)
+	// ReleaseIPConfigs releases the IPs of a Pod interface.
+	ReleaseIPConfigs(context.Context, *IPConfigsRequest) (*ReleaseIPConfigsResponse, error)
+	// CreateOrUpdateNetworkContainer creates a network container, or updates it if it exists.
+	CreateOrUpdateNetworkContainer(context.Context, *CreateNetworkContainerRequest) (*CreateNetworkContainerResponse, error)
+	// GetNetworkContainer gets the network container of an orchestrator context.
+	GetNetworkContainer(context.Context, *GetNetworkContainerRequest) (*GetNetworkContainerResponse, error)
+	// GetAllNetworkContainers gets all network containers of an orchestrator context.
+	GetAllNetworkContainers(context.Context, *GetNetworkContainerRequest) (*GetAllNetworkContainersResponse, error)
+	// DeleteNetworkContainer deletes a network container.
+	DeleteNetworkContainer(context.Context, *DeleteNetworkContainerRequest) (*DeleteNetworkContainerResponse, error)
+	// GetIPAddresses gets the IPs of the IPAM pool in any of the requested states.
+	GetIPAddresses(context.Context, *GetIPAddressesRequest) (*GetIPAddressesResponse, error)
+	mustEmbedUnimplementedCNSServer()
+}
+
+// UnimplementedCNSServer must be embedded to have forward compatible implementations.
+type UnimplementedCNSServer struct {
+}
+
+func (UnimplementedCNSServer) RequestIPConfigs(context.Context, *IPConfigsRequest) (*IPConfigsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestIPConfigs not implemented")
+}
+func (UnimplementedCNSServer) ReleaseIPConfigs(context.Context, *IPConfigsRequest) (*ReleaseIPConfigsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseIPConfigs not implemented")
+}
+func (UnimplementedCNSServer) CreateOrUpdateNetworkContainer(context.Context, *CreateNetworkContainerRequest) (*CreateNetworkContainerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateOrUpdateNetworkContainer not implemented")
+}
+func (UnimplementedCNSServer) GetNetworkContainer(context.Context, *GetNetworkContainerRequest) (*GetNetworkContainerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNetworkContainer not implemented")
+}
+func (UnimplementedCNSServer) GetAllNetworkContainers(context.Context, *GetNetworkContainerRequest) (*GetAllNetworkContainersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAllNetworkContainers not implemented")
+}
+func (UnimplementedCNSServer) DeleteNetworkContainer(context.Context, *DeleteNetworkContainerRequest) (*DeleteNetworkContainerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteNetworkContainer not implemented")
+}
+func (UnimplementedCNSServer) GetIPAddresses(context.Context, *GetIPAddressesRequest) (*GetIPAddressesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetIPAddresses not implemented")
+}
+func (UnimplementedCNSServer) mustEmbedUnimplementedCNSServer() {}
+
+// UnsafeCNSServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CNSServer will
+// result in compilation errors.
+type UnsafeCNSServer interface {
+	mustEmbedUnimplementedCNSServer()
+}
+
+func RegisterCNSServer(s grpc.ServiceRegistrar, srv CNSServer) {
+	s.RegisterService(&CNS_ServiceDesc, srv)
+}
+
+func _CNS_RequestIPConfigs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IPConfigsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).RequestIPConfigs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cns.v1alpha.CNS/RequestIPConfigs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).RequestIPConfigs(ctx, req.(*IPConfigsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNS_ReleaseIPConfigs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IPConfigsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).ReleaseIPConfigs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cns.v1alpha.CNS/ReleaseIPConfigs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).ReleaseIPConfigs(ctx, req.(*IPConfigsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNS_CreateOrUpdateNetworkContainer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateNetworkContainerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).CreateOrUpdateNetworkContainer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cns.v1alpha.CNS/CreateOrUpdateNetworkContainer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).CreateOrUpdateNetworkContainer(ctx, req.(*CreateNetworkContainerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNS_GetNetworkContainer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNetworkContainerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).GetNetworkContainer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cns.v1alpha.CNS/GetNetworkContainer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).GetNetworkContainer(ctx, req.(*GetNetworkContainerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNS_GetAllNetworkContainers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNetworkContainerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).GetAllNetworkContainers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cns.v1alpha.CNS/GetAllNetworkContainers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).GetAllNetworkContainers(ctx, req.(*GetNetworkContainerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNS_DeleteNetworkContainer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteNetworkContainerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).DeleteNetworkContainer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cns.v1alpha.CNS/DeleteNetworkContainer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).DeleteNetworkContainer(ctx, req.(*DeleteNetworkContainerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNS_GetIPAddresses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetIPAddressesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).GetIPAddresses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cns.v1alpha.CNS/GetIPAddresses",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).GetIPAddresses(ctx, req.(*GetIPAddressesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CNS_ServiceDesc is the grpc.ServiceDesc for CNS service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CNS_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cns.v1alpha.CNS",
+	HandlerType: (*CNSServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RequestIPConfigs",
+			Handler:    _CNS_RequestIPConfigs_Handler,
+		},
+		{
+			MethodName: "ReleaseIPConfigs",
+			Handler:    _CNS_ReleaseIPConfigs_Handler,
+		},
+		{
+			MethodName: "CreateOrUpdateNetworkContainer",
+			Handler:    _CNS_CreateOrUpdateNetworkContainer_Handler,
+		},
+		{
+			MethodName: "GetNetworkContainer",
+			Handler:    _CNS_GetNetworkContainer_Handler,
+		},
+		{
+			MethodName: "GetAllNetworkContainers",
+			Handler:    _CNS_GetAllNetworkContainers_Handler,
+		},
+		{
+			MethodName: "DeleteNetworkContainer",
+			Handler:    _CNS_DeleteNetworkContainer_Handler,
+		},
+		{
+			MethodName: "GetIPAddresses",
+			Handler:    _CNS_GetIPAddresses_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "cns.proto",
+}
@@ -0,0 +1,351 @@
+package v1alpha
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	nncv1alpha "github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The conversions between the gRPC API and the CNS types, which the REST API serves as JSON.
+
+func ResponseFromCNS(r cns.Response) *Response {
+	return &Response{ReturnCode: int32(r.ReturnCode), Message: r.Message}
+}
+
+func (r *Response) ToCNS() cns.Response {
+	return cns.Response{ReturnCode: types.ResponseCode(r.GetReturnCode()), Message: r.GetMessage()}
+}
+
+func IPConfigsRequestFromCNS(r cns.IPConfigsRequest) *IPConfigsRequest {
+	return &IPConfigsRequest{
+		DesiredIpAddresses:  r.DesiredIPAddresses,
+		PodInterfaceId:      r.PodInterfaceID,
+		InfraContainerId:    r.InfraContainerID,
+		OrchestratorContext: r.OrchestratorContext,
+		Ifname:              r.Ifname,
+	}
+}
+
+func (r *IPConfigsRequest) ToCNS() cns.IPConfigsRequest {
+	return cns.IPConfigsRequest{
+		DesiredIPAddresses:  r.GetDesiredIpAddresses(),
+		PodInterfaceID:      r.GetPodInterfaceId(),
+		InfraContainerID:    r.GetInfraContainerId(),
+		OrchestratorContext: json.RawMessage(r.GetOrchestratorContext()),
+		Ifname:              r.GetIfname(),
+	}
+}
+
+func IPConfigsResponseFromCNS(r *cns.IPConfigsResponse) *IPConfigsResponse {
+	resp := &IPConfigsResponse{Response: ResponseFromCNS(r.Response)}
+	for i := range r.PodIPInfo {
+		resp.PodIpInfo = append(resp.PodIpInfo, podIPInfoFromCNS(&r.PodIPInfo[i]))
+	}
+	return resp
+}
+
+func (r *IPConfigsResponse) ToCNS() *cns.IPConfigsResponse {
+	resp := &cns.IPConfigsResponse{Response: r.GetResponse().ToCNS()}
+	for _, info := range r.GetPodIpInfo() {
+		resp.PodIPInfo = append(resp.PodIPInfo, info.toCNS())
+	}
+	return resp
+}
+
+func CreateNetworkContainerRequestFromCNS(r *cns.CreateNetworkContainerRequest) *CreateNetworkContainerRequest {
+	req := &CreateNetworkContainerRequest{
+		HostPrimaryIp:              r.HostPrimaryIP,
+		Version:                    r.Version,
+		NetworkContainerType:       r.NetworkContainerType,
+		NetworkContainerId:         r.NetworkContainerid,
+		PrimaryInterfaceIdentifier: r.PrimaryInterfaceIdentifier,
+		AuthorizationToken:         r.AuthorizationToken,
+		LocalIpConfiguration:       ipConfigurationFromCNS(r.LocalIPConfiguration),
+		OrchestratorContext:        r.OrchestratorContext,
+		IpConfiguration:            ipConfigurationFromCNS(r.IPConfiguration),
+		MultiTenancyInfo:           multiTenancyInfoFromCNS(r.MultiTenancyInfo),
+		CnetAddressSpace:           ipSubnetsFromCNS(r.CnetAddressSpace),
+		Routes:                     routesFromCNS(r.Routes),
+		AllowHostToNcCommunication: r.AllowHostToNCCommunication,
+		AllowNcToHostCommunication: r.AllowNCToHostCommunication,
+		NcStatus:                   string(r.NCStatus),
+		NetworkInterfaceInfo:       networkInterfaceInfoFromCNS(r.NetworkInterfaceInfo),
+	}
+	if r.SecondaryIPConfigs != nil {
+		req.SecondaryIpConfigs = make(map[string]*SecondaryIPConfig, len(r.SecondaryIPConfigs))
+		for uuid, ipConfig := range r.SecondaryIPConfigs {
+			req.SecondaryIpConfigs[uuid] = &SecondaryIPConfig{IpAddress: ipConfig.IPAddress, NcVersion: int64(ipConfig.NCVersion)}
+		}
+	}
+	for _, policy := range r.EndpointPolicies {
+		req.EndpointPolicies = append(req.EndpointPolicies, &EndpointPolicy{
+			Type:         policy.Type,
+			EndpointType: policy.EndpointType,
+			Settings:     policy.Settings,
+		})
+	}
+	return req
+}
+
+func (r *CreateNetworkContainerRequest) ToCNS() *cns.CreateNetworkContainerRequest {
+	req := &cns.CreateNetworkContainerRequest{
+		HostPrimaryIP:              r.GetHostPrimaryIp(),
+		Version:                    r.GetVersion(),
+		NetworkContainerType:       r.GetNetworkContainerType(),
+		NetworkContainerid:         r.GetNetworkContainerId(),
+		PrimaryInterfaceIdentifier: r.GetPrimaryInterfaceIdentifier(),
+		AuthorizationToken:         r.GetAuthorizationToken(),
+		LocalIPConfiguration:       r.GetLocalIpConfiguration().toCNS(),
+		OrchestratorContext:        json.RawMessage(r.GetOrchestratorContext()),
+		IPConfiguration:            r.GetIpConfiguration().toCNS(),
+		MultiTenancyInfo:           r.GetMultiTenancyInfo().toCNS(),
+		CnetAddressSpace:           ipSubnetsToCNS(r.GetCnetAddressSpace()),
+		Routes:                     routesToCNS(r.GetRoutes()),
+		AllowHostToNCCommunication: r.GetAllowHostToNcCommunication(),
+		AllowNCToHostCommunication: r.GetAllowNcToHostCommunication(),
+		NCStatus:                   nncv1alpha.NCStatus(r.GetNcStatus()),
+		NetworkInterfaceInfo:       r.GetNetworkInterfaceInfo().toCNS(),
+	}
+	if r.GetSecondaryIpConfigs() != nil {
+		req.SecondaryIPConfigs = make(map[string]cns.SecondaryIPConfig, len(r.GetSecondaryIpConfigs()))
+		for uuid, ipConfig := range r.GetSecondaryIpConfigs() {
+			req.SecondaryIPConfigs[uuid] = cns.SecondaryIPConfig{IPAddress: ipConfig.GetIpAddress(), NCVersion: int(ipConfig.GetNcVersion())}
+		}
+	}
+	for _, policy := range r.GetEndpointPolicies() {
+		req.EndpointPolicies = append(req.EndpointPolicies, cns.NetworkContainerRequestPolicies{
+			Type:         policy.GetType(),
+			EndpointType: policy.GetEndpointType(),
+			Settings:     json.RawMessage(policy.GetSettings()),
+		})
+	}
+	return req
+}
+
+func GetNetworkContainerRequestFromCNS(r cns.GetNetworkContainerRequest) *GetNetworkContainerRequest {
+	return &GetNetworkContainerRequest{
+		NetworkContainerId:  r.NetworkContainerid,
+		OrchestratorContext: r.OrchestratorContext,
+	}
+}
+
+func (r *GetNetworkContainerRequest) ToCNS() cns.GetNetworkContainerRequest {
+	return cns.GetNetworkContainerRequest{
+		NetworkContainerid:  r.GetNetworkContainerId(),
+		OrchestratorContext: json.RawMessage(r.GetOrchestratorContext()),
+	}
+}
+
+//nolint:gocritic // passed by value like the other CNS responses
+func GetNetworkContainerResponseFromCNS(r cns.GetNetworkContainerResponse) *GetNetworkContainerResponse {
+	return &GetNetworkContainerResponse{
+		NetworkContainerId:         r.NetworkContainerID,
+		IpConfiguration:            ipConfigurationFromCNS(r.IPConfiguration),
+		Routes:                     routesFromCNS(r.Routes),
+		CnetAddressSpace:           ipSubnetsFromCNS(r.CnetAddressSpace),
+		MultiTenancyInfo:           multiTenancyInfoFromCNS(r.MultiTenancyInfo),
+		PrimaryInterfaceIdentifier: r.PrimaryInterfaceIdentifier,
+		LocalIpConfiguration:       ipConfigurationFromCNS(r.LocalIPConfiguration),
+		Response:                   ResponseFromCNS(r.Response),
+		AllowHostToNcCommunication: r.AllowHostToNCCommunication,
+		AllowNcToHostCommunication: r.AllowNCToHostCommunication,
+		NetworkInterfaceInfo:       networkInterfaceInfoFromCNS(r.NetworkInterfaceInfo),
+	}
+}
+
+func (r *GetNetworkContainerResponse) ToCNS() cns.GetNetworkContainerResponse {
+	return cns.GetNetworkContainerResponse{
+		NetworkContainerID:         r.GetNetworkContainerId(),
+		IPConfiguration:            r.GetIpConfiguration().toCNS(),
+		Routes:                     routesToCNS(r.GetRoutes()),
+		CnetAddressSpace:           ipSubnetsToCNS(r.GetCnetAddressSpace()),
+		MultiTenancyInfo:           r.GetMultiTenancyInfo().toCNS(),
+		PrimaryInterfaceIdentifier: r.GetPrimaryInterfaceIdentifier(),
+		LocalIPConfiguration:       r.GetLocalIpConfiguration().toCNS(),
+		Response:                   r.GetResponse().ToCNS(),
+		AllowHostToNCCommunication: r.GetAllowHostToNcCommunication(),
+		AllowNCToHostCommunication: r.GetAllowNcToHostCommunication(),
+		NetworkInterfaceInfo:       r.GetNetworkInterfaceInfo().toCNS(),
+	}
+}
+
+func GetAllNetworkContainersResponseFromCNS(r *cns.GetAllNetworkContainersResponse) *GetAllNetworkContainersResponse {
+	resp := &GetAllNetworkContainersResponse{Response: ResponseFromCNS(r.Response)}
+	for i := range r.NetworkContainers {
+		resp.NetworkContainers = append(resp.NetworkContainers, GetNetworkContainerResponseFromCNS(r.NetworkContainers[i]))
+	}
+	return resp
+}
+
+func (r *GetAllNetworkContainersResponse) ToCNS() *cns.GetAllNetworkContainersResponse {
+	resp := &cns.GetAllNetworkContainersResponse{Response: r.GetResponse().ToCNS()}
+	for _, nc := range r.GetNetworkContainers() {
+		resp.NetworkContainers = append(resp.NetworkContainers, nc.ToCNS())
+	}
+	return resp
+}
+
+func GetIPAddressesRequestFromCNS(r cns.GetIPAddressesRequest) *GetIPAddressesRequest {
+	req := &GetIPAddressesRequest{}
+	for _, state := range r.IPConfigStateFilter {
+		req.IpConfigStateFilter = append(req.IpConfigStateFilter, string(state))
+	}
+	return req
+}
+
+func (r *GetIPAddressesRequest) ToCNS() cns.GetIPAddressesRequest {
+	req := cns.GetIPAddressesRequest{}
+	for _, state := range r.GetIpConfigStateFilter() {
+		req.IPConfigStateFilter = append(req.IPConfigStateFilter, types.IPState(state))
+	}
+	return req
+}
+
+//nolint:gocritic // passed by value like the CNS IPAM pool state
+func IPConfigurationStatusFromCNS(s cns.IPConfigurationStatus) *IPConfigurationStatus {
+	status := &IPConfigurationStatus{
+		Id:        s.ID,
+		IpAddress: s.IPAddress,
+		NcId:      s.NCID,
+		State:     string(s.GetState()),
+	}
+	if !s.LastStateTransition.IsZero() {
+		status.LastStateTransition = timestamppb.New(s.LastStateTransition)
+	}
+	if s.PodInfo != nil {
+		status.PodInfo = &PodInfo{
+			InfraContainerId: s.PodInfo.InfraContainerID(),
+			InterfaceId:      s.PodInfo.InterfaceID(),
+			Name:             s.PodInfo.Name(),
+			Namespace:        s.PodInfo.Namespace(),
+		}
+	}
+	return status
+}
+
+func (s *IPConfigurationStatus) ToCNS() cns.IPConfigurationStatus {
+	status := cns.IPConfigurationStatus{
+		ID:        s.GetId(),
+		IPAddress: s.GetIpAddress(),
+		NCID:      s.GetNcId(),
+	}
+	status.SetState(types.IPState(s.GetState()))
+	status.LastStateTransition = time.Time{}
+	if s.GetLastStateTransition() != nil {
+		status.LastStateTransition = s.GetLastStateTransition().AsTime()
+	}
+	if info := s.GetPodInfo(); info != nil {
+		status.PodInfo = cns.NewPodInfo(info.GetInfraContainerId(), info.GetInterfaceId(), info.GetName(), info.GetNamespace())
+	}
+	return status
+}
+
+func podIPInfoFromCNS(info *cns.PodIpInfo) *PodIPInfo {
+	return &PodIPInfo{
+		PodIpConfig:                     ipSubnetFromCNS(info.PodIPConfig),
+		NetworkContainerPrimaryIpConfig: ipConfigurationFromCNS(info.NetworkContainerPrimaryIPConfig),
+		HostPrimaryIpInfo: &HostIPInfo{
+			Gateway:   info.HostPrimaryIPInfo.Gateway,
+			PrimaryIp: info.HostPrimaryIPInfo.PrimaryIP,
+			Subnet:    info.HostPrimaryIPInfo.Subnet,
+		},
+		NicType:           string(info.NICType),
+		InterfaceName:     info.InterfaceName,
+		MacAddress:        info.MacAddress,
+		SkipDefaultRoutes: info.SkipDefaultRoutes,
+		Routes:            routesFromCNS(info.Routes),
+	}
+}
+
+func (info *PodIPInfo) toCNS() cns.PodIpInfo {
+	return cns.PodIpInfo{
+		PodIPConfig:                     info.GetPodIpConfig().toCNS(),
+		NetworkContainerPrimaryIPConfig: info.GetNetworkContainerPrimaryIpConfig().toCNS(),
+		HostPrimaryIPInfo: cns.HostIPInfo{
+			Gateway:   info.GetHostPrimaryIpInfo().GetGateway(),
+			PrimaryIP: info.GetHostPrimaryIpInfo().GetPrimaryIp(),
+			Subnet:    info.GetHostPrimaryIpInfo().GetSubnet(),
+		},
+		NICType:           cns.NICType(info.GetNicType()),
+		InterfaceName:     info.GetInterfaceName(),
+		MacAddress:        info.GetMacAddress(),
+		SkipDefaultRoutes: info.GetSkipDefaultRoutes(),
+		Routes:            routesToCNS(info.GetRoutes()),
+	}
+}
+
+func ipSubnetFromCNS(s cns.IPSubnet) *IPSubnet {
+	return &IPSubnet{IpAddress: s.IPAddress, PrefixLength: uint32(s.PrefixLength)}
+}
+
+func (s *IPSubnet) toCNS() cns.IPSubnet {
+	return cns.IPSubnet{IPAddress: s.GetIpAddress(), PrefixLength: uint8(s.GetPrefixLength())}
+}
+
+func ipSubnetsFromCNS(subnets []cns.IPSubnet) []*IPSubnet {
+	var out []*IPSubnet
+	for _, s := range subnets {
+		out = append(out, ipSubnetFromCNS(s))
+	}
+	return out
+}
+
+func ipSubnetsToCNS(subnets []*IPSubnet) []cns.IPSubnet {
+	var out []cns.IPSubnet
+	for _, s := range subnets {
+		out = append(out, s.toCNS())
+	}
+	return out
+}
+
+func ipConfigurationFromCNS(c cns.IPConfiguration) *IPConfiguration {
+	return &IPConfiguration{
+		IpSubnet:         ipSubnetFromCNS(c.IPSubnet),
+		DnsServers:       c.DNSServers,
+		GatewayIpAddress: c.GatewayIPAddress,
+	}
+}
+
+func (c *IPConfiguration) toCNS() cns.IPConfiguration {
+	return cns.IPConfiguration{
+		IPSubnet:         c.GetIpSubnet().toCNS(),
+		DNSServers:       c.GetDnsServers(),
+		GatewayIPAddress: c.GetGatewayIpAddress(),
+	}
+}
+
+func routesFromCNS(routes []cns.Route) []*Route {
+	var out []*Route
+	for _, r := range routes {
+		out = append(out, &Route{IpAddress: r.IPAddress, GatewayIpAddress: r.GatewayIPAddress, InterfaceToUse: r.InterfaceToUse})
+	}
+	return out
+}
+
+func routesToCNS(routes []*Route) []cns.Route {
+	var out []cns.Route
+	for _, r := range routes {
+		out = append(out, cns.Route{IPAddress: r.GetIpAddress(), GatewayIPAddress: r.GetGatewayIpAddress(), InterfaceToUse: r.GetInterfaceToUse()})
+	}
+	return out
+}
+
+func multiTenancyInfoFromCNS(m cns.MultiTenancyInfo) *MultiTenancyInfo {
+	return &MultiTenancyInfo{EncapType: m.EncapType, Id: int64(m.ID)}
+}
+
+func (m *MultiTenancyInfo) toCNS() cns.MultiTenancyInfo {
+	return cns.MultiTenancyInfo{EncapType: m.GetEncapType(), ID: int(m.GetId())}
+}
+
+func networkInterfaceInfoFromCNS(n cns.NetworkInterfaceInfo) *NetworkInterfaceInfo {
+	return &NetworkInterfaceInfo{NicType: string(n.NICType), MacAddress: n.MACAddress}
+}
+
+func (n *NetworkInterfaceInfo) toCNS() cns.NetworkInterfaceInfo {
+	return cns.NetworkInterfaceInfo{NICType: cns.NICType(n.GetNicType()), MACAddress: n.GetMacAddress()}
+}
@@ -0,0 +1,96 @@
+package v1alpha
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	nncv1alpha "github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// roundTrip marshals the message to the wire and back.
+func roundTrip[T proto.Message](t *testing.T, m, out T) T {
+	t.Helper()
+	b, err := proto.Marshal(m)
+	require.NoError(t, err)
+	require.NoError(t, proto.Unmarshal(b, out))
+	return out
+}
+
+func TestCreateNetworkContainerRequestConversion(t *testing.T) {
+	ipConfig := cns.IPConfiguration{
+		IPSubnet:         cns.IPSubnet{IPAddress: "10.0.0.4", PrefixLength: 24},
+		DNSServers:       []string{"168.63.129.16"},
+		GatewayIPAddress: "10.0.0.1",
+	}
+	req := &cns.CreateNetworkContainerRequest{
+		HostPrimaryIP:              "10.224.0.4",
+		Version:                    "2",
+		NetworkContainerType:       cns.Docker,
+		NetworkContainerid:         "f47ac10b-58cc-0372-8567-0e02b2c3d479",
+		PrimaryInterfaceIdentifier: "10.0.0.4",
+		LocalIPConfiguration:       ipConfig,
+		OrchestratorContext:        json.RawMessage(`{"PodName":"pod","PodNamespace":"ns"}`),
+		IPConfiguration:            ipConfig,
+		SecondaryIPConfigs: map[string]cns.SecondaryIPConfig{
+			"uuid": {IPAddress: "10.0.0.5", NCVersion: 2},
+		},
+		MultiTenancyInfo:           cns.MultiTenancyInfo{EncapType: "Vlan", ID: 1},
+		CnetAddressSpace:           []cns.IPSubnet{{IPAddress: "10.1.0.0", PrefixLength: 16}},
+		Routes:                     []cns.Route{{IPAddress: "10.2.0.0/16", GatewayIPAddress: "10.0.0.1"}},
+		AllowHostToNCCommunication: true,
+		EndpointPolicies: []cns.NetworkContainerRequestPolicies{
+			{Type: "ACL", EndpointType: "APIPA", Settings: json.RawMessage(`{"Action":"Allow"}`)},
+		},
+		NCStatus:             nncv1alpha.NCUpdateSubnetFull,
+		NetworkInterfaceInfo: cns.NetworkInterfaceInfo{NICType: cns.DelegatedVMNIC, MACAddress: "00:0d:3a:00:00:01"},
+	}
+
+	got := roundTrip(t, CreateNetworkContainerRequestFromCNS(req), &CreateNetworkContainerRequest{}).ToCNS()
+	assert.Equal(t, req, got)
+}
+
+func TestIPConfigsResponseConversion(t *testing.T) {
+	resp := &cns.IPConfigsResponse{
+		PodIPInfo: []cns.PodIpInfo{
+			{
+				PodIPConfig: cns.IPSubnet{IPAddress: "10.0.0.5", PrefixLength: 24},
+				NetworkContainerPrimaryIPConfig: cns.IPConfiguration{
+					IPSubnet:         cns.IPSubnet{IPAddress: "10.0.0.4", PrefixLength: 24},
+					GatewayIPAddress: "10.0.0.1",
+				},
+				HostPrimaryIPInfo: cns.HostIPInfo{Gateway: "10.224.0.1", PrimaryIP: "10.224.0.4", Subnet: "10.224.0.0/16"},
+				NICType:           cns.InfraNIC,
+			},
+		},
+		Response: cns.Response{ReturnCode: types.Success, Message: "ok"},
+	}
+
+	got := roundTrip(t, IPConfigsResponseFromCNS(resp), &IPConfigsResponse{}).ToCNS()
+	assert.Equal(t, resp, got)
+}
+
+func TestIPConfigurationStatusConversion(t *testing.T) {
+	status := cns.IPConfigurationStatus{
+		ID:        "uuid",
+		IPAddress: "10.0.0.5",
+		NCID:      "nc",
+		PodInfo:   cns.NewPodInfo("infra", "infra-eth0", "pod", "ns"),
+	}
+	status.SetState(types.Assigned)
+	status.LastStateTransition = time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	got := roundTrip(t, IPConfigurationStatusFromCNS(status), &IPConfigurationStatus{}).ToCNS()
+	assert.True(t, status.Equals(got), "expected %s, got %s", status, got)
+	assert.Equal(t, status.LastStateTransition, got.LastStateTransition)
+
+	// IPs which are not assigned have no Pod.
+	got = (&IPConfigurationStatus{State: string(types.Available)}).ToCNS()
+	assert.Nil(t, got.PodInfo)
+	assert.True(t, got.LastStateTransition.IsZero())
+}
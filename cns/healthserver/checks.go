@@ -0,0 +1,117 @@
+package healthserver
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// storeProbeExtension is appended to the store path to name the probe file of StoreWritable.
+const storeProbeExtension = ".probe"
+
+// Checks is a set of named health checks, which can be added to while it is served.
+// It serves the aggregated result of the checks at its root, with their individual results
+// when the verbose query parameter is set, and the result of each check at its name.
+type Checks struct {
+	sync.RWMutex
+	checks map[string]healthz.Checker
+}
+
+// NewChecks creates an empty set of Checks, which is healthy.
+func NewChecks() *Checks {
+	return &Checks{checks: map[string]healthz.Checker{}}
+}
+
+// Add adds the named check, replacing any check of the same name.
+func (c *Checks) Add(name string, check healthz.Checker) {
+	c.Lock()
+	defer c.Unlock()
+	c.checks[name] = check
+}
+
+func (c *Checks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.RLock()
+	checks := make(map[string]healthz.Checker, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.RUnlock()
+	(&healthz.Handler{Checks: checks}).ServeHTTP(w, r)
+}
+
+// Started checks that a component has started.
+func Started(isStarted func() bool) healthz.Checker {
+	return func(*http.Request) error {
+		if !isStarted() {
+			return errors.New("not started")
+		}
+		return nil
+	}
+}
+
+// NMAgentReachable checks that NMAgent answers through wireserver within the timeout.
+func NMAgentReachable(nma interface {
+	SupportedAPIs(context.Context) ([]string, error)
+}, timeout time.Duration,
+) healthz.Checker {
+	return func(r *http.Request) error {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		if _, err := nma.SupportedAPIs(ctx); err != nil {
+			return errors.Wrap(err, "nmagent is unreachable")
+		}
+		return nil
+	}
+}
+
+// StoreWritable checks that a probe file can be written and synced next to the store within the timeout,
+// so that a store on a full, read-only, or hung disk fails the check. The store itself is not written.
+// Checks fail without writing while a previous write is still blocked.
+func StoreWritable(storePath string, timeout time.Duration) healthz.Checker {
+	probePath := storePath + storeProbeExtension
+	return writableWithin(func() error { return writeProbe(probePath) }, timeout)
+}
+
+// writableWithin checks that the write completes within the timeout, and is not repeated while it is blocked.
+func writableWithin(write func() error, timeout time.Duration) healthz.Checker {
+	var pending atomic.Bool
+	return func(*http.Request) error {
+		if !pending.CompareAndSwap(false, true) {
+			return errors.New("store is blocked by a previous write")
+		}
+		done := make(chan error, 1)
+		go func() {
+			defer pending.Store(false)
+			done <- write()
+		}()
+		select {
+		case err := <-done:
+			return errors.Wrap(err, "failed to write to store")
+		case <-time.After(timeout):
+			return errors.Errorf("store write did not complete in %s", timeout)
+		}
+	}
+}
+
+func writeProbe(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600) //nolint:gomnd // file mode
+	if err != nil {
+		return errors.Wrap(err, "failed to create probe file")
+	}
+	if _, err = f.WriteString(time.Now().Format(time.RFC3339Nano)); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to write probe file")
+	}
+	return errors.Wrap(os.Remove(path), "failed to remove probe file")
+}
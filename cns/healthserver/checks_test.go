@@ -0,0 +1,92 @@
+package healthserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t *testing.T, h http.Handler, target string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+	return rec.Code, rec.Body.String()
+}
+
+func TestChecks(t *testing.T) {
+	checks := NewChecks()
+	code, _ := serve(t, checks, "/")
+	assert.Equal(t, http.StatusOK, code)
+
+	started := false
+	checks.Add("nnc", Started(func() bool { return started }))
+	checks.Add("store", func(*http.Request) error { return nil })
+
+	code, body := serve(t, checks, "/")
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Contains(t, body, "[-]nnc failed")
+	code, _ = serve(t, checks, "/nnc")
+	assert.Equal(t, http.StatusInternalServerError, code)
+
+	started = true
+	code, body = serve(t, checks, "/?verbose")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "[+]nnc ok")
+	assert.Contains(t, body, "[+]store ok")
+}
+
+type fakeSupportedAPIs func(context.Context) ([]string, error)
+
+func (f fakeSupportedAPIs) SupportedAPIs(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+func TestNMAgentReachable(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	check := NMAgentReachable(fakeSupportedAPIs(func(context.Context) ([]string, error) { return nil, nil }), time.Second)
+	require.NoError(t, check(req))
+
+	check = NMAgentReachable(fakeSupportedAPIs(func(ctx context.Context) ([]string, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}), time.Millisecond)
+	require.ErrorIs(t, check(req), context.DeadlineExceeded)
+}
+
+func TestStoreWritable(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	storePath := filepath.Join(t.TempDir(), "azure-cns.json")
+	require.NoError(t, StoreWritable(storePath, time.Second)(req))
+	// neither the store nor the probe file are left behind.
+	entries, err := os.ReadDir(filepath.Dir(storePath))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	require.Error(t, StoreWritable(filepath.Join(storePath, "missing", "azure-cns.json"), time.Second)(req))
+}
+
+func TestWritableWithin(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	unblock := make(chan struct{})
+	check := writableWithin(func() error {
+		<-unblock
+		return errors.New("write failed")
+	}, time.Millisecond)
+	require.Error(t, check(req))
+	// the blocked write is not repeated.
+	require.ErrorContains(t, check(req), "blocked by a previous write")
+
+	close(unblock)
+	require.Eventually(t, func() bool {
+		err := check(req)
+		return err != nil && err.Error() == "failed to write to store: write failed"
+	}, time.Second, time.Millisecond)
+}
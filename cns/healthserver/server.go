@@ -6,14 +6,17 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
-	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
-func Start(log *zap.Logger, addr string) {
+// Start serves the liveness checks at /healthz, the readiness checks at /readyz, and the metrics.
+func Start(log *zap.Logger, addr string, liveness, readiness *Checks) {
 	e := echo.New()
 	e.HideBanner = true
-	e.GET("/healthz", echo.WrapHandler(http.StripPrefix("/healthz", &healthz.Handler{})))
+	e.GET("/healthz", echo.WrapHandler(http.StripPrefix("/healthz", liveness)))
+	e.GET("/healthz/*", echo.WrapHandler(http.StripPrefix("/healthz", liveness)))
+	e.GET("/readyz", echo.WrapHandler(http.StripPrefix("/readyz", readiness)))
+	e.GET("/readyz/*", echo.WrapHandler(http.StripPrefix("/readyz", readiness)))
 	e.GET("/metrics", echo.WrapHandler(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{
 		ErrorHandling: promhttp.HTTPErrorOnError,
 	})))
@@ -41,6 +41,14 @@ var (
 		},
 		[]string{subnetLabel, subnetCIDRLabel, podnetARMIDLabel},
 	)
+	ipamCoolingIPCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cx_ipam_cooling_ips",
+			Help:        "IPs released by Pods and quarantined before they are available again (Cooling).",
+			ConstLabels: prometheus.Labels{customerMetricLabel: customerMetricLabelValue},
+		},
+		[]string{subnetLabel, subnetCIDRLabel, podnetARMIDLabel},
+	)
 	ipamCurrentAvailableIPcount = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cx_ipam_current_available_ips",
@@ -120,6 +128,20 @@ var (
 		},
 		[]string{subnetLabel, subnetCIDRLabel, podnetARMIDLabel},
 	)
+	ipamPredictedDemandIPCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cx_ipam_predicted_demand_ips",
+			Help: "IPs predicted to be assigned to Pods within the prediction window.",
+		},
+		[]string{subnetLabel, subnetCIDRLabel, podnetARMIDLabel},
+	)
+	ipamActualDemandIPCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cx_ipam_actual_demand_ips",
+			Help: "IPs assigned to Pods, net of releases, over the last prediction window.",
+		},
+		[]string{subnetLabel, subnetCIDRLabel, podnetARMIDLabel},
+	)
 	ipamTotalIPCount = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cx_ipam_total_ips",
@@ -135,11 +157,14 @@ func init() {
 		ipamAllocatedIPCount,
 		ipamAvailableIPCount,
 		ipamBatchSize,
+		ipamCoolingIPCount,
 		ipamCurrentAvailableIPcount,
 		ipamExpectedAvailableIPCount,
 		ipamMaxIPCount,
 		ipamPendingProgramIPCount,
 		ipamPendingReleaseIPCount,
+		ipamPredictedDemandIPCount,
+		ipamActualDemandIPCount,
 		ipamPrimaryIPCount,
 		ipamRequestedIPConfigCount,
 		ipamSecondaryIPCount,
@@ -154,6 +179,7 @@ func observeIPPoolState(state ipPoolState, meta metaState) {
 	ipamAllocatedIPCount.WithLabelValues(labels...).Set(float64(state.allocatedToPods))
 	ipamAvailableIPCount.WithLabelValues(labels...).Set(float64(state.available))
 	ipamBatchSize.WithLabelValues(labels...).Set(float64(meta.batch))
+	ipamCoolingIPCount.WithLabelValues(labels...).Set(float64(state.cooling))
 	ipamCurrentAvailableIPcount.WithLabelValues(labels...).Set(float64(state.currentAvailableIPs))
 	ipamExpectedAvailableIPCount.WithLabelValues(labels...).Set(float64(state.expectedAvailableIPs))
 	ipamMaxIPCount.WithLabelValues(labels...).Set(float64(meta.max))
@@ -169,3 +195,9 @@ func observeIPPoolState(state ipPoolState, meta metaState) {
 		ipamSubnetExhaustionState.WithLabelValues(labels...).Set(float64(subnetIPNotExhausted))
 	}
 }
+
+func observeIPDemand(predicted, actual int64, meta metaState) {
+	labels := []string{meta.subnet, meta.subnetCIDR, meta.subnetARMID}
+	ipamPredictedDemandIPCount.WithLabelValues(labels...).Set(float64(predicted))
+	ipamActualDemandIPCount.WithLabelValues(labels...).Set(float64(actual))
+}
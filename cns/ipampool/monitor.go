@@ -34,6 +34,11 @@ type nodeNetworkConfigSpecUpdater interface {
 	PatchSpec(context.Context, *v1alpha.NodeNetworkConfigSpec, string) (*v1alpha.NodeNetworkConfig, error)
 }
 
+// cooledIPReleaser is implemented by the services which quarantine released IPs as Cooling.
+type cooledIPReleaser interface {
+	ReleaseCooledIPConfigs()
+}
+
 // metaState is the Monitor's configuration state for the IP pool.
 type metaState struct {
 	batch              int64
@@ -43,6 +48,7 @@ type metaState struct {
 	minFreeCount       int64
 	notInUseCount      int64
 	primaryIPAddresses map[string]struct{}
+	scaler             v1alpha.Scaler
 	subnet             string
 	subnetARMID        string
 	subnetCIDR         string
@@ -51,6 +57,13 @@ type metaState struct {
 type Options struct {
 	RefreshDelay time.Duration
 	MaxIPs       int64
+	// PredictiveScaling grows the free IP targets by the IP demand predicted from the recent
+	// rate of IP assignments, and requests as many batches as needed to meet them at once.
+	PredictiveScaling bool
+	// PredictionWindow is how far ahead IP demand is predicted, usually the DNC round trip latency.
+	PredictionWindow time.Duration
+	// Policy decides when and by how much to scale the pool. Defaults to the ThresholdPolicy.
+	Policy ScalingPolicy
 }
 
 type Monitor struct {
@@ -63,6 +76,8 @@ type Monitor struct {
 	nncSource   chan v1alpha.NodeNetworkConfig
 	started     chan interface{}
 	once        sync.Once
+	predictor   *demandPredictor
+	now         func() time.Time
 }
 
 func NewMonitor(httpService cns.HTTPService, nnccli nodeNetworkConfigSpecUpdater, cssSource <-chan v1alpha1.ClusterSubnetState, opts *Options) *Monitor {
@@ -72,13 +87,31 @@ func NewMonitor(httpService cns.HTTPService, nnccli nodeNetworkConfigSpecUpdater
 	if opts.MaxIPs < 1 {
 		opts.MaxIPs = DefaultMaxIPs
 	}
-	return &Monitor{
+	if opts.Policy == nil {
+		opts.Policy = ThresholdPolicy{}
+	}
+	pm := &Monitor{
 		opts:        opts,
 		httpService: httpService,
 		nnccli:      nnccli,
 		cssSource:   cssSource,
 		nncSource:   make(chan v1alpha.NodeNetworkConfig),
 		started:     make(chan interface{}),
+		now:         time.Now,
+	}
+	if opts.PredictiveScaling {
+		pm.predictor = newDemandPredictor(opts.PredictionWindow)
+	}
+	return pm
+}
+
+// IsStarted reports whether the Monitor has received its first NodeNetworkConfig and is reconciling the pool.
+func (pm *Monitor) IsStarted() bool {
+	select {
+	case <-pm.started:
+		return true
+	default:
+		return false
 	}
 }
 
@@ -142,9 +175,8 @@ func (pm *Monitor) Start(ctx context.Context) error {
 			}
 
 			scaler := nnc.Status.Scaler
-			pm.metastate.batch = scaler.BatchSize
+			pm.metastate.scaler = scaler
 			pm.metastate.max = scaler.MaxIPCount
-			pm.metastate.minFreeCount, pm.metastate.maxFreeCount = CalculateMinFreeIPs(scaler), CalculateMaxFreeIPs(scaler)
 			pm.once.Do(func() {
 				pm.spec = nnc.Spec // set the spec from the NNC initially (afterwards we write the Spec so we know target state).
 				logger.Printf("[ipam-pool-monitor] set initial pool spec %+v", pm.spec)
@@ -165,14 +197,19 @@ type ipPoolState struct {
 	allocatedToPods int64
 	// available are the IPs in state "Available".
 	available int64
-	// currentAvailableIPs are the current available IPs: allocated - assigned - pendingRelease.
+	// cooling are the IPs in state "Cooling", which are quarantined after release and are not free yet.
+	cooling int64
+	// currentAvailableIPs are the current available IPs: allocated - assigned - reserved - cooling - pendingRelease.
 	currentAvailableIPs int64
-	// expectedAvailableIPs are the "future" available IPs, if the requested IP count is honored: requested - assigned.
+	// expectedAvailableIPs are the "future" available IPs, if the requested IP count is honored:
+	// requested - assigned - reserved - cooling.
 	expectedAvailableIPs int64
 	// pendingProgramming are the IPs in state "PendingProgramming".
 	pendingProgramming int64
 	// pendingRelease are the IPs in state "PendingRelease".
 	pendingRelease int64
+	// reserved are the IPs in state "Reserved", which are held for Pods and are not free.
+	reserved int64
 	// requestedIPs are the IPs CNS has requested that it be allocated by DNC.
 	requestedIPs int64
 	// secondaryIPs are all the IPs given to CNS by DNC, not including the primary IP of the NC.
@@ -195,34 +232,55 @@ func buildIPPoolState(ips map[string]cns.IPConfigurationStatus, spec v1alpha.Nod
 			state.pendingProgramming++
 		case types.PendingRelease:
 			state.pendingRelease++
+		case types.Reserved:
+			state.reserved++
+		case types.Cooling:
+			state.cooling++
 		}
 	}
-	state.currentAvailableIPs = state.secondaryIPs - state.allocatedToPods - state.pendingRelease
-	state.expectedAvailableIPs = state.requestedIPs - state.allocatedToPods
+	state.currentAvailableIPs = state.secondaryIPs - state.allocatedToPods - state.reserved - state.cooling - state.pendingRelease
+	state.expectedAvailableIPs = state.requestedIPs - state.allocatedToPods - state.reserved - state.cooling
 	return state
 }
 
 var statelogDownsample int
 
 func (pm *Monitor) reconcile(ctx context.Context) error {
+	// IPs are otherwise only released from Cooling when IPs are requested, and would block scaling down.
+	if releaser, ok := pm.httpService.(cooledIPReleaser); ok {
+		releaser.ReleaseCooledIPConfigs()
+	}
 	allocatedIPs := pm.httpService.GetPodIPConfigState()
-	meta := pm.metastate
 	state := buildIPPoolState(allocatedIPs, pm.spec)
+
+	// the policy decides the batch and free IP targets for this iteration.
+	thresholds := pm.opts.Policy.Thresholds(pm.now(), PoolState{
+		Scaler:    pm.metastate.scaler,
+		Exhausted: pm.metastate.exhausted,
+		Assigned:  state.allocatedToPods + state.reserved + state.cooling,
+		Requested: state.requestedIPs,
+	})
+	pm.metastate.batch, pm.metastate.minFreeCount, pm.metastate.maxFreeCount = thresholds.Batch, thresholds.MinFree, thresholds.MaxFree
+	meta := pm.metastate
 	observeIPPoolState(state, meta)
 
+	if pm.predictor != nil {
+		// make room for the IPs we expect to be assigned before DNC can honor another request.
+		predicted := pm.predictor.observe(allocatedIPs, state.allocatedToPods, pm.now())
+		observeIPDemand(predicted, pm.predictor.actual(), meta)
+		// an exhausted subnet can not afford to get ahead of demand.
+		if !meta.exhausted {
+			meta.minFreeCount += predicted
+			meta.maxFreeCount += predicted
+		}
+	}
+
 	// log every 30th reconcile to reduce the AI load. we will always log when the monitor
 	// changes the pool, below.
 	if statelogDownsample = (statelogDownsample + 1) % 30; statelogDownsample == 0 { //nolint:gomnd //downsample by 30
 		logger.Printf("ipam-pool-monitor state: %+v, meta: %+v", state, meta)
 	}
 
-	// if the subnet is exhausted, overwrite the batch/minfree/maxfree in the meta copy for this iteration
-	if meta.exhausted {
-		meta.batch = 1
-		meta.minFreeCount = 1
-		meta.maxFreeCount = 2
-	}
-
 	switch {
 	// pod count is increasing
 	case state.expectedAvailableIPs < meta.minFreeCount:
@@ -269,6 +327,12 @@ func (pm *Monitor) increasePoolSize(ctx context.Context, meta metaState, state i
 	logger.Printf("[ipam-pool-monitor] modResult of (previously requested IP count mod batch size) = %d", modResult)
 
 	tempNNCSpec.RequestedIPCount += batchSize - modResult
+	if pm.predictor != nil {
+		// the predicted demand may need more than one batch to cover.
+		if target := state.allocatedToPods + state.reserved + state.cooling + meta.minFreeCount; target > tempNNCSpec.RequestedIPCount {
+			tempNNCSpec.RequestedIPCount += (target - tempNNCSpec.RequestedIPCount + batchSize - 1) / batchSize * batchSize
+		}
+	}
 	if tempNNCSpec.RequestedIPCount > meta.max {
 		// We don't want to ask for more ips than the max
 		logger.Printf("[ipam-pool-monitor] Requested IP count (%d) is over max limit (%d), requesting max limit instead.", tempNNCSpec.RequestedIPCount, meta.max)
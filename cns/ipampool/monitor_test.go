@@ -9,6 +9,7 @@ import (
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/fakes"
 	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
 	"github.com/stretchr/testify/assert"
 )
@@ -36,6 +37,7 @@ type directUpdatePoolMonitor struct {
 func (d *directUpdatePoolMonitor) Update(nnc *v1alpha.NodeNetworkConfig) error {
 	scaler := nnc.Status.Scaler
 	d.m.spec = nnc.Spec
+	d.m.metastate.scaler = scaler
 	d.m.metastate.minFreeCount, d.m.metastate.maxFreeCount = CalculateMinFreeIPs(scaler), CalculateMaxFreeIPs(scaler)
 	return nil
 }
@@ -77,6 +79,7 @@ func initFakes(state testState, nnccli nodeNetworkConfigSpecUpdater) (*fakes.HTT
 		batch:     state.batch,
 		max:       state.max,
 		exhausted: state.exhausted,
+		scaler:    scalarUnits,
 	}
 	fakecns.PoolMonitor = &directUpdatePoolMonitor{m: poolmonitor}
 	if err := fakecns.SetNumberOfAssignedIPs(state.assigned); err != nil {
@@ -672,3 +675,24 @@ func TestCalculateIPs(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildIPPoolStateReservedAndCooling(t *testing.T) {
+	ips := map[string]cns.IPConfigurationStatus{}
+	for id, state := range map[string]types.IPState{
+		"a": types.Assigned,
+		"b": types.Reserved,
+		"c": types.Available,
+		"d": types.PendingRelease,
+		"e": types.Cooling,
+	} {
+		ip := cns.IPConfigurationStatus{ID: id}
+		ip.SetState(state)
+		ips[id] = ip
+	}
+	state := buildIPPoolState(ips, v1alpha.NodeNetworkConfigSpec{RequestedIPCount: 4})
+	assert.Equal(t, int64(1), state.reserved)
+	assert.Equal(t, int64(1), state.cooling)
+	// reserved and cooling IPs are held and are not free.
+	assert.Equal(t, int64(1), state.currentAvailableIPs)
+	assert.Equal(t, int64(1), state.expectedAvailableIPs)
+}
@@ -0,0 +1,137 @@
+package ipampool
+
+import (
+	"time"
+
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+)
+
+// PoolState is the view of the IP pool that a ScalingPolicy decides on.
+type PoolState struct {
+	// Scaler is the scaling configuration from the NodeNetworkConfig.
+	Scaler v1alpha.Scaler
+	// Exhausted is set if the subnet has run out of IPs.
+	Exhausted bool
+	// Assigned are the IPs currently assigned to, reserved for, or cooling after release by, Pods.
+	Assigned int64
+	// Requested are the IPs CNS has requested that DNC allocate.
+	Requested int64
+}
+
+// Thresholds are the sizing decisions of a ScalingPolicy.
+// The Monitor increases the pool when fewer than MinFree IPs are expected to be free,
+// decreases it when MaxFree or more IPs are free, and scales in multiples of Batch.
+type Thresholds struct {
+	Batch   int64
+	MinFree int64
+	MaxFree int64
+}
+
+// ScalingPolicy decides when, and by how much, the Monitor scales the IP pool.
+type ScalingPolicy interface {
+	Thresholds(now time.Time, state PoolState) Thresholds
+}
+
+// exhaustedThresholds scales one IP at a time when the subnet is exhausted, to conserve the subnet.
+var exhaustedThresholds = Thresholds{Batch: 1, MinFree: 1, MaxFree: 2}
+
+// ThresholdPolicy is the default ScalingPolicy. It keeps the free IPs between the
+// request and release threshold percentages of the batch size set in the Scaler.
+type ThresholdPolicy struct{}
+
+//nolint:gocritic // ignore hugeparam
+func (ThresholdPolicy) Thresholds(_ time.Time, state PoolState) Thresholds {
+	if state.Exhausted {
+		return exhaustedThresholds
+	}
+	return Thresholds{
+		Batch:   state.Scaler.BatchSize,
+		MinFree: CalculateMinFreeIPs(state.Scaler),
+		MaxFree: CalculateMaxFreeIPs(state.Scaler),
+	}
+}
+
+// WarmPoolPolicy keeps a fixed number of free IPs warm, regardless of the batch size,
+// and releases a batch once there is a full batch more than that.
+type WarmPoolPolicy struct {
+	Size int64
+}
+
+//nolint:gocritic // ignore hugeparam
+func (p WarmPoolPolicy) Thresholds(_ time.Time, state PoolState) Thresholds {
+	if state.Exhausted {
+		return exhaustedThresholds
+	}
+	return Thresholds{
+		Batch:   state.Scaler.BatchSize,
+		MinFree: p.Size,
+		MaxFree: p.Size + state.Scaler.BatchSize,
+	}
+}
+
+// ScheduleEntry applies a ScalingPolicy between Start and End, which are offsets from midnight UTC.
+// If End is before Start, the entry wraps around midnight.
+type ScheduleEntry struct {
+	Start  time.Duration
+	End    time.Duration
+	Policy ScalingPolicy
+}
+
+func (e ScheduleEntry) contains(sinceMidnight time.Duration) bool {
+	if e.Start <= e.End {
+		return sinceMidnight >= e.Start && sinceMidnight < e.End
+	}
+	return sinceMidnight >= e.Start || sinceMidnight < e.End
+}
+
+// SchedulePolicy applies the policy of the first ScheduleEntry that contains the time of day,
+// and the Default policy outside of all of them.
+type SchedulePolicy struct {
+	Entries []ScheduleEntry
+	Default ScalingPolicy
+}
+
+//nolint:gocritic // ignore hugeparam
+func (p SchedulePolicy) Thresholds(now time.Time, state PoolState) Thresholds {
+	now = now.UTC()
+	sinceMidnight := now.Sub(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+	for _, e := range p.Entries {
+		if e.contains(sinceMidnight) {
+			return e.Policy.Thresholds(now, state)
+		}
+	}
+	return p.Default.Thresholds(now, state)
+}
+
+// HysteresisPolicy wraps a ScalingPolicy to dampen oscillation: it widens the band between
+// MinFree and MaxFree by a batch, and after the pool is scaled it does not decrease until the
+// Cooldown has passed. The pool is always increased when it is short of free IPs.
+type HysteresisPolicy struct {
+	Policy   ScalingPolicy
+	Cooldown time.Duration
+
+	lastRequested int64
+	lastScaled    time.Time
+}
+
+//nolint:gocritic // ignore hugeparam
+func (p *HysteresisPolicy) Thresholds(now time.Time, state PoolState) Thresholds {
+	t := p.Policy.Thresholds(now, state)
+	if state.Exhausted {
+		return t
+	}
+	t.MaxFree += t.Batch
+
+	// the requested IP count only changes when the pool is scaled.
+	if state.Requested != p.lastRequested {
+		if p.lastRequested != 0 {
+			p.lastScaled = now
+		}
+		p.lastRequested = state.Requested
+	}
+	if now.Sub(p.lastScaled) < p.Cooldown {
+		// never free enough to decrease.
+		t.MaxFree = state.Scaler.MaxIPCount + 1
+	}
+	return t
+}
@@ -0,0 +1,75 @@
+package ipampool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/stretchr/testify/assert"
+)
+
+var testScaler = v1alpha.Scaler{
+	BatchSize:               10,
+	MaxIPCount:              100,
+	RequestThresholdPercent: 50,
+	ReleaseThresholdPercent: 150,
+}
+
+func TestThresholdPolicy(t *testing.T) {
+	p := ThresholdPolicy{}
+	assert.Equal(t, Thresholds{Batch: 10, MinFree: 5, MaxFree: 15}, p.Thresholds(time.Now(), PoolState{Scaler: testScaler}))
+	assert.Equal(t, exhaustedThresholds, p.Thresholds(time.Now(), PoolState{Scaler: testScaler, Exhausted: true}))
+}
+
+func TestWarmPoolPolicy(t *testing.T) {
+	p := WarmPoolPolicy{Size: 3}
+	assert.Equal(t, Thresholds{Batch: 10, MinFree: 3, MaxFree: 13}, p.Thresholds(time.Now(), PoolState{Scaler: testScaler}))
+	assert.Equal(t, exhaustedThresholds, p.Thresholds(time.Now(), PoolState{Scaler: testScaler, Exhausted: true}))
+}
+
+func TestSchedulePolicy(t *testing.T) {
+	p := SchedulePolicy{
+		Entries: []ScheduleEntry{
+			{Start: 8 * time.Hour, End: 18 * time.Hour, Policy: WarmPoolPolicy{Size: 20}},
+			// wraps around midnight.
+			{Start: 22 * time.Hour, End: 2 * time.Hour, Policy: WarmPoolPolicy{Size: 1}},
+		},
+		Default: ThresholdPolicy{},
+	}
+	at := func(hour int) time.Time { return time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC) }
+	state := PoolState{Scaler: testScaler}
+
+	assert.Equal(t, int64(20), p.Thresholds(at(8), state).MinFree)
+	assert.Equal(t, int64(20), p.Thresholds(at(17), state).MinFree)
+	assert.Equal(t, int64(5), p.Thresholds(at(18), state).MinFree)
+	assert.Equal(t, int64(1), p.Thresholds(at(23), state).MinFree)
+	assert.Equal(t, int64(1), p.Thresholds(at(1), state).MinFree)
+	assert.Equal(t, int64(5), p.Thresholds(at(2), state).MinFree)
+	// schedules are in UTC regardless of the location of the time.
+	assert.Equal(t, int64(20), p.Thresholds(at(8).In(time.FixedZone("UTC-5", -5*60*60)), state).MinFree)
+}
+
+func TestHysteresisPolicy(t *testing.T) {
+	p := &HysteresisPolicy{Policy: ThresholdPolicy{}, Cooldown: time.Minute}
+	start := time.Now()
+
+	// the band is widened by a batch.
+	assert.Equal(t, Thresholds{Batch: 10, MinFree: 5, MaxFree: 25}, p.Thresholds(start, PoolState{Scaler: testScaler, Requested: 10}))
+
+	// after an increase, the pool is not decreased during the cooldown.
+	th := p.Thresholds(start.Add(time.Second), PoolState{Scaler: testScaler, Requested: 20})
+	assert.Equal(t, int64(5), th.MinFree)
+	assert.Greater(t, th.MaxFree, testScaler.MaxIPCount)
+
+	// after the cooldown, the widened band applies again.
+	th = p.Thresholds(start.Add(2*time.Minute), PoolState{Scaler: testScaler, Requested: 20})
+	assert.Equal(t, Thresholds{Batch: 10, MinFree: 5, MaxFree: 25}, th)
+
+	// after a decrease, the pool is not decreased again during the cooldown, but is still increased.
+	th = p.Thresholds(start.Add(3*time.Minute), PoolState{Scaler: testScaler, Requested: 10})
+	assert.Equal(t, int64(5), th.MinFree)
+	assert.Greater(t, th.MaxFree, testScaler.MaxIPCount)
+
+	// the exhausted thresholds are not widened.
+	assert.Equal(t, exhaustedThresholds, p.Thresholds(start, PoolState{Scaler: testScaler, Exhausted: true}))
+}
@@ -0,0 +1,96 @@
+package ipampool
+
+import (
+	"math"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+)
+
+const (
+	// DefaultPredictionWindow is the default time that predicted IP demand must cover, which should
+	// be about as long as DNC takes to honor a pool increase.
+	DefaultPredictionWindow = 30 * time.Second
+	// predictorSmoothing is the weight of the newest sample in the moving average of the assignment and release rates.
+	predictorSmoothing = 0.3
+)
+
+type allocationSample struct {
+	at        time.Time
+	allocated int64
+}
+
+// demandPredictor estimates how many IPs will be assigned to Pods on this Node within the next window,
+// based on the recent rate of IPs transitioning to Assigned and being released again (churn).
+type demandPredictor struct {
+	window      time.Duration
+	last        time.Time
+	lastAlloc   int64
+	assignRate  float64
+	releaseRate float64
+	// samples is the history of allocated IPs over the last window, used to observe the actual demand.
+	samples []allocationSample
+}
+
+func newDemandPredictor(window time.Duration) *demandPredictor {
+	if window <= 0 {
+		window = DefaultPredictionWindow
+	}
+	return &demandPredictor{window: window}
+}
+
+// observe ingests the current IP pool and returns the predicted IP demand over the next window.
+func (p *demandPredictor) observe(ips map[string]cns.IPConfigurationStatus, allocatedToPods int64, now time.Time) int64 {
+	if p.last.IsZero() {
+		p.last, p.lastAlloc = now, allocatedToPods
+		p.samples = append(p.samples, allocationSample{at: now, allocated: allocatedToPods})
+		return 0
+	}
+	elapsed := now.Sub(p.last).Seconds()
+	if elapsed <= 0 {
+		return p.predicted()
+	}
+
+	// IPs that have been Assigned since the last observation. IPs that were assigned and released
+	// in between are missed, but they did not contribute to the demand either.
+	var assigned int64
+	for i := range ips {
+		ip := ips[i]
+		if ip.GetState() == types.Assigned && ip.LastStateTransition.After(p.last) {
+			assigned++
+		}
+	}
+	// whatever was assigned and is not reflected in the allocated count has been released.
+	released := p.lastAlloc + assigned - allocatedToPods
+	if released < 0 {
+		released = 0
+	}
+
+	p.assignRate = predictorSmoothing*(float64(assigned)/elapsed) + (1-predictorSmoothing)*p.assignRate
+	p.releaseRate = predictorSmoothing*(float64(released)/elapsed) + (1-predictorSmoothing)*p.releaseRate
+	p.last, p.lastAlloc = now, allocatedToPods
+
+	p.samples = append(p.samples, allocationSample{at: now, allocated: allocatedToPods})
+	for len(p.samples) > 1 && now.Sub(p.samples[1].at) >= p.window {
+		p.samples = p.samples[1:]
+	}
+	return p.predicted()
+}
+
+// predicted is the net IP demand expected over the next window at the current rates.
+func (p *demandPredictor) predicted() int64 {
+	net := p.assignRate - p.releaseRate
+	if net <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(net * p.window.Seconds()))
+}
+
+// actual is the net IP demand observed over the last window.
+func (p *demandPredictor) actual() int64 {
+	if len(p.samples) == 0 {
+		return 0
+	}
+	return p.samples[len(p.samples)-1].allocated - p.samples[0].allocated
+}
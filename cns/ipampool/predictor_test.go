@@ -0,0 +1,71 @@
+package ipampool
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func assignedIPs(n int) map[string]cns.IPConfigurationStatus {
+	ips := map[string]cns.IPConfigurationStatus{}
+	for i := 0; i < n; i++ {
+		ip := cns.IPConfigurationStatus{ID: strconv.Itoa(i)}
+		ip.SetState(types.Assigned)
+		ips[ip.ID] = ip
+	}
+	return ips
+}
+
+func TestDemandPredictor(t *testing.T) {
+	p := newDemandPredictor(10 * time.Second)
+	start := time.Now().Add(-2 * time.Second)
+
+	// the first observation only sets the baseline.
+	assert.Equal(t, int64(0), p.observe(nil, 0, start))
+
+	// 10 IPs assigned in a second predicts demand over the window.
+	predicted := p.observe(assignedIPs(10), 10, start.Add(time.Second))
+	assert.Equal(t, int64(30), predicted) // ceil(0.3 * 10/s * 10s)
+	assert.Equal(t, int64(10), p.actual())
+
+	// with nothing assigned and everything released the prediction decays to zero.
+	for i := 2; i < 20; i++ {
+		predicted = p.observe(nil, 0, start.Add(time.Duration(i)*time.Second))
+	}
+	assert.Equal(t, int64(0), predicted)
+	// the burst has fallen out of the window.
+	assert.Equal(t, int64(0), p.actual())
+}
+
+func TestPredictivePoolSizeIncrease(t *testing.T) {
+	in := testState{
+		allocated:               10,
+		assigned:                8,
+		batch:                   10,
+		max:                     100,
+		releaseThresholdPercent: 150,
+		requestThresholdPercent: 50,
+	}
+	_, fakerc, poolmonitor := initFakes(in, nil)
+	require.NoError(t, fakerc.Reconcile(true))
+
+	// pin the predictor at 1 IP/s over a 30s window by stopping the clock at its last observation.
+	now := time.Now()
+	poolmonitor.now = func() time.Time { return now }
+	poolmonitor.predictor = &demandPredictor{window: 30 * time.Second, assignRate: 1, last: now}
+
+	require.NoError(t, poolmonitor.reconcile(context.Background()))
+	// 8 assigned + 5 min free + 30 predicted, rounded up to the batch.
+	assert.Equal(t, int64(50), poolmonitor.spec.RequestedIPCount)
+
+	// once honored, the pool is not scaled down while the demand is still predicted.
+	require.NoError(t, fakerc.Reconcile(true))
+	require.NoError(t, poolmonitor.reconcile(context.Background()))
+	assert.Equal(t, int64(50), poolmonitor.spec.RequestedIPCount)
+}
@@ -0,0 +1,193 @@
+// Package iphistory keeps a bounded, persisted history of the state changes of the IPs in the CNS IPAM pool,
+// so that it is possible to find out which Pod had an IP at some point in the past.
+package iphistory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultEventsPerIP is the default number of state changes kept for each IP.
+	DefaultEventsPerIP = 16
+	// DefaultMaxIPs is the default number of IPs that state changes are kept for.
+	DefaultMaxIPs = 1024
+	// DefaultFlushInterval is the default interval at which the history is persisted.
+	DefaultFlushInterval = 30 * time.Second
+
+	storeKey = "IPHistory"
+)
+
+// Recorder keeps a ring buffer of the most recent state changes of each IP. When there are more than
+// maxIPs IPs, the history of the IP that changed least recently is dropped.
+type Recorder struct {
+	sync.Mutex
+	eventsPerIP int
+	maxIPs      int
+	// history is the ring of events by IP address, oldest first.
+	history map[string][]cns.IPHistoryEvent
+	store   store.KeyValueStore
+	dirty   bool
+}
+
+// New creates a Recorder that persists to the store, if it is not nil.
+// eventsPerIP and maxIPs default if they are not positive.
+func New(kvs store.KeyValueStore, eventsPerIP, maxIPs int) *Recorder {
+	if eventsPerIP <= 0 {
+		eventsPerIP = DefaultEventsPerIP
+	}
+	if maxIPs <= 0 {
+		maxIPs = DefaultMaxIPs
+	}
+	return &Recorder{
+		eventsPerIP: eventsPerIP,
+		maxIPs:      maxIPs,
+		history:     map[string][]cns.IPHistoryEvent{},
+		store:       kvs,
+	}
+}
+
+// Record adds the event to the history of its IP.
+func (r *Recorder) Record(event *cns.IPHistoryEvent) {
+	r.Lock()
+	defer r.Unlock()
+	events, found := r.history[event.IPAddress]
+	if !found && len(r.history) >= r.maxIPs {
+		r.evictUntransacted()
+	}
+	if len(events) >= r.eventsPerIP {
+		events = events[len(events)-r.eventsPerIP+1:]
+	}
+	r.history[event.IPAddress] = append(events, *event)
+	r.dirty = true
+}
+
+// evictUntransacted drops the history of the IP that changed least recently.
+func (r *Recorder) evictUntransacted() {
+	var oldestIP string
+	var oldest time.Time
+	for ip, events := range r.history {
+		if last := events[len(events)-1].Timestamp; oldestIP == "" || last.Before(oldest) {
+			oldestIP, oldest = ip, last
+		}
+	}
+	delete(r.history, oldestIP)
+}
+
+// Query returns the events that match the request, oldest first.
+func (r *Recorder) Query(req *cns.GetIPHistoryRequest) []cns.IPHistoryEvent {
+	r.Lock()
+	defer r.Unlock()
+	var candidates map[string][]cns.IPHistoryEvent
+	if req.IPAddress != "" {
+		candidates = map[string][]cns.IPHistoryEvent{req.IPAddress: r.history[req.IPAddress]}
+	} else {
+		candidates = r.history
+	}
+	matches := []cns.IPHistoryEvent{}
+	for _, events := range candidates {
+		for i := range events {
+			if matchesRequest(&events[i], req) {
+				matches = append(matches, events[i])
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Timestamp.Before(matches[j].Timestamp)
+	})
+	return matches
+}
+
+func matchesRequest(event *cns.IPHistoryEvent, req *cns.GetIPHistoryRequest) bool {
+	if req.PodName != "" && event.PodName != req.PodName {
+		return false
+	}
+	if req.PodNamespace != "" && event.PodNamespace != req.PodNamespace {
+		return false
+	}
+	if !req.Since.IsZero() && event.Timestamp.Before(req.Since) {
+		return false
+	}
+	if !req.Until.IsZero() && event.Timestamp.After(req.Until) {
+		return false
+	}
+	return true
+}
+
+// Restore loads the persisted history, if there is any.
+func (r *Recorder) Restore() error {
+	if r.store == nil || !r.store.Exists() {
+		return nil
+	}
+	history := map[string][]cns.IPHistoryEvent{}
+	if err := r.store.Read(storeKey, &history); err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) || errors.Is(err, store.ErrStoreEmpty) {
+			return nil
+		}
+		return errors.Wrap(err, "failed to read IP history")
+	}
+	r.Lock()
+	defer r.Unlock()
+	for ip, events := range history {
+		if len(events) == 0 {
+			continue
+		}
+		if len(events) > r.eventsPerIP {
+			events = events[len(events)-r.eventsPerIP:]
+		}
+		if _, found := r.history[ip]; !found && len(r.history) >= r.maxIPs {
+			r.evictUntransacted()
+		}
+		r.history[ip] = events
+	}
+	return nil
+}
+
+// Flush persists the history if it has changed since it was last persisted.
+func (r *Recorder) Flush() error {
+	if r.store == nil {
+		return nil
+	}
+	r.Lock()
+	defer r.Unlock()
+	if !r.dirty {
+		return nil
+	}
+	if err := r.store.Write(storeKey, r.history); err != nil {
+		return errors.Wrap(err, "failed to write IP history")
+	}
+	if err := r.store.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush IP history")
+	}
+	r.dirty = false
+	return nil
+}
+
+// Run persists the history every interval until the context is canceled, and once more before returning.
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := r.Flush(); err != nil {
+				logger.Errorf("[iphistory] %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				logger.Errorf("[iphistory] %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package iphistory
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/processlock"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC)
+
+func event(ip string, minute int, state types.IPState, pod string) *cns.IPHistoryEvent {
+	return &cns.IPHistoryEvent{
+		Timestamp:    start.Add(time.Duration(minute) * time.Minute),
+		IPAddress:    ip,
+		State:        state,
+		PodName:      pod,
+		PodNamespace: "default",
+	}
+}
+
+func TestRecorderQuery(t *testing.T) {
+	r := New(nil, 0, 0)
+	r.Record(event("10.0.0.1", 1, types.Assigned, "a"))
+	r.Record(event("10.0.0.2", 2, types.Assigned, "b"))
+	r.Record(event("10.0.0.1", 3, types.Available, "a"))
+	r.Record(event("10.0.0.1", 4, types.Assigned, "c"))
+
+	tests := []struct {
+		name string
+		req  cns.GetIPHistoryRequest
+		want []int
+	}{
+		{"all", cns.GetIPHistoryRequest{}, []int{1, 2, 3, 4}},
+		{"by ip", cns.GetIPHistoryRequest{IPAddress: "10.0.0.1"}, []int{1, 3, 4}},
+		{"by pod", cns.GetIPHistoryRequest{PodName: "a", PodNamespace: "default"}, []int{1, 3}},
+		{"by namespace", cns.GetIPHistoryRequest{PodNamespace: "other"}, nil},
+		{"by time", cns.GetIPHistoryRequest{Since: start.Add(2 * time.Minute), Until: start.Add(3 * time.Minute)}, []int{2, 3}},
+		{"unknown ip", cns.GetIPHistoryRequest{IPAddress: "10.0.0.3"}, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			events := r.Query(&tt.req)
+			minutes := []int{}
+			for i := range events {
+				minutes = append(minutes, int(events[i].Timestamp.Sub(start)/time.Minute))
+			}
+			if tt.want == nil {
+				tt.want = []int{}
+			}
+			assert.Equal(t, tt.want, minutes)
+		})
+	}
+}
+
+func TestRecorderBounds(t *testing.T) {
+	r := New(nil, 2, 2)
+	r.Record(event("10.0.0.1", 1, types.Assigned, "a"))
+	r.Record(event("10.0.0.1", 2, types.Available, "a"))
+	r.Record(event("10.0.0.1", 3, types.Assigned, "b"))
+	// only the most recent events of an IP are kept.
+	events := r.Query(&cns.GetIPHistoryRequest{IPAddress: "10.0.0.1"})
+	require.Len(t, events, 2)
+	assert.Equal(t, "a", events[0].PodName)
+	assert.Equal(t, types.Available, events[0].State)
+
+	r.Record(event("10.0.0.2", 4, types.Assigned, "c"))
+	r.Record(event("10.0.0.1", 5, types.Available, "b"))
+	// the IP that changed least recently is dropped.
+	r.Record(event("10.0.0.3", 6, types.Assigned, "d"))
+	assert.Empty(t, r.Query(&cns.GetIPHistoryRequest{IPAddress: "10.0.0.2"}))
+	assert.Len(t, r.Query(&cns.GetIPHistoryRequest{}), 3)
+}
+
+func TestRecorderPersistence(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "iphistory.json")
+	kvs, err := store.NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+
+	r := New(kvs, 0, 0)
+	require.NoError(t, r.Restore())
+	r.Record(event("10.0.0.1", 1, types.Assigned, "a"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx, time.Hour)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	kvs, err = store.NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	r = New(kvs, 0, 0)
+	require.NoError(t, r.Restore())
+	events := r.Query(&cns.GetIPHistoryRequest{})
+	require.Len(t, events, 1)
+	assert.Equal(t, *event("10.0.0.1", 1, types.Assigned, "a"), events[0])
+}
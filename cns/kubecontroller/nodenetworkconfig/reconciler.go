@@ -156,6 +156,16 @@ func (r *Reconciler) Started(ctx context.Context) (bool, error) {
 	}
 }
 
+// IsStarted reports without blocking whether the Reconciler has reconciled at least once.
+func (r *Reconciler) IsStarted() bool {
+	select {
+	case <-r.started:
+		return true
+	default:
+		return false
+	}
+}
+
 // SetupWithManager Sets up the reconciler with a new manager, filtering using NodeNetworkConfigFilter on nodeName.
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, node *v1.Node) error {
 	r.nnccli = nodenetworkconfig.NewClient(mgr.GetClient())
@@ -19,4 +19,9 @@ const (
 	AllowHostToNCCommunicationStr = "AllowHostToNCCommunication"
 	NetworkContainerTypeStr       = "NetworkContainerType"
 	OrchestratorContextStr        = "OrchestratorContext"
+	// Store recovery properties
+	StoreRecoveredEventStr = "CNSStoreRecovered"
+	StoreFileStr           = "StoreFile"
+	StoreSnapshotStr       = "Snapshot"
+	StoreRecoveryReasonStr = "Reason"
 )
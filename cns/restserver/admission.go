@@ -0,0 +1,196 @@
+package restserver
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	acn "github.com/Azure/azure-container-networking/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	// RetryAfterHeader carries how long a caller of a throttled request should wait before retrying it.
+	RetryAfterHeader = "Retry-After"
+
+	shedReasonQueueFull    = "queue_full"
+	shedReasonQueueTimeout = "queue_timeout"
+)
+
+var (
+	errAdmissionQueueFull    = errors.New("too many requests are queued")
+	errAdmissionQueueTimeout = errors.New("request was queued for too long")
+)
+
+// AdmissionSettings bound the IP requests CNS serves at once, so that bursts of them do not all contend on the
+// service lock. The zero value admits all requests.
+type AdmissionSettings struct {
+	// MaxConcurrentPerCaller is the number of IP requests of each caller served at once. 0 disables admission control.
+	MaxConcurrentPerCaller int
+	// MaxQueued is the number of IP requests of all callers which wait to be served. Requests beyond it are shed.
+	MaxQueued int
+	// QueueTimeout is how long IP requests wait to be served before they are shed. 0 waits until the caller gives up.
+	QueueTimeout time.Duration
+	// RetryAfter is how long callers of shed requests are asked to wait before retrying them.
+	RetryAfter time.Duration
+}
+
+// callerSlots are the slots of the requests of a caller which are being served.
+type callerSlots struct {
+	sem  chan struct{}
+	refs int // requests being served or queued
+}
+
+// admissionController admits the IP requests of each caller up to a concurrency limit, queues those beyond it,
+// and sheds them when the queue is full or they were queued for too long.
+type admissionController struct {
+	settings AdmissionSettings
+	mu       sync.Mutex
+	callers  map[string]*callerSlots
+	queued   int
+}
+
+// newAdmissionController returns an admissionController for the settings, or nil if they disable admission control.
+func newAdmissionController(settings AdmissionSettings) *admissionController {
+	if settings.MaxConcurrentPerCaller <= 0 {
+		return nil
+	}
+	return &admissionController{
+		settings: settings,
+		callers:  map[string]*callerSlots{},
+	}
+}
+
+// admit waits until a request of the caller can be served, and returns a func to call once it has been.
+// It fails if the request is shed instead. A nil admissionController admits all requests.
+func (a *admissionController) admit(ctx context.Context, caller string) (func(), error) {
+	if a == nil {
+		return func() {}, nil
+	}
+	a.mu.Lock()
+	slots, ok := a.callers[caller]
+	if !ok {
+		slots = &callerSlots{sem: make(chan struct{}, a.settings.MaxConcurrentPerCaller)}
+		a.callers[caller] = slots
+	}
+	slots.refs++
+	release := func() {
+		<-slots.sem
+		a.unref(caller, slots)
+	}
+	select {
+	case slots.sem <- struct{}{}:
+		a.mu.Unlock()
+		return release, nil
+	default:
+	}
+	if a.queued >= a.settings.MaxQueued {
+		a.mu.Unlock()
+		a.unref(caller, slots)
+		admissionRequestsShed.WithLabelValues(shedReasonQueueFull).Inc()
+		return nil, errAdmissionQueueFull
+	}
+	a.queued++
+	admissionQueueDepth.Set(float64(a.queued))
+	a.mu.Unlock()
+
+	dequeue := func() {
+		a.mu.Lock()
+		a.queued--
+		admissionQueueDepth.Set(float64(a.queued))
+		a.mu.Unlock()
+	}
+	var timeout <-chan time.Time
+	if a.settings.QueueTimeout > 0 {
+		timer := time.NewTimer(a.settings.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	start := time.Now()
+	select {
+	case slots.sem <- struct{}{}:
+		dequeue()
+		admissionQueueWait.Observe(time.Since(start).Seconds())
+		return release, nil
+	case <-timeout:
+		dequeue()
+		a.unref(caller, slots)
+		admissionRequestsShed.WithLabelValues(shedReasonQueueTimeout).Inc()
+		return nil, errAdmissionQueueTimeout
+	case <-ctx.Done():
+		dequeue()
+		a.unref(caller, slots)
+		return nil, errors.Wrap(ctx.Err(), "request was cancelled while queued")
+	}
+}
+
+// unref drops a reference to the slots of the caller, forgetting them once no requests of the caller remain.
+func (a *admissionController) unref(caller string, slots *callerSlots) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	slots.refs--
+	if slots.refs == 0 {
+		delete(a.callers, caller)
+	}
+}
+
+// retryAfterSeconds is the Retry-After hint of shed requests, rounded up to whole seconds.
+func (a *admissionController) retryAfterSeconds() int {
+	return int(math.Ceil(a.settings.RetryAfter.Seconds()))
+}
+
+// peerCaller identifies the caller of a request on a unix socket by the UID of its peer credentials.
+func peerCaller(creds *acn.PeerCredentials) string {
+	return "uid:" + strconv.FormatUint(uint64(creds.UID), 10)
+}
+
+// callerOf identifies the caller of the request by its peer credentials on the unix socket, by its client
+// certificate on the HTTPS endpoint, or else by its remote host.
+func callerOf(req *http.Request) string {
+	if creds, ok := acn.PeerCredentialsFromContext(req.Context()); ok {
+		return peerCaller(creds)
+	}
+	if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+		return "cn:" + req.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// admitHandlerFunc is a common.Middleware that admits the IP requests served on the path through the admission
+// controller. Shed requests are answered with RequestThrottled and a Retry-After hint. Other paths are not affected.
+func (service *HTTPRestService) admitHandlerFunc(path string, handler http.HandlerFunc) http.HandlerFunc {
+	if path != cns.RequestIPConfig && path != cns.RequestIPConfigs {
+		return handler
+	}
+	return func(w http.ResponseWriter, req *http.Request) {
+		caller := callerOf(req)
+		release, err := service.admission.admit(req.Context(), caller)
+		if err != nil {
+			logger.Errorf("[Azure CNS] Shed %s from %s: %v", path, caller, err)
+			// both IPConfigResponse and IPConfigsResponse carry the cns.Response under the same key.
+			resp := cns.IPConfigsResponse{
+				Response: cns.Response{
+					ReturnCode: types.RequestThrottled,
+					Message:    err.Error(),
+				},
+			}
+			w.Header().Set(RetryAfterHeader, strconv.Itoa(service.admission.retryAfterSeconds()))
+			w.Header().Set(cnsReturnCode, resp.Response.ReturnCode.String())
+			_ = service.Listener.Encode(w, &resp)
+			return
+		}
+		defer release()
+		handler(w, req)
+	}
+}
@@ -0,0 +1,124 @@
+package restserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/fakes"
+	"github.com/Azure/azure-container-networking/cns/types"
+	acn "github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmissionControllerDisabled(t *testing.T) {
+	a := newAdmissionController(AdmissionSettings{})
+	require.Nil(t, a)
+	release, err := a.admit(context.Background(), "caller")
+	require.NoError(t, err)
+	release()
+}
+
+func TestAdmissionControllerConcurrencyPerCaller(t *testing.T) {
+	a := newAdmissionController(AdmissionSettings{MaxConcurrentPerCaller: 1, MaxQueued: 1})
+	release, err := a.admit(context.Background(), "cni")
+	require.NoError(t, err)
+
+	// other callers are admitted while the caller is at its limit.
+	releaseOther, err := a.admit(context.Background(), "other")
+	require.NoError(t, err)
+	releaseOther()
+
+	// the next request of the caller is queued until the first is released.
+	admitted := make(chan func())
+	go func() {
+		r, err := a.admit(context.Background(), "cni")
+		assert.NoError(t, err)
+		admitted <- r
+	}()
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(admissionQueueDepth) == 1
+	}, time.Second, time.Millisecond)
+
+	// the queue is full.
+	shed := testutil.ToFloat64(admissionRequestsShed.WithLabelValues(shedReasonQueueFull))
+	_, err = a.admit(context.Background(), "cni")
+	require.ErrorIs(t, err, errAdmissionQueueFull)
+	assert.InDelta(t, shed+1, testutil.ToFloat64(admissionRequestsShed.WithLabelValues(shedReasonQueueFull)), 0)
+
+	release()
+	(<-admitted)()
+	assert.Zero(t, testutil.ToFloat64(admissionQueueDepth))
+	assert.Empty(t, a.callers, "callers without requests should be forgotten")
+}
+
+func TestAdmissionControllerQueueTimeout(t *testing.T) {
+	a := newAdmissionController(AdmissionSettings{MaxConcurrentPerCaller: 1, MaxQueued: 1, QueueTimeout: time.Millisecond})
+	release, err := a.admit(context.Background(), "cni")
+	require.NoError(t, err)
+	defer release()
+
+	shed := testutil.ToFloat64(admissionRequestsShed.WithLabelValues(shedReasonQueueTimeout))
+	_, err = a.admit(context.Background(), "cni")
+	require.ErrorIs(t, err, errAdmissionQueueTimeout)
+	assert.InDelta(t, shed+1, testutil.ToFloat64(admissionRequestsShed.WithLabelValues(shedReasonQueueTimeout)), 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = a.admit(ctx, "cni")
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestAdmitHandlerFunc(t *testing.T) {
+	var config common.ServiceConfig
+	// getTestService would replace the service the package tests share.
+	svc, err := NewHTTPRestService(&config, &fakes.WireserverClientFake{}, &fakes.WireserverProxyFake{}, &fakes.NMAgentClientFake{}, store.NewMockStore(""), nil, nil)
+	require.NoError(t, err)
+	svc.admission = newAdmissionController(AdmissionSettings{MaxConcurrentPerCaller: 1, RetryAfter: 1500 * time.Millisecond})
+	served := make(chan struct{})
+	block := make(chan struct{})
+	handler := svc.admitHandlerFunc(cns.RequestIPConfigs, func(w http.ResponseWriter, _ *http.Request) {
+		served <- struct{}{}
+		<-block
+		w.WriteHeader(http.StatusOK)
+	})
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, cns.RequestIPConfigs, http.NoBody)
+		return req.WithContext(acn.WithPeerCredentials(req.Context(), &acn.PeerCredentials{UID: 0}))
+	}
+
+	go handler(httptest.NewRecorder(), newRequest())
+	<-served
+	w := httptest.NewRecorder()
+	handler(w, newRequest())
+	close(block)
+
+	assert.Equal(t, "2", w.Header().Get(RetryAfterHeader))
+	var resp cns.IPConfigsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, types.RequestThrottled, resp.Response.ReturnCode)
+
+	// other paths are not admitted.
+	passthrough := svc.admitHandlerFunc(cns.ReleaseIPConfigs, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	w = httptest.NewRecorder()
+	passthrough(w, newRequest())
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestCallerOf(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, cns.RequestIPConfigs, http.NoBody)
+	req.RemoteAddr = "10.0.0.1:12345"
+	assert.Equal(t, "10.0.0.1", callerOf(req))
+	req = req.WithContext(acn.WithPeerCredentials(req.Context(), &acn.PeerCredentials{UID: 1000}))
+	assert.Equal(t, "uid:1000", callerOf(req))
+}
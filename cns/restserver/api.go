@@ -890,6 +890,15 @@ func (service *HTTPRestService) getAllNetworkContainers(w http.ResponseWriter, r
 		return
 	}
 
+	resp := service.getAllNetworkContainersResponse(req)
+
+	err = service.Listener.Encode(w, &resp)
+	logger.Response(service.Name, resp, resp.Response.ReturnCode, err)
+}
+
+// getAllNetworkContainersResponse gets all network containers of the OrchestratorContext of the request,
+// failing if any of them could not be gotten.
+func (service *HTTPRestService) getAllNetworkContainersResponse(req cns.GetNetworkContainerRequest) cns.GetAllNetworkContainersResponse {
 	getAllNetworkContainerResponses := service.getAllNetworkContainerResponses(req) // nolint
 
 	var resp cns.GetAllNetworkContainersResponse
@@ -916,8 +925,7 @@ func (service *HTTPRestService) getAllNetworkContainers(w http.ResponseWriter, r
 		resp.Response.Message = "Successfully retrieved NCs"
 	}
 
-	err = service.Listener.Encode(w, &resp)
-	logger.Response(service.Name, resp, resp.Response.ReturnCode, err)
+	return resp
 }
 
 func (service *HTTPRestService) getNetworkContainerByOrchestratorContext(w http.ResponseWriter, r *http.Request) {
@@ -1017,6 +1025,8 @@ func (service *HTTPRestService) deleteNetworkContainer(w http.ResponseWriter, r
 		}
 
 		service.saveState()
+		service.publishNCChange(cns.WatchEventNCDeleted, ncid)
+		service.invalidateNCVersionList()
 	default:
 		returnMessage = "[Azure CNS] Error. DeleteNetworkContainer did not receive a POST."
 		returnCode = types.InvalidParameter
@@ -1267,6 +1277,7 @@ func (service *HTTPRestService) publishNetworkContainer(w http.ResponseWriter, r
 
 	publishBytes, _ := io.ReadAll(publishResp.Body)
 	_ = publishResp.Body.Close()
+	service.invalidateNCVersionList()
 
 	resp := cns.PublishNetworkContainerResponse{
 		PublishStatusCode:   publishResp.StatusCode,
@@ -1365,6 +1376,7 @@ func (service *HTTPRestService) unpublishNetworkContainer(w http.ResponseWriter,
 
 	publishBytes, _ := io.ReadAll(publishResp.Body)
 	_ = publishResp.Body.Close()
+	service.invalidateNCVersionList()
 
 	resp := cns.UnpublishNetworkContainerResponse{
 		UnpublishStatusCode:   publishResp.StatusCode,
@@ -0,0 +1,90 @@
+package restserver
+
+import (
+	"crypto/x509"
+	"net/http"
+
+	"github.com/Azure/azure-container-networking/cns/configuration"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	acn "github.com/Azure/azure-container-networking/common"
+)
+
+// clientIdentities returns the identities of the client with the certificate.
+func clientIdentities(cert *x509.Certificate) []string {
+	identities := make([]string, 0, 1+len(cert.DNSNames)+len(cert.URIs))
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, cert.Subject.CommonName)
+	}
+	identities = append(identities, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	return identities
+}
+
+// authorizeClient returns whether the rules allow the client with the identities to call the path.
+// Without rules, all clients are allowed.
+func authorizeClient(rules []configuration.ClientAuthorizationRule, path string, identities []string) bool {
+	if len(rules) == 0 {
+		return true
+	}
+	for i := range rules {
+		if !pathsMatch(rules[i].Paths, path) {
+			continue
+		}
+		for _, allowed := range rules[i].Identities {
+			for _, identity := range identities {
+				if allowed == identity {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// clientRulesCover returns whether any of the rules applies to the path.
+func clientRulesCover(rules []configuration.ClientAuthorizationRule, path string) bool {
+	for i := range rules {
+		if pathsMatch(rules[i].Paths, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeClientHandlerFunc is a common.Middleware that denies the requests served on the HTTPS endpoint
+// unless the ClientAuthorizationRules allow the identities of their client certificates to call the path.
+// The paths the rules apply to are denied on the HTTP endpoint, which has no client certificates, so that
+// they can not be called around the rules. Requests served on the unix socket are authorized by their peers.
+func (service *HTTPRestService) authorizeClientHandlerFunc(path string, handler http.HandlerFunc) http.HandlerFunc {
+	denied := tlsClientRequestsDenied.WithLabelValues(path)
+	return func(w http.ResponseWriter, req *http.Request) {
+		if len(service.ClientAuthorizationRules) == 0 {
+			handler(w, req)
+			return
+		}
+		if req.TLS == nil {
+			if _, ok := acn.PeerCredentialsFromContext(req.Context()); ok || !clientRulesCover(service.ClientAuthorizationRules, path) {
+				handler(w, req)
+				return
+			}
+			logger.Errorf("[Azure CNS] Denied %s %s without a client certificate from %s", req.Method, path, req.RemoteAddr)
+			denied.Inc()
+			http.Error(w, path+" requires a client certificate", http.StatusForbidden)
+			return
+		}
+		var identities []string
+		if len(req.TLS.PeerCertificates) > 0 {
+			identities = clientIdentities(req.TLS.PeerCertificates[0])
+		}
+		if !authorizeClient(service.ClientAuthorizationRules, path, identities) {
+			logger.Errorf("[Azure CNS] Denied %s %s on HTTPS endpoint to client %v from %s",
+				req.Method, path, identities, req.RemoteAddr)
+			denied.Inc()
+			http.Error(w, "client is not authorized to call "+path, http.StatusForbidden)
+			return
+		}
+		handler(w, req)
+	}
+}
@@ -0,0 +1,97 @@
+package restserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/configuration"
+	acn "github.com/Azure/azure-container-networking/common"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIdentities(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://cluster/dnc")
+	cert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "dnc.azure.com"},
+		DNSNames: []string{"dnc-1.azure.com"},
+		URIs:     []*url.URL{spiffe},
+	}
+	assert.Equal(t, []string{"dnc.azure.com", "dnc-1.azure.com", "spiffe://cluster/dnc"}, clientIdentities(cert))
+	assert.Empty(t, clientIdentities(&x509.Certificate{}))
+}
+
+func TestAuthorizeClient(t *testing.T) {
+	rules := []configuration.ClientAuthorizationRule{
+		{Identities: []string{"dnc.azure.com"}, Paths: []string{cns.CreateOrUpdateNetworkContainer, "/network/nc/"}},
+		{Identities: []string{"node.azure.com", "dnc.azure.com"}, Paths: []string{cns.RequestIPConfigs}},
+	}
+
+	tests := []struct {
+		name       string
+		rules      []configuration.ClientAuthorizationRule
+		path       string
+		identities []string
+		allowed    bool
+	}{
+		{name: "no rules", path: cns.CreateOrUpdateNetworkContainer, identities: []string{"node.azure.com"}, allowed: true},
+		{name: "identity on path", rules: rules, path: cns.CreateOrUpdateNetworkContainer, identities: []string{"dnc.azure.com"}, allowed: true},
+		{name: "identity under path prefix", rules: rules, path: "/network/nc/delete", identities: []string{"dnc.azure.com"}, allowed: true},
+		{name: "any identity on path", rules: rules, path: cns.RequestIPConfigs, identities: []string{"other", "node.azure.com"}, allowed: true},
+		{name: "identity on other path", rules: rules, path: cns.CreateOrUpdateNetworkContainer, identities: []string{"node.azure.com"}, allowed: false},
+		{name: "unmatched path", rules: rules, path: cns.ReleaseIPConfigs, identities: []string{"dnc.azure.com"}, allowed: false},
+		{name: "no identities", rules: rules, path: cns.RequestIPConfigs, allowed: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, authorizeClient(tt.rules, tt.path, tt.identities))
+		})
+	}
+}
+
+func TestAuthorizeClientHandlerFunc(t *testing.T) {
+	svc := &HTTPRestService{ClientAuthorizationRules: []configuration.ClientAuthorizationRule{
+		{Identities: []string{"dnc.azure.com"}, Paths: []string{cns.CreateOrUpdateNetworkContainer}},
+	}}
+	path := cns.CreateOrUpdateNetworkContainer
+	handler := svc.authorizeClientHandlerFunc(path, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	denied := testutil.ToFloat64(tlsClientRequestsDenied.WithLabelValues(path))
+
+	request := func(commonName string) int {
+		req := httptest.NewRequest(http.MethodPost, path, http.NoBody)
+		if commonName != "" {
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: commonName}}}}
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("dnc.azure.com"))
+	assert.Equal(t, http.StatusForbidden, request("node.azure.com"))
+	// the paths of the rules can not be called around them over HTTP.
+	assert.Equal(t, http.StatusForbidden, request(""))
+	assert.InDelta(t, denied+2, testutil.ToFloat64(tlsClientRequestsDenied.WithLabelValues(path)), 0)
+
+	// requests served on the unix socket are authorized by their peers.
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, http.NoBody)
+	handler(w, req.WithContext(acn.WithPeerCredentials(req.Context(), &acn.PeerCredentials{PID: 1})))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// paths which no rule applies to are served over HTTP.
+	w = httptest.NewRecorder()
+	svc.authorizeClientHandlerFunc(cns.RequestIPConfigs, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})(w, httptest.NewRequest(http.MethodPost, cns.RequestIPConfigs, http.NoBody))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
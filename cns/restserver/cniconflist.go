@@ -0,0 +1,68 @@
+package restserver
+
+import (
+	"net/http"
+	"net/netip"
+	"sort"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+)
+
+// handleDebugCNIConflist diffs the CNI conflist CNS renders for its current config against the conflist on disk.
+func (service *HTTPRestService) handleDebugCNIConflist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var resp cns.GetCNIConflistDiffResponse
+	if service.CNIConflistDiffer == nil {
+		resp.Response = cns.Response{
+			ReturnCode: types.NotFound,
+			Message:    "CNI conflist generation is not enabled",
+		}
+	} else if diff, err := service.CNIConflistDiffer(); err != nil {
+		resp.Response = cns.Response{
+			ReturnCode: types.UnexpectedError,
+			Message:    err.Error(),
+		}
+	} else {
+		resp.Diff = diff
+	}
+	w.Header().Set(cnsReturnCode, resp.Response.ReturnCode.String())
+	err := service.Listener.Encode(w, &resp)
+	logger.Response(service.Name, resp.Response, resp.Response.ReturnCode, err)
+}
+
+// NCSubnets returns the subnets of the NCs on the Node, which CNI conflist templates are rendered with.
+func (service *HTTPRestService) NCSubnets() []netip.Prefix {
+	service.RLock()
+	defer service.RUnlock()
+	seen := map[netip.Prefix]struct{}{}
+	subnets := []netip.Prefix{}
+	for _, status := range service.state.ContainerStatus {
+		ipSubnet := status.CreateNetworkContainerRequest.IPConfiguration.IPSubnet
+		addr, err := netip.ParseAddr(ipSubnet.IPAddress)
+		if err != nil {
+			continue
+		}
+		subnet, err := addr.Prefix(int(ipSubnet.PrefixLength))
+		if err != nil {
+			continue
+		}
+		if _, ok := seen[subnet]; ok {
+			continue
+		}
+		seen[subnet] = struct{}{}
+		subnets = append(subnets, subnet)
+	}
+	// map iteration is random, so keep the rendered conflist stable.
+	sort.Slice(subnets, func(i, j int) bool {
+		if subnets[i].Addr() != subnets[j].Addr() {
+			return subnets[i].Addr().Less(subnets[j].Addr())
+		}
+		return subnets[i].Bits() < subnets[j].Bits()
+	})
+	return subnets
+}
@@ -0,0 +1,63 @@
+package restserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/fakes"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleDebugCNIConflist(t *testing.T) {
+	var config common.ServiceConfig
+	svc, err := NewHTTPRestService(&config, &fakes.WireserverClientFake{}, &fakes.WireserverProxyFake{}, &fakes.NMAgentClientFake{}, store.NewMockStore(""), nil, nil)
+	require.NoError(t, err)
+	get := func() cns.GetCNIConflistDiffResponse {
+		w := httptest.NewRecorder()
+		svc.handleDebugCNIConflist(w, httptest.NewRequest(http.MethodGet, cns.PathDebugCNIConflist, http.NoBody))
+		var resp cns.GetCNIConflistDiffResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return resp
+	}
+
+	// conflist generation is not enabled.
+	resp := get()
+	assert.Equal(t, types.NotFound, resp.Response.ReturnCode)
+	assert.Nil(t, resp.Diff)
+
+	want := &cns.CNIConflistDiff{Path: "/etc/cni/net.d/15-azure-swift-overlay.conflist", Exists: true, Changed: true, Diff: "-a\n+b\n"}
+	svc.CNIConflistDiffer = func() (*cns.CNIConflistDiff, error) {
+		return want, nil
+	}
+	resp = get()
+	assert.Equal(t, types.Success, resp.Response.ReturnCode)
+	assert.Equal(t, want, resp.Diff)
+}
+
+func TestNCSubnets(t *testing.T) {
+	svc := &HTTPRestService{state: &httpRestServiceState{ContainerStatus: map[string]containerstatus{}}}
+	for id, subnet := range map[string]cns.IPSubnet{
+		"nc1": {IPAddress: "fd00:10:244::5", PrefixLength: 64},
+		"nc2": {IPAddress: "10.244.0.5", PrefixLength: 24},
+		"nc3": {IPAddress: "10.244.0.6", PrefixLength: 24},
+		"nc4": {IPAddress: "", PrefixLength: 24},
+	} {
+		svc.state.ContainerStatus[id] = containerstatus{
+			CreateNetworkContainerRequest: cns.CreateNetworkContainerRequest{
+				IPConfiguration: cns.IPConfiguration{IPSubnet: subnet},
+			},
+		}
+	}
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.244.0.0/24"),
+		netip.MustParsePrefix("fd00:10:244::/64"),
+	}, svc.NCSubnets())
+}
@@ -6,8 +6,13 @@ const (
 	// Key against which CNS state is persisted.
 	storeKey         = "ContainerNetworkService"
 	EndpointStoreKey = "Endpoints"
-	attach           = "Attach"
-	detach           = "Detach"
+	// Key against which the IP reservations are persisted, apart from the rest of the state
+	// so that they can be written without holding the service lock.
+	ipReservationsStoreKey = "IPReservations"
+	// Key against which the IPs that are Cooling are persisted, with when they started Cooling.
+	coolingIPsStoreKey = "CoolingIPs"
+	attach             = "Attach"
+	detach             = "Detach"
 	// Rest service state identifier for named lock
 	stateJoinedNetworks = "JoinedNetworks"
 	dncApiVersion       = "?api-version=2018-03-01"
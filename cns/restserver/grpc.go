@@ -0,0 +1,232 @@
+package restserver
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/filter"
+	"github.com/Azure/azure-container-networking/cns/grpc/v1alpha"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	acn "github.com/Azure/azure-container-networking/common"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// peerAuthInfo is the credentials.AuthInfo of a gRPC connection on the unix socket, with the peer credentials of its caller.
+type peerAuthInfo struct {
+	credentials.CommonAuthInfo
+	creds *acn.PeerCredentials
+}
+
+func (peerAuthInfo) AuthType() string {
+	return "peercred"
+}
+
+// peerCredentials are the credentials.TransportCredentials of the gRPC server on the unix socket. They read the
+// peer credentials of the callers in the handshake, and close the connections whose peer credentials can not be read.
+type peerCredentials struct{}
+
+func (peerCredentials) ClientHandshake(context.Context, string, net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("peer credentials are only read by the server")
+}
+
+func (peerCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	creds, err := acn.GetPeerCredentials(conn)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read peer credentials")
+	}
+	return conn, peerAuthInfo{CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.NoSecurity}, creds: creds}, nil
+}
+
+func (peerCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "peercred"}
+}
+
+func (c peerCredentials) Clone() credentials.TransportCredentials {
+	return c
+}
+
+func (peerCredentials) OverrideServerName(string) error {
+	return nil
+}
+
+// grpcPeerCredentials returns the peer credentials of the caller of a gRPC method served on the unix socket.
+func grpcPeerCredentials(ctx context.Context) (*acn.PeerCredentials, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	info, ok := p.AuthInfo.(peerAuthInfo)
+	return info.creds, ok && info.creds != nil
+}
+
+// authorizeGRPCPeer denies the call of the gRPC method unless the PeerAuthorizationRules allow its caller to call it.
+// The paths of the rules are matched against the full names of the methods, like /cns.v1alpha.CNS/RequestIPConfigs.
+func (service *HTTPRestService) authorizeGRPCPeer(ctx context.Context, method string) error {
+	creds, ok := grpcPeerCredentials(ctx)
+	if !ok {
+		unixSocketRequestsDenied.WithLabelValues(method).Inc()
+		return status.Error(codes.Unauthenticated, "caller has no peer credentials")
+	}
+	executable := procExecutable(creds.PID)
+	if !authorizePeer(service.PeerAuthorizationRules, method, creds, executable) {
+		logger.Errorf("[Azure CNS] Denied %s on gRPC socket to pid %d uid %d gid %d executable %q",
+			method, creds.PID, creds.UID, creds.GID, executable())
+		unixSocketRequestsDenied.WithLabelValues(method).Inc()
+		return status.Error(codes.PermissionDenied, "caller is not authorized to call "+method)
+	}
+	return nil
+}
+
+func (service *HTTPRestService) authorizePeerUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := service.authorizeGRPCPeer(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (service *HTTPRestService) authorizePeerStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := service.authorizeGRPCPeer(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// grpcServer serves the CNS gRPC API with the same operations as the REST API.
+type grpcServer struct {
+	v1alpha.UnimplementedCNSServer
+	service *HTTPRestService
+}
+
+// RegisterGRPCServer registers the CNS gRPC API of the service with the gRPC server.
+func (service *HTTPRestService) RegisterGRPCServer(s grpc.ServiceRegistrar) {
+	v1alpha.RegisterCNSServer(s, &grpcServer{service: service})
+}
+
+// ServeGRPC serves the CNS gRPC API on the unix socket at the path until the context is done.
+// Only the owner of the socket may connect to it, and the calls are authorized by the PeerAuthorizationRules
+// like the REST API on the unix socket.
+func (service *HTTPRestService) ServeGRPC(ctx context.Context, socketPath string) error {
+	if !acn.PeerCredentialsSupported {
+		return errors.New("gRPC API requires peer credentials, which are not supported on this platform")
+	}
+	// remove the socket left behind by a previous CNS.
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to remove stale socket %s", socketPath)
+	}
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", socketPath)
+	}
+	if err := os.Chmod(socketPath, 0o600); err != nil {
+		_ = l.Close()
+		return errors.Wrapf(err, "failed to restrict permissions of %s", socketPath)
+	}
+	s := grpc.NewServer(
+		grpc.Creds(peerCredentials{}),
+		grpc.UnaryInterceptor(service.authorizePeerUnaryInterceptor),
+		grpc.StreamInterceptor(service.authorizePeerStreamInterceptor),
+	)
+	service.RegisterGRPCServer(s)
+	go func() {
+		<-ctx.Done()
+		s.GracefulStop()
+	}()
+	logger.Printf("[Azure CNS] Serving the gRPC API on %s", socketPath)
+	return errors.Wrap(s.Serve(l), "failed to serve the gRPC API")
+}
+
+func (s *grpcServer) RequestIPConfigs(ctx context.Context, req *v1alpha.IPConfigsRequest) (*v1alpha.IPConfigsResponse, error) {
+	ipconfigsRequest := req.ToCNS()
+	logger.Request(s.service.Name+"grpcRequestIPConfigs", ipconfigsRequest, nil)
+	// callers on the REST unix socket and the gRPC socket share their slots.
+	caller := "grpc"
+	if creds, ok := grpcPeerCredentials(ctx); ok {
+		caller = peerCaller(creds)
+	}
+	release, err := s.service.admission.admit(ctx, caller)
+	if err != nil {
+		logger.Errorf("[Azure CNS] Shed grpcRequestIPConfigs from %s: %v", caller, err)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RetryAfterHeader, strconv.Itoa(s.service.admission.retryAfterSeconds())))
+		return &v1alpha.IPConfigsResponse{
+			Response: &v1alpha.Response{ReturnCode: int32(types.RequestThrottled), Message: err.Error()},
+		}, nil
+	}
+	defer release()
+	resp, err := s.service.requestIPConfigHandlerHelper(ctx, ipconfigsRequest)
+	logger.ResponseEx(s.service.Name+"grpcRequestIPConfigs", ipconfigsRequest, resp, resp.Response.ReturnCode, err)
+	return v1alpha.IPConfigsResponseFromCNS(resp), nil
+}
+
+func (s *grpcServer) ReleaseIPConfigs(ctx context.Context, req *v1alpha.IPConfigsRequest) (*v1alpha.ReleaseIPConfigsResponse, error) {
+	ipconfigsRequest := req.ToCNS()
+	logger.Request(s.service.Name+"grpcReleaseIPConfigs", ipconfigsRequest, nil)
+	resp, err := s.service.releaseIPConfigHandlerHelper(ctx, ipconfigsRequest)
+	logger.ResponseEx(s.service.Name+"grpcReleaseIPConfigs", ipconfigsRequest, resp, resp.ReturnCode, err)
+	return &v1alpha.ReleaseIPConfigsResponse{Response: v1alpha.ResponseFromCNS(*resp)}, nil
+}
+
+func (s *grpcServer) CreateOrUpdateNetworkContainer(_ context.Context, req *v1alpha.CreateNetworkContainerRequest) (*v1alpha.CreateNetworkContainerResponse, error) {
+	ncRequest := req.ToCNS()
+	logger.Request(s.service.Name+"grpcCreateOrUpdateNetworkContainer", ncRequest.String(), nil)
+	resp := cns.Response{}
+	if err := ncRequest.Validate(); err != nil {
+		resp.ReturnCode = types.InvalidRequest
+		resp.Message = err.Error()
+	} else if resp.ReturnCode = s.service.CreateOrUpdateNetworkContainerInternal(ncRequest); resp.ReturnCode != types.Success {
+		resp.Message = "failed to create or update network container: " + resp.ReturnCode.String()
+	}
+	logger.Response(s.service.Name+"grpcCreateOrUpdateNetworkContainer", resp, resp.ReturnCode, nil)
+	return &v1alpha.CreateNetworkContainerResponse{Response: v1alpha.ResponseFromCNS(resp)}, nil
+}
+
+func (s *grpcServer) GetNetworkContainer(_ context.Context, req *v1alpha.GetNetworkContainerRequest) (*v1alpha.GetNetworkContainerResponse, error) {
+	ncRequest := req.ToCNS()
+	logger.Request(s.service.Name+"grpcGetNetworkContainer", &ncRequest, nil)
+	resp, returnCode := s.service.GetNetworkContainerInternal(ncRequest)
+	logger.Response(s.service.Name+"grpcGetNetworkContainer", resp, returnCode, nil)
+	return v1alpha.GetNetworkContainerResponseFromCNS(resp), nil
+}
+
+func (s *grpcServer) GetAllNetworkContainers(_ context.Context, req *v1alpha.GetNetworkContainerRequest) (*v1alpha.GetAllNetworkContainersResponse, error) {
+	ncRequest := req.ToCNS()
+	logger.Request(s.service.Name+"grpcGetAllNetworkContainers", &ncRequest, nil)
+	resp := s.service.getAllNetworkContainersResponse(ncRequest)
+	logger.Response(s.service.Name+"grpcGetAllNetworkContainers", resp, resp.Response.ReturnCode, nil)
+	return v1alpha.GetAllNetworkContainersResponseFromCNS(&resp), nil
+}
+
+func (s *grpcServer) DeleteNetworkContainer(_ context.Context, req *v1alpha.DeleteNetworkContainerRequest) (*v1alpha.DeleteNetworkContainerResponse, error) {
+	ncRequest := cns.DeleteNetworkContainerRequest{NetworkContainerid: req.GetNetworkContainerId()}
+	logger.Request(s.service.Name+"grpcDeleteNetworkContainer", &ncRequest, nil)
+	resp := cns.Response{}
+	if ncRequest.NetworkContainerid == "" {
+		resp.ReturnCode = types.NetworkContainerNotSpecified
+		resp.Message = "NetworkContainerid is empty"
+	} else {
+		resp.ReturnCode = s.service.DeleteNetworkContainerInternal(ncRequest)
+	}
+	logger.Response(s.service.Name+"grpcDeleteNetworkContainer", resp, resp.ReturnCode, nil)
+	return &v1alpha.DeleteNetworkContainerResponse{Response: v1alpha.ResponseFromCNS(resp)}, nil
+}
+
+func (s *grpcServer) GetIPAddresses(_ context.Context, req *v1alpha.GetIPAddressesRequest) (*v1alpha.GetIPAddressesResponse, error) {
+	ipRequest := req.ToCNS()
+	s.service.RLock()
+	ipConfigs := filter.MatchAnyIPConfigState(s.service.PodIPConfigState, filter.PredicatesForStates(ipRequest.IPConfigStateFilter...)...)
+	s.service.RUnlock()
+	resp := &v1alpha.GetIPAddressesResponse{Response: &v1alpha.Response{}}
+	for i := range ipConfigs {
+		resp.IpConfigurationStatus = append(resp.IpConfigurationStatus, v1alpha.IPConfigurationStatusFromCNS(ipConfigs[i]))
+	}
+	return resp, nil
+}
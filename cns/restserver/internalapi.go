@@ -321,6 +321,8 @@ func (service *HTTPRestService) ReconcileIPAMState(ncReqs []*cns.CreateNetworkCo
 	//   }
 	//
 	// such that we can iterate over pod interfaces, and assign all IPs for it at once.
+	service.yieldReservedIPsToPods(podInfoByIP)
+	service.yieldCoolingIPsToPods(podInfoByIP)
 	podKeyToPodIPs, err := newPodKeyToPodIPsMap(podInfoByIP)
 	if err != nil {
 		logger.Errorf("could not transform pods indexed by IP address to pod IPs indexed by interface: %v", err)
@@ -383,6 +385,8 @@ func (service *HTTPRestService) ReconcileIPAMState(ncReqs []*cns.CreateNetworkCo
 		return types.UnexpectedError
 	}
 
+	service.pruneIPReservations(time.Now())
+
 	return 0
 }
 
@@ -472,6 +476,8 @@ func (service *HTTPRestService) DeleteNetworkContainerInternal(
 	}
 
 	service.saveState()
+	service.publishNCChange(cns.WatchEventNCDeleted, ncid)
+	service.invalidateNCVersionList()
 	return types.Success
 }
 
@@ -503,6 +509,7 @@ func (service *HTTPRestService) MustEnsureNoStaleNCs(validNCIDs []string) {
 
 			logger.Errorf("[Azure CNS] Found stale NC ID %s in CNS state. Removing...", ncID)
 			delete(service.state.ContainerStatus, ncID)
+			service.publishNCChange(cns.WatchEventNCDeleted, ncID)
 			mutated = true
 		}
 	}
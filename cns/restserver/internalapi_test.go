@@ -154,6 +154,22 @@ func TestCreateOrUpdateNCWithLargerVersionComparedToNMAgent(t *testing.T) {
 	validateCreateNCInternal(t, 2, "1")
 }
 
+func TestNCChangesInvalidateNCVersionList(t *testing.T) {
+	restartService()
+	setEnv(t)
+	setOrchestratorTypeInternal(cns.KubernetesCRD)
+	invalidations := 0
+	cleanup := setMockNMAgent(svc, &fakes.NMAgentClientFake{InvalidateNCVersionListF: func() { invalidations++ }})
+	defer cleanup()
+
+	secondaryIPConfigs := map[string]cns.SecondaryIPConfig{uuid.New().String(): newSecondaryIPConfig("10.0.0.16", 0)}
+	createNCReqInternal(t, secondaryIPConfigs, ncID, "0")
+	assert.Equal(t, 1, invalidations)
+
+	require.Equal(t, types.Success, svc.DeleteNetworkContainerInternal(cns.DeleteNetworkContainerRequest{NetworkContainerid: ncID}))
+	assert.Equal(t, 2, invalidations)
+}
+
 func TestCreateAndUpdateNCWithSecondaryIPNCVersion(t *testing.T) {
 	restartService()
 	setEnv(t)
@@ -9,6 +9,7 @@ import (
 	"net"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/filter"
@@ -423,12 +424,23 @@ func (service *HTTPRestService) removeEndpointState(podInfo cns.PodInfo) error {
 // It will try to update [totalIpsToRelease]  number of ips.
 func (service *HTTPRestService) MarkIPAsPendingRelease(totalIpsToRelease int) (map[string]cns.IPConfigurationStatus, error) {
 	pendingReleasedIps := make(map[string]cns.IPConfigurationStatus)
+	// the reservations are saved once the service lock is released.
+	expired := false
+	defer func() {
+		if expired {
+			service.saveIPReservations()
+		}
+	}()
 	service.Lock()
 	defer service.Unlock()
+	// IPs held by expired reservations can be released.
+	now := service.now()
+	expired = service.expireIPReservationsUntransacted(now)
+	service.releaseCooledIPsUntransacted(now)
 
 	for uuid, existingIpConfig := range service.PodIPConfigState {
 		if existingIpConfig.GetState() == types.PendingProgramming {
-			updatedIPConfig, err := service.updateIPConfigState(uuid, types.PendingRelease, existingIpConfig.PodInfo)
+			updatedIPConfig, err := service.updateIPConfigState(uuid, types.PendingRelease, existingIpConfig.PodInfo, "MarkIPAsPendingRelease")
 			if err != nil {
 				return nil, err
 			}
@@ -443,7 +455,7 @@ func (service *HTTPRestService) MarkIPAsPendingRelease(totalIpsToRelease int) (m
 	// if not all expected IPs are set to PendingRelease, then check the Available IPs
 	for uuid, existingIpConfig := range service.PodIPConfigState {
 		if existingIpConfig.GetState() == types.Available {
-			updatedIPConfig, err := service.updateIPConfigState(uuid, types.PendingRelease, existingIpConfig.PodInfo)
+			updatedIPConfig, err := service.updateIPConfigState(uuid, types.PendingRelease, existingIpConfig.PodInfo, "MarkIPAsPendingRelease")
 			if err != nil {
 				return nil, err
 			}
@@ -461,12 +473,16 @@ func (service *HTTPRestService) MarkIPAsPendingRelease(totalIpsToRelease int) (m
 }
 
 // TODO: Add a change so that we should only update the current state if it is different than the new state
-func (service *HTTPRestService) updateIPConfigState(ipID string, updatedState types.IPState, podInfo cns.PodInfo) (cns.IPConfigurationStatus, error) {
+// The caller names the CNS operation that changed the state in the IP history.
+func (service *HTTPRestService) updateIPConfigState(ipID string, updatedState types.IPState, podInfo cns.PodInfo, caller string) (cns.IPConfigurationStatus, error) {
 	if ipConfig, found := service.PodIPConfigState[ipID]; found {
 		logger.Printf("[updateIPConfigState] Changing IpId [%s] state to [%s], podInfo [%+v]. Current config [%+v]", ipID, updatedState, podInfo, ipConfig)
+		previous := ipConfig
 		ipConfig.SetState(updatedState)
 		ipConfig.PodInfo = podInfo
 		service.PodIPConfigState[ipID] = ipConfig
+		service.recordIPHistory(&previous, &ipConfig, caller)
+		service.publishIPStateChange(previous.GetState(), &ipConfig)
 		return ipConfig, nil
 	}
 
@@ -492,9 +508,12 @@ func (service *HTTPRestService) MarkIpsAsAvailableUntransacted(ncID string, newH
 				if ipConfigStatus, exist := service.PodIPConfigState[uuid]; !exist {
 					logger.Errorf("IP %s with uuid as %s exist in service state Secondary IP list but can't find in PodIPConfigState", ipConfigStatus.IPAddress, uuid)
 				} else if ipConfigStatus.GetState() == types.PendingProgramming && secondaryIPConfigs.NCVersion <= newHostNCVersion {
-					_, err := service.updateIPConfigState(uuid, types.Available, nil)
+					_, err := service.updateIPConfigState(uuid, types.Available, nil, "MarkIpsAsAvailableUntransacted")
 					if err != nil {
 						logger.Errorf("Error updating IPConfig [%+v] state to Available, err: %+v", ipConfigStatus, err)
+					} else {
+						service.holdIfReservedUntransacted(uuid)
+						service.coolIfCoolingUntransacted(uuid, service.now())
 					}
 
 					// Following 2 sentence assign new host version to secondary ip config.
@@ -567,6 +586,26 @@ func (service *HTTPRestService) handleDebugIPAddresses(w http.ResponseWriter, r
 	logger.ResponseEx(service.Name, req, resp, resp.Response.ReturnCode, err)
 }
 
+func (service *HTTPRestService) handleDebugIPHistory(w http.ResponseWriter, r *http.Request) {
+	var req cns.GetIPHistoryRequest
+	if err := service.Listener.Decode(w, r, &req); err != nil {
+		resp := cns.GetIPHistoryResponse{
+			Response: cns.Response{
+				ReturnCode: types.UnexpectedError,
+				Message:    err.Error(),
+			},
+		}
+		err = service.Listener.Encode(w, &resp)
+		logger.ResponseEx(service.Name, req, resp, resp.Response.ReturnCode, err)
+		return
+	}
+	resp := cns.GetIPHistoryResponse{
+		Events: service.IPHistory.Query(&req),
+	}
+	err := service.Listener.Encode(w, &resp)
+	logger.ResponseEx(service.Name, req, resp, resp.Response.ReturnCode, err)
+}
+
 // GetAssignedIPConfigs returns a filtered list of IPs which are in
 // Assigned State.
 func (service *HTTPRestService) GetAssignedIPConfigs() []cns.IPConfigurationStatus {
@@ -600,8 +639,8 @@ func (service *HTTPRestService) GetPendingReleaseIPConfigs() []cns.IPConfigurati
 }
 
 // assignIPConfig assigns the the ipconfig to the passed Pod, sets the state as Assigned, does not take a lock.
-func (service *HTTPRestService) assignIPConfig(ipconfig cns.IPConfigurationStatus, podInfo cns.PodInfo) error { //nolint:gocritic // ignore hugeparam
-	ipconfig, err := service.updateIPConfigState(ipconfig.ID, types.Assigned, podInfo)
+func (service *HTTPRestService) assignIPConfig(ipconfig cns.IPConfigurationStatus, podInfo cns.PodInfo, caller string) error { //nolint:gocritic // ignore hugeparam
+	ipconfig, err := service.updateIPConfigState(ipconfig.ID, types.Assigned, podInfo, caller)
 	if err != nil {
 		return err
 	}
@@ -615,12 +654,24 @@ func (service *HTTPRestService) assignIPConfig(ipconfig cns.IPConfigurationStatu
 	return nil
 }
 
-// unassignIPConfig unassigns the ipconfig from the passed Pod, sets the state as Available, does not take a lock.
-func (service *HTTPRestService) unassignIPConfig(ipconfig cns.IPConfigurationStatus, podInfo cns.PodInfo) (cns.IPConfigurationStatus, error) { //nolint:gocritic // ignore hugeparam
-	ipconfig, err := service.updateIPConfigState(ipconfig.ID, types.Available, nil)
+// unassignIPConfig unassigns the ipconfig from the passed Pod, sets the state as Available, or Reserved if
+// the IP is still reserved for the Pod, does not take a lock.
+// If cool is set, and the service has an IPCooldown, the IP is quarantined as Cooling instead of Available.
+func (service *HTTPRestService) unassignIPConfig(ipconfig cns.IPConfigurationStatus, podInfo cns.PodInfo, cool bool, now time.Time, caller string) (cns.IPConfigurationStatus, error) { //nolint:gocritic // ignore hugeparam
+	state, owner := types.Available, cns.PodInfo(nil)
+	switch {
+	case service.isReservedForUntransacted(ipconfig.ID, podInfo, now):
+		state, owner = types.Reserved, cns.NewPodInfo("", "", podInfo.Name(), podInfo.Namespace())
+	case cool && service.IPCooldown > 0:
+		state = types.Cooling
+	}
+	ipconfig, err := service.updateIPConfigState(ipconfig.ID, state, owner, caller)
 	if err != nil {
 		return cns.IPConfigurationStatus{}, err
 	}
+	if state == types.Cooling {
+		service.coolingIPs[ipconfig.ID] = now
+	}
 
 	delete(service.PodIPIDByPodInterfaceKey, podInfo.Key())
 	logger.Printf("[setIPConfigAsAvailable] Deleted outdated pod info %s from PodIPIDByOrchestratorContext since IP %s with ID %s will be released and set as Available",
@@ -631,6 +682,13 @@ func (service *HTTPRestService) unassignIPConfig(ipconfig cns.IPConfigurationSta
 // Todo - CNI should also pass the IPAddress which needs to be released to validate if that is the right IP allcoated
 // in the first place.
 func (service *HTTPRestService) releaseIPConfigs(podInfo cns.PodInfo) error {
+	// the cooling IPs are saved once the service lock is released.
+	cooling := false
+	defer func() {
+		if cooling {
+			service.saveCoolingIPs()
+		}
+	}()
 	service.Lock()
 	defer service.Unlock()
 	ipsToBeReleased := make([]cns.IPConfigurationStatus, 0)
@@ -650,13 +708,16 @@ func (service *HTTPRestService) releaseIPConfigs(podInfo cns.PodInfo) error {
 	}
 
 	failedToReleaseIP := false
+	now := service.now()
 	for _, ip := range ipsToBeReleased { //nolint:gocritic // ignore copy
 		logger.Printf("[releaseIPConfigs] Releasing IP %s for pod %+v", ip.IPAddress, podInfo)
-		if _, err := service.unassignIPConfig(ip, podInfo); err != nil {
+		released, err := service.unassignIPConfig(ip, podInfo, true, now, "releaseIPConfigs")
+		if err != nil {
 			logger.Errorf("[releaseIPConfigs] Failed to release IP %s for pod %+v error: %+v", ip.IPAddress, podInfo, err)
 			failedToReleaseIP = true
 			break
 		}
+		cooling = cooling || released.GetState() == types.Cooling
 
 		logger.Printf("[releaseIPConfigs] Released IP %s for pod %+v", ip.IPAddress, podInfo)
 	}
@@ -664,7 +725,7 @@ func (service *HTTPRestService) releaseIPConfigs(podInfo cns.PodInfo) error {
 	if failedToReleaseIP {
 		// reassigns all of the released IPs if we aren't able to release all of them
 		for _, ip := range ipsToBeReleased { //nolint:gocritic // ignore copy
-			if err := service.assignIPConfig(ip, podInfo); err != nil {
+			if err := service.assignIPConfig(ip, podInfo, "releaseIPConfigs"); err != nil {
 				logger.Errorf("[releaseIPConfigs] failed to mark IPConfig [%+v] back to Assigned. err: %v", ip, err)
 			}
 		}
@@ -688,8 +749,11 @@ func (service *HTTPRestService) MarkExistingIPsAsPendingRelease(pendingIPIDs []s
 			}
 
 			logger.Printf("[MarkExistingIPsAsPending]: Marking IP [%+v] to PendingRelease", ipconfig)
+			previous := ipconfig
 			ipconfig.SetState(types.PendingRelease)
 			service.PodIPConfigState[id] = ipconfig
+			service.recordIPHistory(&previous, &ipconfig, "MarkExistingIPsAsPendingRelease")
+			service.publishIPStateChange(previous.GetState(), &ipconfig)
 		} else {
 			logger.Errorf("Inconsistent state, ipconfig with ID [%v] marked as pending release, but does not exist in state", id)
 		}
@@ -727,6 +791,13 @@ func (service *HTTPRestService) GetExistingIPConfig(podInfo cns.PodInfo) ([]cns.
 
 // Assigns a pod with all IPs desired
 func (service *HTTPRestService) AssignDesiredIPConfigs(podInfo cns.PodInfo, desiredIPAddresses []string) ([]cns.PodIpInfo, error) {
+	// the reservations are saved once the service lock is released.
+	expired := false
+	defer func() {
+		if expired {
+			service.saveIPReservations()
+		}
+	}()
 	service.Lock()
 	defer service.Unlock()
 
@@ -736,6 +807,9 @@ func (service *HTTPRestService) AssignDesiredIPConfigs(podInfo cns.PodInfo, desi
 	if numOfNCs == 0 {
 		return nil, ErrNoNCs
 	}
+	now := service.now()
+	expired = service.expireIPReservationsUntransacted(now)
+	service.releaseCooledIPsUntransacted(now)
 	// Sets the number of desired IPs equal to the number of desired IPs passed in
 	numDesiredIPAddresses := len(desiredIPAddresses)
 	// Creates a slice of PodIpInfo with the size as number of NCs to hold the result for assigned IP configs
@@ -775,6 +849,12 @@ func (service *HTTPRestService) AssignDesiredIPConfigs(podInfo cns.PodInfo, desi
 			// This race can happen during restart, where CNS state is lost and thus we have lost the NC programmed version
 			// As part of reconcile, we mark IPs as Assigned which are already assigned to Pods (listed from APIServer)
 			ipConfigsToAssign = append(ipConfigsToAssign, ipConfig)
+		case types.Reserved:
+			if !service.isReservedForUntransacted(ipConfig.ID, podInfo, now) {
+				//nolint:goerr113 // return error
+				return podIPInfo, fmt.Errorf("[AssignDesiredIPConfigs] Desired IP is reserved for another pod %+v, requested for pod %+v", ipConfig, podInfo)
+			}
+			ipConfigsToAssign = append(ipConfigsToAssign, ipConfig)
 		default:
 			logger.Errorf("[AssignDesiredIPConfigs] Desired IP is not available %+v", ipConfig)
 			//nolint:goerr113 // return error
@@ -796,7 +876,7 @@ func (service *HTTPRestService) AssignDesiredIPConfigs(podInfo cns.PodInfo, desi
 	failedToAssignIP := false
 	// assigns all IPs that were found as available to the pod
 	for i := range ipConfigsToAssign {
-		if err := service.assignIPConfig(ipConfigsToAssign[i], podInfo); err != nil {
+		if err := service.assignIPConfig(ipConfigsToAssign[i], podInfo, "AssignDesiredIPConfigs"); err != nil {
 			logger.Errorf(err.Error())
 			failedToAssignIP = true
 			break
@@ -814,7 +894,7 @@ func (service *HTTPRestService) AssignDesiredIPConfigs(podInfo cns.PodInfo, desi
 	if failedToAssignIP {
 		logger.Printf("[AssignDesiredIPConfigs] Failed to retrieve all desired IPs. Releasing all IPs that were found")
 		for i := range ipConfigsToAssign {
-			_, err := service.unassignIPConfig(ipConfigsToAssign[i], podInfo)
+			_, err := service.unassignIPConfig(ipConfigsToAssign[i], podInfo, false, now, "AssignDesiredIPConfigs")
 			if err != nil {
 				logger.Errorf("[AssignDesiredIPConfigs] failed to mark IPConfig [%+v] back to Available. err: %v", ipConfigsToAssign[i], err)
 			}
@@ -836,12 +916,23 @@ func (service *HTTPRestService) AssignAvailableIPConfigs(podInfo cns.PodInfo) ([
 	if numOfNCs == 0 {
 		return nil, ErrNoNCs
 	}
+	// the reservations are saved once the service lock is released.
+	expired := false
+	defer func() {
+		if expired {
+			service.saveIPReservations()
+		}
+	}()
 	service.Lock()
 	defer service.Unlock()
+	now := service.now()
+	expired = service.expireIPReservationsUntransacted(now)
+	service.releaseCooledIPsUntransacted(now)
 	// Creates a slice of PodIpInfo with the size as number of NCs to hold the result for assigned IP configs
 	podIPInfo := make([]cns.PodIpInfo, numOfNCs)
-	// This map is used to store whether or not we have found an available IP from an NC when looping through the pool
-	ipsToAssign := make(map[string]cns.IPConfigurationStatus)
+	// This map is used to store whether or not we have found an available IP from an NC when looping through the pool.
+	// It starts with any IPs reserved for the pod.
+	ipsToAssign := service.reservedIPConfigsUntransacted(podInfo)
 
 	// Searches for available IPs in the pool
 	for _, ipState := range service.PodIPConfigState {
@@ -875,7 +966,7 @@ func (service *HTTPRestService) AssignAvailableIPConfigs(podInfo cns.PodInfo) ([
 	numIPConfigsAssigned := 0
 	// assigns all IPs in the map to the pod
 	for _, ip := range ipsToAssign { //nolint:gocritic // ignore copy
-		if err := service.assignIPConfig(ip, podInfo); err != nil {
+		if err := service.assignIPConfig(ip, podInfo, "AssignAvailableIPConfigs"); err != nil {
 			logger.Errorf(err.Error())
 			failedToAssignIP = true
 			break
@@ -893,7 +984,7 @@ func (service *HTTPRestService) AssignAvailableIPConfigs(podInfo cns.PodInfo) ([
 	if failedToAssignIP {
 		logger.Printf("[AssignAvailableIPConfigs] failed to assign enough IPs. Releasing all IPs that were found")
 		for _, ipState := range ipsToAssign { //nolint:gocritic // ignore copy
-			_, err := service.unassignIPConfig(ipState, podInfo)
+			_, err := service.unassignIPConfig(ipState, podInfo, false, now, "AssignAvailableIPConfigs")
 			if err != nil {
 				logger.Errorf("[AssignAvailableIPConfigs] failed to mark IPConfig [%+v] back to Available. err: %v", ipState, err)
 			}
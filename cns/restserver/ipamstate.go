@@ -0,0 +1,253 @@
+package restserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	acn "github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/pkg/errors"
+)
+
+// IPAMStateVersion is the version of the IPAMState format. It is bumped on incompatible changes,
+// and archives of other versions are not imported.
+const IPAMStateVersion = 1
+
+var (
+	ErrIPAMStateNoSigningKey = errors.New("no IPAM state signing key configured")
+	ErrIPAMStateSignature    = errors.New("invalid IPAM state signature")
+	ErrIPAMStateVersion      = errors.New("unsupported IPAM state version")
+	ErrIPAMStateInconsistent = errors.New("IPAM state is inconsistent with the NNC")
+	ErrIPAMStateNode         = errors.New("IPAM state is of another node")
+)
+
+// IPAMState is a snapshot of the Pod IP assignments of a Node, used to recover the state of a Node
+// without draining its Pods. It only holds what is not rebuilt from the NNC: the NCs, and the IPs
+// which are not assigned, are reconciled from the NNC when the state is imported, so it can only be
+// imported in CRD mode.
+type IPAMState struct {
+	Version   int
+	CreatedAt time.Time
+	NodeID    string
+	// AssignedIPConfigs are the Assigned IPs with their Pods, by IP ID.
+	AssignedIPConfigs map[string]cns.IPConfigurationStatus
+	EndpointState     map[string]*EndpointInfo
+}
+
+var _ cns.PodInfoByIPProvider = (*IPAMState)(nil)
+
+// IPAMStateArchive is an IPAMState signed with HMAC-SHA256, as exported by CNS.
+type IPAMStateArchive struct {
+	Version   int
+	State     json.RawMessage
+	Signature []byte
+}
+
+// ExportIPAMStateResponse is the response to an IPAM state export.
+type ExportIPAMStateResponse struct {
+	Archive  *IPAMStateArchive
+	Response Response
+}
+
+// ExportIPAMState returns a snapshot of the IPAM state.
+func (service *HTTPRestService) ExportIPAMState() *IPAMState {
+	service.RLock()
+	defer service.RUnlock()
+	state := &IPAMState{
+		Version:           IPAMStateVersion,
+		CreatedAt:         time.Now(),
+		NodeID:            service.state.NodeID,
+		AssignedIPConfigs: make(map[string]cns.IPConfigurationStatus),
+		EndpointState:     make(map[string]*EndpointInfo, len(service.EndpointState)),
+	}
+	for ipID := range service.PodIPConfigState {
+		if ipConfig := service.PodIPConfigState[ipID]; ipConfig.GetState() == types.Assigned {
+			state.AssignedIPConfigs[ipID] = ipConfig
+		}
+	}
+	// the endpoint infos are updated in place, so they are copied before the lock is released.
+	for containerID, info := range service.EndpointState {
+		state.EndpointState[containerID] = info.clone()
+	}
+	return state
+}
+
+func (e *EndpointInfo) clone() *EndpointInfo {
+	if e == nil {
+		return nil
+	}
+	c := &EndpointInfo{
+		PodName:       e.PodName,
+		PodNamespace:  e.PodNamespace,
+		IfnameToIPMap: make(map[string]*IPInfo, len(e.IfnameToIPMap)),
+	}
+	for ifname, ipInfo := range e.IfnameToIPMap {
+		if ipInfo == nil {
+			c.IfnameToIPMap[ifname] = nil
+			continue
+		}
+		c.IfnameToIPMap[ifname] = &IPInfo{
+			IPv4: append([]net.IPNet(nil), ipInfo.IPv4...),
+			IPv6: append([]net.IPNet(nil), ipInfo.IPv6...),
+		}
+	}
+	return c
+}
+
+// SignIPAMState signs the IPAM state with the key.
+func SignIPAMState(state *IPAMState, key []byte) (*IPAMStateArchive, error) {
+	if len(key) == 0 {
+		return nil, ErrIPAMStateNoSigningKey
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal IPAM state")
+	}
+	return &IPAMStateArchive{
+		Version:   state.Version,
+		State:     b,
+		Signature: signIPAMState(b, key),
+	}, nil
+}
+
+// OpenIPAMStateArchive verifies the signature and version of the archive and returns its IPAM state.
+func OpenIPAMStateArchive(archive *IPAMStateArchive, key []byte) (*IPAMState, error) {
+	if len(key) == 0 {
+		return nil, ErrIPAMStateNoSigningKey
+	}
+	if !hmac.Equal(archive.Signature, signIPAMState(archive.State, key)) {
+		return nil, ErrIPAMStateSignature
+	}
+	if archive.Version != IPAMStateVersion {
+		return nil, errors.Wrapf(ErrIPAMStateVersion, "archive version %d, supported version %d", archive.Version, IPAMStateVersion)
+	}
+	var state IPAMState
+	if err := json.Unmarshal(archive.State, &state); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal IPAM state")
+	}
+	if state.Version != archive.Version {
+		return nil, errors.Wrapf(ErrIPAMStateVersion, "state version %d, archive version %d", state.Version, archive.Version)
+	}
+	return &state, nil
+}
+
+func signIPAMState(b, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return mac.Sum(nil)
+}
+
+// ValidateNodeID checks that the state was exported by the Node, so that a state of another Node which
+// is signed with the same key is not imported.
+func (s *IPAMState) ValidateNodeID(nodeID string) error {
+	if s.NodeID != nodeID {
+		return errors.Wrapf(ErrIPAMStateNode, "state of node %q imported on node %q", s.NodeID, nodeID)
+	}
+	return nil
+}
+
+// PodInfoByIP implements cns.PodInfoByIPProvider with the Pods of the assigned IPs of the state,
+// so that an imported state can be reconciled like any other source of Pods.
+func (s *IPAMState) PodInfoByIP() (map[string]cns.PodInfo, error) {
+	podInfoByIP := map[string]cns.PodInfo{}
+	for ipID := range s.AssignedIPConfigs {
+		ipConfig := s.AssignedIPConfigs[ipID]
+		if ipConfig.GetState() != types.Assigned || ipConfig.PodInfo == nil {
+			continue
+		}
+		if prev, found := podInfoByIP[ipConfig.IPAddress]; found {
+			return nil, errors.Wrapf(cns.ErrDuplicateIP, "duplicate ip %s found for different pods: pod: %+v, pod: %+v", ipConfig.IPAddress, ipConfig.PodInfo, prev)
+		}
+		podInfoByIP[ipConfig.IPAddress] = ipConfig.PodInfo
+	}
+	return podInfoByIP, nil
+}
+
+// ValidateNNC checks that every assigned IP of the state belongs to the same NC in the NNC,
+// so that an imported state does not assign IPs the Node does not have anymore.
+func (s *IPAMState) ValidateNNC(nnc *v1alpha.NodeNetworkConfig) error {
+	ncs := make(map[string]*v1alpha.NetworkContainer, len(nnc.Status.NetworkContainers))
+	for i := range nnc.Status.NetworkContainers {
+		ncs[nnc.Status.NetworkContainers[i].ID] = &nnc.Status.NetworkContainers[i]
+	}
+	for ipID := range s.AssignedIPConfigs {
+		ipConfig := s.AssignedIPConfigs[ipID]
+		if ipConfig.GetState() != types.Assigned {
+			continue
+		}
+		nc, found := ncs[ipConfig.NCID]
+		if !found {
+			return errors.Wrapf(ErrIPAMStateInconsistent, "NC %s of assigned IP %s is not in the NNC", ipConfig.NCID, ipConfig.IPAddress)
+		}
+		if !ncHasIP(nc, ipConfig.IPAddress) {
+			return errors.Wrapf(ErrIPAMStateInconsistent, "assigned IP %s is not in NC %s", ipConfig.IPAddress, ipConfig.NCID)
+		}
+	}
+	return nil
+}
+
+func ncHasIP(nc *v1alpha.NetworkContainer, ip string) bool {
+	for _, assignment := range nc.IPAssignments {
+		if assignment.IP == ip {
+			return true
+		}
+	}
+	if nc.AssignmentMode != v1alpha.Static {
+		return false
+	}
+	// static NCs are assigned a prefix of IPs as their primary IP.
+	prefix, err := netip.ParsePrefix(nc.PrimaryIP)
+	if err != nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	return err == nil && prefix.Contains(addr)
+}
+
+// ImportEndpointState replaces the endpoint state with the one of the IPAM state, if CNS manages the
+// endpoint state. The IP assignments of the IPAM state are restored by reconciling it as a cns.PodInfoByIPProvider,
+// which validates it against the NNC, so the endpoint state is only imported once that has succeeded.
+func (service *HTTPRestService) ImportEndpointState(s *IPAMState) error {
+	if service.Options[acn.OptManageEndpointState] != true || service.EndpointStateStore == nil {
+		return nil
+	}
+	service.Lock()
+	defer service.Unlock()
+	service.EndpointState = make(map[string]*EndpointInfo, len(s.EndpointState))
+	for containerID, info := range s.EndpointState {
+		service.EndpointState[containerID] = info
+	}
+	if err := service.EndpointStateStore.Write(EndpointStoreKey, service.EndpointState); err != nil {
+		return errors.Wrap(err, "failed to write imported endpoint state")
+	}
+	return nil
+}
+
+// handleDebugIPAMState exports the IPAM state as a signed archive.
+func (service *HTTPRestService) handleDebugIPAMState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var resp ExportIPAMStateResponse
+	archive, err := SignIPAMState(service.ExportIPAMState(), service.IPAMStateSigningKey)
+	if err != nil {
+		resp.Response = Response{
+			ReturnCode: types.UnexpectedError,
+			Message:    err.Error(),
+		}
+	} else {
+		resp.Archive = archive
+	}
+	w.Header().Set(cnsReturnCode, resp.Response.ReturnCode.String())
+	err = service.Listener.Encode(w, &resp)
+	logger.Response(service.Name, resp.Response, resp.Response.ReturnCode, err)
+}
@@ -0,0 +1,131 @@
+package restserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testIPAMStateKey = []byte("test-key")
+
+func TestIPAMStateArchive(t *testing.T) {
+	state := &IPAMState{Version: IPAMStateVersion, NodeID: "node"}
+	_, err := SignIPAMState(state, nil)
+	require.ErrorIs(t, err, ErrIPAMStateNoSigningKey)
+
+	archive, err := SignIPAMState(state, testIPAMStateKey)
+	require.NoError(t, err)
+	opened, err := OpenIPAMStateArchive(archive, testIPAMStateKey)
+	require.NoError(t, err)
+	assert.Equal(t, "node", opened.NodeID)
+
+	_, err = OpenIPAMStateArchive(archive, []byte("other-key"))
+	require.ErrorIs(t, err, ErrIPAMStateSignature)
+
+	tampered := *archive
+	tampered.State = []byte(`{"Version":1,"NodeID":"other"}`)
+	_, err = OpenIPAMStateArchive(&tampered, testIPAMStateKey)
+	require.ErrorIs(t, err, ErrIPAMStateSignature)
+
+	state.Version = IPAMStateVersion + 1
+	archive, err = SignIPAMState(state, testIPAMStateKey)
+	require.NoError(t, err)
+	_, err = OpenIPAMStateArchive(archive, testIPAMStateKey)
+	require.ErrorIs(t, err, ErrIPAMStateVersion)
+}
+
+func TestIPAMStateValidateNodeID(t *testing.T) {
+	state := &IPAMState{Version: IPAMStateVersion, NodeID: "node"}
+	require.NoError(t, state.ValidateNodeID("node"))
+	require.ErrorIs(t, state.ValidateNodeID("other"), ErrIPAMStateNode)
+}
+
+func TestIPAMStateValidateNNC(t *testing.T) {
+	assigned, _ := NewPodStateWithOrchestratorContext(testIP1, testIPID1, testNCID, types.Assigned, ipPrefixBitsv4, 0, testPod1Info)
+	state := &IPAMState{AssignedIPConfigs: map[string]cns.IPConfigurationStatus{
+		assigned.ID: assigned,
+		testIPID2:   NewPodState(testIP2, testIPID2, "other-nc", types.Available, 0),
+	}}
+
+	tests := []struct {
+		name    string
+		nc      v1alpha.NetworkContainer
+		wantErr bool
+	}{
+		{"ip assigned to nc", v1alpha.NetworkContainer{ID: testNCID, IPAssignments: []v1alpha.IPAssignment{{IP: testIP1}}}, false},
+		{"ip in static nc prefix", v1alpha.NetworkContainer{ID: testNCID, AssignmentMode: v1alpha.Static, PrimaryIP: "10.0.0.0/28"}, false},
+		{"ip not in nc", v1alpha.NetworkContainer{ID: testNCID, IPAssignments: []v1alpha.IPAssignment{{IP: testIP2}}}, true},
+		{"nc not in nnc", v1alpha.NetworkContainer{ID: "other-nc", IPAssignments: []v1alpha.IPAssignment{{IP: testIP1}}}, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			nnc := &v1alpha.NodeNetworkConfig{Status: v1alpha.NodeNetworkConfigStatus{NetworkContainers: []v1alpha.NetworkContainer{tt.nc}}}
+			err := state.ValidateNNC(nnc)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrIPAMStateInconsistent)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestIPAMStateExportAndReconcile(t *testing.T) {
+	svc := getTestService()
+	ipconfigs := map[string]cns.IPConfigurationStatus{}
+	for _, state := range []cns.IPConfigurationStatus{
+		NewPodState(testIP1, testIPID1, testNCID, types.Available, 0),
+		NewPodState(testIP2, testIPID2, testNCID, types.Available, 0),
+	} {
+		ipconfigs[state.ID] = state
+	}
+	require.NoError(t, UpdatePodIPConfigState(t, svc, ipconfigs, testNCID))
+	_, err := requestIPAddressAndGetState(t, newIPConfigsRequest(t, testPod1Info, testIP2))
+	require.NoError(t, err)
+
+	archive, err := SignIPAMState(svc.ExportIPAMState(), testIPAMStateKey)
+	require.NoError(t, err)
+	state, err := OpenIPAMStateArchive(archive, testIPAMStateKey)
+	require.NoError(t, err)
+	// only the assigned IPs are exported.
+	require.Len(t, state.AssignedIPConfigs, 1)
+	assert.Contains(t, state.AssignedIPConfigs, testIPID2)
+
+	podInfoByIP, err := state.PodInfoByIP()
+	require.NoError(t, err)
+	require.Len(t, podInfoByIP, 1)
+	assert.Equal(t, testPod1Info.Key(), podInfoByIP[testIP2].Key())
+
+	// a CNS that lost its state assigns the same IPs to the Pods again, with the NCs of the NNC.
+	ncReq := svc.state.ContainerStatus[testNCID].CreateNetworkContainerRequest
+	restored := getTestService()
+	code := restored.ReconcileIPAMState([]*cns.CreateNetworkContainerRequest{&ncReq}, podInfoByIP, &v1alpha.NodeNetworkConfig{})
+	require.Equal(t, types.Success, code)
+	assert.Equal(t, types.Assigned, getIPState(restored, testIPID2))
+	assert.Equal(t, types.Available, getIPState(restored, testIPID1))
+	assert.Equal(t, []string{testIPID2}, restored.PodIPIDByPodInterfaceKey[testPod1Info.Key()])
+}
+
+func TestIPAMStateExportCopiesEndpointState(t *testing.T) {
+	svc := getTestService()
+	_, ipNet, err := net.ParseCIDR("10.0.0.1/24")
+	require.NoError(t, err)
+	svc.EndpointState["container"] = &EndpointInfo{
+		PodName:       testPod1Info.Name(),
+		IfnameToIPMap: map[string]*IPInfo{"eth0": {IPv4: []net.IPNet{*ipNet}}},
+	}
+
+	state := svc.ExportIPAMState()
+	// the endpoint state can change once the export has released the lock.
+	svc.EndpointState["container"].IfnameToIPMap["eth1"] = &IPInfo{}
+	svc.EndpointState["container"].IfnameToIPMap["eth0"].IPv4[0] = net.IPNet{}
+
+	require.Len(t, state.EndpointState["container"].IfnameToIPMap, 1)
+	assert.Equal(t, *ipNet, state.EndpointState["container"].IfnameToIPMap["eth0"].IPv4[0])
+}
@@ -0,0 +1,123 @@
+package restserver
+
+import (
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/pkg/errors"
+)
+
+// ReleaseCooledIPConfigs makes the IPs that have been Cooling for the IPCooldown Available.
+// The pool monitor calls it periodically, so that IPs do not stay Cooling on a Node without IP requests.
+func (service *HTTPRestService) ReleaseCooledIPConfigs() {
+	service.Lock()
+	defer service.Unlock()
+	service.releaseCooledIPsUntransacted(service.now())
+}
+
+// releaseCooledIPsUntransacted makes the IPs that have been Cooling for the IPCooldown at now Available.
+func (service *HTTPRestService) releaseCooledIPsUntransacted(now time.Time) {
+	for ipID, since := range service.coolingIPs {
+		if now.Sub(since) <= service.IPCooldown {
+			continue
+		}
+		delete(service.coolingIPs, ipID)
+		// the IP may have been assigned again by a rolled back release, or removed with its NC.
+		if ipConfig, found := service.PodIPConfigState[ipID]; !found || ipConfig.GetState() != types.Cooling {
+			continue
+		}
+		if _, err := service.updateIPConfigState(ipID, types.Available, nil, "releaseCooledIPs"); err != nil {
+			logger.Errorf("[releaseCooledIPs] Failed to make cooled IP %s available: %v", ipID, err)
+		}
+	}
+}
+
+// loadCoolingIPs reads the persisted cooling IPs which are still Cooling at now. It runs when the state is
+// restored, before the IPs are known, so the IPs are made Cooling again as they are added to the pool.
+func (service *HTTPRestService) loadCoolingIPs(now time.Time) {
+	if service.store == nil {
+		return
+	}
+	var coolingIPs map[string]time.Time
+	if err := service.store.Read(coolingIPsStoreKey, &coolingIPs); err != nil {
+		if !errors.Is(err, store.ErrKeyNotFound) {
+			logger.Errorf("[loadCoolingIPs] Failed to restore cooling IPs: %v", err)
+		}
+		return
+	}
+
+	service.Lock()
+	defer service.Unlock()
+	for ipID, since := range coolingIPs {
+		if now.Sub(since) <= service.IPCooldown {
+			service.coolingIPs[ipID] = since
+		}
+	}
+	logger.Printf("[loadCoolingIPs] Restored %d cooling IPs", len(service.coolingIPs))
+}
+
+// coolIfCoolingUntransacted makes the Available IP Cooling again if it was Cooling at now.
+// It is called when an IP is added to the pool or becomes Available, so Cooling survives restarts.
+func (service *HTTPRestService) coolIfCoolingUntransacted(ipID string, now time.Time) {
+	since, found := service.coolingIPs[ipID]
+	if !found {
+		return
+	}
+	if now.Sub(since) > service.IPCooldown {
+		delete(service.coolingIPs, ipID)
+		return
+	}
+	if ipConfig := service.PodIPConfigState[ipID]; ipConfig.GetState() != types.Available {
+		return
+	}
+	if _, err := service.updateIPConfigState(ipID, types.Cooling, nil, "coolIfCooling"); err != nil {
+		logger.Errorf("[coolIfCooling] Failed to make IP %s cooling: %v", ipID, err)
+	}
+}
+
+// yieldCoolingIPsToPods stops Cooling the IPs that the running Pods use, so that the IPs can be assigned
+// to the Pods at reconcile.
+func (service *HTTPRestService) yieldCoolingIPsToPods(podInfoByIP map[string]cns.PodInfo) {
+	service.Lock()
+	defer service.Unlock()
+	for ipID := range service.coolingIPs {
+		ipConfig, found := service.PodIPConfigState[ipID]
+		if !found {
+			continue
+		}
+		podInfo, used := podInfoByIP[ipConfig.IPAddress]
+		if !used {
+			continue
+		}
+		logger.Printf("[yieldCoolingIPsToPods] Cooling IP %s is used by pod %s", ipConfig.IPAddress, podInfo.Key())
+		delete(service.coolingIPs, ipID)
+		if ipConfig.GetState() == types.Cooling {
+			if _, err := service.updateIPConfigState(ipID, types.Available, nil, "yieldCoolingIPsToPods"); err != nil {
+				logger.Errorf("[yieldCoolingIPsToPods] Failed to make cooling IP %s available: %v", ipConfig.IPAddress, err)
+			}
+		}
+	}
+}
+
+// saveCoolingIPs persists the cooling IPs. It must not be called while holding the service lock.
+// A cooling IP that fails to be persisted is Available again if CNS restarts.
+func (service *HTTPRestService) saveCoolingIPs() {
+	if service.store == nil {
+		return
+	}
+	// the save lock makes a snapshot taken later be written later, so the last write is never stale.
+	service.coolingIPsSaveLock.Lock()
+	defer service.coolingIPsSaveLock.Unlock()
+	service.RLock()
+	coolingIPs := make(map[string]time.Time, len(service.coolingIPs))
+	for ipID, since := range service.coolingIPs {
+		coolingIPs[ipID] = since
+	}
+	service.RUnlock()
+	if err := service.store.Write(coolingIPsStoreKey, coolingIPs); err != nil {
+		logger.Errorf("[saveCoolingIPs] Failed to persist cooling IPs: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package restserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withClock makes the service read the time from the returned clock, which only moves when advanced.
+func withClock(svc *HTTPRestService) (advance func(time.Duration)) {
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestReleasedIPCoolsBeforeReassignment(t *testing.T) {
+	svc := getTestService()
+	svc.IPCooldown = time.Hour
+	advance := withClock(svc)
+	available := NewPodState(testIP1, testIPID1, testNCID, types.Available, 0)
+	require.NoError(t, UpdatePodIPConfigState(t, svc, map[string]cns.IPConfigurationStatus{available.ID: available}, testNCID))
+
+	_, err := requestIPAddressAndGetState(t, newIPConfigsRequest(t, testPod1Info))
+	require.NoError(t, err)
+	require.NoError(t, svc.releaseIPConfigs(testPod1Info))
+	assert.Equal(t, types.Cooling, getIPState(svc, testIPID1))
+
+	// the Cooling IP is not assigned, even if desired, and is not released by the pool monitor.
+	_, err = requestIPAddressAndGetState(t, newIPConfigsRequest(t, testPod2Info))
+	require.Error(t, err)
+	_, err = requestIPAddressAndGetState(t, newIPConfigsRequest(t, testPod2Info, testIP1))
+	require.Error(t, err)
+	released, err := svc.MarkIPAsPendingRelease(1)
+	require.NoError(t, err)
+	assert.Empty(t, released)
+
+	// once cooled, the IP is available again.
+	advance(time.Hour + time.Second)
+	states, err := requestIPAddressAndGetState(t, newIPConfigsRequest(t, testPod2Info))
+	require.NoError(t, err)
+	assert.Equal(t, testIP1, states[0].IPAddress)
+}
+
+func TestReleasedIPCooldownDisabled(t *testing.T) {
+	svc := getTestService()
+	available := NewPodState(testIP1, testIPID1, testNCID, types.Available, 0)
+	require.NoError(t, UpdatePodIPConfigState(t, svc, map[string]cns.IPConfigurationStatus{available.ID: available}, testNCID))
+
+	_, err := requestIPAddressAndGetState(t, newIPConfigsRequest(t, testPod1Info))
+	require.NoError(t, err)
+	require.NoError(t, svc.releaseIPConfigs(testPod1Info))
+	assert.Equal(t, types.Available, getIPState(svc, testIPID1))
+}
+
+func TestReleaseCooledIPConfigs(t *testing.T) {
+	svc := getTestService()
+	svc.IPCooldown = time.Hour
+	advance := withClock(svc)
+	available := NewPodState(testIP1, testIPID1, testNCID, types.Available, 0)
+	require.NoError(t, UpdatePodIPConfigState(t, svc, map[string]cns.IPConfigurationStatus{available.ID: available}, testNCID))
+
+	_, err := requestIPAddressAndGetState(t, newIPConfigsRequest(t, testPod1Info))
+	require.NoError(t, err)
+	require.NoError(t, svc.releaseIPConfigs(testPod1Info))
+
+	advance(time.Hour)
+	svc.ReleaseCooledIPConfigs()
+	assert.Equal(t, types.Cooling, getIPState(svc, testIPID1))
+
+	// the sweep releases the cooled IP without any IP request.
+	advance(time.Second)
+	svc.ReleaseCooledIPConfigs()
+	assert.Equal(t, types.Available, getIPState(svc, testIPID1))
+}
+
+func TestLoadCoolingIPs(t *testing.T) {
+	svc := getTestService()
+	svc.store = store.NewMockStore("")
+	svc.IPCooldown = time.Hour
+	advance := withClock(svc)
+	available := NewPodState(testIP1, testIPID1, testNCID, types.Available, 0)
+	require.NoError(t, UpdatePodIPConfigState(t, svc, map[string]cns.IPConfigurationStatus{available.ID: available}, testNCID))
+	_, err := requestIPAddressAndGetState(t, newIPConfigsRequest(t, testPod1Info))
+	require.NoError(t, err)
+	require.NoError(t, svc.releaseIPConfigs(testPod1Info))
+
+	// after a restart, the IP is Cooling again once it is added to the pool, until it has cooled.
+	restarted := getTestService()
+	restarted.store = svc.store
+	restarted.IPCooldown = time.Hour
+	restarted.now = svc.now
+	advance(time.Minute)
+	restarted.loadCoolingIPs(restarted.now())
+	require.NoError(t, UpdatePodIPConfigState(t, restarted, map[string]cns.IPConfigurationStatus{available.ID: available}, testNCID))
+	assert.Equal(t, types.Cooling, getIPState(restarted, testIPID1))
+
+	advance(time.Hour)
+	restarted.ReleaseCooledIPConfigs()
+	assert.Equal(t, types.Available, getIPState(restarted, testIPID1))
+}
+
+func TestYieldCoolingIPsToPods(t *testing.T) {
+	svc := getTestService()
+	svc.IPCooldown = time.Hour
+	available := NewPodState(testIP1, testIPID1, testNCID, types.Available, 0)
+	require.NoError(t, UpdatePodIPConfigState(t, svc, map[string]cns.IPConfigurationStatus{available.ID: available}, testNCID))
+	_, err := requestIPAddressAndGetState(t, newIPConfigsRequest(t, testPod1Info))
+	require.NoError(t, err)
+	require.NoError(t, svc.releaseIPConfigs(testPod1Info))
+
+	// a running Pod already uses the cooling IP, so it can be assigned to the Pod at reconcile.
+	svc.yieldCoolingIPsToPods(map[string]cns.PodInfo{testIP1: testPod2Info})
+	assert.Equal(t, types.Available, getIPState(svc, testIPID1))
+	assert.Empty(t, svc.coolingIPs)
+}
@@ -0,0 +1,35 @@
+package restserver
+
+import (
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+)
+
+// recordIPHistory records the state change of the IP from previous to current.
+func (service *HTTPRestService) recordIPHistory(previous, current *cns.IPConfigurationStatus, caller string) {
+	if service.IPHistory == nil {
+		return
+	}
+	event := cns.IPHistoryEvent{
+		Timestamp:     time.Now(),
+		IPAddress:     current.IPAddress,
+		ID:            current.ID,
+		NCID:          current.NCID,
+		PreviousState: previous.GetState(),
+		State:         current.GetState(),
+		Caller:        caller,
+	}
+	// on release the Pod is no longer set, so record the Pod that released the IP.
+	podInfo := current.PodInfo
+	if podInfo == nil {
+		podInfo = previous.PodInfo
+	}
+	if podInfo != nil {
+		event.PodKey = podInfo.Key()
+		event.PodName = podInfo.Name()
+		event.PodNamespace = podInfo.Namespace()
+		event.InfraContainerID = podInfo.InfraContainerID()
+	}
+	service.IPHistory.Record(&event)
+}
@@ -0,0 +1,43 @@
+package restserver
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPHistoryRecordsAssignAndRelease(t *testing.T) {
+	svc := getTestService()
+	available := NewPodState(testIP1, testIPID1, testNCID, types.Available, 0)
+	require.NoError(t, UpdatePodIPConfigState(t, svc, map[string]cns.IPConfigurationStatus{available.ID: available}, testNCID))
+
+	_, err := requestIPAddressAndGetState(t, newIPConfigsRequest(t, testPod1Info))
+	require.NoError(t, err)
+	require.NoError(t, svc.releaseIPConfigs(testPod1Info))
+	_, err = svc.MarkIPAsPendingRelease(1)
+	require.NoError(t, err)
+
+	events := svc.IPHistory.Query(&cns.GetIPHistoryRequest{IPAddress: testIP1})
+	require.Len(t, events, 3)
+	for i, want := range []struct {
+		previous, state types.IPState
+		caller, pod     string
+	}{
+		{types.Available, types.Assigned, "AssignAvailableIPConfigs", testPod1Info.Name()},
+		{types.Assigned, types.Available, "releaseIPConfigs", testPod1Info.Name()},
+		{types.Available, types.PendingRelease, "MarkIPAsPendingRelease", ""},
+	} {
+		assert.Equal(t, want.previous, events[i].PreviousState)
+		assert.Equal(t, want.state, events[i].State)
+		assert.Equal(t, want.caller, events[i].Caller)
+		assert.Equal(t, want.pod, events[i].PodName)
+		assert.Equal(t, testNCID, events[i].NCID)
+	}
+	assert.Equal(t, testPod1Info.InfraContainerID(), events[1].InfraContainerID)
+
+	// the Pod that had the IP can be found.
+	assert.Len(t, svc.IPHistory.Query(&cns.GetIPHistoryRequest{PodName: testPod1Info.Name(), PodNamespace: testPod1Info.Namespace()}), 2)
+}
@@ -0,0 +1,392 @@
+package restserver
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/pkg/errors"
+)
+
+// ipReservation holds IPs for a Pod, by namespace and name, so that they are only assigned to that Pod.
+// Reserved IPs that are not assigned are in state Reserved. IPs that are assigned to the Pod stay reserved,
+// and go back to Reserved instead of Available when the Pod releases them, until the reservation expires.
+// Reservations are persisted apart from the rest of the CNS state, loaded when CNS starts, and reapplied to
+// their IPs as the IPs become Available.
+type ipReservation struct {
+	IPIDs  []string
+	Expiry time.Time
+}
+
+func ipReservationKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// reserveIPConfigsHandler reserves IPConfigs for a Pod ahead of its CNI ADD.
+func (service *HTTPRestService) reserveIPConfigsHandler(w http.ResponseWriter, r *http.Request) {
+	var req cns.ReserveIPConfigsRequest
+	err := service.Listener.Decode(w, r, &req)
+	operationName := "reserveIPConfigsHandler"
+	logger.Request(service.Name+operationName, req, err)
+	if err != nil {
+		return
+	}
+
+	resp := service.reserveIPConfigs(&req, time.Now())
+	if resp.Response.ReturnCode == types.Success {
+		publishIPStateMetrics(service.buildIPState())
+	}
+	w.Header().Set(cnsReturnCode, resp.Response.ReturnCode.String())
+	err = service.Listener.Encode(w, &resp)
+	logger.ResponseEx(service.Name+operationName, req, resp, resp.Response.ReturnCode, err)
+}
+
+// unreserveIPConfigsHandler drops the reservation of a Pod.
+func (service *HTTPRestService) unreserveIPConfigsHandler(w http.ResponseWriter, r *http.Request) {
+	var req cns.UnreserveIPConfigsRequest
+	err := service.Listener.Decode(w, r, &req)
+	operationName := "unreserveIPConfigsHandler"
+	logger.Request(service.Name+operationName, req, err)
+	if err != nil {
+		return
+	}
+
+	resp := cns.Response{}
+	service.Lock()
+	key := ipReservationKey(req.PodNamespace, req.PodName)
+	if _, found := service.state.IPReservations[key]; found {
+		service.dropIPReservationUntransacted(key)
+	} else {
+		resp.ReturnCode = types.NotFound
+		resp.Message = "no IPs are reserved for pod " + key
+	}
+	service.Unlock()
+	if resp.ReturnCode == types.Success {
+		service.saveIPReservations()
+		publishIPStateMetrics(service.buildIPState())
+	}
+
+	w.Header().Set(cnsReturnCode, resp.ReturnCode.String())
+	err = service.Listener.Encode(w, &resp)
+	logger.ResponseEx(service.Name+operationName, req, resp, resp.ReturnCode, err)
+}
+
+// reserveIPConfigs reserves the IPs for the Pod in the request, or extends its existing reservation.
+func (service *HTTPRestService) reserveIPConfigs(req *cns.ReserveIPConfigsRequest, now time.Time) cns.ReserveIPConfigsResponse {
+	failed := func(code types.ResponseCode, err error) cns.ReserveIPConfigsResponse {
+		return cns.ReserveIPConfigsResponse{Response: cns.Response{ReturnCode: code, Message: err.Error()}}
+	}
+	if req.PodName == "" || req.PodNamespace == "" {
+		return failed(types.InvalidRequest, errors.New("pod name and namespace are required"))
+	}
+	if req.TTLSeconds < 0 {
+		return failed(types.InvalidRequest, errors.Errorf("invalid reservation TTL %d", req.TTLSeconds))
+	}
+	if err := validateDesiredIPAddresses(req.DesiredIPAddresses); err != nil {
+		return failed(types.InvalidRequest, err)
+	}
+	ttl := cns.DefaultIPReservationTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+
+	// deferred first so that the reservations are saved once the service lock is released.
+	defer service.saveIPReservations()
+	service.Lock()
+	defer service.Unlock()
+	service.expireIPReservationsUntransacted(now)
+
+	key := ipReservationKey(req.PodNamespace, req.PodName)
+	if reservation, found := service.state.IPReservations[key]; found {
+		addresses := service.ipAddressesUntransacted(reservation.IPIDs)
+		if len(req.DesiredIPAddresses) != 0 && !sameIPAddresses(addresses, req.DesiredIPAddresses) {
+			return failed(types.InvalidRequest, errors.Errorf("pod %s already has IPs %v reserved", key, addresses))
+		}
+		reservation.Expiry = now.Add(ttl)
+		service.state.IPReservations[key] = reservation
+		logger.Printf("[reserveIPConfigs] Extended reservation of IPs %v for pod %s until %s", addresses, key, reservation.Expiry)
+		return cns.ReserveIPConfigsResponse{IPAddresses: addresses, Expiry: reservation.Expiry}
+	}
+
+	ipConfigs, err := service.selectIPConfigsToReserveUntransacted(req)
+	if err != nil {
+		return failed(types.FailedToAllocateIPConfig, err)
+	}
+
+	podInfo := cns.NewPodInfo("", "", req.PodName, req.PodNamespace)
+	reservation := ipReservation{Expiry: now.Add(ttl)}
+	for i := range ipConfigs {
+		// IPs already assigned to the Pod stay assigned, and are only held once it releases them.
+		if ipConfigs[i].GetState() != types.Assigned {
+			if _, err := service.updateIPConfigState(ipConfigs[i].ID, types.Reserved, podInfo, "reserveIPConfigs"); err != nil {
+				return failed(types.UnexpectedError, err)
+			}
+		}
+		reservation.IPIDs = append(reservation.IPIDs, ipConfigs[i].ID)
+	}
+	if service.state.IPReservations == nil {
+		service.state.IPReservations = map[string]ipReservation{}
+	}
+	service.state.IPReservations[key] = reservation
+
+	addresses := service.ipAddressesUntransacted(reservation.IPIDs)
+	logger.Printf("[reserveIPConfigs] Reserved IPs %v for pod %s until %s", addresses, key, reservation.Expiry)
+	return cns.ReserveIPConfigsResponse{IPAddresses: addresses, Expiry: reservation.Expiry}
+}
+
+// selectIPConfigsToReserveUntransacted picks the desired IPs, or the IPs already assigned to the Pod,
+// or else one available IP from each NC.
+func (service *HTTPRestService) selectIPConfigsToReserveUntransacted(req *cns.ReserveIPConfigsRequest) ([]cns.IPConfigurationStatus, error) {
+	assignedToPod := func(ipConfig *cns.IPConfigurationStatus) bool {
+		return ipConfig.GetState() == types.Assigned && ipConfig.PodInfo != nil &&
+			ipConfig.PodInfo.Name() == req.PodName && ipConfig.PodInfo.Namespace() == req.PodNamespace
+	}
+
+	if len(req.DesiredIPAddresses) != 0 {
+		desired := make(map[string]struct{}, len(req.DesiredIPAddresses))
+		for _, ip := range req.DesiredIPAddresses {
+			desired[ip] = struct{}{}
+		}
+		ipConfigs := make([]cns.IPConfigurationStatus, 0, len(desired))
+		for _, ipConfig := range service.PodIPConfigState { //nolint:gocritic // ignore copy
+			if _, found := desired[ipConfig.IPAddress]; !found {
+				continue
+			}
+			switch {
+			case ipConfig.GetState() == types.Available, assignedToPod(&ipConfig):
+				ipConfigs = append(ipConfigs, ipConfig)
+			default:
+				return nil, errors.Errorf("desired IP %s is not available, it is %s", ipConfig.IPAddress, ipConfig.GetState())
+			}
+		}
+		if len(ipConfigs) != len(desired) {
+			return nil, errors.Errorf("not all desired IPs %v were found in the pool", req.DesiredIPAddresses)
+		}
+		return ipConfigs, nil
+	}
+
+	var assigned []cns.IPConfigurationStatus
+	available := map[string]cns.IPConfigurationStatus{}
+	for _, ipConfig := range service.PodIPConfigState { //nolint:gocritic // ignore copy
+		if assignedToPod(&ipConfig) {
+			assigned = append(assigned, ipConfig)
+			continue
+		}
+		if _, found := available[ipConfig.NCID]; !found && ipConfig.GetState() == types.Available {
+			available[ipConfig.NCID] = ipConfig
+		}
+	}
+	if len(assigned) != 0 {
+		return assigned, nil
+	}
+	ipConfigs := make([]cns.IPConfigurationStatus, 0, len(service.state.ContainerStatus))
+	for ncID := range service.state.ContainerStatus {
+		ipConfig, found := available[ncID]
+		if !found {
+			return nil, errors.Errorf("not enough IPs available for %s to reserve", ncID)
+		}
+		ipConfigs = append(ipConfigs, ipConfig)
+	}
+	if len(ipConfigs) == 0 {
+		return nil, ErrNoNCs
+	}
+	return ipConfigs, nil
+}
+
+// expireIPReservationsUntransacted drops the reservations that have expired at now, and returns whether any did.
+// The caller saves the reservations once it releases the service lock.
+func (service *HTTPRestService) expireIPReservationsUntransacted(now time.Time) bool {
+	expired := false
+	for key, reservation := range service.state.IPReservations {
+		if now.Before(reservation.Expiry) {
+			continue
+		}
+		logger.Printf("[expireIPReservations] Reservation of IPs %v for pod %s expired", service.ipAddressesUntransacted(reservation.IPIDs), key)
+		service.dropIPReservationUntransacted(key)
+		expired = true
+	}
+	return expired
+}
+
+// loadIPReservations reads the persisted reservations which are unexpired at now. It runs when the state is
+// restored, before the IPs are known, so the IPs are held as they are added to the pool.
+func (service *HTTPRestService) loadIPReservations(now time.Time) {
+	if service.store == nil {
+		return
+	}
+	var reservations map[string]ipReservation
+	if err := service.store.Read(ipReservationsStoreKey, &reservations); err != nil {
+		if !errors.Is(err, store.ErrKeyNotFound) {
+			logger.Errorf("[loadIPReservations] Failed to restore IP reservations: %v", err)
+		}
+		return
+	}
+
+	service.Lock()
+	defer service.Unlock()
+	for key, reservation := range reservations {
+		if !now.Before(reservation.Expiry) {
+			continue
+		}
+		service.state.IPReservations[key] = reservation
+		logger.Printf("[loadIPReservations] Restored reservation of IPs %v for pod %s until %s", reservation.IPIDs, key, reservation.Expiry)
+	}
+}
+
+// holdIfReservedUntransacted makes the Available IP Reserved again if a reservation holds it.
+// It is called when an IP is added to the pool or becomes Available, so reservations survive restarts.
+func (service *HTTPRestService) holdIfReservedUntransacted(ipID string) {
+	for key, reservation := range service.state.IPReservations {
+		for _, id := range reservation.IPIDs {
+			if id != ipID {
+				continue
+			}
+			namespace, name, _ := strings.Cut(key, "/")
+			if _, err := service.updateIPConfigState(ipID, types.Reserved, cns.NewPodInfo("", "", name, namespace), "holdIfReserved"); err != nil {
+				logger.Errorf("[holdIfReserved] Failed to reserve IP %s for pod %s: %v", ipID, key, err)
+			}
+			return
+		}
+	}
+}
+
+// pruneIPReservations drops the expired reservations and the IPs of the reservations that are no longer
+// in the pool, once the IPAM state is reconciled.
+func (service *HTTPRestService) pruneIPReservations(now time.Time) {
+	service.Lock()
+	service.expireIPReservationsUntransacted(now)
+	for key, reservation := range service.state.IPReservations {
+		ipIDs := make([]string, 0, len(reservation.IPIDs))
+		for _, id := range reservation.IPIDs {
+			if _, found := service.PodIPConfigState[id]; found {
+				ipIDs = append(ipIDs, id)
+			}
+		}
+		reservation.IPIDs = ipIDs
+		service.state.IPReservations[key] = reservation
+	}
+	service.Unlock()
+	service.saveIPReservations()
+}
+
+// yieldReservedIPsToPods drops the IPs from the reservations of other Pods when the running Pods already use them,
+// since reservations don't take back assigned IPs, so that the IPs can be assigned to the Pods at reconcile.
+func (service *HTTPRestService) yieldReservedIPsToPods(podInfoByIP map[string]cns.PodInfo) {
+	service.Lock()
+	defer service.Unlock()
+	for key, reservation := range service.state.IPReservations {
+		ipIDs := make([]string, 0, len(reservation.IPIDs))
+		for _, id := range reservation.IPIDs {
+			ipConfig, found := service.PodIPConfigState[id]
+			if !found {
+				ipIDs = append(ipIDs, id)
+				continue
+			}
+			podInfo, used := podInfoByIP[ipConfig.IPAddress]
+			if !used || ipReservationKey(podInfo.Namespace(), podInfo.Name()) == key {
+				ipIDs = append(ipIDs, id)
+				continue
+			}
+			logger.Printf("[yieldReservedIPsToPods] IP %s reserved for pod %s is used by pod %s", ipConfig.IPAddress, key, podInfo.Key())
+			if ipConfig.GetState() == types.Reserved {
+				if _, err := service.updateIPConfigState(id, types.Available, nil, "yieldReservedIPsToPods"); err != nil {
+					logger.Errorf("[yieldReservedIPsToPods] Failed to make reserved IP %s available: %v", ipConfig.IPAddress, err)
+				}
+			}
+		}
+		reservation.IPIDs = ipIDs
+		service.state.IPReservations[key] = reservation
+	}
+}
+
+// saveIPReservations persists the reservations. It must not be called while holding the service lock.
+// A reservation that fails to be persisted still holds its IPs until CNS restarts.
+func (service *HTTPRestService) saveIPReservations() {
+	if service.store == nil {
+		return
+	}
+	// the save lock makes a snapshot taken later be written later, so the last write is never stale.
+	service.ipReservationsSaveLock.Lock()
+	defer service.ipReservationsSaveLock.Unlock()
+	service.RLock()
+	// the IP ID slices of a reservation are replaced, never changed in place, so copying the map is enough.
+	reservations := make(map[string]ipReservation, len(service.state.IPReservations))
+	for key, reservation := range service.state.IPReservations {
+		reservations[key] = reservation
+	}
+	service.RUnlock()
+	if err := service.store.Write(ipReservationsStoreKey, reservations); err != nil {
+		logger.Errorf("[saveIPReservations] Failed to persist IP reservations: %v", err)
+	}
+}
+
+// dropIPReservationUntransacted drops the reservation and makes its IPs that are not assigned Available.
+func (service *HTTPRestService) dropIPReservationUntransacted(key string) {
+	for _, id := range service.state.IPReservations[key].IPIDs {
+		if ipConfig, found := service.PodIPConfigState[id]; found && ipConfig.GetState() == types.Reserved {
+			if _, err := service.updateIPConfigState(id, types.Available, nil, "dropIPReservation"); err != nil {
+				logger.Errorf("[dropIPReservation] Failed to make reserved IP %s available: %v", ipConfig.IPAddress, err)
+			}
+		}
+	}
+	delete(service.state.IPReservations, key)
+}
+
+// reservedIPConfigsUntransacted returns the IPs reserved for the Pod that are not assigned, by NC ID.
+func (service *HTTPRestService) reservedIPConfigsUntransacted(podInfo cns.PodInfo) map[string]cns.IPConfigurationStatus {
+	reserved := map[string]cns.IPConfigurationStatus{}
+	reservation, found := service.state.IPReservations[ipReservationKey(podInfo.Namespace(), podInfo.Name())]
+	if !found {
+		return reserved
+	}
+	for _, id := range reservation.IPIDs {
+		if ipConfig, found := service.PodIPConfigState[id]; found && ipConfig.GetState() == types.Reserved {
+			reserved[ipConfig.NCID] = ipConfig
+		}
+	}
+	return reserved
+}
+
+// isReservedForUntransacted returns whether the IP is held by a reservation for the Pod that is unexpired at now.
+func (service *HTTPRestService) isReservedForUntransacted(ipID string, podInfo cns.PodInfo, now time.Time) bool {
+	reservation, found := service.state.IPReservations[ipReservationKey(podInfo.Namespace(), podInfo.Name())]
+	if !found || !now.Before(reservation.Expiry) {
+		return false
+	}
+	for _, id := range reservation.IPIDs {
+		if id == ipID {
+			return true
+		}
+	}
+	return false
+}
+
+func (service *HTTPRestService) ipAddressesUntransacted(ipIDs []string) []string {
+	addresses := make([]string, 0, len(ipIDs))
+	for _, id := range ipIDs {
+		if ipConfig, found := service.PodIPConfigState[id]; found {
+			addresses = append(addresses, ipConfig.IPAddress)
+		}
+	}
+	return addresses
+}
+
+func sameIPAddresses(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]struct{}, len(a))
+	for _, ip := range a {
+		set[ip] = struct{}{}
+	}
+	for _, ip := range b {
+		if _, found := set[ip]; !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package restserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIPConfigsRequest(t *testing.T, podInfo cns.PodInfo, desiredIPs ...string) cns.IPConfigsRequest {
+	b, err := podInfo.OrchestratorContext()
+	require.NoError(t, err)
+	return cns.IPConfigsRequest{
+		PodInterfaceID:      podInfo.InterfaceID(),
+		InfraContainerID:    podInfo.InfraContainerID(),
+		OrchestratorContext: b,
+		DesiredIPAddresses:  desiredIPs,
+	}
+}
+
+func getIPState(svc *HTTPRestService, ipID string) types.IPState {
+	ipConfig := svc.PodIPConfigState[ipID]
+	return ipConfig.GetState()
+}
+
+func TestReserveIPConfigsHoldsIPForPod(t *testing.T) {
+	svc := getTestService()
+	ipconfigs := map[string]cns.IPConfigurationStatus{}
+	for _, state := range []cns.IPConfigurationStatus{
+		NewPodState(testIP1, testIPID1, testNCID, types.Available, 0),
+		NewPodState(testIP2, testIPID2, testNCID, types.Available, 0),
+		NewPodState(testIP3, testIPID3, testNCID, types.Available, 0),
+	} {
+		ipconfigs[state.ID] = state
+	}
+	require.NoError(t, UpdatePodIPConfigState(t, svc, ipconfigs, testNCID))
+
+	resp := svc.reserveIPConfigs(&cns.ReserveIPConfigsRequest{
+		PodName:            testPod1Info.Name(),
+		PodNamespace:       testPod1Info.Namespace(),
+		DesiredIPAddresses: []string{testIP1},
+	}, time.Now())
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+	assert.Equal(t, []string{testIP1}, resp.IPAddresses)
+	assert.Equal(t, types.Reserved, getIPState(svc, testIPID1))
+
+	// the reserved IP is not assigned to other Pods, even if desired.
+	_, err := requestIPAddressAndGetState(t, newIPConfigsRequest(t, testPod2Info, testIP1))
+	require.Error(t, err)
+	for i := 0; i < 2; i++ {
+		states, err := requestIPAddressAndGetState(t, newIPConfigsRequest(t, testPod2Info))
+		require.NoError(t, err)
+		assert.NotEqual(t, testIP1, states[0].IPAddress)
+		require.NoError(t, svc.releaseIPConfigs(testPod2Info))
+	}
+
+	// the Pod it is reserved for gets it, and it is held again when the Pod releases it.
+	states, err := requestIPAddressAndGetState(t, newIPConfigsRequest(t, testPod1Info))
+	require.NoError(t, err)
+	assert.Equal(t, testIP1, states[0].IPAddress)
+	require.NoError(t, svc.releaseIPConfigs(testPod1Info))
+	assert.Equal(t, types.Reserved, getIPState(svc, testIPID1))
+
+	// the pool monitor does not release reserved IPs.
+	released, err := svc.MarkIPAsPendingRelease(3)
+	require.NoError(t, err)
+	assert.Len(t, released, 2)
+	assert.NotContains(t, released, testIPID1)
+}
+
+func TestReserveIPConfigsAssignedToPod(t *testing.T) {
+	svc := getTestService()
+	state, _ := NewPodStateWithOrchestratorContext(testIP1, testIPID1, testNCID, types.Assigned, ipPrefixBitsv4, 0, testPod1Info)
+	available := NewPodState(testIP2, testIPID2, testNCID, types.Available, 0)
+	require.NoError(t, UpdatePodIPConfigState(t, svc, map[string]cns.IPConfigurationStatus{state.ID: state, available.ID: available}, testNCID))
+
+	req := &cns.ReserveIPConfigsRequest{PodName: testPod1Info.Name(), PodNamespace: testPod1Info.Namespace(), TTLSeconds: 60}
+	now := time.Now()
+	resp := svc.reserveIPConfigs(req, now)
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+	assert.Equal(t, []string{testIP1}, resp.IPAddresses)
+	assert.Equal(t, now.Add(time.Minute), resp.Expiry)
+	// the IP stays assigned to the running Pod.
+	assert.Equal(t, types.Assigned, getIPState(svc, testIPID1))
+
+	// reserving again extends the reservation, but does not change its IPs.
+	resp = svc.reserveIPConfigs(req, now.Add(time.Second))
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+	assert.Equal(t, now.Add(time.Second+time.Minute), resp.Expiry)
+	req.DesiredIPAddresses = []string{testIP2}
+	resp = svc.reserveIPConfigs(req, now.Add(time.Second))
+	assert.Equal(t, types.InvalidRequest, resp.Response.ReturnCode)
+
+	require.NoError(t, svc.releaseIPConfigs(testPod1Info))
+	assert.Equal(t, types.Reserved, getIPState(svc, testIPID1))
+
+	// once expired, the IP is available again.
+	svc.expireIPReservationsUntransacted(now.Add(2 * time.Minute))
+	assert.Equal(t, types.Available, getIPState(svc, testIPID1))
+	assert.Empty(t, svc.state.IPReservations)
+}
+
+func TestReserveIPConfigsInvalid(t *testing.T) {
+	svc := getTestService()
+	available := NewPodState(testIP1, testIPID1, testNCID, types.Available, 0)
+	pending := NewPodState(testIP2, testIPID2, testNCID, types.PendingProgramming, 0)
+	require.NoError(t, UpdatePodIPConfigState(t, svc, map[string]cns.IPConfigurationStatus{available.ID: available, pending.ID: pending}, testNCID))
+	svc.PodIPConfigState[pending.ID] = pending
+
+	tests := []struct {
+		name string
+		req  cns.ReserveIPConfigsRequest
+		code types.ResponseCode
+	}{
+		{"no pod name", cns.ReserveIPConfigsRequest{PodNamespace: "ns"}, types.InvalidRequest},
+		{"negative ttl", cns.ReserveIPConfigsRequest{PodName: "p", PodNamespace: "ns", TTLSeconds: -1}, types.InvalidRequest},
+		{"invalid ip", cns.ReserveIPConfigsRequest{PodName: "p", PodNamespace: "ns", DesiredIPAddresses: []string{"invalid"}}, types.InvalidRequest},
+		{"ip not in pool", cns.ReserveIPConfigsRequest{PodName: "p", PodNamespace: "ns", DesiredIPAddresses: []string{testIP4}}, types.FailedToAllocateIPConfig},
+		{"ip pending programming", cns.ReserveIPConfigsRequest{PodName: "p", PodNamespace: "ns", DesiredIPAddresses: []string{testIP2}}, types.FailedToAllocateIPConfig},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			resp := svc.reserveIPConfigs(&tt.req, time.Now())
+			assert.Equal(t, tt.code, resp.Response.ReturnCode)
+		})
+	}
+	assert.Empty(t, svc.state.IPReservations)
+}
+
+func TestLoadIPReservations(t *testing.T) {
+	svc := getTestService()
+	svc.store = store.NewMockStore("")
+	pod1Key := ipReservationKey(testPod1Info.Namespace(), testPod1Info.Name())
+	pod2Key := ipReservationKey(testPod2Info.Namespace(), testPod2Info.Name())
+
+	// reservations persisted before CNS restarted, which are loaded before the IPs are known.
+	now := time.Now()
+	require.NoError(t, svc.store.Write(ipReservationsStoreKey, map[string]ipReservation{
+		pod1Key: {IPIDs: []string{testIPID1, "gone"}, Expiry: now.Add(time.Minute)},
+		pod2Key: {IPIDs: []string{testIPID2}, Expiry: now},
+	}))
+	svc.loadIPReservations(now)
+	require.Len(t, svc.state.IPReservations, 1)
+
+	// the reserved IPs are held as they are added to the pool.
+	ipconfigs := map[string]cns.IPConfigurationStatus{}
+	for _, state := range []cns.IPConfigurationStatus{
+		NewPodState(testIP1, testIPID1, testNCID, types.Available, 0),
+		NewPodState(testIP2, testIPID2, testNCID, types.Available, 0),
+	} {
+		ipconfigs[state.ID] = state
+	}
+	require.NoError(t, UpdatePodIPConfigState(t, svc, ipconfigs, testNCID))
+	assert.Equal(t, types.Reserved, getIPState(svc, testIPID1))
+	assert.Equal(t, types.Available, getIPState(svc, testIPID2))
+	assert.True(t, svc.isReservedForUntransacted(testIPID1, testPod1Info, now))
+	assert.False(t, svc.isReservedForUntransacted(testIPID1, testPod1Info, now.Add(time.Minute)))
+
+	// the IPs that are not in the pool are pruned, and the pruned reservations are persisted.
+	svc.pruneIPReservations(now)
+	assert.Equal(t, []string{testIPID1}, svc.state.IPReservations[pod1Key].IPIDs)
+	var persisted map[string]ipReservation
+	require.NoError(t, svc.store.Read(ipReservationsStoreKey, &persisted))
+	require.Len(t, persisted, 1)
+	assert.Equal(t, []string{testIPID1}, persisted[pod1Key].IPIDs)
+}
+
+func TestYieldReservedIPsToPods(t *testing.T) {
+	svc := getTestService()
+	available := NewPodState(testIP1, testIPID1, testNCID, types.Available, 0)
+	require.NoError(t, UpdatePodIPConfigState(t, svc, map[string]cns.IPConfigurationStatus{available.ID: available}, testNCID))
+	resp := svc.reserveIPConfigs(&cns.ReserveIPConfigsRequest{
+		PodName:            testPod1Info.Name(),
+		PodNamespace:       testPod1Info.Namespace(),
+		DesiredIPAddresses: []string{testIP1},
+	}, time.Now())
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+
+	// another Pod already uses the reserved IP, so the reservation does not take it back.
+	svc.yieldReservedIPsToPods(map[string]cns.PodInfo{testIP1: testPod2Info})
+	assert.Equal(t, types.Available, getIPState(svc, testIPID1))
+	assert.Empty(t, svc.state.IPReservations[ipReservationKey(testPod1Info.Namespace(), testPod1Info.Name())].IPIDs)
+}
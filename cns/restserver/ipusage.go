@@ -16,6 +16,10 @@ type ipState struct {
 	programmingIPs int64
 	// releasingIPs are the IPs in state "PendingReleasr".
 	releasingIPs int64
+	// reservedIPs are the IPs in state "Reserved".
+	reservedIPs int64
+	// coolingIPs are the IPs in state "Cooling".
+	coolingIPs int64
 }
 
 func (service *HTTPRestService) buildIPState() *ipState {
@@ -28,6 +32,8 @@ func (service *HTTPRestService) buildIPState() *ipState {
 		availableIPs:   0,
 		programmingIPs: 0,
 		releasingIPs:   0,
+		reservedIPs:    0,
+		coolingIPs:     0,
 	}
 
 	//nolint:gocritic // This has to iterate over the IP Config state to get the counts.
@@ -45,14 +51,22 @@ func (service *HTTPRestService) buildIPState() *ipState {
 		if ipConfig.GetState() == types.PendingRelease {
 			state.releasingIPs++
 		}
+		if ipConfig.GetState() == types.Reserved {
+			state.reservedIPs++
+		}
+		if ipConfig.GetState() == types.Cooling {
+			state.coolingIPs++
+		}
 	}
 
-	logger.Printf("[IP Usage] Allocated IPs: %d, Assigned IPs: %d, Available IPs: %d, PendingProgramming IPs: %d, PendingRelease IPs: %d",
+	logger.Printf("[IP Usage] Allocated IPs: %d, Assigned IPs: %d, Available IPs: %d, PendingProgramming IPs: %d, PendingRelease IPs: %d, Reserved IPs: %d, Cooling IPs: %d",
 		state.allocatedIPs,
 		state.assignedIPs,
 		state.availableIPs,
 		state.programmingIPs,
 		state.releasingIPs,
+		state.reservedIPs,
+		state.coolingIPs,
 	)
 	return &state
 }
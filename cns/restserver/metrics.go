@@ -1,12 +1,18 @@
 package restserver
 
 import (
+	"encoding/json"
 	"net/http"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
@@ -17,18 +23,71 @@ const (
 	cnsReturnCode            = "cns_return_code"
 	customerMetricLabel      = "customer_metric"
 	customerMetricLabelValue = "customer metric"
+
+	// maxSniffedResponseBytes bounds the size of responses decoded to find the CNS response code.
+	maxSniffedResponseBytes = 4096
+	tracerName              = "github.com/Azure/azure-container-networking/cns/restserver"
 )
 
 var (
 	httpRequestLatency = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name: "http_request_latency_seconds",
-			Help: "Request latency in seconds by endpoint, verb, and response code.",
+			Help: "Request latency in seconds by endpoint, verb, and response code, excluding streams.",
 			//nolint:gomnd // default bucket consts
 			Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1 ms to ~16 seconds
 		},
 		[]string{"url", "verb", "cns_return_code"},
 	)
+	httpRequestCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Count of requests by endpoint, verb, and response code.",
+		},
+		[]string{"url", "verb", "cns_return_code"},
+	)
+	httpRequestsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Count of requests currently being served by endpoint, excluding streams.",
+		},
+		[]string{"url"},
+	)
+	unixSocketRequestsDenied = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "unix_socket_requests_denied_total",
+			Help: "Count of requests on the unix socket denied to their callers by endpoint.",
+		},
+		[]string{"url"},
+	)
+	tlsClientRequestsDenied = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tls_client_requests_denied_total",
+			Help: "Count of requests on the HTTPS endpoint denied to their client certificates by endpoint.",
+		},
+		[]string{"url"},
+	)
+	admissionQueueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "admission_queue_depth",
+			Help: "Count of IP requests queued by the admission controller.",
+		},
+	)
+	admissionQueueWait = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name: "admission_queue_wait_seconds",
+			Help: "Time IP requests were queued by the admission controller before they were admitted.",
+			//nolint:gomnd // default bucket consts
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1 ms to ~16 seconds
+		},
+	)
+	admissionRequestsShed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "admission_requests_shed_total",
+			Help: "Count of IP requests shed by the admission controller by reason.",
+		},
+		[]string{"reason"},
+	)
 	ipAssignmentLatency = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Name: "ip_assignment_latency_seconds",
@@ -102,11 +161,34 @@ var (
 		},
 		[]string{},
 	)
+	coolingIPCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cx_cooling_ips_v2",
+			Help:        "Count of IPs in Cooling State",
+			ConstLabels: prometheus.Labels{customerMetricLabel: customerMetricLabelValue},
+		},
+		[]string{},
+	)
+	reservedIPCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cx_reserved_ips_v2",
+			Help:        "Count of IPs in Reserved State",
+			ConstLabels: prometheus.Labels{customerMetricLabel: customerMetricLabelValue},
+		},
+		[]string{},
+	)
 )
 
 func init() {
 	metrics.Registry.MustRegister(
 		httpRequestLatency,
+		httpRequestCount,
+		httpRequestsInFlight,
+		unixSocketRequestsDenied,
+		tlsClientRequestsDenied,
+		admissionQueueDepth,
+		admissionQueueWait,
+		admissionRequestsShed,
 		ipAssignmentLatency,
 		ipConfigStatusStateTransitionTime,
 		syncHostNCVersionCount,
@@ -116,22 +198,107 @@ func init() {
 		availableIPCount,
 		pendingProgrammingIPCount,
 		pendingReleaseIPCount,
+		reservedIPCount,
+		coolingIPCount,
 	)
 }
 
 // Every http response is 200 so we really want cns  response code.
-// Hard tto do with middleware unless we derserialize the responses but making it an explit header works around it.
-// if that doesn't work we could have a separate countervec just for response codes.
+// Handlers that care set it as an explicit header. It is otherwise decoded from small JSON responses
+// that carry a cns.Response.
 
-func newHandlerFuncWithHistogram(handler http.HandlerFunc, histogram *prometheus.HistogramVec) http.HandlerFunc {
+// instrumentHandlerFunc is a common.Middleware that records the latency, count, and in-flight requests
+// for the path, and starts a span continuing any W3C trace context propagated by the caller.
+// Spans are only exported if a TracerProvider is registered with otel.
+// Streams are only counted, since they are served for as long as their callers watch them.
+func instrumentHandlerFunc(path string, handler http.HandlerFunc) http.HandlerFunc {
+	if path == cns.Watch {
+		return func(w http.ResponseWriter, req *http.Request) {
+			rw := &responseCodeRecorder{ResponseWriter: w}
+			defer func() {
+				httpRequestCount.WithLabelValues(path, req.Method, rw.returnCode()).Inc()
+			}()
+			handler(rw, req)
+		}
+	}
+	tracer := otel.Tracer(tracerName)
+	inFlight := httpRequestsInFlight.WithLabelValues(path)
 	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := propagation.TraceContext{}.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := tracer.Start(ctx, path, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.String("http.method", req.Method)))
+		rw := &responseCodeRecorder{ResponseWriter: w}
+		inFlight.Inc()
 		start := time.Now()
 		defer func() {
-			code := w.Header().Get(cnsReturnCode)
-			histogram.WithLabelValues(req.URL.RequestURI(), req.Method, code).Observe(time.Since(start).Seconds())
+			inFlight.Dec()
+			code := rw.returnCode()
+			httpRequestLatency.WithLabelValues(path, req.Method, code).Observe(time.Since(start).Seconds())
+			httpRequestCount.WithLabelValues(path, req.Method, code).Inc()
+			span.SetAttributes(attribute.String(cnsReturnCode, code))
+			if code != "" && code != types.Success.String() {
+				span.SetStatus(codes.Error, code)
+			}
+			span.End()
 		}()
-		handler(w, req)
+		handler(rw, req.WithContext(ctx))
+	}
+}
+
+// responseCodeRecorder captures the CNS response code of the response written through it.
+type responseCodeRecorder struct {
+	http.ResponseWriter
+	code string
+}
+
+func (r *responseCodeRecorder) Write(b []byte) (int, error) {
+	if r.code == "" {
+		r.code = r.Header().Get(cnsReturnCode)
+		if r.code == "" {
+			r.code = sniffReturnCode(b)
+		}
+	}
+	return r.ResponseWriter.Write(b) //nolint:wrapcheck // passthrough
+}
+
+// Flush lets streaming handlers flush through the recorder.
+func (r *responseCodeRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (r *responseCodeRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *responseCodeRecorder) returnCode() string {
+	if r.code == "" {
+		return r.Header().Get(cnsReturnCode)
+	}
+	return r.code
+}
+
+// sniffReturnCode decodes the return code from a JSON response that is a cns.Response or embeds one.
+func sniffReturnCode(b []byte) string {
+	if len(b) > maxSniffedResponseBytes {
+		return ""
+	}
+	var resp struct {
+		ReturnCode *types.ResponseCode
+		Response   *cns.Response
+	}
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return ""
+	}
+	if resp.Response != nil {
+		return resp.Response.ReturnCode.String()
+	}
+	if resp.ReturnCode != nil {
+		return resp.ReturnCode.String()
 	}
+	return ""
 }
 
 func stateTransitionMiddleware(i *cns.IPConfigurationStatus, s types.IPState) {
@@ -149,4 +316,6 @@ func publishIPStateMetrics(state *ipState) {
 	availableIPCount.WithLabelValues(labels...).Set(float64(state.availableIPs))
 	pendingProgrammingIPCount.WithLabelValues(labels...).Set(float64(state.programmingIPs))
 	pendingReleaseIPCount.WithLabelValues(labels...).Set(float64(state.releasingIPs))
+	reservedIPCount.WithLabelValues(labels...).Set(float64(state.reservedIPs))
+	coolingIPCount.WithLabelValues(labels...).Set(float64(state.coolingIPs))
 }
@@ -0,0 +1,97 @@
+package restserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSniffReturnCode(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "embedded response",
+			body: `{"Response":{"ReturnCode":0,"Message":""}}`,
+			want: types.Success.String(),
+		},
+		{
+			name: "top level response",
+			body: `{"ReturnCode":5,"Message":"bad"}`,
+			want: types.ResponseCode(5).String(),
+		},
+		{
+			name: "no response code",
+			body: `{"Foo":"bar"}`,
+			want: "",
+		},
+		{
+			name: "not json",
+			body: "Failed to decode request",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sniffReturnCode([]byte(tt.body)))
+		})
+	}
+}
+
+func TestInstrumentHandlerFunc(t *testing.T) {
+	const path = "/test/instrumented"
+	traceID := trace.TraceID{1}
+	var gotSpan trace.SpanContext
+	handler := instrumentHandlerFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		gotSpan = trace.SpanContextFromContext(r.Context())
+		w.Header().Set(cnsReturnCode, types.UnexpectedError.String())
+		assert.InDelta(t, 1, testutil.ToFloat64(httpRequestsInFlight.WithLabelValues(path)), 0)
+		_, _ = w.Write([]byte(`{"Response":{"ReturnCode":0}}`))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, path, http.NoBody)
+	propagation.TraceContext{}.Inject(trace.ContextWithRemoteSpanContext(req.Context(),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled})),
+		propagation.HeaderCarrier(req.Header))
+	handler(httptest.NewRecorder(), req)
+
+	// the explicit header wins over the decoded body.
+	require.InDelta(t, 1, testutil.ToFloat64(httpRequestCount.WithLabelValues(path, http.MethodPost, types.UnexpectedError.String())), 0)
+	require.InDelta(t, 0, testutil.ToFloat64(httpRequestsInFlight.WithLabelValues(path)), 0)
+	// the caller's trace context is propagated to the handler.
+	require.Equal(t, traceID, gotSpan.TraceID())
+
+	// without the header, the response code is decoded from the body, whether or not tracing is enabled.
+	handler = instrumentHandlerFunc(path, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"Response":{"ReturnCode":0}}`))
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, http.NoBody))
+	require.InDelta(t, 1, testutil.ToFloat64(httpRequestCount.WithLabelValues(path, http.MethodPost, types.Success.String())), 0)
+}
+
+func TestInstrumentHandlerFuncStream(t *testing.T) {
+	latencies := testutil.CollectAndCount(httpRequestLatency)
+	// streams are not counted in flight, timed, or traced while they are served.
+	handler := instrumentHandlerFunc(cns.Watch, func(_ http.ResponseWriter, req *http.Request) {
+		assert.InDelta(t, 0, testutil.ToFloat64(httpRequestsInFlight.WithLabelValues(cns.Watch)), 0)
+		assert.False(t, trace.SpanContextFromContext(req.Context()).IsValid())
+	})
+	req := httptest.NewRequest(http.MethodGet, cns.Watch, http.NoBody)
+	propagation.TraceContext{}.Inject(trace.ContextWithRemoteSpanContext(req.Context(),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled})),
+		propagation.HeaderCarrier(req.Header))
+	handler(httptest.NewRecorder(), req)
+	require.InDelta(t, 1, testutil.ToFloat64(httpRequestCount.WithLabelValues(cns.Watch, http.MethodGet, "")), 0)
+	require.Equal(t, latencies, testutil.CollectAndCount(httpRequestLatency))
+}
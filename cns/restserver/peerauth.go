@@ -0,0 +1,110 @@
+package restserver
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/cns/configuration"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	acn "github.com/Azure/azure-container-networking/common"
+)
+
+// pathsMatch returns whether any of the paths matches the path, where a path ending with "/" matches
+// all paths under it and no paths match all paths.
+func pathsMatch(paths []string, path string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, p := range paths {
+		if p == path || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerAllowed returns whether the rule allows the caller with the credentials, regardless of the path.
+func peerAllowed(r *configuration.UnixSocketAuthorizationRule, creds *acn.PeerCredentials, executable func() string) bool {
+	for _, uid := range r.UIDs {
+		if uid == creds.UID {
+			return true
+		}
+	}
+	for _, gid := range r.GIDs {
+		if gid == creds.GID {
+			return true
+		}
+	}
+	if len(r.Executables) == 0 {
+		return false
+	}
+	exe := executable()
+	if exe == "" {
+		return false
+	}
+	for _, e := range r.Executables {
+		if e == exe {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizePeer returns whether the rules allow the caller with the credentials to call the path.
+// Without rules, only root is allowed.
+// executable returns the path of the executable of the caller, or empty if it is unknown.
+func authorizePeer(rules []configuration.UnixSocketAuthorizationRule, path string, creds *acn.PeerCredentials, executable func() string) bool {
+	if len(rules) == 0 {
+		return creds.UID == 0
+	}
+	for i := range rules {
+		if pathsMatch(rules[i].Paths, path) && peerAllowed(&rules[i], creds, executable) {
+			return true
+		}
+	}
+	return false
+}
+
+// procExecutable returns a func which reads the executable of the process from procfs once it is called.
+// The executable is that of the process with the PID when it is read, so only processes which can not
+// be replaced by the callers should be trusted by their executable.
+func procExecutable(pid int32) func() string {
+	var (
+		exe  string
+		read bool
+	)
+	return func() string {
+		if !read {
+			read = true
+			var err error
+			if exe, err = os.Readlink("/proc/" + strconv.Itoa(int(pid)) + "/exe"); err != nil {
+				logger.Errorf("[Azure CNS] Failed to read executable of pid %d: %v", pid, err)
+			}
+		}
+		return exe
+	}
+}
+
+// authorizePeerHandlerFunc is a common.Middleware that denies the requests served on the unix socket
+// unless the PeerAuthorizationRules allow their callers to call the path. Other requests are not affected.
+func (service *HTTPRestService) authorizePeerHandlerFunc(path string, handler http.HandlerFunc) http.HandlerFunc {
+	denied := unixSocketRequestsDenied.WithLabelValues(path)
+	return func(w http.ResponseWriter, req *http.Request) {
+		creds, ok := acn.PeerCredentialsFromContext(req.Context())
+		if !ok {
+			handler(w, req)
+			return
+		}
+		executable := procExecutable(creds.PID)
+		if !authorizePeer(service.PeerAuthorizationRules, path, creds, executable) {
+			logger.Errorf("[Azure CNS] Denied %s %s on unix socket to pid %d uid %d gid %d executable %q",
+				req.Method, path, creds.PID, creds.UID, creds.GID, executable())
+			denied.Inc()
+			http.Error(w, "caller is not authorized to call "+path, http.StatusForbidden)
+			return
+		}
+		handler(w, req)
+	}
+}
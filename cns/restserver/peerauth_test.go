@@ -0,0 +1,135 @@
+package restserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/configuration"
+	acn "github.com/Azure/azure-container-networking/common"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorizePeer(t *testing.T) {
+	rules := []configuration.UnixSocketAuthorizationRule{
+		{Paths: []string{cns.CreateOrUpdateNetworkContainer, "/network/nc/"}, UIDs: []uint32{1000}},
+		{Paths: []string{cns.RequestIPConfigs, cns.ReleaseIPConfigs}, GIDs: []uint32{2000}, Executables: []string{"/opt/cni/bin/azure-vnet"}},
+	}
+	cni := func() string { return "/opt/cni/bin/azure-vnet" }
+	unknown := func() string { return "" }
+
+	tests := []struct {
+		name       string
+		rules      []configuration.UnixSocketAuthorizationRule
+		path       string
+		creds      acn.PeerCredentials
+		executable func() string
+		allowed    bool
+	}{
+		{name: "root without rules", path: cns.CreateOrUpdateNetworkContainer, creds: acn.PeerCredentials{UID: 0}, executable: unknown, allowed: true},
+		{name: "user without rules", path: cns.RequestIPConfigs, creds: acn.PeerCredentials{UID: 1000}, executable: cni, allowed: false},
+		{name: "uid on path", rules: rules, path: cns.CreateOrUpdateNetworkContainer, creds: acn.PeerCredentials{UID: 1000}, executable: unknown, allowed: true},
+		{name: "uid under path prefix", rules: rules, path: "/network/nc/delete", creds: acn.PeerCredentials{UID: 1000}, executable: unknown, allowed: true},
+		{name: "uid on other path", rules: rules, path: cns.RequestIPConfigs, creds: acn.PeerCredentials{UID: 1000}, executable: unknown, allowed: false},
+		{name: "gid on path", rules: rules, path: cns.ReleaseIPConfigs, creds: acn.PeerCredentials{UID: 1, GID: 2000}, executable: unknown, allowed: true},
+		{name: "executable on path", rules: rules, path: cns.RequestIPConfigs, creds: acn.PeerCredentials{UID: 1}, executable: cni, allowed: true},
+		{name: "executable on other path", rules: rules, path: cns.CreateOrUpdateNetworkContainer, creds: acn.PeerCredentials{UID: 1}, executable: cni, allowed: false},
+		{name: "unknown executable", rules: rules, path: cns.RequestIPConfigs, creds: acn.PeerCredentials{UID: 1}, executable: unknown, allowed: false},
+		{name: "root with rules", rules: rules, path: cns.RequestIPConfigs, creds: acn.PeerCredentials{UID: 0}, executable: unknown, allowed: false},
+		{name: "rule for all paths", rules: []configuration.UnixSocketAuthorizationRule{{UIDs: []uint32{0}}}, path: cns.RequestIPConfigs, creds: acn.PeerCredentials{UID: 0}, executable: unknown, allowed: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, authorizePeer(tt.rules, tt.path, &tt.creds, tt.executable))
+		})
+	}
+}
+
+func TestAuthorizePeerHandlerFunc(t *testing.T) {
+	svc := &HTTPRestService{PeerAuthorizationRules: []configuration.UnixSocketAuthorizationRule{{Paths: []string{cns.RequestIPConfigs}, UIDs: []uint32{1000}}}}
+	path := cns.CreateOrUpdateNetworkContainer
+	handler := svc.authorizePeerHandlerFunc(path, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	denied := testutil.ToFloat64(unixSocketRequestsDenied.WithLabelValues(path))
+
+	// requests which were not served on the unix socket are not authorized by their peers.
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, path, http.NoBody))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, http.NoBody)
+	handler(w, req.WithContext(acn.WithPeerCredentials(req.Context(), &acn.PeerCredentials{PID: 1, UID: 1000})))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.InDelta(t, denied+1, testutil.ToFloat64(unixSocketRequestsDenied.WithLabelValues(path)), 0)
+}
+
+func TestUnixSocketListenerPeerCredentials(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("peer credentials are only supported on linux")
+	}
+	u, err := url.Parse("tcp://localhost:0")
+	require.NoError(t, err)
+	listener, err := acn.NewListener(u)
+	require.NoError(t, err)
+
+	creds := make(chan *acn.PeerCredentials, 1)
+	listener.AddHandler(cns.RequestIPConfigs, func(w http.ResponseWriter, r *http.Request) {
+		c, _ := acn.PeerCredentialsFromContext(r.Context())
+		creds <- c
+		w.WriteHeader(http.StatusOK)
+	})
+	socketPath := filepath.Join(t.TempDir(), "cns.sock")
+	require.NoError(t, listener.StartUnix(make(chan error, 1), socketPath))
+	t.Cleanup(listener.Stop)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+			},
+		},
+		Timeout: 5 * time.Second,
+	}
+	resp, err := client.Post("http://cns"+cns.RequestIPConfigs, "application/json", http.NoBody)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	c := <-creds
+	require.NotNil(t, c)
+	assert.EqualValues(t, os.Getpid(), c.PID)
+	assert.EqualValues(t, os.Getuid(), c.UID)
+	assert.EqualValues(t, os.Getgid(), c.GID)
+}
+
+func TestPProfEndpointsAuthorizePeers(t *testing.T) {
+	u, err := url.Parse("tcp://localhost:0")
+	require.NoError(t, err)
+	listener, err := acn.NewListener(u)
+	require.NoError(t, err)
+	svc := &HTTPRestService{Service: &cns.Service{Listener: listener}}
+	listener.Use(svc.authorizePeerHandlerFunc)
+	svc.RegisterPProfEndpoints()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/debug/pprof/cmdline", http.NoBody)
+	listener.GetMux().ServeHTTP(w, req.WithContext(acn.WithPeerCredentials(req.Context(), &acn.PeerCredentials{PID: 1, UID: 1000})))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	listener.GetMux().ServeHTTP(w, req.WithContext(acn.WithPeerCredentials(req.Context(), &acn.PeerCredentials{PID: 1, UID: 0})))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -6,17 +6,21 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/configuration"
 	"github.com/Azure/azure-container-networking/cns/dockerclient"
 	"github.com/Azure/azure-container-networking/cns/ipamclient"
+	"github.com/Azure/azure-container-networking/cns/iphistory"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/cns/networkcontainers"
 	"github.com/Azure/azure-container-networking/cns/routes"
 	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/cns/types/bounded"
+	"github.com/Azure/azure-container-networking/cns/watch"
 	"github.com/Azure/azure-container-networking/cns/wireserver"
 	acn "github.com/Azure/azure-container-networking/common"
 	nma "github.com/Azure/azure-container-networking/nmagent"
@@ -43,6 +47,11 @@ type nmagentClient interface {
 	GetHomeAz(context.Context) (nma.AzResponse, error)
 }
 
+// ncVersionListInvalidator is implemented by nmagentClients that cache the NC version list.
+type ncVersionListInvalidator interface {
+	InvalidateNCVersionList()
+}
+
 type wireserverProxy interface {
 	JoinNetwork(ctx context.Context, vnetID string) (*http.Response, error)
 	PublishNC(ctx context.Context, ncParams cns.NetworkContainerParameters, payload []byte) (*http.Response, error)
@@ -62,16 +71,30 @@ type HTTPRestService struct {
 	PodIPIDByPodInterfaceKey map[string][]string                  // PodInterfaceId is key and value is slice of Pod IP (SecondaryIP) uuids.
 	PodIPConfigState         map[string]cns.IPConfigurationStatus // Secondary IP ID(uuid) is key
 	IPAMPoolMonitor          cns.IPAMPoolMonitor
+	IPHistory                *iphistory.Recorder
+	IPCooldown               time.Duration                               // how long released IPs are Cooling before they can be assigned again
+	IPAMStateSigningKey      []byte                                      // signs exported IPAM state archives
+	PeerAuthorizationRules   []configuration.UnixSocketAuthorizationRule // authorize the callers of the API on the unix socket
+	ClientAuthorizationRules []configuration.ClientAuthorizationRule     // authorize the clients of the API on the HTTPS endpoint
+	AdmissionSettings        AdmissionSettings                           // bound the IP requests served at once
+	CNIConflistDiffer        func() (*cns.CNIConflistDiff, error)        // diffs the rendered CNI conflist against the one on disk
+	admission                *admissionController
 	routingTable             *routes.RoutingTable
 	store                    store.KeyValueStore
 	state                    *httpRestServiceState
 	podsPendingIPAssignment  *bounded.TimedSet
+	coolingIPs               map[string]time.Time // Secondary IP ID(uuid) is key, and the value is when the IP started Cooling
+	now                      func() time.Time
+	watchers                 *watch.Broadcaster
 	sync.RWMutex
+	ipReservationsSaveLock     sync.Mutex // orders the writes of the IP reservations, which are made outside of the service lock
+	coolingIPsSaveLock         sync.Mutex // orders the writes of the cooling IPs, which are made outside of the service lock
 	dncPartitionKey            string
 	EndpointState              map[string]*EndpointInfo // key : container id
 	EndpointStateStore         store.KeyValueStore
 	cniConflistGenerator       CNIConflistGenerator
 	generateCNIConflistOnce    sync.Once
+	cniConflistGenerated       atomic.Bool
 	ipConfigsRequestValidators []cns.IPConfigsRequestValidator
 	SWIFTv2Middleware          cns.SWIFTv2Middleware
 }
@@ -137,6 +160,7 @@ type httpRestServiceState struct {
 	Initialized                      bool
 	ContainerIDByOrchestratorContext map[string]*ncList         // OrchestratorContext is the key and value is a list of NetworkContainerIDs separated by comma
 	ContainerStatus                  map[string]containerstatus // NetworkContainerID is key.
+	IPReservations                   map[string]ipReservation   `json:"-"` // Pod namespace/name is key. Persisted under their own key.
 	Networks                         map[string]*networkInfo
 	TimeStamp                        time.Time
 	joinedNetworks                   map[string]struct{}
@@ -181,6 +205,7 @@ func NewHTTPRestService(config *common.ServiceConfig, wscli interfaceGetter, wsp
 
 	serviceState := &httpRestServiceState{
 		Networks:         make(map[string]*networkInfo),
+		IPReservations:   make(map[string]ipReservation),
 		joinedNetworks:   make(map[string]struct{}),
 		primaryInterface: primaryInterface,
 	}
@@ -203,9 +228,13 @@ func NewHTTPRestService(config *common.ServiceConfig, wscli interfaceGetter, wsp
 		networkContainer:         nc,
 		PodIPIDByPodInterfaceKey: podIPIDByPodInterfaceKey,
 		PodIPConfigState:         podIPConfigState,
+		IPHistory:                iphistory.New(nil, iphistory.DefaultEventsPerIP, iphistory.DefaultMaxIPs),
 		routingTable:             routingTable,
 		state:                    serviceState,
 		podsPendingIPAssignment:  bounded.NewTimedSet(250), // nolint:gomnd // maxpods
+		coolingIPs:               make(map[string]time.Time),
+		now:                      time.Now,
+		watchers:                 watch.New(watch.DefaultHistory),
 		EndpointStateStore:       endpointStateStore,
 		EndpointState:            make(map[string]*EndpointInfo),
 		homeAzMonitor:            homeAzMonitor,
@@ -222,6 +251,8 @@ func (service *HTTPRestService) Init(config *common.ServiceConfig) error {
 	}
 
 	service.restoreState()
+	service.loadIPReservations(time.Now())
+	service.loadCoolingIPs(time.Now())
 	err = service.restoreNetworkState()
 	if err != nil {
 		logger.Errorf("[Azure CNS]  Failed to restore network state, err:%v.", err)
@@ -233,6 +264,8 @@ func (service *HTTPRestService) Init(config *common.ServiceConfig) error {
 
 	// Add handlers.
 	listener := service.Listener
+	service.admission = newAdmissionController(service.AdmissionSettings)
+	listener.Use(instrumentHandlerFunc, service.authorizePeerHandlerFunc, service.authorizeClientHandlerFunc, service.admitHandlerFunc)
 	// default handlers
 	listener.AddHandler(cns.SetEnvironmentPath, service.setEnvironment)
 	listener.AddHandler(cns.CreateNetworkPath, service.createNetwork)
@@ -257,14 +290,20 @@ func (service *HTTPRestService) Init(config *common.ServiceConfig) error {
 	listener.AddHandler(cns.DeleteHostNCApipaEndpointPath, service.deleteHostNCApipaEndpoint)
 	listener.AddHandler(cns.PublishNetworkContainer, service.publishNetworkContainer)
 	listener.AddHandler(cns.UnpublishNetworkContainer, service.unpublishNetworkContainer)
-	listener.AddHandler(cns.RequestIPConfig, newHandlerFuncWithHistogram(service.requestIPConfigHandler, httpRequestLatency))
-	listener.AddHandler(cns.RequestIPConfigs, newHandlerFuncWithHistogram(service.requestIPConfigsHandler, httpRequestLatency))
-	listener.AddHandler(cns.ReleaseIPConfig, newHandlerFuncWithHistogram(service.releaseIPConfigHandler, httpRequestLatency))
-	listener.AddHandler(cns.ReleaseIPConfigs, newHandlerFuncWithHistogram(service.releaseIPConfigsHandler, httpRequestLatency))
+	listener.AddHandler(cns.RequestIPConfig, service.requestIPConfigHandler)
+	listener.AddHandler(cns.RequestIPConfigs, service.requestIPConfigsHandler)
+	listener.AddHandler(cns.ReleaseIPConfig, service.releaseIPConfigHandler)
+	listener.AddHandler(cns.ReleaseIPConfigs, service.releaseIPConfigsHandler)
+	listener.AddHandler(cns.ReserveIPConfigs, service.reserveIPConfigsHandler)
+	listener.AddHandler(cns.UnreserveIPConfigs, service.unreserveIPConfigsHandler)
+	listener.AddHandler(cns.Watch, service.watchHandler)
 	listener.AddHandler(cns.NmAgentSupportedApisPath, service.nmAgentSupportedApisHandler)
 	listener.AddHandler(cns.PathDebugIPAddresses, service.handleDebugIPAddresses)
 	listener.AddHandler(cns.PathDebugPodContext, service.handleDebugPodContext)
 	listener.AddHandler(cns.PathDebugRestData, service.handleDebugRestData)
+	listener.AddHandler(cns.PathDebugIPHistory, service.handleDebugIPHistory)
+	listener.AddHandler(cns.PathDebugIPAMState, service.handleDebugIPAMState)
+	listener.AddHandler(cns.PathDebugCNIConflist, service.handleDebugCNIConflist)
 	listener.AddHandler(cns.NetworkContainersURLPath, service.getOrRefreshNetworkContainers)
 	listener.AddHandler(cns.GetHomeAz, service.getHomeAz)
 
@@ -304,20 +343,22 @@ func (service *HTTPRestService) Init(config *common.ServiceConfig) error {
 	return nil
 }
 
+// RegisterPProfEndpoints registers the pprof endpoints behind the middlewares of the listener,
+// so that they are authorized like the rest of the API.
 func (service *HTTPRestService) RegisterPProfEndpoints() {
 	if service.Listener != nil {
-		mux := service.Listener.GetMux()
-		mux.Handle("/debug/pprof/allocs", pprof.Handler("allocs"))
-		mux.Handle("/debug/pprof/block", pprof.Handler("block"))
-		mux.Handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
-		mux.Handle("/debug/pprof/heap", pprof.Handler("heap"))
-		mux.Handle("/debug/pprof/mutex", pprof.Handler("mutex"))
-		mux.Handle("/debug/pprof/threadcreate", pprof.Handler("threadcreate"))
-		mux.HandleFunc("/debug/pprof/", pprof.Index)
-		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
-		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		listener := service.Listener
+		listener.AddHandler("/debug/pprof/allocs", pprof.Handler("allocs").ServeHTTP)
+		listener.AddHandler("/debug/pprof/block", pprof.Handler("block").ServeHTTP)
+		listener.AddHandler("/debug/pprof/goroutine", pprof.Handler("goroutine").ServeHTTP)
+		listener.AddHandler("/debug/pprof/heap", pprof.Handler("heap").ServeHTTP)
+		listener.AddHandler("/debug/pprof/mutex", pprof.Handler("mutex").ServeHTTP)
+		listener.AddHandler("/debug/pprof/threadcreate", pprof.Handler("threadcreate").ServeHTTP)
+		listener.AddHandler("/debug/pprof/", pprof.Index)
+		listener.AddHandler("/debug/pprof/cmdline", pprof.Cmdline)
+		listener.AddHandler("/debug/pprof/profile", pprof.Profile)
+		listener.AddHandler("/debug/pprof/symbol", pprof.Symbol)
+		listener.AddHandler("/debug/pprof/trace", pprof.Trace)
 	}
 }
 
@@ -350,9 +391,15 @@ func (service *HTTPRestService) MustGenerateCNIConflistOnce() {
 		if err := service.cniConflistGenerator.Close(); err != nil {
 			panic("unable to close the cni conflist output stream: " + err.Error())
 		}
+		service.cniConflistGenerated.Store(true)
 	})
 }
 
+// CNIConflistGenerated reports whether the CNI conflist has been generated.
+func (service *HTTPRestService) CNIConflistGenerated() bool {
+	return service.cniConflistGenerated.Load()
+}
+
 func (service *HTTPRestService) AttachSWIFTv2Middleware(middleware cns.SWIFTv2Middleware) {
 	service.SWIFTv2Middleware = middleware
 	// adding the SWIFT v2 ipconfigs request validator
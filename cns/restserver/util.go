@@ -47,6 +47,13 @@ func (service *HTTPRestService) removeNetworkInfo(networkName string) {
 	delete(service.state.Networks, networkName)
 }
 
+// invalidateNCVersionList drops the NC version list cached by the NMAgent client, if any, after the NCs change.
+func (service *HTTPRestService) invalidateNCVersionList() {
+	if invalidator, ok := service.nma.(ncVersionListInvalidator); ok {
+		invalidator.InvalidateNCVersionList()
+	}
+}
+
 // saveState writes CNS state to persistent store.
 func (service *HTTPRestService) saveState() error {
 	logger.Printf("[Azure CNS] saveState")
@@ -216,6 +223,8 @@ func (service *HTTPRestService) saveNetworkContainerGoalState(req cns.CreateNetw
 	}
 
 	service.saveState()
+	service.publishNCChange(cns.WatchEventNCCreatedOrUpdated, req.NetworkContainerid)
+	service.invalidateNCVersionList()
 	return 0, ""
 }
 
@@ -314,6 +323,11 @@ func (service *HTTPRestService) addIPConfigStateUntransacted(ncID string, hostVe
 		logger.Printf("[Azure-Cns] Add IP %s as %s", ipconfig.IPAddress, newIPCNSStatus)
 
 		service.PodIPConfigState[ipID] = ipconfigStatus
+		service.publishIPStateChange("", &ipconfigStatus)
+		if newIPCNSStatus == types.Available {
+			service.holdIfReservedUntransacted(ipID)
+			service.coolIfCoolingUntransacted(ipID, service.now())
+		}
 
 		// Todo Update batch API and maintain the count
 	}
@@ -351,7 +365,10 @@ func (service *HTTPRestService) removeToBeDeletedIPStateUntransacted(
 	logger.Printf("[Azure-Cns] Delete the PodIpConfigState, IpId: %s, IPConfigStatus: %v",
 		ipID,
 		service.PodIPConfigState[ipID])
-	delete(service.PodIPConfigState, ipID)
+	if ipConfigStatus, exists := service.PodIPConfigState[ipID]; exists {
+		delete(service.PodIPConfigState, ipID)
+		service.publishIPDeleted(ipConfigStatus)
+	}
 	return 0, ""
 }
 
@@ -0,0 +1,152 @@
+package restserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/cns/watch"
+	"github.com/pkg/errors"
+)
+
+const (
+	// watchHeartbeatInterval keeps idle watch streams from being closed by proxies.
+	watchHeartbeatInterval = 30 * time.Second
+	resourceVersionParam   = "resourceVersion"
+	lastEventIDHeader      = "Last-Event-ID"
+)
+
+// publishIPStateChange sends the change of the IP to watchers. previous is empty if the IP was added.
+func (service *HTTPRestService) publishIPStateChange(previous types.IPState, current *cns.IPConfigurationStatus) {
+	if service.watchers == nil {
+		return
+	}
+	ipConfig := *current
+	service.watchers.Publish(cns.WatchEvent{
+		Type:          cns.WatchEventIPStateChanged,
+		IPConfig:      &ipConfig,
+		PreviousState: previous,
+	})
+}
+
+// publishIPDeleted sends the removal of the IP from the pool to watchers.
+func (service *HTTPRestService) publishIPDeleted(ipConfig cns.IPConfigurationStatus) { //nolint:gocritic // ignore hugeparam
+	if service.watchers == nil {
+		return
+	}
+	service.watchers.Publish(cns.WatchEvent{
+		Type:          cns.WatchEventIPDeleted,
+		IPConfig:      &ipConfig,
+		PreviousState: ipConfig.GetState(),
+	})
+}
+
+// publishNCChange sends the change of the NC to watchers.
+func (service *HTTPRestService) publishNCChange(eventType cns.WatchEventType, ncID string) {
+	if service.watchers == nil {
+		return
+	}
+	service.watchers.Publish(cns.WatchEvent{
+		Type:               eventType,
+		NetworkContainerID: ncID,
+	})
+}
+
+// watchHandler streams the changes of IPs and NCs as Server-Sent Events, each with the resource version as
+// its id. The watch resumes after the resourceVersion query parameter, or the Last-Event-ID header sent by
+// reconnecting EventSource clients. Without either, only new changes are sent.
+func (service *HTTPRestService) watchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	resourceVersion, err := parseResourceVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set(cnsReturnCode, types.Success.String())
+
+	watcher, err := service.watchers.Watch(resourceVersion)
+	if errors.Is(err, watch.ErrExpired) {
+		logger.Printf("[watch] Resource version %d from %s expired", resourceVersion, r.RemoteAddr)
+		expired := cns.WatchEvent{
+			ResourceVersion: service.watchers.ResourceVersion(),
+			Type:            cns.WatchEventExpired,
+			Timestamp:       time.Now(),
+		}
+		if err := writeWatchEvent(w, &expired); err != nil {
+			logger.Errorf("[watch] Failed to write event: %v", err)
+		}
+		flusher.Flush()
+		return
+	}
+	defer watcher.Stop()
+	logger.Printf("[watch] Watching from %s after resource version %d", r.RemoteAddr, resourceVersion)
+
+	// send the headers now, so the watcher knows it is connected before the first change.
+	flusher.Flush()
+	heartbeat := time.NewTicker(watchHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-watcher.Events():
+			if !ok {
+				// the watcher fell behind, it can resume after the last event it got.
+				logger.Printf("[watch] Stopped slow watcher %s", r.RemoteAddr)
+				return
+			}
+			if err := writeWatchEvent(w, &event); err != nil {
+				logger.Errorf("[watch] Failed to write event to %s: %v", r.RemoteAddr, err)
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func parseResourceVersion(r *http.Request) (uint64, error) {
+	v := r.URL.Query().Get(resourceVersionParam)
+	if v == "" {
+		v = r.Header.Get(lastEventIDHeader)
+	}
+	if v == "" {
+		return 0, nil
+	}
+	resourceVersion, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid resource version %q", v)
+	}
+	return resourceVersion, nil
+}
+
+func writeWatchEvent(w http.ResponseWriter, event *cns.WatchEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal watch event")
+	}
+	if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ResourceVersion, event.Type, data); err != nil {
+		return errors.Wrap(err, "failed to write watch event")
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package restserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// watchEvents reads n events from the watch stream after the resource version.
+func watchEvents(t *testing.T, url string, resourceVersion uint64, n int) []cns.WatchEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"?resourceVersion="+strconv.FormatUint(resourceVersion, 10), http.NoBody)
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	events := []cns.WatchEvent{}
+	scanner := bufio.NewScanner(res.Body)
+	for len(events) < n && scanner.Scan() {
+		if data, found := strings.CutPrefix(scanner.Text(), "data: "); found {
+			var event cns.WatchEvent
+			require.NoError(t, json.Unmarshal([]byte(data), &event))
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestWatchStreamsIPAndNCChanges(t *testing.T) {
+	svc := getTestService()
+	srv := httptest.NewServer(http.HandlerFunc(svc.watchHandler))
+	defer srv.Close()
+
+	start := svc.watchers.ResourceVersion()
+	available := NewPodState(testIP1, testIPID1, testNCID, types.Available, 0)
+	require.NoError(t, UpdatePodIPConfigState(t, svc, map[string]cns.IPConfigurationStatus{available.ID: available}, testNCID))
+	_, err := requestIPAddressAndGetState(t, newIPConfigsRequest(t, testPod1Info))
+	require.NoError(t, err)
+
+	events := watchEvents(t, srv.URL, start, 3)
+	require.Len(t, events, 3)
+	assert.Equal(t, cns.WatchEventIPStateChanged, events[0].Type)
+	assert.Equal(t, types.IPState(""), events[0].PreviousState)
+	assert.Equal(t, testIP1, events[0].IPConfig.IPAddress)
+	assert.Equal(t, cns.WatchEventNCCreatedOrUpdated, events[1].Type)
+	assert.Equal(t, testNCID, events[1].NetworkContainerID)
+	assert.Equal(t, cns.WatchEventIPStateChanged, events[2].Type)
+	assert.Equal(t, types.Available, events[2].PreviousState)
+	assert.Equal(t, types.Assigned, events[2].IPConfig.GetState())
+	assert.Equal(t, testPod1Info.Name(), events[2].IPConfig.PodInfo.Name())
+	for i := range events {
+		assert.Equal(t, start+uint64(i)+1, events[i].ResourceVersion)
+	}
+
+	// the watch resumes after the last event seen.
+	require.NoError(t, svc.releaseIPConfigs(testPod1Info))
+	events = watchEvents(t, srv.URL, start+3, 1)
+	require.Len(t, events, 1)
+	assert.Equal(t, types.Assigned, events[0].PreviousState)
+	assert.Equal(t, types.Available, events[0].IPConfig.GetState())
+}
+
+func TestWatchExpiredResourceVersion(t *testing.T) {
+	svc := getTestService()
+	srv := httptest.NewServer(http.HandlerFunc(svc.watchHandler))
+	defer srv.Close()
+
+	events := watchEvents(t, srv.URL, 1, 2)
+	require.Len(t, events, 1)
+	assert.Equal(t, cns.WatchEventExpired, events[0].Type)
+	assert.Equal(t, svc.watchers.ResourceVersion(), events[0].ResourceVersion)
+
+	res, err := http.Get(srv.URL + "?resourceVersion=invalid") //nolint:noctx // test
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
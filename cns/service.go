@@ -110,15 +110,44 @@ func (service *Service) Initialize(config *common.ServiceConfig) error {
 }
 
 func getTLSConfig(tlsSettings localtls.TlsSettings, errChan chan<- error) (*tls.Config, error) {
-	if tlsSettings.TLSCertificatePath != "" {
-		return getTLSConfigFromFile(tlsSettings)
+	var (
+		tlsConfig *tls.Config
+		err       error
+	)
+	switch {
+	case tlsSettings.TLSCertificatePath != "":
+		tlsConfig, err = getTLSConfigFromFile(tlsSettings)
+	case tlsSettings.KeyVaultURL != "":
+		tlsConfig, err = getTLSConfigFromKeyVault(tlsSettings, errChan)
+	default:
+		return nil, errors.Errorf("invalid tls settings: %+v", tlsSettings)
 	}
-
-	if tlsSettings.KeyVaultURL != "" {
-		return getTLSConfigFromKeyVault(tlsSettings, errChan)
+	if err != nil || tlsSettings.ClientCAFile == "" {
+		return tlsConfig, err
 	}
+	return withClientAuth(tlsConfig, tlsSettings.ClientCAFile)
+}
 
-	return nil, errors.Errorf("invalid tls settings: %+v", tlsSettings)
+// withClientAuth requires the clients to present certificates signed by the CAs in the bundle file.
+// The bundle is reloaded when it changes, and clients are authorized by the identities in their certificates
+// rather than by the certificates themselves, so that both CAs and client certificates can be rotated.
+func withClientAuth(tlsConfig *tls.Config, clientCAFile string) (*tls.Config, error) {
+	clientCAs, err := localtls.NewCertPoolFile(clientCAFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load client CAs")
+	}
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	base := tlsConfig.Clone()
+	tlsConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		pool, err := clientCAs.Pool()
+		if err != nil {
+			logger.Errorf("[Azure CNS] Failed to reload client CAs, using the previous ones: %v", err)
+		}
+		config := base.Clone()
+		config.ClientCAs = pool
+		return config, nil
+	}
+	return tlsConfig, nil
 }
 
 func getTLSConfigFromFile(tlsSettings localtls.TlsSettings) (*tls.Config, error) {
@@ -204,6 +233,11 @@ func (service *Service) StartListener(config *common.ServiceConfig) error {
 		if err := service.Listener.Start(config.ErrChan); err != nil {
 			return err
 		}
+		if config.UnixSocketPath != "" {
+			if err := service.Listener.StartUnix(config.ErrChan, config.UnixSocketPath); err != nil {
+				return err
+			}
+		}
 	} else {
 		return fmt.Errorf("Failed to start a listener, it is not initialized, config %+v", config)
 	}
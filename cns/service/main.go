@@ -8,8 +8,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/fs"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
 	"runtime"
@@ -32,6 +34,7 @@ import (
 	"github.com/Azure/azure-container-networking/cns/healthserver"
 	"github.com/Azure/azure-container-networking/cns/hnsclient"
 	"github.com/Azure/azure-container-networking/cns/ipampool"
+	"github.com/Azure/azure-container-networking/cns/iphistory"
 	cssctrl "github.com/Azure/azure-container-networking/cns/kubecontroller/clustersubnetstate"
 	mtpncctrl "github.com/Azure/azure-container-networking/cns/kubecontroller/multitenantpodnetworkconfig"
 	nncctrl "github.com/Azure/azure-container-networking/cns/kubecontroller/nodenetworkconfig"
@@ -81,6 +84,7 @@ const (
 	name                              = "azure-cns"
 	pluginName                        = "azure-vnet"
 	endpointStoreName                 = "azure-endpoints"
+	ipHistoryStoreName                = "azure-cns-iphistory"
 	endpointStoreLocation             = "/var/run/azure-cns/"
 	defaultCNINetworkConfigFileName   = "10-azure.conflist"
 	dncApiVersion                     = "?api-version=2018-03-01"
@@ -94,6 +98,8 @@ const (
 	envVarEnableCNIConflistGeneration = "CNS_ENABLE_CNI_CONFLIST_GENERATION"
 
 	cnsReqTimeout = 15 * time.Second
+	// healthCheckTimeout bounds the readiness checks of CNS dependencies.
+	healthCheckTimeout = 5 * time.Second
 )
 
 type cniConflistScenario string
@@ -282,7 +288,7 @@ var args = acn.ArgumentList{
 	{
 		Name:         acn.OptDebugCmd,
 		Shorthand:    acn.OptDebugCmdAlias,
-		Description:  "Debug flag to retrieve IPconfigs, available values: assigned, available, all",
+		Description:  "Debug flag to retrieve IPconfigs, available values: get, getPodContexts, getInMemory, getIPHistory, exportIPAMState",
 		Type:         "string",
 		DefaultValue: "",
 	},
@@ -450,6 +456,18 @@ func startTelemetryService(ctx context.Context) {
 	tb.PushData(rootCtx)
 }
 
+// logStoreRecovery sends an event to App Insights when a corrupted state file is restored from a snapshot.
+func logStoreRecovery(e store.RecoveryEvent) {
+	logger.LogEvent(aitelemetry.Event{
+		EventName: logger.StoreRecoveredEventStr,
+		Properties: map[string]string{
+			logger.StoreFileStr:           e.FileName,
+			logger.StoreSnapshotStr:       e.Snapshot,
+			logger.StoreRecoveryReasonStr: e.Reason.Error(),
+		},
+	})
+}
+
 // Main is the entry point for CNS.
 func main() {
 	// Initialize and parse command line arguments.
@@ -531,17 +549,14 @@ func main() {
 
 	_, envEnableConflistGeneration := os.LookupEnv(envVarEnableCNIConflistGeneration)
 	var conflistGenerator restserver.CNIConflistGenerator
+	var conflistDiffer func() (*cns.CNIConflistDiff, error)
+	var httpRestService *restserver.HTTPRestService
 	if cnsconfig.EnableCNIConflistGeneration || envEnableConflistGeneration {
 		conflistFilepath := cnsconfig.CNIConflistFilepath
 		if cniConflistFilepathArg != "" {
 			// allow the filepath to get overidden by command line arg
 			conflistFilepath = cniConflistFilepathArg
 		}
-		writer, newWriterErr := acnfs.NewAtomicWriter(conflistFilepath)
-		if newWriterErr != nil {
-			logger.Errorf("unable to create atomic writer to generate cni conflist: %v", newWriterErr)
-			os.Exit(1)
-		}
 
 		// allow the scenario to get overridden by command line arg
 		scenarioString := cnsconfig.CNIConflistScenario
@@ -549,38 +564,75 @@ func main() {
 			scenarioString = cniConflistScenarioArg
 		}
 
-		switch scenario := cniConflistScenario(scenarioString); scenario {
-		case scenarioV4Overlay:
-			conflistGenerator = &cniconflist.V4OverlayGenerator{Writer: writer}
-		case scenarioDualStackOverlay:
-			conflistGenerator = &cniconflist.DualStackOverlayGenerator{Writer: writer}
-		case scenarioOverlay:
-			conflistGenerator = &cniconflist.OverlayGenerator{Writer: writer}
-		case scenarioCilium:
-			conflistGenerator = &cniconflist.CiliumGenerator{Writer: writer}
-		case scenarioSWIFT:
-			conflistGenerator = &cniconflist.SWIFTGenerator{Writer: writer}
-		default:
-			logger.Errorf("unable to generate cni conflist for unknown scenario: %s", scenario)
+		newGenerator, scenarioErr := conflistGeneratorFor(cniConflistScenario(scenarioString))
+		if templateSettings := cnsconfig.CNIConflistTemplateSettings; templateSettings.TemplateFile != "" {
+			newGenerator, scenarioErr = conflistTemplateGenerator(&templateSettings, func() []netip.Prefix {
+				// the template is only rendered once the service has NCs.
+				return httpRestService.NCSubnets()
+			})
+		}
+		if scenarioErr != nil {
+			logger.Errorf("unable to generate cni conflist: %v", scenarioErr)
 			os.Exit(1)
 		}
+
+		var writer io.WriteCloser
+		if cnsconfig.CNIConflistDryRun {
+			// only log how the conflist on disk would change.
+			writer = &cniconflist.DryRunWriter{Path: conflistFilepath, Report: logCNIConflistDiff}
+		} else {
+			atomicWriter, newWriterErr := acnfs.NewAtomicWriter(conflistFilepath)
+			if newWriterErr != nil {
+				logger.Errorf("unable to create atomic writer to generate cni conflist: %v", newWriterErr)
+				os.Exit(1)
+			}
+			writer = atomicWriter
+		}
+		conflistGenerator = newGenerator(writer)
+		conflistDiffer = func() (*cns.CNIConflistDiff, error) {
+			rendered, err := cniconflist.Render(newGenerator)
+			if err != nil {
+				return nil, err //nolint:wrapcheck // already wrapped
+			}
+			return cniconflist.DiffFile(conflistFilepath, rendered) //nolint:wrapcheck // already wrapped
+		}
 	}
 
-	// start the health server
+	// start the health server. readiness checks are added as the dependencies they check are created.
+	livenessChecks, readinessChecks := healthserver.NewChecks(), healthserver.NewChecks()
+	livenessChecks.Add("ping", healthz.Ping)
 	z, _ := zap.NewProduction()
-	go healthserver.Start(z, cnsconfig.MetricsBindAddress)
+	go healthserver.Start(z, cnsconfig.MetricsBindAddress, livenessChecks, readinessChecks)
 
 	nmaConfig, err := nmagent.NewConfig(cnsconfig.WireserverIP)
 	if err != nil {
 		logger.Errorf("[Azure CNS] Failed to produce NMAgent config from the supplied wireserver ip: %v", err)
 		return
 	}
+	if nmaSettings := cnsconfig.NMAgentSettings; nmaSettings.CircuitBreakerFailureThreshold > 0 {
+		nmaConfig.CircuitBreaker = nmagent.NewCircuitBreaker(nmagent.CircuitBreakerConfig{
+			FailureThreshold: nmaSettings.CircuitBreakerFailureThreshold,
+			OpenTimeout:      time.Duration(nmaSettings.CircuitBreakerOpenTimeoutSecs) * time.Second,
+		})
+	}
+	nmaConfig.RequestsPerSecond = cnsconfig.NMAgentSettings.RequestsPerSecond
+	nmaConfig.RequestBurst = cnsconfig.NMAgentSettings.RequestBurst
+	nmaConfig.MaxConcurrentRequests = cnsconfig.NMAgentSettings.MaxConcurrentRequests
 
-	nmaClient, err := nmagent.NewClient(nmaConfig)
+	nmaUncachedClient, err := nmagent.NewClient(nmaConfig)
 	if err != nil {
 		logger.Errorf("[Azure CNS] Failed to start nmagent client due to error: %v", err)
 		return
 	}
+	// calls are only cached if their TTL is configured.
+	nmaClient := nmagent.NewCachingClient(nmaUncachedClient, nmagent.CacheConfig{
+		SupportedAPIsTTL: time.Duration(cnsconfig.NMAgentSettings.SupportedAPIsCacheTTLSecs) * time.Second,
+		NCVersionListTTL: time.Duration(cnsconfig.NMAgentSettings.NCVersionListCacheTTLSecs) * time.Second,
+		HomeAzTTL:        time.Duration(cnsconfig.NMAgentSettings.HomeAzCacheTTLSecs) * time.Second,
+	})
+	if cnsconfig.NMAgentSettings.ReadinessCheck {
+		readinessChecks.Add("nmagent", healthserver.NMAgentReachable(nmaClient, healthCheckTimeout))
+	}
 
 	homeAzMonitor := restserver.NewHomeAzMonitor(nmaClient, time.Duration(cnsconfig.AZRSettings.PopulateHomeAzCacheRetryIntervalSecs)*time.Second)
 	if cnsconfig.AZRSettings.EnableAZR {
@@ -642,11 +694,13 @@ func main() {
 
 	// Create the key value store.
 	storeFileName := storeFileLocation + name + ".json"
-	config.Store, err = store.NewJsonFileStore(storeFileName, lockclient, nil)
+	config.Store, err = store.New(cnsconfig.StoreType, storeFileName, lockclient, nil,
+		store.WithSnapshots(store.DefaultSnapshotCount), store.WithRecoveryHandler(logStoreRecovery))
 	if err != nil {
 		logger.Errorf("Failed to create store file: %s, due to error %v\n", storeFileName, err)
 		return
 	}
+	readinessChecks.Add("store", healthserver.StoreWritable(storeFileName, healthCheckTimeout))
 
 	// Initialize endpoint state store if cns is managing endpoint state.
 	if cnsconfig.ManageEndpointState {
@@ -665,7 +719,8 @@ func main() {
 		}
 		// Create the key value store.
 		storeFileName := endpointStoreLocation + endpointStoreName + ".json"
-		endpointStateStore, err = store.NewJsonFileStore(storeFileName, endpointStoreLock, nil)
+		endpointStateStore, err = store.New(cnsconfig.StoreType, storeFileName, endpointStoreLock, nil,
+			store.WithSnapshots(store.DefaultSnapshotCount), store.WithRecoveryHandler(logStoreRecovery))
 		if err != nil {
 			logger.Errorf("Failed to create endpoint state store file: %s, due to error %v\n", storeFileName, err)
 			return
@@ -683,12 +738,50 @@ func main() {
 		Logger:     logger.Log,
 	}
 
-	httpRestService, err := restserver.NewHTTPRestService(&config, wsclient, &wsProxy, nmaClient,
+	httpRestService, err = restserver.NewHTTPRestService(&config, wsclient, &wsProxy, nmaClient,
 		endpointStateStore, conflistGenerator, homeAzMonitor)
 	if err != nil {
 		logger.Errorf("Failed to create CNS object, err:%v.\n", err)
 		return
 	}
+	if conflistGenerator != nil {
+		readinessChecks.Add("conflist", healthserver.Started(httpRestService.CNIConflistGenerated))
+	}
+	httpRestService.CNIConflistDiffer = conflistDiffer
+
+	// Keep the history of IP state changes across restarts.
+	ipHistoryStoreLock, err := processlock.NewFileLock(platform.CNILockPath + ipHistoryStoreName + store.LockExtension)
+	if err != nil {
+		logger.Errorf("Error initializing IP history store file lock:%v", err)
+		return
+	}
+	ipHistoryStoreFileName := storeFileLocation + ipHistoryStoreName + ".json"
+	ipHistoryStore, err := store.New(cnsconfig.StoreType, ipHistoryStoreFileName, ipHistoryStoreLock, nil)
+	if err != nil {
+		logger.Errorf("Failed to create IP history store file: %s, due to error %v\n", ipHistoryStoreFileName, err)
+		return
+	}
+	httpRestService.IPHistory = iphistory.New(ipHistoryStore, cnsconfig.IPHistorySettings.EventsPerIP, cnsconfig.IPHistorySettings.MaxIPs)
+	if err = httpRestService.IPHistory.Restore(); err != nil {
+		logger.Errorf("Failed to restore IP history, starting without it: %v", err)
+	}
+	go httpRestService.IPHistory.Run(rootCtx, time.Duration(cnsconfig.IPHistorySettings.FlushIntervalSecs)*time.Second)
+	httpRestService.IPCooldown = time.Duration(cnsconfig.IPCooldownSecs) * time.Second
+	if cnsconfig.IPAMStateSettings.SigningKeyFile != "" {
+		key, err := os.ReadFile(cnsconfig.IPAMStateSettings.SigningKeyFile)
+		if err != nil {
+			logger.Errorf("Failed to read IPAM state signing key file: %s, due to error %v\n", cnsconfig.IPAMStateSettings.SigningKeyFile, err)
+			return
+		}
+		httpRestService.IPAMStateSigningKey = bytes.TrimSpace(key)
+	}
+	httpRestService.AdmissionSettings = restserver.AdmissionSettings{
+		MaxConcurrentPerCaller: cnsconfig.AdmissionSettings.MaxConcurrentRequestsPerCaller,
+		MaxQueued:              cnsconfig.AdmissionSettings.MaxQueuedRequests,
+		QueueTimeout:           time.Duration(cnsconfig.AdmissionSettings.QueueTimeoutMs) * time.Millisecond,
+		RetryAfter:             time.Duration(cnsconfig.AdmissionSettings.RetryAfterSecs) * time.Second,
+	}
+	httpRestService.PeerAuthorizationRules = cnsconfig.UnixSocketSettings.Rules
 
 	// Set CNS options.
 	httpRestService.SetOption(acn.OptCnsURL, cnsURL)
@@ -721,9 +814,16 @@ func main() {
 				KeyVaultCertificateName:            cnsconfig.KeyVaultSettings.CertificateName,
 				MSIResourceID:                      cnsconfig.MSISettings.ResourceID,
 				KeyVaultCertificateRefreshInterval: time.Duration(cnsconfig.KeyVaultSettings.RefreshIntervalInHrs) * time.Hour,
+				ClientCAFile:                       cnsconfig.MTLSSettings.ClientCAFile,
 			}
+			if err = cnsconfig.MTLSSettings.Validate(); err != nil {
+				logger.Errorf("Invalid MTLSSettings, err:%v.\n", err)
+				return
+			}
+			httpRestService.ClientAuthorizationRules = cnsconfig.MTLSSettings.Rules
 		}
 
+		config.UnixSocketPath = cnsconfig.UnixSocketSettings.Path
 		err = httpRestService.Init(&config)
 		if err != nil {
 			logger.Errorf("Failed to init HTTPService, err:%v.\n", err)
@@ -788,7 +888,7 @@ func main() {
 
 		logger.Printf("Set GlobalPodInfoScheme %v (InitializeFromCNI=%t)", cns.GlobalPodInfoScheme, cnsconfig.InitializeFromCNI)
 
-		err = InitializeCRDState(rootCtx, httpRestService, cnsconfig)
+		err = InitializeCRDState(rootCtx, httpRestService, cnsconfig, readinessChecks)
 		if err != nil {
 			logger.Errorf("Failed to start CRD Controller, err:%v.\n", err)
 			return
@@ -817,6 +917,14 @@ func main() {
 			logger.Errorf("Failed to start CNS, err:%v.\n", err)
 			return
 		}
+
+		if cnsconfig.GRPCSocketPath != "" {
+			go func() {
+				if err := httpRestService.ServeGRPC(rootCtx, cnsconfig.GRPCSocketPath); err != nil {
+					logger.Errorf("Failed to serve the CNS gRPC API, err:%v.\n", err)
+				}
+			}()
+		}
 	}
 
 	if cnsconfig.EnableAsyncPodDelete {
@@ -911,7 +1019,8 @@ func main() {
 
 		// Create the key value store.
 		pluginStoreFile := storeFileLocation + pluginName + ".json"
-		pluginConfig.Store, err = store.NewJsonFileStore(pluginStoreFile, lockclientCnm, nil)
+		pluginConfig.StoreType = cnsconfig.StoreType
+		pluginConfig.Store, err = store.New(pluginConfig.StoreType, pluginStoreFile, lockclientCnm, nil)
 		if err != nil {
 			logger.Errorf("Failed to create plugin store file %s, due to error : %v\n", pluginStoreFile, err)
 			return
@@ -1058,6 +1167,11 @@ type nodeNetworkConfigGetter interface {
 	Get(context.Context) (*v1alpha.NodeNetworkConfig, error)
 }
 
+// nncValidator is implemented by PodInfoByIPProviders that have to be consistent with the NNC, such as imported IPAM state.
+type nncValidator interface {
+	ValidateNNC(*v1alpha.NodeNetworkConfig) error
+}
+
 type ipamStateReconciler interface {
 	ReconcileIPAMState(ncRequests []*cns.CreateNetworkContainerRequest, podInfoByIP map[string]cns.PodInfo, nnc *v1alpha.NodeNetworkConfig) cnstypes.ResponseCode
 }
@@ -1084,6 +1198,12 @@ func reconcileInitialCNSState(ctx context.Context, cli nodeNetworkConfigGetter,
 		return errors.New("failed to init CNS state: no NCs found in NNC CRD")
 	}
 
+	if v, ok := podInfoByIPProvider.(nncValidator); ok {
+		if err := v.ValidateNNC(nnc); err != nil {
+			return errors.Wrap(err, "failed to init CNS state: provider is inconsistent with the NNC")
+		}
+	}
+
 	// Get previous PodInfo state from podInfoByIPProvider
 	podInfoByIP, err := podInfoByIPProvider.PodInfoByIP()
 	if err != nil {
@@ -1121,8 +1241,125 @@ func reconcileInitialCNSState(ctx context.Context, cli nodeNetworkConfigGetter,
 	return nil
 }
 
+// loadIPAMStateArchive reads the signed IPAM state archive from the file and verifies it with the key.
+func loadIPAMStateArchive(path string, key []byte) (*restserver.IPAMState, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read IPAM state archive")
+	}
+	var archive restserver.IPAMStateArchive
+	if err := json.Unmarshal(b, &archive); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal IPAM state archive")
+	}
+	state, err := restserver.OpenIPAMStateArchive(&archive, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open IPAM state archive")
+	}
+	return state, nil
+}
+
+// rejectIPAMStateArchive renames the archive so that it is not imported again on restart, which falls back
+// to the other sources of Pods.
+func rejectIPAMStateArchive(path string) {
+	if err := os.Rename(path, path+".rejected"); err != nil {
+		logger.Errorf("failed to rename rejected IPAM state archive %s: %v", path, err)
+	}
+}
+
+// conflistGeneratorFor returns a func that creates the CNI conflist Generator of the scenario writing to a writer.
+func conflistGeneratorFor(scenario cniConflistScenario) (func(io.WriteCloser) cniconflist.Generator, error) {
+	switch scenario {
+	case scenarioV4Overlay:
+		return func(w io.WriteCloser) cniconflist.Generator { return &cniconflist.V4OverlayGenerator{Writer: w} }, nil
+	case scenarioDualStackOverlay:
+		return func(w io.WriteCloser) cniconflist.Generator { return &cniconflist.DualStackOverlayGenerator{Writer: w} }, nil
+	case scenarioOverlay:
+		return func(w io.WriteCloser) cniconflist.Generator { return &cniconflist.OverlayGenerator{Writer: w} }, nil
+	case scenarioCilium:
+		return func(w io.WriteCloser) cniconflist.Generator { return &cniconflist.CiliumGenerator{Writer: w} }, nil
+	case scenarioSWIFT:
+		return func(w io.WriteCloser) cniconflist.Generator { return &cniconflist.SWIFTGenerator{Writer: w} }, nil
+	default:
+		return nil, errors.Errorf("unknown scenario: %s", scenario)
+	}
+}
+
+// conflistTemplateGenerator returns a func that creates a Generator rendering the CNI conflist template, with the
+// node facts of the NC subnets at the time it is rendered.
+func conflistTemplateGenerator(settings *configuration.CNIConflistTemplateSettings,
+	ncSubnets func() []netip.Prefix,
+) (func(io.WriteCloser) cniconflist.Generator, error) {
+	tmpl, err := cniconflist.ParseTemplateFile(settings.TemplateFile)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped
+	}
+	// fail now rather than when the conflist is generated.
+	if err := cniconflist.ValidateTemplate(tmpl, settings.MTU, settings.DNSIP); err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped
+	}
+	facts := func() cniconflist.NodeFacts {
+		return cniconflist.NewNodeFacts(ncSubnets(), settings.MTU, settings.DNSIP)
+	}
+	return func(w io.WriteCloser) cniconflist.Generator {
+		return &cniconflist.TemplateGenerator{Writer: w, Template: tmpl, Facts: facts}
+	}, nil
+}
+
+// logCNIConflistDiff logs how the CNI conflist on disk would change in dry-run mode.
+func logCNIConflistDiff(diff *cns.CNIConflistDiff) {
+	switch {
+	case !diff.Exists:
+		logger.Printf("[Azure CNS] Dry run: would write the cni conflist %s:\n%s", diff.Path, diff.Rendered)
+	case !diff.Changed:
+		logger.Printf("[Azure CNS] Dry run: the cni conflist %s is up to date", diff.Path)
+	default:
+		logger.Printf("[Azure CNS] Dry run: would change the cni conflist %s:\n%s", diff.Path, diff.Diff)
+	}
+}
+
+// newPoolScalingPolicy builds the IPAM pool ScalingPolicy selected in the CNS config.
+func newPoolScalingPolicy(settings *configuration.PoolScalingSettings) (ipampool.ScalingPolicy, error) {
+	switch settings.Policy {
+	case "", configuration.PoolScalingPolicyThreshold:
+		return ipampool.ThresholdPolicy{}, nil
+	case configuration.PoolScalingPolicyWarmPool:
+		if settings.WarmPoolSize <= 0 {
+			return nil, errors.Errorf("invalid warm pool size %d", settings.WarmPoolSize)
+		}
+		return ipampool.WarmPoolPolicy{Size: int64(settings.WarmPoolSize)}, nil
+	case configuration.PoolScalingPolicySchedule:
+		policy := ipampool.SchedulePolicy{Default: ipampool.ThresholdPolicy{}}
+		for _, e := range settings.Schedule {
+			start, err := time.Parse("15:04", e.Start)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid schedule start time %s", e.Start)
+			}
+			end, err := time.Parse("15:04", e.End)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid schedule end time %s", e.End)
+			}
+			if e.WarmPoolSize <= 0 {
+				return nil, errors.Errorf("invalid warm pool size %d for schedule window %s-%s", e.WarmPoolSize, e.Start, e.End)
+			}
+			policy.Entries = append(policy.Entries, ipampool.ScheduleEntry{
+				Start:  time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute,
+				End:    time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute,
+				Policy: ipampool.WarmPoolPolicy{Size: int64(e.WarmPoolSize)},
+			})
+		}
+		return policy, nil
+	case configuration.PoolScalingPolicyHysteresis:
+		return &ipampool.HysteresisPolicy{
+			Policy:   ipampool.ThresholdPolicy{},
+			Cooldown: time.Duration(settings.CooldownSecs) * time.Second,
+		}, nil
+	default:
+		return nil, errors.Errorf("unknown pool scaling policy %q", settings.Policy)
+	}
+}
+
 // InitializeCRDState builds and starts the CRD controllers.
-func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cnsconfig *configuration.CNSConfig) error {
+func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cnsconfig *configuration.CNSConfig, readinessChecks *healthserver.Checks) error {
 	// convert interface type to implementation type
 	httpRestServiceImplementation, ok := httpRestService.(*restserver.HTTPRestService)
 	if !ok {
@@ -1131,9 +1368,16 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 			httpRestService)
 	}
 
-	// Set orchestrator type
+	// get nodename for scoping kube requests to node.
+	nodeName, err := configuration.NodeName()
+	if err != nil {
+		return errors.Wrap(err, "failed to get NodeName")
+	}
+
+	// Set orchestrator type, and the node name as node ID so that exported IPAM state is bound to the node.
 	orchestrator := cns.SetOrchestratorTypeRequest{
 		OrchestratorType: cns.KubernetesCRD,
+		NodeID:           nodeName,
 	}
 	httpRestServiceImplementation.SetNodeOrchestrator(&orchestrator)
 
@@ -1150,12 +1394,6 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		return errors.Wrap(err, "failed to build clientset")
 	}
 
-	// get nodename for scoping kube requests to node.
-	nodeName, err := configuration.NodeName()
-	if err != nil {
-		return errors.Wrap(err, "failed to get NodeName")
-	}
-
 	node, err := clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
 	if err != nil {
 		return errors.Wrapf(err, "failed to get node %s", nodeName)
@@ -1169,8 +1407,30 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		// register the noop mtpnc reconciler to populate the cache
 	}
 
+	importFile := cnsconfig.IPAMStateSettings.ImportFile
+	var importedState *restserver.IPAMState
+	if importFile != "" {
+		importedState, err = loadIPAMStateArchive(importFile, httpRestServiceImplementation.IPAMStateSigningKey)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return errors.Wrap(err, "failed to load IPAM state archive")
+			}
+			// the archive is renamed once it is imported or rejected.
+			logger.Printf("IPAM state archive %s not found, it was already consumed", importFile)
+		}
+	}
+	if importedState != nil {
+		if err = importedState.ValidateNodeID(nodeName); err != nil {
+			rejectIPAMStateArchive(importFile)
+			return errors.Wrap(err, "failed to import IPAM state archive")
+		}
+	}
+
 	var podInfoByIPProvider cns.PodInfoByIPProvider
 	switch {
+	case importedState != nil:
+		logger.Printf("Initializing from IPAM state archive %s", importFile)
+		podInfoByIPProvider = importedState
 	case cnsconfig.ManageEndpointState:
 		logger.Printf("Initializing from self managed endpoint store")
 		podInfoByIPProvider, err = cnireconciler.NewCNSPodInfoProvider(httpRestServiceImplementation.EndpointStateStore) // get reference to endpoint state store from rest server
@@ -1220,6 +1480,7 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 	// aks addons to come up so retry a bit more aggresively here.
 	// will retry 10 times maxing out at a minute taking about 8 minutes before it gives up.
 	attempt := 0
+	rejected := false
 	err = retry.Do(func() error {
 		attempt++
 		logger.Printf("reconciling initial CNS state attempt: %d", attempt)
@@ -1227,12 +1488,30 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		if err != nil {
 			logger.Errorf("failed to reconcile initial CNS state, attempt: %d err: %v", attempt, err)
 		}
+		if errors.Is(err, restserver.ErrIPAMStateInconsistent) {
+			// an archive that does not match the NNC will not match it on the next attempt either.
+			rejected = true
+			return retry.Unrecoverable(errors.Wrap(err, "failed to initialize CNS state"))
+		}
 		return errors.Wrap(err, "failed to initialize CNS state")
 	}, retry.Context(ctx), retry.Delay(initCNSInitalDelay), retry.MaxDelay(time.Minute))
+	if rejected {
+		rejectIPAMStateArchive(importFile)
+	}
 	if err != nil {
 		return err
 	}
 	logger.Printf("reconciled initial CNS state after %d attempts", attempt)
+	if importedState != nil {
+		// the endpoint state is only replaced once the archive has been validated and reconciled.
+		if err := httpRestServiceImplementation.ImportEndpointState(importedState); err != nil { //nolint:govet // ignore err shadow
+			return errors.Wrap(err, "failed to import endpoint state")
+		}
+		// the archive is stale once imported, so it must not be imported again on restart.
+		if err := os.Rename(importFile, importFile+".imported"); err != nil { //nolint:govet // ignore err shadow
+			return errors.Wrapf(err, "failed to rename imported IPAM state archive %s", importFile)
+		}
+	}
 
 	scheme := kuberuntime.NewScheme()
 	if err := corev1.AddToScheme(scheme); err != nil { //nolint:govet // intentional shadow
@@ -1292,8 +1571,15 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 	// reconciler has pushed the Monitor a NodeNetworkConfig.
 	cachedscopedcli := nncctrl.NewScopedClient(nodenetworkconfig.NewClient(manager.GetClient()), types.NamespacedName{Namespace: "kube-system", Name: nodeName})
 
+	poolPolicy, err := newPoolScalingPolicy(&cnsconfig.PoolScalingSettings)
+	if err != nil {
+		return errors.Wrap(err, "failed to create pool scaling policy")
+	}
 	poolOpts := ipampool.Options{
-		RefreshDelay: poolIPAMRefreshRateInMilliseconds * time.Millisecond,
+		RefreshDelay:      poolIPAMRefreshRateInMilliseconds * time.Millisecond,
+		PredictiveScaling: cnsconfig.PoolScalingSettings.EnablePredictiveScaling,
+		PredictionWindow:  time.Duration(cnsconfig.PoolScalingSettings.PredictionWindowSecs) * time.Second,
+		Policy:            poolPolicy,
 	}
 	poolMonitor := ipampool.NewMonitor(httpRestServiceImplementation, cachedscopedcli, clusterSubnetStateChan, &poolOpts)
 	httpRestServiceImplementation.IPAMPoolMonitor = poolMonitor
@@ -1309,6 +1595,8 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 	if err := nncReconciler.SetupWithManager(manager, node); err != nil { //nolint:govet // intentional shadow
 		return errors.Wrapf(err, "failed to setup nnc reconciler with manager")
 	}
+	readinessChecks.Add("nnc", healthserver.Started(nncReconciler.IsStarted))
+	readinessChecks.Add("ipampool", healthserver.Started(poolMonitor.IsStarted))
 
 	if cnsconfig.EnableSubnetScarcity {
 		// ClusterSubnetState reconciler
@@ -1335,9 +1623,10 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		httpRestService.AttachSWIFTv2Middleware(&swiftV2Middleware)
 	}
 
-	// adding some routes to the root service mux
-	mux := httpRestServiceImplementation.Listener.GetMux()
-	mux.Handle("/readyz", http.StripPrefix("/readyz", &healthz.Handler{}))
+	// adding some routes to the root service, behind its middlewares
+	listener := httpRestServiceImplementation.Listener
+	listener.AddHandler("/readyz", http.StripPrefix("/readyz", readinessChecks).ServeHTTP)
+	listener.AddHandler("/readyz/", http.StripPrefix("/readyz", readinessChecks).ServeHTTP)
 	if cnsconfig.EnablePprof {
 		httpRestServiceImplementation.RegisterPProfEndpoints()
 	}
@@ -43,6 +43,7 @@ const (
 	NmAgentInternalServerError             ResponseCode = 41
 	StatusUnauthorized                     ResponseCode = 42
 	UnsupportedAPI                         ResponseCode = 43
+	RequestThrottled                       ResponseCode = 44
 	UnexpectedError                        ResponseCode = 99
 )
 
@@ -91,6 +92,8 @@ func (c ResponseCode) String() string {
 		return "NotFound"
 	case PrimaryCANotSame:
 		return "PrimaryCANotSame"
+	case RequestThrottled:
+		return "RequestThrottled"
 	case ReservationNotFound:
 		return "ReservationNotFound"
 	case Success:
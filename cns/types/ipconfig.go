@@ -13,4 +13,8 @@ const (
 	PendingRelease IPState = "PendingRelease"
 	// PendingProgramming IPConfigState for allocated IPs pending programming.
 	PendingProgramming IPState = "PendingProgramming"
+	// Reserved IPConfigState for allocated IPs held for a Pod ahead of its CNI ADD.
+	Reserved IPState = "Reserved"
+	// Cooling IPConfigState for allocated IPs released by Pods that are quarantined before they are Available again.
+	Cooling IPState = "Cooling"
 )
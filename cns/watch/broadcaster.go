@@ -0,0 +1,136 @@
+// Package watch fans out changes of the CNS state to watchers, and keeps the most recent changes so that
+// watchers can resume after the last change they saw.
+package watch
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultHistory is the default number of recent events kept for resuming watches.
+	DefaultHistory = 1024
+	// watcherBuffer is the number of events a watcher can fall behind before it is stopped.
+	watcherBuffer = 256
+)
+
+// ErrExpired is returned when the events since the requested resource version are no longer kept,
+// or the resource version was never issued by this Broadcaster.
+var ErrExpired = errors.New("resource version expired")
+
+// Broadcaster assigns resource versions to events and sends them to its Watchers.
+// Resource versions start at the creation time in nanoseconds, so that versions from a previous
+// run of CNS are older than any kept event and expire rather than skipping changes.
+type Broadcaster struct {
+	sync.Mutex
+	size int
+	// history is the ring of the most recent events, oldest first.
+	history []cns.WatchEvent
+	// floor is the oldest resource version a watch can resume after.
+	floor    uint64
+	version  uint64
+	watchers map[*Watcher]struct{}
+}
+
+// Watcher receives the events published after its resource version.
+type Watcher struct {
+	b      *Broadcaster
+	events chan cns.WatchEvent
+	once   sync.Once
+}
+
+// New creates a Broadcaster that keeps the size most recent events. size defaults if it is not positive.
+func New(size int) *Broadcaster {
+	if size <= 0 {
+		size = DefaultHistory
+	}
+	version := uint64(time.Now().UnixNano())
+	return &Broadcaster{
+		size:     size,
+		history:  make([]cns.WatchEvent, 0, size),
+		floor:    version,
+		version:  version,
+		watchers: map[*Watcher]struct{}{},
+	}
+}
+
+// ResourceVersion returns the resource version of the last event.
+func (b *Broadcaster) ResourceVersion() uint64 {
+	b.Lock()
+	defer b.Unlock()
+	return b.version
+}
+
+// Publish assigns the next resource version to the event and sends it to the Watchers.
+// Publish does not block: Watchers that fell too far behind are stopped, and can resume.
+func (b *Broadcaster) Publish(event cns.WatchEvent) { //nolint:gocritic // events are passed by value to every watcher
+	b.Lock()
+	defer b.Unlock()
+	b.version++
+	event.ResourceVersion = b.version
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if len(b.history) == b.size {
+		b.floor = b.history[0].ResourceVersion
+		b.history = append(b.history[:0], b.history[1:]...)
+	}
+	b.history = append(b.history, event)
+	for w := range b.watchers {
+		select {
+		case w.events <- event:
+		default:
+			b.stopUntransacted(w)
+		}
+	}
+}
+
+// Watch returns a Watcher that receives the events after the resource version, or only new events
+// if it is 0. It returns ErrExpired if the events after the resource version are not kept.
+func (b *Broadcaster) Watch(resourceVersion uint64) (*Watcher, error) {
+	b.Lock()
+	defer b.Unlock()
+	var backlog []cns.WatchEvent
+	if resourceVersion != 0 {
+		if resourceVersion < b.floor || resourceVersion > b.version {
+			return nil, ErrExpired
+		}
+		for i := range b.history {
+			if b.history[i].ResourceVersion > resourceVersion {
+				backlog = b.history[i:]
+				break
+			}
+		}
+	}
+	w := &Watcher{
+		b:      b,
+		events: make(chan cns.WatchEvent, len(backlog)+watcherBuffer),
+	}
+	for i := range backlog {
+		w.events <- backlog[i]
+	}
+	b.watchers[w] = struct{}{}
+	return w, nil
+}
+
+// Events returns the events of the Watcher. It is closed when the Watcher is stopped.
+func (w *Watcher) Events() <-chan cns.WatchEvent {
+	return w.events
+}
+
+// Stop stops sending events to the Watcher.
+func (w *Watcher) Stop() {
+	w.b.Lock()
+	defer w.b.Unlock()
+	w.b.stopUntransacted(w)
+}
+
+func (b *Broadcaster) stopUntransacted(w *Watcher) {
+	delete(b.watchers, w)
+	w.once.Do(func() {
+		close(w.events)
+	})
+}
@@ -0,0 +1,84 @@
+package watch
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ncEvent(ncID string) cns.WatchEvent {
+	return cns.WatchEvent{Type: cns.WatchEventNCCreatedOrUpdated, NetworkContainerID: ncID}
+}
+
+func receive(t *testing.T, w *Watcher, n int) []string {
+	t.Helper()
+	ncIDs := []string{}
+	for i := 0; i < n; i++ {
+		select {
+		case event, ok := <-w.Events():
+			require.True(t, ok, "watcher stopped")
+			ncIDs = append(ncIDs, event.NetworkContainerID)
+		default:
+			require.Failf(t, "missing events", "got %v, want %d", ncIDs, n)
+		}
+	}
+	return ncIDs
+}
+
+func TestBroadcasterWatch(t *testing.T) {
+	b := New(0)
+	start := b.ResourceVersion()
+	w, err := b.Watch(0)
+	require.NoError(t, err)
+	b.Publish(ncEvent("a"))
+	b.Publish(ncEvent("b"))
+	assert.Equal(t, start+2, b.ResourceVersion())
+	assert.Equal(t, []string{"a", "b"}, receive(t, w, 2))
+
+	// a watch resumes after the resource version.
+	resumed, err := b.Watch(start + 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, receive(t, resumed, 1))
+	b.Publish(ncEvent("c"))
+	assert.Equal(t, []string{"c"}, receive(t, resumed, 1))
+	assert.Equal(t, []string{"c"}, receive(t, w, 1))
+
+	w.Stop()
+	_, ok := <-w.Events()
+	assert.False(t, ok)
+	b.Publish(ncEvent("d"))
+	assert.Equal(t, []string{"d"}, receive(t, resumed, 1))
+}
+
+func TestBroadcasterWatchExpired(t *testing.T) {
+	b := New(2)
+	start := b.ResourceVersion()
+	for _, ncID := range []string{"a", "b", "c"} {
+		b.Publish(ncEvent(ncID))
+	}
+	// "a" is no longer kept.
+	_, err := b.Watch(start)
+	require.ErrorIs(t, err, ErrExpired)
+	_, err = b.Watch(start + 4)
+	require.ErrorIs(t, err, ErrExpired)
+
+	w, err := b.Watch(start + 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, receive(t, w, 2))
+}
+
+func TestBroadcasterStopsSlowWatcher(t *testing.T) {
+	b := New(0)
+	w, err := b.Watch(0)
+	require.NoError(t, err)
+	for i := 0; i <= watcherBuffer; i++ {
+		b.Publish(ncEvent("a"))
+	}
+	receive(t, w, watcherBuffer)
+	_, ok := <-w.Events()
+	assert.False(t, ok)
+	// stopping again is safe.
+	w.Stop()
+}
@@ -110,6 +110,10 @@ const (
 	OptStoreFileLocation      = "store-file-path"
 	OptStoreFileLocationAlias = "storefilepath"
 
+	// Store type
+	OptStoreType      = "store-type"
+	OptStoreTypeAlias = "storetype"
+
 	// Private Endpoint
 	OptPrivateEndpoint      = "private-endpoint"
 	OptPrivateEndpointAlias = "pe"
@@ -15,6 +15,9 @@ import (
 	"github.com/pkg/errors"
 )
 
+// Middleware wraps the handler registered for path.
+type Middleware func(path string, next http.HandlerFunc) http.HandlerFunc
+
 // Listener represents an HTTP listener.
 type Listener struct {
 	URL          *url.URL
@@ -24,7 +27,10 @@ type Listener struct {
 	active       bool
 	listener     net.Listener
 	tlsListener  net.Listener
+	unixListener net.Listener
+	unixSocket   string
 	mux          *http.ServeMux
+	middlewares  []Middleware
 }
 
 // NewListener creates a new Listener.
@@ -86,6 +92,39 @@ func (l *Listener) Start(errChan chan<- error) error {
 	return nil
 }
 
+// StartUnix creates a unix socket at the path and starts serving the HTTP server on it too.
+// The requests served on the socket carry the PeerCredentials of their callers in their contexts.
+func (l *Listener) StartUnix(errChan chan<- error, socketPath string) error {
+	if !PeerCredentialsSupported {
+		return errors.New("unix socket listener requires peer credentials, which are not supported on this platform")
+	}
+
+	// remove the socket left behind by a previous listener.
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to remove stale socket %s", socketPath)
+	}
+	list, err := net.Listen("unix", socketPath)
+	if err != nil {
+		log.Printf("[Listener] Failed to listen on unix socket: %+v", err)
+		return errors.Wrapf(err, "failed to listen on %s", socketPath)
+	}
+
+	l.unixListener = peerListener{Listener: list}
+	l.unixSocket = socketPath
+	log.Printf("[Listener] Started listening on unix socket %s.", socketPath)
+
+	server := http.Server{
+		Handler:     l.mux,
+		ConnContext: peerConnContext,
+	}
+	go func() {
+		errChan <- server.Serve(l.unixListener)
+	}()
+
+	l.active = true
+	return nil
+}
+
 // Stop stops listening for requests.
 func (l *Listener) Stop() {
 	// Ignore if not active.
@@ -95,7 +134,9 @@ func (l *Listener) Stop() {
 	l.active = false
 
 	// Stop servicing requests.
-	_ = l.listener.Close()
+	if l.listener != nil {
+		_ = l.listener.Close()
+	}
 
 	if l.tlsListener != nil {
 		// Stop servicing requests on secure listener
@@ -107,6 +148,12 @@ func (l *Listener) Stop() {
 		_ = os.Remove(l.localAddress)
 	}
 
+	if l.unixListener != nil {
+		// Stop servicing requests on the unix socket listener.
+		_ = l.unixListener.Close()
+		_ = os.Remove(l.unixSocket)
+	}
+
 	log.Printf("[Listener] Stopped listening on %s", l.localAddress)
 }
 
@@ -125,8 +172,18 @@ func (l *Listener) AddEndpoint(endpoint string) {
 	l.endpoints = append(l.endpoints, endpoint)
 }
 
+// Use appends middlewares that wrap every handler registered with AddHandler after the call.
+// The first middleware is the outermost. Handlers registered before the call, or directly on the
+// mux returned by GetMux, are not wrapped, so Use must be called before the handlers are added.
+func (l *Listener) Use(middlewares ...Middleware) {
+	l.middlewares = append(l.middlewares, middlewares...)
+}
+
 // AddHandler registers a protocol handler.
 func (l *Listener) AddHandler(path string, handler http.HandlerFunc) {
+	for i := len(l.middlewares) - 1; i >= 0; i-- {
+		handler = l.middlewares[i](path, handler)
+	}
 	l.mux.HandleFunc(path, handler)
 }
 
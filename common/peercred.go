@@ -0,0 +1,65 @@
+package common
+
+import (
+	"context"
+	"net"
+
+	"github.com/Azure/azure-container-networking/log"
+)
+
+// PeerCredentials identify the process on the other end of a unix socket connection.
+type PeerCredentials struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+type peerCredentialsKey struct{}
+
+// WithPeerCredentials returns a copy of the context carrying the peer credentials.
+func WithPeerCredentials(ctx context.Context, creds *PeerCredentials) context.Context {
+	return context.WithValue(ctx, peerCredentialsKey{}, creds)
+}
+
+// PeerCredentialsFromContext returns the peer credentials of a request served on a unix socket.
+// ok is false for requests which were not served on a unix socket.
+func PeerCredentialsFromContext(ctx context.Context) (creds *PeerCredentials, ok bool) {
+	creds, ok = ctx.Value(peerCredentialsKey{}).(*PeerCredentials)
+	return creds, ok && creds != nil
+}
+
+// peerConn is a unix socket connection with the credentials of its peer.
+type peerConn struct {
+	net.Conn
+	creds *PeerCredentials
+}
+
+// peerListener accepts the connections of a unix socket listener and reads the credentials of their peers.
+// Connections whose peer credentials can not be read are closed, so that every request served has them.
+type peerListener struct {
+	net.Listener
+}
+
+func (l peerListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		creds, err := GetPeerCredentials(conn)
+		if err != nil {
+			log.Printf("[Listener] Closing connection without peer credentials: %v", err)
+			_ = conn.Close()
+			continue
+		}
+		return peerConn{Conn: conn, creds: creds}, nil
+	}
+}
+
+// peerConnContext adds the peer credentials of the connection to the contexts of its requests.
+func peerConnContext(ctx context.Context, conn net.Conn) context.Context {
+	if pc, ok := conn.(peerConn); ok {
+		return WithPeerCredentials(ctx, pc.creds)
+	}
+	return ctx
+}
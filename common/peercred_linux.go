@@ -0,0 +1,36 @@
+package common
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// PeerCredentialsSupported is whether the peer credentials of unix socket connections can be read.
+const PeerCredentialsSupported = true
+
+// GetPeerCredentials reads the SO_PEERCRED credentials of the peer of the unix socket connection.
+func GetPeerCredentials(conn net.Conn) (*PeerCredentials, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, errors.Errorf("%T is not a unix socket connection", conn)
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get raw connection")
+	}
+	var (
+		ucred   *unix.Ucred
+		credErr error
+	)
+	if err := raw.Control(func(fd uintptr) {
+		ucred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to control raw connection")
+	}
+	if credErr != nil {
+		return nil, errors.Wrap(credErr, "failed to get SO_PEERCRED")
+	}
+	return &PeerCredentials{PID: ucred.Pid, UID: ucred.Uid, GID: ucred.Gid}, nil
+}
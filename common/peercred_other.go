@@ -0,0 +1,17 @@
+//go:build !linux && !windows
+
+package common
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// PeerCredentialsSupported is whether the peer credentials of unix socket connections can be read.
+const PeerCredentialsSupported = false
+
+// GetPeerCredentials is not supported on platforms other than Linux, which has SO_PEERCRED.
+func GetPeerCredentials(net.Conn) (*PeerCredentials, error) {
+	return nil, errors.New("peer credentials are not supported on this platform")
+}
@@ -34,12 +34,15 @@ type IpamApi interface{}
 
 // Plugin common configuration.
 type PluginConfig struct {
-	Version  string
-	NetApi   NetApi
-	IpamApi  IpamApi
-	Listener *Listener
-	ErrChan  chan error
-	Store    store.KeyValueStore
+	Version   string
+	NetApi    NetApi
+	IpamApi   IpamApi
+	Listener  *Listener
+	ErrChan   chan error
+	Store     store.KeyValueStore
+	StoreType store.Type
+	// StoreRecoveryHandler is notified when a corrupted store file is restored from a snapshot.
+	StoreRecoveryHandler store.RecoveryHandler
 }
 
 // NewPlugin creates a new Plugin object.
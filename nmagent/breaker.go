@@ -0,0 +1,153 @@
+package nmagent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCircuitOpen is returned without contacting NMAgent while the CircuitBreaker is open.
+var ErrCircuitOpen = errors.New("nmagent circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets all requests through.
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets a limited number of probe requests through to decide whether to close again.
+	BreakerHalfOpen
+	// BreakerOpen rejects all requests.
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig configures a CircuitBreaker.
+type CircuitBreakerConfig struct {
+	// Name identifies the breaker in metrics. Defaults to "nmagent".
+	Name string
+	// FailureThreshold is the number of consecutive failures that open the breaker. Defaults to 5.
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before it probes NMAgent again. Defaults to 30 seconds.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of concurrent probe requests let through while half-open. Defaults to 1.
+	HalfOpenProbes int
+}
+
+// CircuitBreaker stops requests to NMAgent after consecutive failures, so that the clients sharing it
+// do not amplify the load on a degraded wireserver. After the OpenTimeout it lets probe requests
+// through, and closes again once one succeeds.
+type CircuitBreaker struct {
+	sync.Mutex
+	config   CircuitBreakerConfig
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probes   int
+	now      func() time.Time
+}
+
+// NewCircuitBreaker creates a closed CircuitBreaker.
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.Name == "" {
+		config.Name = "nmagent"
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second //nolint:gomnd // default open timeout
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = 1
+	}
+	breakerState.WithLabelValues(config.Name).Set(float64(BreakerClosed))
+	return &CircuitBreaker{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.Lock()
+	defer b.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.config.OpenTimeout {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// allow reports whether a request may be sent, and whether it is a probe of a half-open breaker.
+// Every allowed request must be followed by a call to done.
+func (b *CircuitBreaker) allow() (probe bool, err error) {
+	if b == nil {
+		return false, nil
+	}
+	b.Lock()
+	defer b.Unlock()
+	if b.state == BreakerOpen {
+		if b.now().Sub(b.openedAt) < b.config.OpenTimeout {
+			return false, ErrCircuitOpen
+		}
+		b.transitionUntransacted(BreakerHalfOpen)
+	}
+	if b.state == BreakerHalfOpen {
+		if b.probes >= b.config.HalfOpenProbes {
+			return false, ErrCircuitOpen
+		}
+		b.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// done records the outcome of an allowed request. A request that was not sent is recorded as
+// neither a success nor a failure, so that it frees its probe.
+func (b *CircuitBreaker) done(probe, sent, success bool) {
+	if b == nil {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+	if probe {
+		b.probes--
+	}
+	if !sent {
+		return
+	}
+	if success {
+		b.failures = 0
+		if b.state == BreakerHalfOpen {
+			b.transitionUntransacted(BreakerClosed)
+		}
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.config.FailureThreshold) {
+		b.openedAt = b.now()
+		b.transitionUntransacted(BreakerOpen)
+	}
+}
+
+func (b *CircuitBreaker) transitionUntransacted(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	breakerState.WithLabelValues(b.config.Name).Set(float64(state))
+	breakerTransitions.WithLabelValues(b.config.Name, state.String()).Inc()
+}
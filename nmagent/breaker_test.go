@@ -0,0 +1,76 @@
+package nmagent
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := NewCircuitBreaker(CircuitBreakerConfig{Name: t.Name(), FailureThreshold: 2, OpenTimeout: time.Minute})
+	b.now = func() time.Time { return now }
+
+	mustAllow := func(wantProbe bool) {
+		t.Helper()
+		probe, err := b.allow()
+		if err != nil {
+			t.Fatalf("expected the request to be allowed, got: %v", err)
+		}
+		if probe != wantProbe {
+			t.Fatalf("expected probe %t, got %t", wantProbe, probe)
+		}
+	}
+	mustReject := func() {
+		t.Helper()
+		if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expected ErrCircuitOpen, got: %v", err)
+		}
+	}
+	mustBe := func(want BreakerState) {
+		t.Helper()
+		if got := b.State(); got != want {
+			t.Fatalf("expected breaker to be %s, got %s", want, got)
+		}
+	}
+
+	// a success resets the consecutive failures.
+	mustAllow(false)
+	b.done(false, true, false)
+	mustAllow(false)
+	b.done(false, true, true)
+	mustAllow(false)
+	b.done(false, true, false)
+	mustBe(BreakerClosed)
+	mustAllow(false)
+	b.done(false, true, false)
+	mustBe(BreakerOpen)
+	mustReject()
+
+	// after the open timeout a single probe is let through, and reopens the breaker if it fails.
+	now = now.Add(time.Minute)
+	mustBe(BreakerHalfOpen)
+	mustAllow(true)
+	mustReject()
+	b.done(true, true, false)
+	mustBe(BreakerOpen)
+	mustReject()
+
+	// a probe that is not sent frees its slot without deciding.
+	now = now.Add(time.Minute)
+	mustAllow(true)
+	b.done(true, false, false)
+	mustBe(BreakerHalfOpen)
+	mustAllow(true)
+	b.done(true, true, true)
+	mustBe(BreakerClosed)
+	mustAllow(false)
+}
+
+func TestNilCircuitBreaker(t *testing.T) {
+	var b *CircuitBreaker
+	if _, err := b.allow(); err != nil {
+		t.Fatalf("expected a nil breaker to allow requests, got: %v", err)
+	}
+	b.done(false, true, false)
+}
@@ -0,0 +1,145 @@
+package nmagent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	supportedAPIsKey = "SupportedAPIs"
+	ncVersionListKey = "NCVersionList"
+	homeAzKey        = "HomeAz"
+)
+
+// CacheConfig is the time the responses of each cached call are reused for. A call is not cached if its TTL is 0.
+type CacheConfig struct {
+	SupportedAPIsTTL time.Duration
+	NCVersionListTTL time.Duration
+	HomeAzTTL        time.Duration
+}
+
+type cacheEntry struct {
+	value   any
+	expires time.Time
+}
+
+// CachingClient is a Client that reuses the responses of the NMAgent calls that are made often but change
+// rarely, and makes concurrent calls share a single request. The NC version list is invalidated when NCs are
+// put or deleted through the CachingClient, and can be invalidated explicitly when they are changed otherwise.
+type CachingClient struct {
+	*Client
+	config CacheConfig
+	group  singleflight.Group
+
+	mu sync.Mutex
+	// generations of each key are bumped on invalidation, so that calls in flight don't cache stale responses.
+	generations map[string]uint64
+	entries     map[string]cacheEntry
+	now         func() time.Time
+}
+
+// NewCachingClient decorates the Client with the response cache.
+func NewCachingClient(client *Client, config CacheConfig) *CachingClient {
+	return &CachingClient{
+		Client:      client,
+		config:      config,
+		generations: map[string]uint64{},
+		entries:     map[string]cacheEntry{},
+		now:         time.Now,
+	}
+}
+
+// SupportedAPIs returns the cached capabilities of NMAgent.
+func (c *CachingClient) SupportedAPIs(ctx context.Context) ([]string, error) {
+	apis, err := cached(ctx, c, supportedAPIsKey, c.config.SupportedAPIsTTL, c.Client.SupportedAPIs)
+	return append([]string(nil), apis...), err
+}
+
+// GetNCVersionList returns the cached versions of the NCs programmed by NMAgent.
+func (c *CachingClient) GetNCVersionList(ctx context.Context) (NCVersionList, error) {
+	list, err := cached(ctx, c, ncVersionListKey, c.config.NCVersionListTTL, c.Client.GetNCVersionList)
+	list.Containers = append([]NCVersion(nil), list.Containers...)
+	return list, err
+}
+
+// GetHomeAz returns the cached home AZ of the node.
+func (c *CachingClient) GetHomeAz(ctx context.Context) (AzResponse, error) {
+	return cached(ctx, c, homeAzKey, c.config.HomeAzTTL, c.Client.GetHomeAz)
+}
+
+// PutNetworkContainer puts the NC and invalidates the NC version list.
+func (c *CachingClient) PutNetworkContainer(ctx context.Context, pncr *PutNetworkContainerRequest) error {
+	defer c.InvalidateNCVersionList()
+	return c.Client.PutNetworkContainer(ctx, pncr)
+}
+
+// DeleteNetworkContainer deletes the NC and invalidates the NC version list.
+func (c *CachingClient) DeleteNetworkContainer(ctx context.Context, dcr DeleteContainerRequest) error {
+	defer c.InvalidateNCVersionList()
+	return c.Client.DeleteNetworkContainer(ctx, dcr)
+}
+
+// InvalidateNCVersionList drops the cached NC version list, so that the next call fetches it from NMAgent.
+func (c *CachingClient) InvalidateNCVersionList() {
+	c.invalidate(ncVersionListKey)
+}
+
+// Invalidate drops all cached responses.
+func (c *CachingClient) Invalidate() {
+	c.invalidate(supportedAPIsKey, ncVersionListKey, homeAzKey)
+}
+
+func (c *CachingClient) invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		c.generations[key]++
+		delete(c.entries, key)
+		c.group.Forget(key)
+	}
+}
+
+// cached returns the response of the call from the cache if it has not expired, or makes the call once
+// for all the concurrent callers and caches its response. Shared calls are made with the context of
+// the first caller, but every caller stops waiting when its own context is done.
+func cached[T any](ctx context.Context, c *CachingClient, key string, ttl time.Duration, call func(context.Context) (T, error)) (T, error) {
+	if ttl <= 0 {
+		return call(ctx)
+	}
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && c.now().Before(entry.expires) {
+		c.mu.Unlock()
+		cacheRequests.WithLabelValues(key, cacheHit).Inc()
+		return entry.value.(T), nil
+	}
+	c.mu.Unlock()
+	cacheRequests.WithLabelValues(key, cacheMiss).Inc()
+
+	results := c.group.DoChan(key, func() (any, error) {
+		c.mu.Lock()
+		generation := c.generations[key]
+		c.mu.Unlock()
+		value, err := call(ctx)
+		if err != nil {
+			return value, err
+		}
+		c.mu.Lock()
+		if c.generations[key] == generation {
+			c.entries[key] = cacheEntry{value: value, expires: c.now().Add(ttl)}
+		}
+		c.mu.Unlock()
+		return value, nil
+	})
+	select {
+	case res := <-results:
+		value, _ := res.Val.(T)
+		return value, res.Err //nolint:wrapcheck // the error of the call is returned as it is
+	case <-ctx.Done():
+		var zero T
+		return zero, errors.Wrapf(ctx.Err(), "waiting for %s", key)
+	}
+}
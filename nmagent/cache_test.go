@@ -0,0 +1,108 @@
+package nmagent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newCountingCachingClient returns a CachingClient of an NMAgent with a single NC, whose version is
+// the number of NC version list requests it got.
+func newCountingCachingClient(config CacheConfig, wait <-chan struct{}) (*CachingClient, *int32) {
+	var count int32
+	client := NewTestClient(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		n := atomic.AddInt32(&count, 1)
+		if wait != nil {
+			<-wait
+		}
+		rr := httptest.NewRecorder()
+		rr.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(rr, `{"httpStatusCode":"200","networkContainers":[{"networkContainerId":"nc","version":"%d"}]}`, n)
+		return rr.Result(), nil
+	}))
+	return NewCachingClient(client, config), &count
+}
+
+func TestCachingClientTTLAndInvalidation(t *testing.T) {
+	c, count := newCountingCachingClient(CacheConfig{NCVersionListTTL: time.Minute}, nil)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	versionOf := func() string {
+		t.Helper()
+		list, err := c.GetNCVersionList(ctx)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		return list.Containers[0].Version
+	}
+
+	if got := versionOf(); got != "1" {
+		t.Fatalf("expected version 1, got %s", got)
+	}
+	if got := versionOf(); got != "1" {
+		t.Fatalf("expected the cached version 1, got %s", got)
+	}
+
+	now = now.Add(time.Minute)
+	if got := versionOf(); got != "2" {
+		t.Fatalf("expected the expired version to be fetched again, got %s", got)
+	}
+
+	c.InvalidateNCVersionList()
+	if got := versionOf(); got != "3" {
+		t.Fatalf("expected the invalidated version to be fetched again, got %s", got)
+	}
+	if got := atomic.LoadInt32(count); got != 3 {
+		t.Fatalf("expected 3 requests, got %d", got)
+	}
+}
+
+func TestCachingClientSingleflight(t *testing.T) {
+	wait := make(chan struct{})
+	c, count := newCountingCachingClient(CacheConfig{NCVersionListTTL: time.Minute}, wait)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetNCVersionList(ctx); err != nil {
+				t.Error("unexpected error:", err)
+			}
+		}()
+	}
+	// let the callers pile up behind the first request.
+	time.Sleep(50 * time.Millisecond)
+	close(wait)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(count); got != 1 {
+		t.Fatalf("expected concurrent calls to share 1 request, got %d", got)
+	}
+}
+
+func TestCachingClientUncached(t *testing.T) {
+	c, count := newCountingCachingClient(CacheConfig{}, nil)
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetNCVersionList(context.Background()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+	if got := atomic.LoadInt32(count); got != 2 {
+		t.Fatalf("expected calls without a TTL not to be cached, got %d requests", got)
+	}
+}
@@ -23,9 +23,9 @@ func NewClient(c Config) (*Client, error) {
 
 	client := &Client{
 		httpClient: &http.Client{
-			Transport: &internal.WireserverTransport{
+			Transport: newGuardedTransport(c, &internal.WireserverTransport{
 				Transport: http.DefaultTransport,
-			},
+			}),
 		},
 		host:      c.Host,
 		port:      c.Port,
@@ -322,7 +322,7 @@ func (c *Client) buildRequest(ctx context.Context, req Request) (*http.Request,
 	}
 
 	// nolint:wrapcheck // wrapping doesn't provide useful information
-	return http.NewRequestWithContext(ctx, req.Method(), fullURL.String(), body)
+	return http.NewRequestWithContext(withEndpoint(ctx, req), req.Method(), fullURL.String(), body)
 }
 
 func (c *Client) scheme() string {
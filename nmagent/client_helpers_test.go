@@ -22,3 +22,11 @@ func NewTestClient(transport http.RoundTripper) *Client {
 		},
 	}
 }
+
+// NewGuardedTestClient creates a test client with a mock transport, which
+// guards its requests with the circuit breaker and limits of the config.
+func NewGuardedTestClient(transport http.RoundTripper, c Config) *Client {
+	client := NewTestClient(transport)
+	client.httpClient.Transport = newGuardedTransport(c, client.httpClient.Transport)
+	return client
+}
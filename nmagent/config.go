@@ -22,6 +22,19 @@ type Config struct {
 	// Optional Config //
 	/////////////////////
 	UseTLS bool // forces all connections to use TLS
+
+	// CircuitBreaker stops requests after consecutive failures. Clients can
+	// share it to back off together. Requests are never stopped if it is nil.
+	CircuitBreaker *CircuitBreaker
+
+	// RequestsPerSecond is the sustained rate of requests the client may send,
+	// in bursts of up to RequestBurst requests. The rate is unlimited if it is 0.
+	RequestsPerSecond float64
+	RequestBurst      int
+
+	// MaxConcurrentRequests limits the requests in flight to each NMAgent
+	// endpoint. The requests are unlimited if it is 0.
+	MaxConcurrentRequests int
 }
 
 // Validate reports whether this configuration is a valid configuration for a
@@ -0,0 +1,116 @@
+package nmagent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// ErrRequestBudgetExhausted is returned without contacting NMAgent when the client has used up its request budget.
+var ErrRequestBudgetExhausted = errors.New("nmagent request budget exhausted")
+
+type endpointKey struct{}
+
+// withEndpoint names the NMAgent endpoint of the request in the context, for limits and metrics.
+func withEndpoint(ctx context.Context, req Request) context.Context {
+	name := fmt.Sprintf("%T", req)
+	name = strings.TrimSuffix(name[strings.LastIndex(name, ".")+1:], "Request")
+	return context.WithValue(ctx, endpointKey{}, name)
+}
+
+func endpointFrom(ctx context.Context) string {
+	name, _ := ctx.Value(endpointKey{}).(string)
+	return name
+}
+
+// guardedTransport protects NMAgent from the client: it stops requests while the circuit breaker is open,
+// spends a token of the request budget on every request, and limits the requests in flight to each endpoint.
+// Rejected requests fail with errors that are not temporary, so that they are not retried.
+type guardedTransport struct {
+	transport http.RoundTripper
+	breaker   *CircuitBreaker
+	budget    *rate.Limiter
+
+	maxConcurrent int
+	mu            sync.Mutex
+	slots         map[string]chan struct{}
+}
+
+func newGuardedTransport(c Config, transport http.RoundTripper) *guardedTransport {
+	t := &guardedTransport{
+		transport:     transport,
+		breaker:       c.CircuitBreaker,
+		maxConcurrent: c.MaxConcurrentRequests,
+		slots:         map[string]chan struct{}{},
+	}
+	if c.RequestsPerSecond > 0 {
+		burst := c.RequestBurst
+		if burst <= 0 {
+			burst = 1
+		}
+		t.budget = rate.NewLimiter(rate.Limit(c.RequestsPerSecond), burst)
+	}
+	return t
+}
+
+func (t *guardedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := endpointFrom(req.Context())
+	probe, err := t.breaker.allow()
+	if err != nil {
+		rejectedRequests.WithLabelValues(endpoint, rejectedCircuitOpen).Inc()
+		return nil, err
+	}
+	if t.budget != nil && !t.budget.Allow() {
+		t.breaker.done(probe, false, false)
+		rejectedRequests.WithLabelValues(endpoint, rejectedBudgetExhausted).Inc()
+		return nil, ErrRequestBudgetExhausted
+	}
+	release, err := t.acquire(req.Context(), endpoint)
+	if err != nil {
+		t.breaker.done(probe, false, false)
+		return nil, err
+	}
+	defer release()
+
+	inflightRequests.WithLabelValues(endpoint).Inc()
+	defer inflightRequests.WithLabelValues(endpoint).Dec()
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil && (errors.Is(err, context.Canceled) || errors.Is(req.Context().Err(), context.Canceled)) {
+		// the caller gave up on the request, which says nothing about the health of NMAgent.
+		t.breaker.done(probe, false, false)
+		return nil, err //nolint:wrapcheck // the error of the wrapped transport is returned as it is
+	}
+	t.breaker.done(probe, true, err == nil && !isFailureStatus(resp.StatusCode))
+	return resp, err //nolint:wrapcheck // the error of the wrapped transport is returned as it is
+}
+
+// acquire waits for a free slot of the endpoint, and returns the func to release it.
+func (t *guardedTransport) acquire(ctx context.Context, endpoint string) (func(), error) {
+	if t.maxConcurrent <= 0 {
+		return func() {}, nil
+	}
+	t.mu.Lock()
+	slots, ok := t.slots[endpoint]
+	if !ok {
+		slots = make(chan struct{}, t.maxConcurrent)
+		t.slots[endpoint] = slots
+	}
+	t.mu.Unlock()
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, errors.Wrapf(ctx.Err(), "waiting for a free %s request slot", endpoint)
+	}
+}
+
+// isFailureStatus reports whether the status shows that NMAgent or wireserver is degraded, rather than that
+// the request was refused.
+func isFailureStatus(code int) bool {
+	return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests
+}
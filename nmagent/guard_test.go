@@ -0,0 +1,134 @@
+package nmagent_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/nmagent"
+)
+
+// countingTripper responds to every request with the NMAgent status, and counts the requests.
+func countingTripper(status *int, count *int32) *TestTripper {
+	return &TestTripper{
+		RoundTripF: func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(count, 1)
+			rr := httptest.NewRecorder()
+			rr.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(rr, `{"httpStatusCode":"%d","homeAz":1}`, *status)
+			rr.WriteHeader(http.StatusOK)
+			return rr.Result(), nil
+		},
+	}
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	status := http.StatusInternalServerError
+	var count int32
+	breaker := nmagent.NewCircuitBreaker(nmagent.CircuitBreakerConfig{Name: t.Name(), FailureThreshold: 2, OpenTimeout: time.Hour})
+	// clients sharing the breaker back off together.
+	client := nmagent.NewGuardedTestClient(countingTripper(&status, &count), nmagent.Config{CircuitBreaker: breaker})
+	other := nmagent.NewGuardedTestClient(countingTripper(&status, &count), nmagent.Config{CircuitBreaker: breaker})
+
+	ctx, cancel := testContext(t)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetHomeAz(ctx); err == nil {
+			t.Fatal("expected an error from NMAgent")
+		}
+	}
+	if breaker.State() != nmagent.BreakerOpen {
+		t.Fatalf("expected the breaker to be open, got %s", breaker.State())
+	}
+
+	status = http.StatusOK
+	if _, err := other.GetHomeAz(ctx); !errors.Is(err, nmagent.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got: %v", err)
+	}
+	if got := atomic.LoadInt32(&count); got != 2 {
+		t.Fatalf("expected 2 requests to reach NMAgent, got %d", got)
+	}
+}
+
+func TestClientCircuitBreakerIgnoresCanceledRequests(t *testing.T) {
+	breaker := nmagent.NewCircuitBreaker(nmagent.CircuitBreakerConfig{Name: t.Name(), FailureThreshold: 1, OpenTimeout: time.Hour})
+	client := nmagent.NewGuardedTestClient(&TestTripper{
+		RoundTripF: func(*http.Request) (*http.Response, error) {
+			// the caller gave up while the request was in flight.
+			return nil, context.Canceled
+		},
+	}, nmagent.Config{CircuitBreaker: breaker})
+
+	ctx, cancel := testContext(t)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetHomeAz(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got: %v", err)
+		}
+	}
+	if breaker.State() != nmagent.BreakerClosed {
+		t.Fatalf("expected the breaker to stay closed, got %s", breaker.State())
+	}
+}
+
+func TestClientRequestBudget(t *testing.T) {
+	status := http.StatusOK
+	var count int32
+	client := nmagent.NewGuardedTestClient(countingTripper(&status, &count), nmagent.Config{RequestsPerSecond: 0.001, RequestBurst: 2})
+
+	ctx, cancel := testContext(t)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetHomeAz(ctx); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+	if _, err := client.GetHomeAz(ctx); !errors.Is(err, nmagent.ErrRequestBudgetExhausted) {
+		t.Fatalf("expected ErrRequestBudgetExhausted, got: %v", err)
+	}
+}
+
+func TestClientMaxConcurrentRequests(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	client := nmagent.NewGuardedTestClient(&TestTripper{
+		RoundTripF: func(req *http.Request) (*http.Response, error) {
+			close(started)
+			<-unblock
+			rr := httptest.NewRecorder()
+			rr.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprint(rr, `{"httpStatusCode":"200","homeAz":1}`)
+			return rr.Result(), nil
+		},
+	}, nmagent.Config{MaxConcurrentRequests: 1})
+
+	ctx, cancel := testContext(t)
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		_, err := client.GetHomeAz(ctx)
+		done <- err
+	}()
+	<-started
+
+	// the second request to the endpoint waits for the first.
+	waitCtx, waitCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer waitCancel()
+	if _, err := client.GetHomeAz(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the request to wait for a free slot, got: %v", err)
+	}
+
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
@@ -0,0 +1,69 @@
+package nmagent
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	breakerLabel  = "breaker"
+	stateLabel    = "state"
+	endpointLabel = "endpoint"
+	reasonLabel   = "reason"
+	callLabel     = "call"
+	resultLabel   = "result"
+
+	rejectedCircuitOpen     = "circuit_open"
+	rejectedBudgetExhausted = "budget_exhausted"
+
+	cacheHit  = "hit"
+	cacheMiss = "miss"
+)
+
+var (
+	breakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "nmagent_circuit_breaker_state",
+			Help: "State of the NMAgent client circuit breaker: 0 closed, 1 half-open, 2 open.",
+		},
+		[]string{breakerLabel},
+	)
+	breakerTransitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nmagent_circuit_breaker_transitions_total",
+			Help: "Transitions of the NMAgent client circuit breaker by the state transitioned to.",
+		},
+		[]string{breakerLabel, stateLabel},
+	)
+	rejectedRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nmagent_requests_rejected_total",
+			Help: "NMAgent requests rejected by the client without being sent, by endpoint and reason.",
+		},
+		[]string{endpointLabel, reasonLabel},
+	)
+	inflightRequests = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "nmagent_requests_in_flight",
+			Help: "NMAgent requests in flight by endpoint.",
+		},
+		[]string{endpointLabel},
+	)
+	cacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nmagent_cache_requests_total",
+			Help: "Cached NMAgent calls by call and whether they were served from the cache.",
+		},
+		[]string{callLabel, resultLabel},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		breakerState,
+		breakerTransitions,
+		rejectedRequests,
+		inflightRequests,
+		cacheRequests,
+	)
+}
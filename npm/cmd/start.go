@@ -28,6 +28,8 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog"
 	"k8s.io/utils/exec"
+	policyclientset "sigs.k8s.io/network-policy-api/pkg/client/clientset/versioned"
+	policyinformers "sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions"
 )
 
 var npmV2DataplaneCfg = &dataplane.Config{
@@ -120,6 +122,7 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 	k8sServerVersion := k8sServerVersion(clientset)
 
 	var dp dataplane.GenericDataplane
+	var npmV2Dataplane *dataplane.DataPlane
 	stopChannel := wait.NeverStop
 	if config.Toggles.EnableV2NPM {
 		// update the dataplane config
@@ -157,6 +160,13 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 		}
 
 		npmV2DataplaneCfg.PlaceAzureChainFirst = config.Toggles.PlaceAzureChainFirst
+		npmV2DataplaneCfg.PolicyManagerCfg.Nftables = config.Toggles.EnableNftables
+		npmV2DataplaneCfg.IPSetManagerCfg.Nftables = config.Toggles.EnableNftables
+		npmV2DataplaneCfg.PolicyManagerCfg.DualStack = config.Toggles.EnableDualStack
+		npmV2DataplaneCfg.IPSetManagerCfg.DualStack = config.Toggles.EnableDualStack
+		npmV2DataplaneCfg.PolicyManagerCfg.AdminNetworkPolicy = config.Toggles.EnableAdminNetworkPolicy && !util.IsWindowsDP()
+		npmV2DataplaneCfg.PolicyManagerCfg.Audit = config.Toggles.EnablePolicyAudit && !util.IsWindowsDP()
+		npmV2DataplaneCfg.PolicyManagerCfg.Counters = config.Toggles.EnablePolicyCounters && !util.IsWindowsDP()
 		if config.Toggles.ApplyIPSetsOnNeed {
 			npmV2DataplaneCfg.IPSetMode = ipsets.ApplyOnNeed
 		} else {
@@ -174,14 +184,25 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 		}
 		npmV2DataplaneCfg.NodeIP = nodeIP
 
-		dp, err = dataplane.NewDataPlane(models.GetNodeName(), common.NewIOShim(), npmV2DataplaneCfg, stopChannel)
+		npmV2Dataplane, err = dataplane.NewDataPlane(models.GetNodeName(), common.NewIOShim(), npmV2DataplaneCfg, stopChannel)
 		if err != nil {
 			metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to create dataplane with error %v", err)
 			return fmt.Errorf("failed to create dataplane with error %w", err)
 		}
+		dp = npmV2Dataplane
 		dp.RunPeriodicTasks()
 	}
 	npMgr := npm.NewNetworkPolicyManager(config, factory, dp, exec.New(), version, k8sServerVersion)
+	if npmV2DataplaneCfg.PolicyManagerCfg.AdminNetworkPolicy {
+		policyClientset, err := policyclientset.NewForConfig(k8sConfig)
+		if err != nil {
+			return fmt.Errorf("failed to generate network policy API clientset with cluster config: %w", err)
+		}
+		npMgr.EnableAdminNetworkPolicies(policyinformers.NewSharedInformerFactory(policyClientset, resyncPeriod))
+	}
+	if npmV2DataplaneCfg.PolicyManagerCfg.Audit {
+		npMgr.EnablePolicyAudit(npmV2Dataplane)
+	}
 	err = metrics.CreateTelemetryHandle(config.NPMVersion(), version, npm.GetAIMetadata())
 	if err != nil {
 		klog.Infof("CreateTelemetryHandle failed with error %v. AITelemetry is not initialized.", err)
@@ -51,6 +51,12 @@ var DefaultConfig = Config{
 		ApplyInBackground: true,
 		// NetPolInBackground is currently used in Linux to apply NetPol controller Add events in the background
 		NetPolInBackground: true,
+		EnableNftables:     false,
+		EnableDualStack:    false,
+		// EnableAdminNetworkPolicy requires the AdminNetworkPolicy and BaselineAdminNetworkPolicy CRDs to be installed
+		EnableAdminNetworkPolicy: false,
+		EnablePolicyAudit:        false,
+		EnablePolicyCounters:     false,
 	},
 }
 
@@ -94,6 +100,20 @@ type Toggles struct {
 	ApplyInBackground bool
 	// NetPolInBackground
 	NetPolInBackground bool
+	// EnableNftables applies for Linux only. It programs policies and ipsets with nftables instead of iptables and ipset.
+	EnableNftables bool
+	// EnableDualStack applies for Linux only. It programs IPv6 pod IPs and CIDRs alongside IPv4 ones.
+	// When disabled, IPv6 pod IPs are ignored.
+	EnableDualStack bool
+	// EnableAdminNetworkPolicy applies for Linux only. It enforces AdminNetworkPolicies and BaselineAdminNetworkPolicies
+	// from the policy.networking.k8s.io API group around NetworkPolicies.
+	EnableAdminNetworkPolicy bool
+	// EnablePolicyAudit applies for Linux only. ACLs log the packets they match, and NPM exports them as logs
+	// and as the npm_policy_audit_events_total metric. This adds a rule per ACL with iptables.
+	EnablePolicyAudit bool
+	// EnablePolicyCounters applies for Linux only. NPM exports the packet and byte counters of each policy's ACLs
+	// as the npm_policy_packets_total and npm_policy_bytes_total metrics.
+	EnablePolicyCounters bool
 }
 
 type Flags struct {
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// IncPolicyAuditEvents counts an audit event logged by an ACL of the policy.
+func IncPolicyAuditEvents(policyKey, direction, verdict string) {
+	policyAuditEvents.With(policyAuditLabels(policyKey, direction, verdict)).Inc()
+}
+
+// RemovePolicyAuditEvents deletes the audit event counts of the policy keys.
+// Audit tags have the hash of the policy key when the key is too long, so callers should include the hashed key too.
+func RemovePolicyAuditEvents(policyKeys ...string) {
+	for _, policyKey := range policyKeys {
+		policyAuditEvents.DeletePartialMatch(prometheus.Labels{policyLabel: policyKey})
+	}
+}
+
+// TotalPolicyAuditEvents is intended for UTs.
+func TotalPolicyAuditEvents(policyKey, direction, verdict string) (int, error) {
+	return counterValue(policyAuditEvents.With(policyAuditLabels(policyKey, direction, verdict)))
+}
+
+func policyAuditLabels(policyKey, direction, verdict string) prometheus.Labels {
+	return prometheus.Labels{
+		policyLabel:    policyKey,
+		directionLabel: direction,
+		verdictLabel:   verdict,
+	}
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncPolicyAuditEvents(t *testing.T) {
+	IncPolicyAuditEvents("x/test1", "IN", "DROP")
+	IncPolicyAuditEvents("x/test1", "IN", "DROP")
+	IncPolicyAuditEvents("x/test1", "OUT", "ALLOW")
+
+	val, err := TotalPolicyAuditEvents("x/test1", "IN", "DROP")
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 2, val, "should have counted two ingress drops")
+
+	val, err = TotalPolicyAuditEvents("x/test1", "OUT", "ALLOW")
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 1, val, "should have counted one egress allow")
+}
+
+func TestRemovePolicyAuditEvents(t *testing.T) {
+	IncPolicyAuditEvents("x/test2", "IN", "DROP")
+	IncPolicyAuditEvents("#12345", "OUT", "ALLOW")
+	IncPolicyAuditEvents("x/test3", "IN", "DROP")
+
+	RemovePolicyAuditEvents("x/test2", "#12345")
+
+	val, err := TotalPolicyAuditEvents("x/test2", "IN", "DROP")
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 0, val, "should have deleted the policy's counts")
+
+	val, err = TotalPolicyAuditEvents("#12345", "OUT", "ALLOW")
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 0, val, "should have deleted the hashed policy's counts")
+
+	val, err = TotalPolicyAuditEvents("x/test3", "IN", "DROP")
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 1, val, "should have kept other policies' counts")
+}
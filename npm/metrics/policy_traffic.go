@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PolicyTrafficKey identifies the ACL rules of a policy with the same direction and verdict.
+type PolicyTrafficKey struct {
+	PolicyKey string
+	Direction string
+	Verdict   string
+}
+
+// PolicyTraffic is the sum of the kernel counters of ACL rules.
+type PolicyTraffic struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// policyTrafficCollector exports the last traffic read from the kernel.
+// The kernel counters are already totals, so they're exported as constant counters instead of being added to a CounterVec.
+// A counter restarts when its policy is updated since the policy's rules are rewritten.
+type policyTrafficCollector struct {
+	sync.Mutex
+	traffic     map[PolicyTrafficKey]PolicyTraffic
+	packetsDesc *prometheus.Desc
+	bytesDesc   *prometheus.Desc
+}
+
+func newPolicyTrafficCollector() *policyTrafficCollector {
+	return &policyTrafficCollector{
+		traffic: make(map[PolicyTrafficKey]PolicyTraffic),
+		packetsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "policy_packets_total"),
+			"Number of packets matched by ACLs by policy, direction, and verdict label. Only exported when policy counters are enabled",
+			policyVerdictLabels,
+			nil,
+		),
+		bytesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "policy_bytes_total"),
+			"Number of bytes matched by ACLs by policy, direction, and verdict label. Only exported when policy counters are enabled",
+			policyVerdictLabels,
+			nil,
+		),
+	}
+}
+
+func (c *policyTrafficCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.packetsDesc
+	ch <- c.bytesDesc
+}
+
+func (c *policyTrafficCollector) Collect(ch chan<- prometheus.Metric) {
+	c.Lock()
+	defer c.Unlock()
+
+	for key, traffic := range c.traffic {
+		ch <- prometheus.MustNewConstMetric(c.packetsDesc, prometheus.CounterValue, float64(traffic.Packets), key.PolicyKey, key.Direction, key.Verdict)
+		ch <- prometheus.MustNewConstMetric(c.bytesDesc, prometheus.CounterValue, float64(traffic.Bytes), key.PolicyKey, key.Direction, key.Verdict)
+	}
+}
+
+// SetPolicyTraffic replaces the traffic of all policies. Policies which aren't in traffic are no longer exported.
+func SetPolicyTraffic(traffic map[PolicyTrafficKey]PolicyTraffic) {
+	policyTraffic.Lock()
+	defer policyTraffic.Unlock()
+	policyTraffic.traffic = traffic
+}
+
+// GetPolicyTraffic is intended for UTs.
+func GetPolicyTraffic(key PolicyTrafficKey) (PolicyTraffic, bool) {
+	policyTraffic.Lock()
+	defer policyTraffic.Unlock()
+	traffic, ok := policyTraffic.traffic[key]
+	return traffic, ok
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/http/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetPolicyTraffic(t *testing.T) {
+	ingressDrops := PolicyTrafficKey{PolicyKey: "x/test1", Direction: "IN", Verdict: "DROP"}
+	egressAllows := PolicyTrafficKey{PolicyKey: "x/test1", Direction: "OUT", Verdict: "ALLOW"}
+	SetPolicyTraffic(map[PolicyTrafficKey]PolicyTraffic{
+		ingressDrops: {Packets: 3, Bytes: 180},
+		egressAllows: {Packets: 5, Bytes: 300},
+	})
+
+	traffic, ok := GetPolicyTraffic(ingressDrops)
+	require.True(t, ok)
+	require.Equal(t, PolicyTraffic{Packets: 3, Bytes: 180}, traffic)
+
+	rr := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, api.NodeMetricsPath, nil)
+	require.NoError(t, err)
+	GetHandler(NodeMetrics).ServeHTTP(rr, req)
+	require.Contains(t, rr.Body.String(), `npm_policy_packets_total{direction="IN",policy="x/test1",verdict="DROP"} 3`)
+	require.Contains(t, rr.Body.String(), `npm_policy_bytes_total{direction="OUT",policy="x/test1",verdict="ALLOW"} 300`)
+
+	// a removed policy is no longer exported
+	SetPolicyTraffic(map[PolicyTrafficKey]PolicyTraffic{
+		egressAllows: {Packets: 6, Bytes: 360},
+	})
+	_, ok = GetPolicyTraffic(ingressDrops)
+	require.False(t, ok)
+
+	rr = httptest.NewRecorder()
+	GetHandler(NodeMetrics).ServeHTTP(rr, req)
+	require.NotContains(t, rr.Body.String(), `npm_policy_packets_total{direction="IN",policy="x/test1",verdict="DROP"}`)
+	require.Contains(t, rr.Body.String(), `npm_policy_packets_total{direction="OUT",policy="x/test1",verdict="ALLOW"} 6`)
+}
@@ -127,6 +127,22 @@ var (
 	iptablesRestoreFailures *prometheus.CounterVec
 )
 
+// labels of linux per-policy metrics
+const (
+	policyLabel    = "policy"
+	directionLabel = "direction"
+	verdictLabel   = "verdict"
+)
+
+// linux policy audit metrics
+var (
+	policyAuditEvents   *prometheus.CounterVec
+	policyVerdictLabels = []string{policyLabel, directionLabel, verdictLabel}
+)
+
+// linux policy traffic metrics, which are read from the kernel counters of ACL rules
+var policyTraffic *policyTrafficCollector
+
 type RegistryType string
 
 const (
@@ -196,6 +212,8 @@ func InitializeAll() {
 		register(itpablesRestoreLatency, "iptables_restore_latency_seconds", NodeMetrics)
 		register(iptablesDeleteLatency, "iptables_delete_latency_seconds", NodeMetrics)
 		register(iptablesRestoreFailures, "iptables_restore_failure_total", NodeMetrics)
+		register(policyAuditEvents, "policy_audit_events_total", NodeMetrics)
+		register(policyTraffic, "policy_traffic", NodeMetrics)
 	}
 
 	log.Logf("Finished initializing all Prometheus metrics")
@@ -354,6 +372,17 @@ func InitializeLinuxMetrics() {
 		},
 		[]string{operationLabel},
 	)
+
+	policyAuditEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "policy_audit_events_total",
+			Help:      "Number of audit events logged by ACLs by policy, direction, and verdict label. Only counted when policy audit is enabled",
+		},
+		policyVerdictLabels,
+	)
+
+	policyTraffic = newPolicyTrafficCollector()
 }
 
 // GetHandler returns the HTTP handler for the metrics endpoint
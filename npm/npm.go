@@ -9,6 +9,7 @@ import (
 
 	npmconfig "github.com/Azure/azure-container-networking/npm/config"
 	"github.com/Azure/azure-container-networking/npm/ipsm"
+	"github.com/Azure/azure-container-networking/npm/pkg/audit"
 	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/common"
 	controllersv1 "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/v1"
 	controllersv2 "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/v2"
@@ -21,6 +22,7 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog"
 	utilexec "k8s.io/utils/exec"
+	policyinformers "sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions"
 )
 
 var aiMetadata string //nolint // aiMetadata is set in Makefile
@@ -53,6 +55,9 @@ type NetworkPolicyManager struct {
 
 	// Azure-specific variables
 	models.AzureConfig
+
+	// auditor exports the events logged by ACLs when policy audit is enabled
+	auditor *audit.Auditor
 }
 
 // NewNetworkPolicyManager creates a NetworkPolicyManager
@@ -101,6 +106,23 @@ func NewNetworkPolicyManager(config npmconfig.Config,
 	return npMgr
 }
 
+// EnableAdminNetworkPolicies creates the v2 controller for AdminNetworkPolicies and BaselineAdminNetworkPolicies.
+// It must be called before Start.
+func (npMgr *NetworkPolicyManager) EnableAdminNetworkPolicies(policyInformerFactory policyinformers.SharedInformerFactory) {
+	npMgr.PolicyInformerFactory = policyInformerFactory
+	npMgr.AdminNetPolControllerV2 = controllersv2.NewAdminNetworkPolicyController(
+		policyInformerFactory.Policy().V1alpha1().AdminNetworkPolicies(),
+		policyInformerFactory.Policy().V1alpha1().BaselineAdminNetworkPolicies(),
+		npMgr.Dataplane,
+	)
+}
+
+// EnablePolicyAudit creates the auditor for the events which ACLs log when policy audit is enabled in the v2 dataplane.
+// It must be called before Start.
+func (npMgr *NetworkPolicyManager) EnablePolicyAudit(policies audit.PolicyResolver) {
+	npMgr.auditor = audit.NewAuditor(npMgr.PodControllerV2, policies)
+}
+
 // Dear Time Traveler:
 // This is the server end of the debug dragons den. Several of these properties of the
 // npMgr struct have overridden methods which override the MarshalJson, just as this one
@@ -200,9 +222,22 @@ func (npMgr *NetworkPolicyManager) Start(config npmconfig.Config, stopCh <-chan
 		return fmt.Errorf("NetworkPolicy informer error: %w", models.ErrInformerSyncFailure)
 	}
 
+	if npMgr.PolicyInformerFactory != nil {
+		npMgr.PolicyInformerFactory.Start(stopCh)
+
+		anpInformer := npMgr.PolicyInformerFactory.Policy().V1alpha1().AdminNetworkPolicies().Informer()
+		banpInformer := npMgr.PolicyInformerFactory.Policy().V1alpha1().BaselineAdminNetworkPolicies().Informer()
+		if !cache.WaitForCacheSync(stopCh, anpInformer.HasSynced, banpInformer.HasSynced) {
+			return fmt.Errorf("AdminNetworkPolicy informer error: %w", models.ErrInformerSyncFailure)
+		}
+	}
+
 	// start v2 NPM controllers after synced
 	if config.Toggles.EnableV2NPM {
 		go npMgr.NetPolControllerV2.Run(stopCh)
+		if npMgr.AdminNetPolControllerV2 != nil {
+			go npMgr.AdminNetPolControllerV2.Run(stopCh)
+		}
 
 		if util.IsWindowsDP() && config.Toggles.ApplyInBackground {
 			klog.Infof("optimizing NPM bootup by letting NetPol controller process changes first. waiting %v before starting pod and namespace controllers", waitDurationAfterStartingNetPolController)
@@ -214,6 +249,12 @@ func (npMgr *NetworkPolicyManager) Start(config npmconfig.Config, stopCh <-chan
 		go npMgr.PodControllerV2.Run(stopCh)
 		go npMgr.NamespaceControllerV2.Run(stopCh)
 
+		if npMgr.auditor != nil {
+			if err := npMgr.auditor.Start(stopCh); err != nil {
+				return fmt.Errorf("failed to start policy audit: %w", err)
+			}
+		}
+
 		return nil
 	}
 
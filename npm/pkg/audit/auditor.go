@@ -0,0 +1,114 @@
+// Package audit reads the events which ACLs log when policy audit is enabled,
+// and exports them as structured logs and Prometheus metrics.
+package audit
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"golang.org/x/time/rate"
+	"k8s.io/klog"
+)
+
+const (
+	// audit events are counted in metrics, but only this many per second are logged so a busy node doesn't flood its logs
+	maxLoggedEventsPerSecond = 100
+	maxLoggedEventsBurst     = 100
+)
+
+// PodResolver finds the pod with an IP. It's implemented by the v2 PodController.
+type PodResolver interface {
+	PodKeyByIP(podIP string) (string, bool)
+}
+
+// PolicyResolver finds the policy key of a hashed audit tag. It's implemented by the DataPlane.
+type PolicyResolver interface {
+	PolicyKeyByHash(hash string) (string, bool)
+}
+
+// Event is a packet which matched an ACL of a policy.
+// The verdict is the target of the ACL. For a NetworkPolicy, a drop verdict is only final
+// if no other NetworkPolicy allows the packet.
+type Event struct {
+	PolicyKey string
+	Direction policies.Direction
+	Verdict   policies.Verdict
+	Protocol  string
+	SrcIP     string
+	SrcPort   uint16
+	DstIP     string
+	DstPort   uint16
+	// SrcPod and DstPod are the "namespace/name" of the pod with the IP, or empty if NPM doesn't know the IP.
+	SrcPod string
+	DstPod string
+}
+
+// String formats the event as key=value pairs.
+func (e *Event) String() string {
+	return fmt.Sprintf("policy=%s direction=%s verdict=%s protocol=%s src=%s srcPod=%s dst=%s dstPod=%s",
+		e.PolicyKey, e.Direction, e.Verdict, e.Protocol, hostPort(e.SrcIP, e.SrcPort), e.SrcPod, hostPort(e.DstIP, e.DstPort), e.DstPod)
+}
+
+func hostPort(ip string, port uint16) string {
+	if port == 0 {
+		return ip
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(int(port)))
+}
+
+type Auditor struct {
+	pods     PodResolver
+	policies PolicyResolver
+	// logLimiter rate limits the logs of events. skippedLogs is the number of events which weren't logged since the last log.
+	logLimiter  *rate.Limiter
+	skippedLogs int
+}
+
+func NewAuditor(pods PodResolver, policies PolicyResolver) *Auditor {
+	return &Auditor{
+		pods:       pods,
+		policies:   policies,
+		logLimiter: rate.NewLimiter(maxLoggedEventsPerSecond, maxLoggedEventsBurst),
+	}
+}
+
+// newEvent creates an Event from the log prefix and the (possibly truncated) packet of an audit event.
+// A packet which can't be parsed still makes an Event, but without addresses.
+func (a *Auditor) newEvent(prefix string, packet []byte) (*Event, error) {
+	tag, err := policies.ParseAuditTag(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	policyKey := tag.PolicyKey
+	if policyKey == "" {
+		var ok bool
+		policyKey, ok = a.policies.PolicyKeyByHash(tag.PolicyHash)
+		if !ok {
+			// the policy may have been removed since the packet was logged
+			policyKey = "#" + tag.PolicyHash
+		}
+	}
+
+	event := &Event{
+		PolicyKey: policyKey,
+		Direction: tag.Direction,
+		Verdict:   tag.Verdict,
+	}
+
+	f, err := parsePacket(packet)
+	if err != nil {
+		klog.V(2).Infof("[Audit] failed to parse packet for policy %s: %s", policyKey, err.Error())
+		return event, nil
+	}
+	event.Protocol = f.protocol
+	event.SrcIP = f.srcIP.String()
+	event.SrcPort = f.srcPort
+	event.DstIP = f.dstIP.String()
+	event.DstPort = f.dstPort
+	event.SrcPod, _ = a.pods.PodKeyByIP(event.SrcIP)
+	event.DstPod, _ = a.pods.PodKeyByIP(event.DstIP)
+	return event, nil
+}
@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog"
+)
+
+// Start listens for audit events on the NFLOG group util.AuditNFLogGroup and handles them in the background until stopCh is closed.
+func (a *Auditor) Start(stopCh <-chan struct{}) error {
+	s, err := newNFLogSocket(util.AuditNFLogGroup)
+	if err != nil {
+		return fmt.Errorf("failed to listen for audit events: %w", err)
+	}
+
+	klog.Infof("[Audit] listening for audit events on NFLOG group %d", util.AuditNFLogGroup)
+	go a.run(s, stopCh)
+	return nil
+}
+
+func (a *Auditor) run(s *nflogSocket, stopCh <-chan struct{}) {
+	defer s.close()
+	for {
+		select {
+		case <-stopCh:
+			klog.Infof("[Audit] stopped listening for audit events")
+			return
+		default:
+		}
+
+		packets, err := s.receive()
+		switch {
+		case errors.Is(err, unix.EAGAIN), errors.Is(err, unix.EINTR):
+			continue
+		case errors.Is(err, unix.ENOBUFS):
+			// the kernel drops events when NPM can't keep up
+			klog.Warningf("[Audit] lost audit events since the socket buffer is full")
+			continue
+		case err != nil:
+			metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to receive audit events, so audit events won't be exported: %s", err.Error())
+			return
+		}
+
+		for _, packet := range packets {
+			a.handle(packet.prefix, packet.payload)
+		}
+	}
+}
+
+// handle counts the audit event for its policy and logs it, unless too many events have been logged recently.
+// handle is only called by run, so it doesn't need a lock.
+func (a *Auditor) handle(prefix string, packet []byte) {
+	event, err := a.newEvent(prefix, packet)
+	if err != nil {
+		if a.allowLog() {
+			klog.Warningf("[Audit] ignoring event: %s", err.Error())
+		}
+		return
+	}
+
+	metrics.IncPolicyAuditEvents(event.PolicyKey, string(event.Direction), string(event.Verdict))
+	if a.allowLog() {
+		klog.Infof("[Audit] %s", event.String())
+	}
+}
+
+// allowLog returns whether an event can be logged, and logs how many events were skipped before it.
+func (a *Auditor) allowLog() bool {
+	if !a.logLimiter.Allow() {
+		a.skippedLogs++
+		return false
+	}
+	if a.skippedLogs > 0 {
+		klog.Warningf("[Audit] skipped logging %d events since more than %d events per second were logged", a.skippedLogs, maxLoggedEventsPerSecond)
+		a.skippedLogs = 0
+	}
+	return true
+}
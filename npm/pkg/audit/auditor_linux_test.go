@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestHandle(t *testing.T) {
+	auditor := newTestAuditor()
+	packet := ipv4Packet(protocolTCP, "10.0.0.1", "10.0.0.3", transportHeader)
+	auditor.handle("IN-DROP-x/test1", packet)
+	auditor.handle("IN-DROP-x/test1", packet)
+	auditor.handle("OUT-ALLOW-x/test1", packet)
+	// ignored
+	auditor.handle("not-an-audit-tag", packet)
+
+	val, err := metrics.TotalPolicyAuditEvents("x/test1", "IN", "DROP")
+	require.NoError(t, err)
+	require.Equal(t, 2, val)
+
+	val, err = metrics.TotalPolicyAuditEvents("x/test1", "OUT", "ALLOW")
+	require.NoError(t, err)
+	require.Equal(t, 1, val)
+}
+
+func TestHandleRateLimitsLogs(t *testing.T) {
+	auditor := newTestAuditor()
+	auditor.logLimiter = rate.NewLimiter(0, 1)
+	packet := ipv4Packet(protocolTCP, "10.0.0.1", "10.0.0.3", transportHeader)
+	for i := 0; i < 3; i++ {
+		auditor.handle("IN-DROP-x/test2", packet)
+	}
+
+	// every event is counted even though only the first is logged
+	val, err := metrics.TotalPolicyAuditEvents("x/test2", "IN", "DROP")
+	require.NoError(t, err)
+	require.Equal(t, 3, val)
+	require.Equal(t, 2, auditor.skippedLogs)
+
+	// the skipped count is reset once an event is logged again
+	auditor.logLimiter = rate.NewLimiter(rate.Inf, 1)
+	auditor.handle("IN-DROP-x/test2", packet)
+	require.Equal(t, 0, auditor.skippedLogs)
+}
+
+func TestMain(m *testing.M) {
+	metrics.InitializeAll()
+
+	exitCode := m.Run()
+
+	os.Exit(exitCode)
+}
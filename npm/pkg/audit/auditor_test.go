@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePods map[string]string
+
+func (p fakePods) PodKeyByIP(podIP string) (string, bool) {
+	podKey, ok := p[podIP]
+	return podKey, ok
+}
+
+type fakePolicies map[string]string
+
+func (p fakePolicies) PolicyKeyByHash(hash string) (string, bool) {
+	policyKey, ok := p[hash]
+	return policyKey, ok
+}
+
+const longPolicyKey = "x/a-network-policy-whose-name-is-too-long-to-fit-in-an-audit-tag"
+
+func newTestAuditor() *Auditor {
+	pods := fakePods{"10.0.0.1": "x/a", "fd00::2": "y/b"}
+	policies := fakePolicies{util.Hash(longPolicyKey): longPolicyKey}
+	return NewAuditor(pods, policies)
+}
+
+func TestNewEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		packet   []byte
+		expected *Event
+	}{
+		{
+			name:   "IPv4 with a source pod",
+			prefix: "IN-DROP-x/test1",
+			packet: ipv4Packet(protocolTCP, "10.0.0.1", "10.0.0.3", transportHeader),
+			expected: &Event{
+				PolicyKey: "x/test1",
+				Direction: policies.Ingress,
+				Verdict:   policies.Dropped,
+				Protocol:  "TCP",
+				SrcIP:     "10.0.0.1",
+				SrcPort:   1234,
+				DstIP:     "10.0.0.3",
+				DstPort:   80,
+				SrcPod:    "x/a",
+			},
+		},
+		{
+			name:   "IPv6 with a destination pod and a hashed policy key",
+			prefix: "OUT-ALLOW-#" + util.Hash(longPolicyKey),
+			packet: ipv6Packet(protocolUDP, "fd00::1", "fd00::2", transportHeader),
+			expected: &Event{
+				PolicyKey: longPolicyKey,
+				Direction: policies.Egress,
+				Verdict:   policies.Allowed,
+				Protocol:  "UDP",
+				SrcIP:     "fd00::1",
+				SrcPort:   1234,
+				DstIP:     "fd00::2",
+				DstPort:   80,
+				DstPod:    "y/b",
+			},
+		},
+		{
+			name:   "unknown hash and a packet which can't be parsed",
+			prefix: "OUT-PASS-#123",
+			packet: []byte{0x45},
+			expected: &Event{
+				PolicyKey: "#123",
+				Direction: policies.Egress,
+				Verdict:   policies.Passed,
+			},
+		},
+	}
+
+	auditor := newTestAuditor()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := auditor.newEvent(tt.prefix, tt.packet)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, event)
+		})
+	}
+}
+
+func TestNewEventWithInvalidPrefix(t *testing.T) {
+	_, err := newTestAuditor().newEvent("not-an-audit-tag", nil)
+	require.Error(t, err)
+}
+
+func TestEventString(t *testing.T) {
+	event := &Event{
+		PolicyKey: "x/test1",
+		Direction: policies.Ingress,
+		Verdict:   policies.Dropped,
+		Protocol:  "TCP",
+		SrcIP:     "fd00::1",
+		SrcPort:   1234,
+		DstIP:     "fd00::2",
+		DstPod:    "y/b",
+	}
+	require.Equal(t, "policy=x/test1 direction=IN verdict=DROP protocol=TCP src=[fd00::1]:1234 srcPod= dst=fd00::2 dstPod=y/b", event.String())
+}
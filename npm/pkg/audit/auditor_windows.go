@@ -0,0 +1,10 @@
+package audit
+
+import "errors"
+
+var errAuditUnsupported = errors.New("policy audit is only supported in Linux")
+
+// Start returns an error since ACLs don't log audit events in Windows.
+func (a *Auditor) Start(_ <-chan struct{}) error {
+	return errAuditUnsupported
+}
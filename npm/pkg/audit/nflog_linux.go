@@ -0,0 +1,210 @@
+package audit
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// nfnetlink_log constants from linux/netfilter/nfnetlink_log.h, which x/sys/unix doesn't have
+const (
+	nfulnlMsgPacket = 0
+	nfulnlMsgConfig = 1
+
+	nfulaCfgCmd  = 1
+	nfulaCfgMode = 2
+
+	nfulaPayload = 9
+	nfulaPrefix  = 10
+
+	nfulnlCfgCmdBind = 1
+	nfulnlCopyPacket = 2
+)
+
+const (
+	nfgenmsgLength = 4
+	nlaTypeMask    = ^uint16(unix.NLA_F_NESTED | unix.NLA_F_NET_BYTEORDER)
+	// copyRange is how much of each packet is copied. It's enough for the IP header and ports.
+	copyRange = 128
+	// receiveBufferSize fits many packets of copyRange bytes
+	receiveBufferSize = 65536
+	// receiveTimeout is how often the socket stops waiting for events to check if it should stop
+	receiveTimeout = time.Second
+)
+
+var errNetlinkAck = errors.New("netlink request failed")
+
+// nflogPacket is a packet logged to an NFLOG group.
+type nflogPacket struct {
+	prefix  string
+	payload []byte
+}
+
+// nflogSocket is a netfilter netlink socket bound to an NFLOG group.
+type nflogSocket struct {
+	fd     int
+	group  uint16
+	seq    uint32
+	buffer []byte
+}
+
+func newNFLogSocket(group uint16) (*nflogSocket, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_NETFILTER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create netfilter netlink socket: %w", err)
+	}
+
+	s := &nflogSocket{
+		fd:     fd,
+		group:  group,
+		buffer: make([]byte, receiveBufferSize),
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to bind netfilter netlink socket: %w", err)
+	}
+	timeout := unix.NsecToTimeval(receiveTimeout.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to set receive timeout of netfilter netlink socket: %w", err)
+	}
+
+	// bind to the group, then copy the start of each packet
+	if err := s.configure(nfulaCfgCmd, []byte{nfulnlCfgCmdBind}); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to bind to NFLOG group %d: %w", group, err)
+	}
+	mode := make([]byte, 6)
+	binary.BigEndian.PutUint32(mode[0:4], copyRange)
+	mode[4] = nfulnlCopyPacket
+	if err := s.configure(nfulaCfgMode, mode); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to set copy mode of NFLOG group %d: %w", group, err)
+	}
+	return s, nil
+}
+
+func (s *nflogSocket) close() {
+	_ = unix.Close(s.fd)
+}
+
+// configure sends a config message with one attribute for the group and waits for its ack.
+func (s *nflogSocket) configure(attrType uint16, value []byte) error {
+	s.seq++
+	msg := configMessage(s.group, s.seq, attrType, value)
+	if err := unix.Sendto(s.fd, msg, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("failed to send config message: %w", err)
+	}
+
+	for {
+		n, _, err := unix.Recvfrom(s.fd, s.buffer, 0)
+		if err != nil {
+			return fmt.Errorf("failed to receive ack: %w", err)
+		}
+		msgs, err := syscall.ParseNetlinkMessage(s.buffer[:n])
+		if err != nil {
+			return fmt.Errorf("failed to parse ack: %w", err)
+		}
+		for i := range msgs {
+			if msgs[i].Header.Type != unix.NLMSG_ERROR || msgs[i].Header.Seq != s.seq {
+				continue
+			}
+			if len(msgs[i].Data) < 4 {
+				return fmt.Errorf("%w: truncated ack", errNetlinkAck)
+			}
+			if errno := int32(binary.NativeEndian.Uint32(msgs[i].Data[0:4])); errno != 0 {
+				return fmt.Errorf("%w: %w", errNetlinkAck, unix.Errno(-errno))
+			}
+			return nil
+		}
+	}
+}
+
+// receive waits for logged packets. It returns unix.EAGAIN if there are none before the receive timeout.
+// The payloads share a buffer, so they're only valid until the next receive.
+func (s *nflogSocket) receive() ([]nflogPacket, error) {
+	n, _, err := unix.Recvfrom(s.fd, s.buffer, 0)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // callers check the errno
+	}
+	msgs, err := syscall.ParseNetlinkMessage(s.buffer[:n])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse netlink messages: %w", err)
+	}
+	return parseNFLogPackets(msgs), nil
+}
+
+// configMessage is a config message for an NFLOG group: a netlink header, an nfgenmsg, and an attribute.
+func configMessage(group uint16, seq uint32, attrType uint16, value []byte) []byte {
+	attrLength := unix.NLA_HDRLEN + len(value)
+	msgLength := unix.NLMSG_HDRLEN + nfgenmsgLength + nlaAlign(attrLength)
+	msg := make([]byte, msgLength)
+
+	binary.NativeEndian.PutUint32(msg[0:4], uint32(msgLength))
+	binary.NativeEndian.PutUint16(msg[4:6], unix.NFNL_SUBSYS_ULOG<<8|nfulnlMsgConfig)
+	binary.NativeEndian.PutUint16(msg[6:8], unix.NLM_F_REQUEST|unix.NLM_F_ACK)
+	binary.NativeEndian.PutUint32(msg[8:12], seq)
+	// the port ID at msg[12:16] is zero so the kernel assigns it
+
+	nfgenmsg := msg[unix.NLMSG_HDRLEN:]
+	nfgenmsg[0] = unix.AF_UNSPEC
+	nfgenmsg[1] = unix.NFNETLINK_V0
+	binary.BigEndian.PutUint16(nfgenmsg[2:4], group)
+
+	attr := nfgenmsg[nfgenmsgLength:]
+	binary.NativeEndian.PutUint16(attr[0:2], uint16(attrLength))
+	binary.NativeEndian.PutUint16(attr[2:4], attrType)
+	copy(attr[unix.NLA_HDRLEN:], value)
+	return msg
+}
+
+// parseNFLogPackets returns the logged packets in the messages, ignoring any other message.
+func parseNFLogPackets(msgs []syscall.NetlinkMessage) []nflogPacket {
+	packets := make([]nflogPacket, 0, len(msgs))
+	for i := range msgs {
+		if msgs[i].Header.Type != unix.NFNL_SUBSYS_ULOG<<8|nfulnlMsgPacket || len(msgs[i].Data) < nfgenmsgLength {
+			continue
+		}
+
+		var packet nflogPacket
+		attrs := msgs[i].Data[nfgenmsgLength:]
+		for len(attrs) >= unix.NLA_HDRLEN {
+			attrLength := int(binary.NativeEndian.Uint16(attrs[0:2]))
+			if attrLength < unix.NLA_HDRLEN || attrLength > len(attrs) {
+				break
+			}
+			value := attrs[unix.NLA_HDRLEN:attrLength]
+			switch binary.NativeEndian.Uint16(attrs[2:4]) & nlaTypeMask {
+			case nfulaPrefix:
+				packet.prefix = nullTerminatedString(value)
+			case nfulaPayload:
+				packet.payload = value
+			}
+
+			next := nlaAlign(attrLength)
+			if next > len(attrs) {
+				break
+			}
+			attrs = attrs[next:]
+		}
+		packets = append(packets, packet)
+	}
+	return packets
+}
+
+func nlaAlign(length int) int {
+	return (length + unix.NLA_ALIGNTO - 1) &^ (unix.NLA_ALIGNTO - 1)
+}
+
+func nullTerminatedString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"encoding/binary"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestConfigMessage(t *testing.T) {
+	msg := configMessage(100, 7, nfulaCfgCmd, []byte{nfulnlCfgCmdBind})
+	// header, nfgenmsg, and an attribute of 5 bytes padded to 8
+	require.Len(t, msg, 28)
+
+	msgs, err := syscall.ParseNetlinkMessage(msg)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, uint16(0x401), msgs[0].Header.Type)
+	require.Equal(t, uint16(unix.NLM_F_REQUEST|unix.NLM_F_ACK), msgs[0].Header.Flags)
+	require.Equal(t, uint32(7), msgs[0].Header.Seq)
+	// family, version, and the group in big endian
+	require.Equal(t, []byte{unix.AF_UNSPEC, unix.NFNETLINK_V0, 0, 100}, msgs[0].Data[:4])
+	require.Equal(t, uint16(5), binary.NativeEndian.Uint16(msgs[0].Data[4:6]))
+	require.Equal(t, uint16(nfulaCfgCmd), binary.NativeEndian.Uint16(msgs[0].Data[6:8]))
+	require.Equal(t, byte(nfulnlCfgCmdBind), msgs[0].Data[8])
+}
+
+// nflogMessage returns a packet message with the attributes.
+func nflogMessage(msgType uint16, attrs ...[]byte) []byte {
+	data := make([]byte, nfgenmsgLength)
+	for _, attr := range attrs {
+		data = append(data, attr...)
+	}
+	msg := make([]byte, unix.NLMSG_HDRLEN, unix.NLMSG_HDRLEN+len(data))
+	binary.NativeEndian.PutUint32(msg[0:4], uint32(unix.NLMSG_HDRLEN+len(data)))
+	binary.NativeEndian.PutUint16(msg[4:6], msgType)
+	return append(msg, data...)
+}
+
+// nflogAttr returns a padded attribute.
+func nflogAttr(attrType uint16, value []byte) []byte {
+	attr := make([]byte, nlaAlign(unix.NLA_HDRLEN+len(value)))
+	binary.NativeEndian.PutUint16(attr[0:2], uint16(unix.NLA_HDRLEN+len(value)))
+	binary.NativeEndian.PutUint16(attr[2:4], attrType)
+	copy(attr[unix.NLA_HDRLEN:], value)
+	return attr
+}
+
+func TestParseNFLogPackets(t *testing.T) {
+	payload := ipv4Packet(protocolTCP, "10.0.0.1", "10.0.0.2", transportHeader)
+	packetMsgType := uint16(unix.NFNL_SUBSYS_ULOG<<8 | nfulnlMsgPacket)
+
+	buffer := nflogMessage(packetMsgType,
+		// an attribute which is ignored
+		nflogAttr(1, []byte{0, 0x08, 0, 0}),
+		nflogAttr(nfulaPrefix, []byte("IN-DROP-x/test1\x00")),
+		nflogAttr(nfulaPayload|unix.NLA_F_NET_BYTEORDER, payload),
+	)
+	// a message which isn't a packet
+	buffer = append(buffer, nflogMessage(unix.NFNL_SUBSYS_ULOG<<8|nfulnlMsgConfig)...)
+	buffer = append(buffer, nflogMessage(packetMsgType, nflogAttr(nfulaPrefix, []byte("OUT-ALLOW-y/test2\x00")))...)
+
+	msgs, err := syscall.ParseNetlinkMessage(buffer)
+	require.NoError(t, err)
+	packets := parseNFLogPackets(msgs)
+	require.Equal(t, []nflogPacket{
+		{prefix: "IN-DROP-x/test1", payload: payload},
+		{prefix: "OUT-ALLOW-y/test2"},
+	}, packets)
+}
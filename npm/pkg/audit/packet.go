@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+const (
+	ipv4MinHeaderLength = 20
+	ipv6HeaderLength    = 40
+
+	protocolICMP   = 1
+	protocolTCP    = 6
+	protocolUDP    = 17
+	protocolICMPv6 = 58
+	protocolSCTP   = 132
+
+	// IPv6 extension headers which can come before the transport header
+	ipv6HopByHop     = 0
+	ipv6Routing      = 43
+	ipv6Fragment     = 44
+	ipv6DestOptions  = 60
+	ipv6FragmentSize = 8
+)
+
+var errInvalidPacket = errors.New("invalid packet")
+
+// flow is the part of a packet which an Event reports.
+type flow struct {
+	protocol string
+	srcIP    net.IP
+	dstIP    net.IP
+	// srcPort and dstPort are zero if the protocol has no ports or they weren't logged
+	srcPort uint16
+	dstPort uint16
+}
+
+// parsePacket parses the IP header and the ports of a packet starting at its IP header.
+func parsePacket(packet []byte) (*flow, error) {
+	if len(packet) == 0 {
+		return nil, fmt.Errorf("%w: empty packet", errInvalidPacket)
+	}
+
+	switch version := packet[0] >> 4; version {
+	case 4:
+		return parseIPv4Packet(packet)
+	case 6:
+		return parseIPv6Packet(packet)
+	default:
+		return nil, fmt.Errorf("%w: unknown IP version %d", errInvalidPacket, version)
+	}
+}
+
+func parseIPv4Packet(packet []byte) (*flow, error) {
+	headerLength := int(packet[0]&0x0f) * 4
+	if len(packet) < ipv4MinHeaderLength || headerLength < ipv4MinHeaderLength || len(packet) < headerLength {
+		return nil, fmt.Errorf("%w: truncated IPv4 header", errInvalidPacket)
+	}
+
+	protocol := packet[9]
+	f := &flow{
+		protocol: protocolName(protocol),
+		srcIP:    net.IP(packet[12:16]),
+		dstIP:    net.IP(packet[16:20]),
+	}
+	// only the first fragment has the transport header
+	fragmentOffset := binary.BigEndian.Uint16(packet[6:8]) & 0x1fff
+	if fragmentOffset == 0 {
+		f.srcPort, f.dstPort = ports(protocol, packet[headerLength:])
+	}
+	return f, nil
+}
+
+func parseIPv6Packet(packet []byte) (*flow, error) {
+	if len(packet) < ipv6HeaderLength {
+		return nil, fmt.Errorf("%w: truncated IPv6 header", errInvalidPacket)
+	}
+
+	f := &flow{
+		srcIP: net.IP(packet[8:24]),
+		dstIP: net.IP(packet[24:40]),
+	}
+
+	nextHeader := packet[6]
+	rest := packet[ipv6HeaderLength:]
+	for {
+		switch nextHeader {
+		case ipv6HopByHop, ipv6Routing, ipv6DestOptions:
+			if len(rest) < 2 {
+				f.protocol = protocolName(nextHeader)
+				return f, nil
+			}
+			length := (int(rest[1]) + 1) * 8
+			if len(rest) < length {
+				f.protocol = protocolName(nextHeader)
+				return f, nil
+			}
+			nextHeader = rest[0]
+			rest = rest[length:]
+		case ipv6Fragment:
+			if len(rest) < ipv6FragmentSize {
+				f.protocol = protocolName(nextHeader)
+				return f, nil
+			}
+			fragmentOffset := binary.BigEndian.Uint16(rest[2:4]) >> 3
+			nextHeader = rest[0]
+			rest = rest[ipv6FragmentSize:]
+			if fragmentOffset != 0 {
+				// only the first fragment has the transport header
+				f.protocol = protocolName(nextHeader)
+				return f, nil
+			}
+		default:
+			f.protocol = protocolName(nextHeader)
+			f.srcPort, f.dstPort = ports(nextHeader, rest)
+			return f, nil
+		}
+	}
+}
+
+// ports returns the ports of a TCP, UDP, or SCTP header, which all start with the source and destination port.
+func ports(protocol uint8, transportHeader []byte) (srcPort, dstPort uint16) {
+	if protocol != protocolTCP && protocol != protocolUDP && protocol != protocolSCTP {
+		return 0, 0
+	}
+	if len(transportHeader) < 4 {
+		return 0, 0
+	}
+	return binary.BigEndian.Uint16(transportHeader[0:2]), binary.BigEndian.Uint16(transportHeader[2:4])
+}
+
+func protocolName(protocol uint8) string {
+	switch protocol {
+	case protocolICMP:
+		return "ICMP"
+	case protocolTCP:
+		return "TCP"
+	case protocolUDP:
+		return "UDP"
+	case protocolICMPv6:
+		return "ICMPv6"
+	case protocolSCTP:
+		return "SCTP"
+	default:
+		return strconv.Itoa(int(protocol))
+	}
+}
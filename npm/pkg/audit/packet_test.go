@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ipv4Packet returns an IPv4 header without options followed by the transport header.
+func ipv4Packet(protocol uint8, src, dst string, transportHeader []byte) []byte {
+	packet := make([]byte, ipv4MinHeaderLength)
+	packet[0] = 0x45
+	packet[9] = protocol
+	copy(packet[12:16], net.ParseIP(src).To4())
+	copy(packet[16:20], net.ParseIP(dst).To4())
+	return append(packet, transportHeader...)
+}
+
+// ipv6Packet returns an IPv6 header followed by the rest of the packet.
+func ipv6Packet(nextHeader uint8, src, dst string, rest []byte) []byte {
+	packet := make([]byte, ipv6HeaderLength)
+	packet[0] = 0x60
+	packet[6] = nextHeader
+	copy(packet[8:24], net.ParseIP(src))
+	copy(packet[24:40], net.ParseIP(dst))
+	return append(packet, rest...)
+}
+
+// ports 1234 -> 80
+var transportHeader = []byte{0x04, 0xd2, 0x00, 0x50, 0, 0, 0, 0}
+
+func TestParsePacket(t *testing.T) {
+	fragment := ipv4Packet(protocolTCP, "10.0.0.1", "10.0.0.2", transportHeader)
+	fragment[6] = 0x00
+	fragment[7] = 0x10
+
+	tests := []struct {
+		name     string
+		packet   []byte
+		expected *flow
+	}{
+		{
+			name:   "IPv4 TCP",
+			packet: ipv4Packet(protocolTCP, "10.0.0.1", "10.0.0.2", transportHeader),
+			expected: &flow{
+				protocol: "TCP",
+				srcIP:    net.ParseIP("10.0.0.1").To4(),
+				dstIP:    net.ParseIP("10.0.0.2").To4(),
+				srcPort:  1234,
+				dstPort:  80,
+			},
+		},
+		{
+			name:   "IPv4 ICMP has no ports",
+			packet: ipv4Packet(protocolICMP, "10.0.0.1", "10.0.0.2", transportHeader),
+			expected: &flow{
+				protocol: "ICMP",
+				srcIP:    net.ParseIP("10.0.0.1").To4(),
+				dstIP:    net.ParseIP("10.0.0.2").To4(),
+			},
+		},
+		{
+			name:   "IPv4 fragment has no ports",
+			packet: fragment,
+			expected: &flow{
+				protocol: "TCP",
+				srcIP:    net.ParseIP("10.0.0.1").To4(),
+				dstIP:    net.ParseIP("10.0.0.2").To4(),
+			},
+		},
+		{
+			name:   "IPv4 truncated transport header",
+			packet: ipv4Packet(protocolUDP, "10.0.0.1", "10.0.0.2", transportHeader[:2]),
+			expected: &flow{
+				protocol: "UDP",
+				srcIP:    net.ParseIP("10.0.0.1").To4(),
+				dstIP:    net.ParseIP("10.0.0.2").To4(),
+			},
+		},
+		{
+			name:   "IPv6 SCTP",
+			packet: ipv6Packet(protocolSCTP, "fd00::1", "fd00::2", transportHeader),
+			expected: &flow{
+				protocol: "SCTP",
+				srcIP:    net.ParseIP("fd00::1"),
+				dstIP:    net.ParseIP("fd00::2"),
+				srcPort:  1234,
+				dstPort:  80,
+			},
+		},
+		{
+			name: "IPv6 UDP after an extension header",
+			// a hop-by-hop options header of 8 bytes
+			packet: ipv6Packet(ipv6HopByHop, "fd00::1", "fd00::2", append([]byte{protocolUDP, 0, 0, 0, 0, 0, 0, 0}, transportHeader...)),
+			expected: &flow{
+				protocol: "UDP",
+				srcIP:    net.ParseIP("fd00::1"),
+				dstIP:    net.ParseIP("fd00::2"),
+				srcPort:  1234,
+				dstPort:  80,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parsePacket(tt.packet)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, f)
+		})
+	}
+}
+
+func TestParseInvalidPacket(t *testing.T) {
+	for _, packet := range [][]byte{
+		nil,
+		{0x45, 0, 0},
+		ipv6Packet(protocolTCP, "fd00::1", "fd00::2", nil)[:ipv6HeaderLength-1],
+		{0x10, 0, 0, 0},
+	} {
+		_, err := parsePacket(packet)
+		require.ErrorIs(t, err, errInvalidPacket)
+	}
+}
@@ -2,7 +2,9 @@ package common
 
 import (
 	"reflect"
+	"slices"
 
+	"github.com/Azure/azure-container-networking/npm/util"
 	corev1 "k8s.io/api/core/v1"
 	k8slabels "k8s.io/apimachinery/pkg/labels"
 )
@@ -11,6 +13,7 @@ type NpmPod struct {
 	Name           string
 	Namespace      string
 	PodIP          string
+	PodIPs         []string // the IPs of each IP family, starting with PodIP
 	Labels         map[string]string
 	ContainerPorts []corev1.ContainerPort
 	Phase          corev1.PodPhase
@@ -36,6 +39,7 @@ func NewNpmPod(podObj *corev1.Pod) *NpmPod {
 		Name:           podObj.ObjectMeta.Name,
 		Namespace:      podObj.ObjectMeta.Namespace,
 		PodIP:          podObj.Status.PodIP,
+		PodIPs:         GetPodIPs(podObj),
 		Labels:         make(map[string]string),
 		ContainerPorts: []corev1.ContainerPort{},
 		Phase:          podObj.Status.Phase,
@@ -76,6 +80,7 @@ func (n *NpmPod) NoUpdate(podObj *corev1.Pod) bool {
 		n.Name == podObj.ObjectMeta.Name &&
 		n.Phase == podObj.Status.Phase &&
 		n.PodIP == podObj.Status.PodIP &&
+		slices.Equal(n.PodIPs, GetPodIPs(podObj)) &&
 		k8slabels.Equals(n.Labels, podObj.ObjectMeta.Labels) &&
 		// TODO(jungukcho) to avoid using DeepEqual for ContainerPorts,
 		// it needs a precise sorting. Will optimize it later if needed.
@@ -89,3 +94,18 @@ func GetContainerPortList(podObj *corev1.Pod) []corev1.ContainerPort {
 	}
 	return portList
 }
+
+// GetPodIPs returns the valid IPv4 and IPv6 IPs of the pod.
+// It falls back to the pod's primary IP if Status.PodIPs isn't set.
+func GetPodIPs(podObj *corev1.Pod) []string {
+	podIPs := make([]string, 0, len(podObj.Status.PodIPs))
+	for _, podIP := range podObj.Status.PodIPs {
+		if util.IsIPV4(podIP.IP) || util.IsIPV6(podIP.IP) {
+			podIPs = append(podIPs, podIP.IP)
+		}
+	}
+	if len(podIPs) == 0 && podObj.Status.PodIP != "" {
+		podIPs = append(podIPs, podObj.Status.PodIP)
+	}
+	return podIPs
+}
@@ -0,0 +1,341 @@
+// Copyright 2018 Microsoft. All rights reserved.
+// MIT License
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/translation"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	policyv1alpha1 "sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	policyinformers "sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions/apis/v1alpha1"
+	policylisters "sigs.k8s.io/network-policy-api/pkg/client/listers/apis/v1alpha1"
+)
+
+var errAdminNetPolKeyFormat = errors.New("invalid admin network policy key format")
+
+// AdminNetworkPolicyController handles both AdminNetworkPolicies and BaselineAdminNetworkPolicies.
+// Both are cluster-scoped, so the workqueue is keyed by the policy key: <kind>/<policyname>.
+type AdminNetworkPolicyController struct {
+	sync.RWMutex
+	anpLister      policylisters.AdminNetworkPolicyLister
+	banpLister     policylisters.BaselineAdminNetworkPolicyLister
+	workqueue      workqueue.RateLimitingInterface
+	rawAnpSpecMap  map[string]*policyv1alpha1.AdminNetworkPolicySpec         // Key is AdminNetworkPolicy/<policyname>
+	rawBanpSpecMap map[string]*policyv1alpha1.BaselineAdminNetworkPolicySpec // Key is BaselineAdminNetworkPolicy/<policyname>
+	dp             dataplane.GenericDataplane
+}
+
+func (c *AdminNetworkPolicyController) GetCache() map[string]*policyv1alpha1.AdminNetworkPolicySpec {
+	c.RLock()
+	defer c.RUnlock()
+	return c.rawAnpSpecMap
+}
+
+func (c *AdminNetworkPolicyController) GetBaselineCache() map[string]*policyv1alpha1.BaselineAdminNetworkPolicySpec {
+	c.RLock()
+	defer c.RUnlock()
+	return c.rawBanpSpecMap
+}
+
+func NewAdminNetworkPolicyController(anpInformer policyinformers.AdminNetworkPolicyInformer,
+	banpInformer policyinformers.BaselineAdminNetworkPolicyInformer, dp dataplane.GenericDataplane,
+) *AdminNetworkPolicyController {
+	adminNetPolController := &AdminNetworkPolicyController{
+		anpLister:      anpInformer.Lister(),
+		banpLister:     banpInformer.Lister(),
+		workqueue:      workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "AdminNetworkPolicy"),
+		rawAnpSpecMap:  make(map[string]*policyv1alpha1.AdminNetworkPolicySpec),
+		rawBanpSpecMap: make(map[string]*policyv1alpha1.BaselineAdminNetworkPolicySpec),
+		dp:             dp,
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    adminNetPolController.addAdminNetworkPolicy,
+		UpdateFunc: adminNetPolController.updateAdminNetworkPolicy,
+		DeleteFunc: adminNetPolController.deleteAdminNetworkPolicy,
+	}
+	anpInformer.Informer().AddEventHandler(handler)
+	banpInformer.Informer().AddEventHandler(handler)
+	return adminNetPolController
+}
+
+func (c *AdminNetworkPolicyController) LengthOfRawAnpMap() int {
+	return len(c.rawAnpSpecMap)
+}
+
+func (c *AdminNetworkPolicyController) LengthOfRawBanpMap() int {
+	return len(c.rawBanpSpecMap)
+}
+
+// getAdminNetworkPolicyKey returns the policy key of an AdminNetworkPolicy or BaselineAdminNetworkPolicy object.
+// If obj is neither, it returns error.
+func (c *AdminNetworkPolicyController) getAdminNetworkPolicyKey(obj interface{}) (string, error) {
+	switch policy := obj.(type) {
+	case *policyv1alpha1.AdminNetworkPolicy:
+		return translation.AdminNetworkPolicyKey(policy.Name), nil
+	case *policyv1alpha1.BaselineAdminNetworkPolicy:
+		return translation.BaselineAdminNetworkPolicyKey(policy.Name), nil
+	default:
+		return "", fmt.Errorf("cannot cast obj (%v) to admin network policy obj err: %w", obj, errAdminNetPolKeyFormat)
+	}
+}
+
+func (c *AdminNetworkPolicyController) addAdminNetworkPolicy(obj interface{}) {
+	key, err := c.getAdminNetworkPolicyKey(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	c.workqueue.Add(key)
+}
+
+func (c *AdminNetworkPolicyController) updateAdminNetworkPolicy(old, newObj interface{}) {
+	key, err := c.getAdminNetworkPolicyKey(newObj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	// Periodic resync will send update events for all known policies.
+	// Two different versions of the same policy will always have different RVs.
+	oldMeta, oldOk := old.(metav1.Object)
+	newMeta, newOk := newObj.(metav1.Object)
+	if oldOk && newOk && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+		return
+	}
+
+	c.workqueue.Add(key)
+}
+
+func (c *AdminNetworkPolicyController) deleteAdminNetworkPolicy(obj interface{}) {
+	// DeleteFunc gets the final state of the resource (if it is known).
+	// Otherwise, it gets an object of type DeletedFinalStateUnknown.
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	key, err := c.getAdminNetworkPolicyKey(obj)
+	if err != nil {
+		metrics.SendErrorLogAndMetric(util.NetpolID, "[ADMIN NETPOL DELETE EVENT] Received unexpected object type: %v", obj)
+		return
+	}
+
+	c.workqueue.Add(key)
+}
+
+func (c *AdminNetworkPolicyController) Run(stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.workqueue.ShutDown()
+
+	klog.Infof("Starting Admin Network Policy worker")
+	go wait.Until(c.runWorker, time.Second, stopCh)
+
+	klog.Infof("Started Admin Network Policy worker")
+	<-stopCh
+	klog.Info("Shutting down Admin Network Policy workers")
+}
+
+func (c *AdminNetworkPolicyController) runWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *AdminNetworkPolicyController) processNextWorkItem() bool {
+	obj, shutdown := c.workqueue.Get()
+
+	if shutdown {
+		return false
+	}
+
+	err := func(obj interface{}) error {
+		defer c.workqueue.Done(obj)
+		var key string
+		var ok bool
+		if key, ok = obj.(string); !ok {
+			// As the item in the workqueue is actually invalid, we call
+			// Forget here else we'd go into a loop of attempting to
+			// process a work item that is invalid.
+			c.workqueue.Forget(obj)
+			utilruntime.HandleError(fmt.Errorf("expected string in workqueue but got %#v, err %w", obj, errWorkqueueFormatting))
+			return nil
+		}
+		if err := c.syncAdminNetPol(key); err != nil {
+			// Put the item back on the workqueue to handle any transient errors.
+			c.workqueue.AddRateLimited(key)
+			return fmt.Errorf("error syncing '%s': %w, requeuing", key, err)
+		}
+		c.workqueue.Forget(obj)
+		klog.Infof("Successfully synced '%s'", key)
+		return nil
+	}(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		metrics.SendErrorLogAndMetric(util.NetpolID, "syncAdminNetPol error due to %v", err)
+		return true
+	}
+
+	return true
+}
+
+// syncAdminNetPol compares the actual state with the desired, and attempts to converge the two.
+func (c *AdminNetworkPolicyController) syncAdminNetPol(key string) error {
+	// timer for recording execution times
+	timer := metrics.StartNewTimer()
+
+	kind, name, found := strings.Cut(key, "/")
+	if !found || (kind != translation.AdminNetworkPolicyKind && kind != translation.BaselineAdminNetworkPolicyKind) {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s err: %w", key, errAdminNetPolKeyFormat))
+		return nil //nolint HandleError  is used instead of returning error to caller
+	}
+
+	// record exec time after syncing
+	var err error
+	operationKind := metrics.NoOp
+	defer func() {
+		metrics.RecordControllerPolicyExecTime(timer, operationKind, err != nil)
+	}()
+
+	var objMeta *metav1.ObjectMeta
+	var spec, cachedSpec interface{}
+	var cached bool
+	var translate func() (*policies.NPMNetworkPolicy, error)
+	if kind == translation.AdminNetworkPolicyKind {
+		var anp *policyv1alpha1.AdminNetworkPolicy
+		anp, err = c.anpLister.Get(name)
+		if err == nil {
+			objMeta, spec = &anp.ObjectMeta, &anp.Spec
+			translate = func() (*policies.NPMNetworkPolicy, error) {
+				return translation.TranslateAdminNetworkPolicy(anp)
+			}
+		}
+		cachedSpec, cached = c.rawAnpSpecMap[key]
+	} else {
+		var banp *policyv1alpha1.BaselineAdminNetworkPolicy
+		banp, err = c.banpLister.Get(name)
+		if err == nil {
+			objMeta, spec = &banp.ObjectMeta, &banp.Spec
+			translate = func() (*policies.NPMNetworkPolicy, error) {
+				return translation.TranslateBaselineAdminNetworkPolicy(banp)
+			}
+		}
+		cachedSpec, cached = c.rawBanpSpecMap[key]
+	}
+
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			klog.Infof("Admin Network Policy %s is not found, may be it is deleted", key)
+			if cached {
+				// record time to delete policy if it exists
+				operationKind = metrics.DeleteOp
+			}
+			err = c.cleanUpAdminNetworkPolicy(key)
+			if err != nil {
+				return fmt.Errorf("[syncAdminNetPol] error: %w when admin network policy is not found", err)
+			}
+			return nil
+		}
+		return err
+	}
+
+	// If DeletionTimestamp is set, start cleaning up lastly applied states.
+	if objMeta.DeletionTimestamp != nil || objMeta.DeletionGracePeriodSeconds != nil {
+		if cached {
+			operationKind = metrics.DeleteOp
+		}
+		err = c.cleanUpAdminNetworkPolicy(key)
+		if err != nil {
+			return fmt.Errorf("error: %w when ObjectMeta.DeletionTimestamp field is set", err)
+		}
+		return nil
+	}
+
+	// the policy doesn't need to be reconciled if its spec is the same as the lastly applied spec
+	if cached && reflect.DeepEqual(cachedSpec, spec) {
+		return nil
+	}
+
+	operationKind, err = c.syncAddAndUpdateAdminNetPol(key, spec, cached, translate)
+	if err != nil {
+		return fmt.Errorf("[syncAdminNetPol] error due to  %w", err)
+	}
+
+	return nil
+}
+
+// syncAddAndUpdateAdminNetPol handles a new or updated admin network policy triggered by add and update events.
+// spec is either an AdminNetworkPolicySpec or a BaselineAdminNetworkPolicySpec, and it's cached once the policy is in the Dataplane.
+func (c *AdminNetworkPolicyController) syncAddAndUpdateAdminNetPol(key string, spec interface{}, policyExisted bool,
+	translate func() (*policies.NPMNetworkPolicy, error),
+) (metrics.OperationKind, error) {
+	npmNetPolObj, err := translate()
+	if err != nil {
+		// The exec time isn't relevant here, so consider a no-op. Returning nil to prevent re-queuing since this is not a transient error.
+		klog.Errorf("Failed to translate Admin Network Policy %s: %s", key, err.Error())
+		return metrics.NoOp, nil
+	}
+
+	operationKind := metrics.CreateOp
+	if policyExisted {
+		operationKind = metrics.UpdateOp
+	}
+
+	// DP update policy call will check if this policy already exists in kernel
+	// if yes: then will delete old rules and program new rules
+	// if no: then will program add new rules
+	err = c.dp.UpdatePolicy(npmNetPolObj)
+	if err != nil {
+		// if error occurred the key is re-queued in workqueue and process this function again,
+		// which eventually meets desired states of the policy
+		return operationKind, fmt.Errorf("[syncAddAndUpdateAdminNetPol] Error: failed to update translated NPMNetworkPolicy into Dataplane due to %w", err)
+	}
+
+	if !policyExisted {
+		// inc metric for NumPolicies only if it a new policy
+		metrics.IncNumPolicies()
+	}
+
+	switch s := spec.(type) {
+	case *policyv1alpha1.AdminNetworkPolicySpec:
+		c.rawAnpSpecMap[key] = s
+	case *policyv1alpha1.BaselineAdminNetworkPolicySpec:
+		c.rawBanpSpecMap[key] = s
+	}
+	return operationKind, nil
+}
+
+// cleanUpAdminNetworkPolicy handles deleting an admin network policy based on its key.
+func (c *AdminNetworkPolicyController) cleanUpAdminNetworkPolicy(key string) error {
+	_, anpExists := c.rawAnpSpecMap[key]
+	_, banpExists := c.rawBanpSpecMap[key]
+	// if there is no applied policy with the key, do not need to clean up process.
+	if !anpExists && !banpExists {
+		return nil
+	}
+
+	err := c.dp.RemovePolicy(key)
+	if err != nil {
+		return fmt.Errorf("[cleanUpAdminNetworkPolicy] Error: failed to remove policy due to %w", err)
+	}
+
+	delete(c.rawAnpSpecMap, key)
+	delete(c.rawBanpSpecMap, key)
+	metrics.DecNumPolicies()
+	return nil
+}
@@ -0,0 +1,279 @@
+// Copyright 2018 Microsoft. All rights reserved.
+// MIT License
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	dpmocks "github.com/Azure/azure-container-networking/npm/pkg/dataplane/mocks"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	policyv1alpha1 "sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	policyfake "sigs.k8s.io/network-policy-api/pkg/client/clientset/versioned/fake"
+	policyinformers "sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions"
+)
+
+type adminNetPolFixture struct {
+	t *testing.T
+
+	// Objects to put in the store.
+	anpLister  []*policyv1alpha1.AdminNetworkPolicy
+	banpLister []*policyv1alpha1.BaselineAdminNetworkPolicy
+
+	adminNetPolController *AdminNetworkPolicyController
+	policyInformer        policyinformers.SharedInformerFactory
+}
+
+func newAdminNetPolFixture(t *testing.T) *adminNetPolFixture {
+	if util.IsWindowsDP() {
+		t.Skip("admin network policies are only supported in linux")
+	}
+	return &adminNetPolFixture{t: t}
+}
+
+func (f *adminNetPolFixture) newAdminNetPolController(dp dataplane.GenericDataplane) {
+	client := policyfake.NewSimpleClientset()
+	f.policyInformer = policyinformers.NewSharedInformerFactory(client, noResyncPeriodFunc())
+
+	f.adminNetPolController = NewAdminNetworkPolicyController(
+		f.policyInformer.Policy().V1alpha1().AdminNetworkPolicies(),
+		f.policyInformer.Policy().V1alpha1().BaselineAdminNetworkPolicies(),
+		dp,
+	)
+
+	for _, anp := range f.anpLister {
+		err := f.anpIndexer().Add(anp)
+		if err != nil {
+			f.t.Errorf("Failed to add admin network policy %s to shared informer cache: %v", anp.Name, err)
+		}
+	}
+	for _, banp := range f.banpLister {
+		err := f.banpIndexer().Add(banp)
+		if err != nil {
+			f.t.Errorf("Failed to add baseline admin network policy %s to shared informer cache: %v", banp.Name, err)
+		}
+	}
+
+	metrics.ReinitializeAll()
+}
+
+func (f *adminNetPolFixture) anpIndexer() cache.Indexer {
+	return f.policyInformer.Policy().V1alpha1().AdminNetworkPolicies().Informer().GetIndexer()
+}
+
+func (f *adminNetPolFixture) banpIndexer() cache.Indexer {
+	return f.policyInformer.Policy().V1alpha1().BaselineAdminNetworkPolicies().Informer().GetIndexer()
+}
+
+func (f *adminNetPolFixture) processNextWorkItem() {
+	if f.adminNetPolController.workqueue.Len() == 0 {
+		return
+	}
+	f.adminNetPolController.processNextWorkItem()
+}
+
+func createAdminNetPol() *policyv1alpha1.AdminNetworkPolicy {
+	return &policyv1alpha1.AdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "deny-from-tenant-b",
+			ResourceVersion: "0",
+		},
+		Spec: policyv1alpha1.AdminNetworkPolicySpec{
+			Priority: 10,
+			Subject: policyv1alpha1.AdminNetworkPolicySubject{
+				Namespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+			},
+			Ingress: []policyv1alpha1.AdminNetworkPolicyIngressRule{
+				{
+					Action: policyv1alpha1.AdminNetworkPolicyRuleActionDeny,
+					From: []policyv1alpha1.AdminNetworkPolicyPeer{
+						{Namespaces: &policyv1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "b"}}}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func createBaselineAdminNetPol() *policyv1alpha1.BaselineAdminNetworkPolicy {
+	return &policyv1alpha1.BaselineAdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "default",
+			ResourceVersion: "0",
+		},
+		Spec: policyv1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: policyv1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+			Egress: []policyv1alpha1.BaselineAdminNetworkPolicyEgressRule{
+				{
+					Action: policyv1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+					To: []policyv1alpha1.AdminNetworkPolicyPeer{
+						{Namespaces: &policyv1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{}}},
+					},
+				},
+			},
+		},
+	}
+}
+
+type expectedAdminNetPolValues struct {
+	expectedLenOfRawAnpMap  int
+	expectedLenOfRawBanpMap int
+	expectedLenOfWorkQueue  int
+	netPolPromVals
+}
+
+func checkAdminNetPolTestResult(f *adminNetPolFixture, expected expectedAdminNetPolValues) {
+	require.Equal(f.t, expected.expectedLenOfRawAnpMap, f.adminNetPolController.LengthOfRawAnpMap(), "Raw ANP Map length")
+	require.Equal(f.t, expected.expectedLenOfRawBanpMap, f.adminNetPolController.LengthOfRawBanpMap(), "Raw BANP Map length")
+	require.Equal(f.t, expected.expectedLenOfWorkQueue, f.adminNetPolController.workqueue.Len(), "Workqueue length")
+	expected.netPolPromVals.testPrometheusMetrics(f.t)
+}
+
+func TestAddAdminNetworkPolicies(t *testing.T) {
+	anp := createAdminNetPol()
+	banp := createBaselineAdminNetPol()
+
+	f := newAdminNetPolFixture(t)
+	f.anpLister = append(f.anpLister, anp)
+	f.banpLister = append(f.banpLister, banp)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f.newAdminNetPolController(dp)
+
+	dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(policy *policies.NPMNetworkPolicy) error {
+		require.Equal(t, "AdminNetworkPolicy/deny-from-tenant-b", policy.PolicyKey)
+		require.Equal(t, policies.AdminTier, policy.Tier)
+		require.Equal(t, int32(10), policy.Priority)
+		return nil
+	}).Times(1)
+	dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(policy *policies.NPMNetworkPolicy) error {
+		require.Equal(t, "BaselineAdminNetworkPolicy/default", policy.PolicyKey)
+		require.Equal(t, policies.BaselineAdminTier, policy.Tier)
+		return nil
+	}).Times(1)
+
+	f.adminNetPolController.addAdminNetworkPolicy(anp)
+	f.processNextWorkItem()
+	f.adminNetPolController.addAdminNetworkPolicy(banp)
+	f.processNextWorkItem()
+
+	// already exists (will be a no-op)
+	f.adminNetPolController.addAdminNetworkPolicy(anp)
+	f.processNextWorkItem()
+
+	checkAdminNetPolTestResult(f, expectedAdminNetPolValues{1, 1, 0, netPolPromVals{2, 2, 0, 0}})
+	require.Equal(t, &anp.Spec, f.adminNetPolController.GetCache()["AdminNetworkPolicy/deny-from-tenant-b"])
+	require.Equal(t, &banp.Spec, f.adminNetPolController.GetBaselineCache()["BaselineAdminNetworkPolicy/default"])
+}
+
+func TestDeleteAdminNetworkPolicy(t *testing.T) {
+	anp := createAdminNetPol()
+
+	f := newAdminNetPolFixture(t)
+	f.anpLister = append(f.anpLister, anp)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f.newAdminNetPolController(dp)
+
+	dp.EXPECT().UpdatePolicy(gomock.Any()).Times(1)
+	dp.EXPECT().RemovePolicy("AdminNetworkPolicy/deny-from-tenant-b").Times(1)
+
+	f.adminNetPolController.addAdminNetworkPolicy(anp)
+	f.processNextWorkItem()
+
+	require.NoError(t, f.anpIndexer().Delete(anp))
+	f.adminNetPolController.deleteAdminNetworkPolicy(anp)
+	f.processNextWorkItem()
+
+	checkAdminNetPolTestResult(f, expectedAdminNetPolValues{0, 0, 0, netPolPromVals{0, 1, 0, 1}})
+}
+
+func TestDeleteBaselineAdminNetworkPolicyWithTombstone(t *testing.T) {
+	banp := createBaselineAdminNetPol()
+
+	f := newAdminNetPolFixture(t)
+	f.banpLister = append(f.banpLister, banp)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f.newAdminNetPolController(dp)
+
+	dp.EXPECT().UpdatePolicy(gomock.Any()).Times(1)
+	dp.EXPECT().RemovePolicy("BaselineAdminNetworkPolicy/default").Times(1)
+
+	f.adminNetPolController.addAdminNetworkPolicy(banp)
+	f.processNextWorkItem()
+
+	require.NoError(t, f.banpIndexer().Delete(banp))
+	tombstone := cache.DeletedFinalStateUnknown{
+		Key: banp.Name,
+		Obj: banp,
+	}
+	f.adminNetPolController.deleteAdminNetworkPolicy(tombstone)
+	f.processNextWorkItem()
+
+	checkAdminNetPolTestResult(f, expectedAdminNetPolValues{0, 0, 0, netPolPromVals{0, 1, 0, 1}})
+}
+
+func TestUpdateAdminNetworkPolicy(t *testing.T) {
+	oldANP := createAdminNetPol()
+
+	f := newAdminNetPolFixture(t)
+	f.anpLister = append(f.anpLister, oldANP)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f.newAdminNetPolController(dp)
+
+	dp.EXPECT().UpdatePolicy(gomock.Any()).Times(2)
+
+	f.adminNetPolController.addAdminNetworkPolicy(oldANP)
+	f.processNextWorkItem()
+
+	// same resource version (periodic resync) isn't enqueued
+	f.adminNetPolController.updateAdminNetworkPolicy(oldANP, oldANP)
+	require.Equal(t, 0, f.adminNetPolController.workqueue.Len())
+
+	newANP := oldANP.DeepCopy()
+	newANP.Spec.Priority = 20
+	newRV, _ := strconv.Atoi(oldANP.ResourceVersion)
+	newANP.ResourceVersion = fmt.Sprintf("%d", newRV+1)
+	require.NoError(t, f.anpIndexer().Update(newANP))
+	f.adminNetPolController.updateAdminNetworkPolicy(oldANP, newANP)
+	f.processNextWorkItem()
+
+	checkAdminNetPolTestResult(f, expectedAdminNetPolValues{1, 0, 0, netPolPromVals{1, 1, 1, 0}})
+}
+
+func TestAddUntranslatableAdminNetworkPolicy(t *testing.T) {
+	anp := createAdminNetPol()
+	anp.Spec.Ingress[0].From[0].Namespaces = &policyv1alpha1.NamespacedPeer{SameLabels: []string{"tenant"}}
+
+	f := newAdminNetPolFixture(t)
+	f.anpLister = append(f.anpLister, anp)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// no calls to the dataplane
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f.newAdminNetPolController(dp)
+
+	f.adminNetPolController.addAdminNetworkPolicy(anp)
+	f.processNextWorkItem()
+
+	checkAdminNetPolTestResult(f, expectedAdminNetPolValues{0, 0, 0, netPolPromVals{0, 0, 0, 0}})
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"slices"
 	"sync"
 	"time"
 
@@ -44,6 +45,8 @@ type PodController struct {
 	workqueue workqueue.RateLimitingInterface
 	dp        dataplane.GenericDataplane
 	podMap    map[string]*common.NpmPod // Key is <nsname>/<podname>
+	// podKeyByIP indexes podMap by pod IP so that audit events can find their pods without scanning podMap
+	podKeyByIP map[string]string
 	sync.RWMutex
 	npmNamespaceCache *NpmNamespaceCache
 }
@@ -54,6 +57,7 @@ func NewPodController(podInformer coreinformer.PodInformer, dp dataplane.Generic
 		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Pods"),
 		dp:                dp,
 		podMap:            make(map[string]*common.NpmPod),
+		podKeyByIP:        make(map[string]string),
 		npmNamespaceCache: npmNamespaceCache,
 	}
 
@@ -83,6 +87,38 @@ func (c *PodController) LengthOfPodMap() int {
 	return len(c.podMap)
 }
 
+// PodKeyByIP returns the key of the cached pod with the IP.
+func (c *PodController) PodKeyByIP(podIP string) (string, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	podKey, ok := c.podKeyByIP[podIP]
+	return podKey, ok
+}
+
+// cachePod adds the pod to podMap and indexes its IPs.
+func (c *PodController) cachePod(podKey string, npmPod *common.NpmPod) {
+	c.podMap[podKey] = npmPod
+	for _, ip := range npmPod.PodIPs {
+		c.podKeyByIP[ip] = podKey
+	}
+}
+
+// uncachePod removes the pod from podMap and its IPs from the index.
+// An IP which has been reused by a newer pod stays indexed to that pod.
+func (c *PodController) uncachePod(podKey string) {
+	npmPod, ok := c.podMap[podKey]
+	if !ok {
+		return
+	}
+	for _, ip := range npmPod.PodIPs {
+		if c.podKeyByIP[ip] == podKey {
+			delete(c.podKeyByIP, ip)
+		}
+	}
+	delete(c.podMap, podKey)
+}
+
 // needSync filters the event if the event is not required to handle
 func (c *PodController) needSync(eventType string, obj interface{}) (string, bool) {
 	needSync := false
@@ -350,8 +386,8 @@ func (c *PodController) syncAddedPod(podObj *corev1.Pod) error {
 	klog.Infof("POD CREATING: [%s/%s/%s/%s/%+v/%s]", string(podObj.GetUID()), podObj.Namespace,
 		podObj.Name, podObj.Spec.NodeName, podObj.Labels, podObj.Status.PodIP)
 
-	if !util.IsIPV4(podObj.Status.PodIP) {
-		msg := fmt.Sprintf("[syncAddedPod] warning: ADD POD  [%s/%s/%s/%+v] ignored as the PodIP is not valid ipv4 address. ip: [%s]", podObj.Namespace,
+	if !util.IsIPV4(podObj.Status.PodIP) && !util.IsIPV6(podObj.Status.PodIP) {
+		msg := fmt.Sprintf("[syncAddedPod] warning: ADD POD  [%s/%s/%s/%+v] ignored as the PodIP is not valid ipv4 or ipv6 address. ip: [%s]", podObj.Namespace,
 			podObj.Name, podObj.Spec.NodeName, podObj.Labels, podObj.Status.PodIP)
 		metrics.SendLog(util.PodID, msg, metrics.PrintLog)
 		// return nil so that we don't requeue.
@@ -363,20 +399,19 @@ func (c *PodController) syncAddedPod(podObj *corev1.Pod) error {
 
 	var err error
 	podKey, _ := cache.MetaNamespaceKeyFunc(podObj)
-
-	podMetadata := dataplane.NewPodMetadata(podKey, podObj.Status.PodIP, podObj.Spec.NodeName)
+	podIPs := common.GetPodIPs(podObj)
 
 	namespaceSet := []*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(podObj.Namespace, ipsets.Namespace)}
 
 	// Add the pod ip information into namespace's ipset.
-	klog.Infof("Adding pod %s (ip : %s) to ipset %s", podKey, podObj.Status.PodIP, podObj.Namespace)
-	if err = c.dp.AddToSets(namespaceSet, podMetadata); err != nil {
+	klog.Infof("Adding pod %s (ips : %v) to ipset %s", podKey, podIPs, podObj.Namespace)
+	if err = c.addPodIPsToSets(namespaceSet, podKey, podIPs, podObj.Spec.NodeName); err != nil {
 		return fmt.Errorf("[syncAddedPod] Error: failed to add pod to namespace ipset with err: %w", err)
 	}
 
 	// Create npmPod and add it to the podMap
 	npmPodObj := common.NewNpmPod(podObj)
-	c.podMap[podKey] = npmPodObj
+	c.cachePod(podKey, npmPodObj)
 	metrics.AddPod()
 
 	// Get lists of podLabelKey and podLabelKey + podLavelValue ,and then start adding them to ipsets.
@@ -388,8 +423,8 @@ func (c *PodController) syncAddedPod(podObj *corev1.Pod) error {
 		allSets := []*ipsets.IPSetMetadata{targetSetKey, targetSetKeyValue}
 
 		klog.Infof("Creating ipsets %+v and %+v if they do not exist", targetSetKey, targetSetKeyValue)
-		klog.Infof("Adding pod %s (ip : %s) to ipset %s and %s", podKey, npmPodObj.PodIP, labelKey, labelKeyValue)
-		if err = c.dp.AddToSets(allSets, podMetadata); err != nil {
+		klog.Infof("Adding pod %s (ips : %v) to ipset %s and %s", podKey, npmPodObj.PodIPs, labelKey, labelKeyValue)
+		if err = c.addPodIPsToSets(allSets, podKey, npmPodObj.PodIPs, podObj.Spec.NodeName); err != nil {
 			return fmt.Errorf("[syncAddedPod] Error: failed to add pod to label ipset with err: %w", err)
 		}
 		npmPodObj.AppendLabels(map[string]string{labelKey: labelVal}, common.AppendToExistingLabels)
@@ -398,7 +433,7 @@ func (c *PodController) syncAddedPod(podObj *corev1.Pod) error {
 	// Add pod's named ports from its ipset.
 	klog.Infof("Adding named port ipsets")
 	containerPorts := common.GetContainerPortList(podObj)
-	if err = c.manageNamedPortIpsets(containerPorts, podKey, npmPodObj.PodIP, podObj.Spec.NodeName, addNamedPort); err != nil {
+	if err = c.manageNamedPortIpsets(containerPorts, podKey, npmPodObj.PodIPs, podObj.Spec.NodeName, addNamedPort); err != nil {
 		return fmt.Errorf("[syncAddedPod] Error: failed to add pod to named port ipset with err: %w", err)
 	}
 	npmPodObj.AppendContainerPorts(podObj)
@@ -445,9 +480,10 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 	// Dealing with #2 pod update event, the IP addresses of cached npmPod and newPodObj are different
 	// NPM should clean up existing references of cached pod obj and its IP.
 	// then, re-add new pod obj.
-	if cachedNpmPod.PodIP != newPodObj.Status.PodIP {
-		klog.Infof("Pod (Namespace:%s, Name:%s, newUid:%s), has cachedPodIp:%s which is different from PodIp:%s",
-			newPodObj.Namespace, newPodObj.Name, string(newPodObj.UID), cachedNpmPod.PodIP, newPodObj.Status.PodIP)
+	newPodIPs := common.GetPodIPs(newPodObj)
+	if cachedNpmPod.PodIP != newPodObj.Status.PodIP || !slices.Equal(cachedNpmPod.PodIPs, newPodIPs) {
+		klog.Infof("Pod (Namespace:%s, Name:%s, newUid:%s), has cachedPodIps:%v which are different from PodIps:%v",
+			newPodObj.Namespace, newPodObj.Name, string(newPodObj.UID), cachedNpmPod.PodIPs, newPodIPs)
 
 		klog.Infof("Deleting cached Pod with key:%s first due to IP Mistmatch", podKey)
 		if er := c.cleanUpDeletedPod(podKey); er != nil {
@@ -463,12 +499,10 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 	// Otherwise it returns list of deleted PodIP from cached pod's labels and list of added PodIp from new pod's labels
 	addToIPSets, deleteFromIPSets := util.GetIPSetListCompareLabels(cachedNpmPod.Labels, newPodObj.Labels)
 
-	newPodMetadata := dataplane.NewPodMetadata(podKey, newPodObj.Status.PodIP, newPodObj.Spec.NodeName)
-	// should have newPodMetadata == cachedPodMetadata since from branch above, we have cachedNpmPod.PodIP == newPodObj.Status.PodIP
-	cachedPodMetadata := dataplane.NewPodMetadata(podKey, cachedNpmPod.PodIP, newPodMetadata.NodeName)
+	// from branch above, we have cachedNpmPod.PodIPs == newPodIPs
 	// Delete the pod from its label's ipset.
 	for _, removeIPSetName := range deleteFromIPSets {
-		klog.Infof("Deleting pod %s (ip : %s) from ipset %s", podKey, cachedNpmPod.PodIP, removeIPSetName)
+		klog.Infof("Deleting pod %s (ips : %v) from ipset %s", podKey, cachedNpmPod.PodIPs, removeIPSetName)
 
 		var toRemoveSet *ipsets.IPSetMetadata
 		if util.IsKeyValueLabelSetName(removeIPSetName) {
@@ -476,7 +510,7 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 		} else {
 			toRemoveSet = ipsets.NewIPSetMetadata(removeIPSetName, ipsets.KeyLabelOfPod)
 		}
-		if err = c.dp.RemoveFromSets([]*ipsets.IPSetMetadata{toRemoveSet}, cachedPodMetadata); err != nil {
+		if err = c.removePodIPsFromSets([]*ipsets.IPSetMetadata{toRemoveSet}, podKey, cachedNpmPod.PodIPs, newPodObj.Spec.NodeName); err != nil {
 			return metrics.UpdateOp, fmt.Errorf("[syncAddAndUpdatePod] Error: failed to delete pod from label ipset with err: %w", err)
 		}
 		// {IMPORTANT} The order of compared list will be key and then key+val. NPM should only append after both key
@@ -499,8 +533,8 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 			toAddSet = ipsets.NewIPSetMetadata(addIPSetName, ipsets.KeyLabelOfPod)
 		}
 
-		klog.Infof("Adding pod %s (ip : %s) to ipset %s", podKey, newPodObj.Status.PodIP, addIPSetName)
-		if err = c.dp.AddToSets([]*ipsets.IPSetMetadata{toAddSet}, newPodMetadata); err != nil {
+		klog.Infof("Adding pod %s (ips : %v) to ipset %s", podKey, newPodIPs, addIPSetName)
+		if err = c.addPodIPsToSets([]*ipsets.IPSetMetadata{toAddSet}, podKey, newPodIPs, newPodObj.Spec.NodeName); err != nil {
 			return metrics.UpdateOp, fmt.Errorf("[syncAddAndUpdatePod] Error: failed to add pod to label ipset with err: %w", err)
 		}
 		// {IMPORTANT} Same as above order is assumed to be key and then key+val. NPM should only append to existing labels
@@ -523,14 +557,14 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 	if !reflect.DeepEqual(cachedNpmPod.ContainerPorts, newPodPorts) {
 		// Delete cached pod's named ports from its ipset.
 		if err = c.manageNamedPortIpsets(
-			cachedNpmPod.ContainerPorts, podKey, cachedNpmPod.PodIP, "", deleteNamedPort); err != nil {
+			cachedNpmPod.ContainerPorts, podKey, cachedNpmPod.PodIPs, "", deleteNamedPort); err != nil {
 			return metrics.UpdateOp, fmt.Errorf("[syncAddAndUpdatePod] Error: failed to delete pod from named port ipset with err: %w", err)
 		}
 		// Since portList ipset deletion is successful, NPM can remove cachedContainerPorts
 		cachedNpmPod.RemoveContainerPorts()
 
 		// Add new pod's named ports from its ipset.
-		if err = c.manageNamedPortIpsets(newPodPorts, podKey, newPodObj.Status.PodIP, newPodObj.Spec.NodeName, addNamedPort); err != nil {
+		if err = c.manageNamedPortIpsets(newPodPorts, podKey, newPodIPs, newPodObj.Spec.NodeName, addNamedPort); err != nil {
 			return metrics.UpdateOp, fmt.Errorf("[syncAddAndUpdatePod] Error: failed to add pod to named port ipset with err: %w", err)
 		}
 		cachedNpmPod.AppendContainerPorts(newPodObj)
@@ -550,25 +584,24 @@ func (c *PodController) cleanUpDeletedPod(cachedNpmPodKey string) error {
 	}
 
 	var err error
-	cachedPodMetadata := dataplane.NewPodMetadata(cachedNpmPodKey, cachedNpmPod.PodIP, "")
 	// Delete the pod from its namespace's ipset.
 	// note: NodeName empty is not going to call update pod
-	if err = c.dp.RemoveFromSets(
+	if err = c.removePodIPsFromSets(
 		[]*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(cachedNpmPod.Namespace, ipsets.Namespace)},
-		cachedPodMetadata); err != nil {
+		cachedNpmPodKey, cachedNpmPod.PodIPs, ""); err != nil {
 		return fmt.Errorf("[cleanUpDeletedPod] Error: failed to delete pod from namespace ipset with err: %w", err)
 	}
 
 	// Get lists of podLabelKey and podLabelKey + podLavelValue ,and then start deleting them from ipsets
 	for labelKey, labelVal := range cachedNpmPod.Labels {
 		labelKeyValue := util.GetIpSetFromLabelKV(labelKey, labelVal)
-		klog.Infof("Deleting pod %s (ip : %s) from ipsets %s and %s", cachedNpmPodKey, cachedNpmPod.PodIP, labelKey, labelKeyValue)
-		if err = c.dp.RemoveFromSets(
+		klog.Infof("Deleting pod %s (ips : %v) from ipsets %s and %s", cachedNpmPodKey, cachedNpmPod.PodIPs, labelKey, labelKeyValue)
+		if err = c.removePodIPsFromSets(
 			[]*ipsets.IPSetMetadata{
 				ipsets.NewIPSetMetadata(labelKey, ipsets.KeyLabelOfPod),
 				ipsets.NewIPSetMetadata(labelKeyValue, ipsets.KeyValueLabelOfPod),
 			},
-			cachedPodMetadata); err != nil {
+			cachedNpmPodKey, cachedNpmPod.PodIPs, ""); err != nil {
 			return fmt.Errorf("[cleanUpDeletedPod] Error: failed to delete pod from label ipset with err: %w", err)
 		}
 		cachedNpmPod.RemoveLabelsWithKey(labelKey)
@@ -576,18 +609,38 @@ func (c *PodController) cleanUpDeletedPod(cachedNpmPodKey string) error {
 
 	// Delete pod's named ports from its ipset. Need to pass true in the manageNamedPortIpsets function call
 	if err = c.manageNamedPortIpsets(
-		cachedNpmPod.ContainerPorts, cachedNpmPodKey, cachedNpmPod.PodIP, "", deleteNamedPort); err != nil {
+		cachedNpmPod.ContainerPorts, cachedNpmPodKey, cachedNpmPod.PodIPs, "", deleteNamedPort); err != nil {
 		return fmt.Errorf("[cleanUpDeletedPod] Error: failed to delete pod from named port ipset with err: %w", err)
 	}
 
 	metrics.RemovePod()
-	delete(c.podMap, cachedNpmPodKey)
+	c.uncachePod(cachedNpmPodKey)
 	return nil
 }
 
-// manageNamedPortIpsets helps with adding or deleting Pod namedPort IPsets.
-func (c *PodController) manageNamedPortIpsets(portList []corev1.ContainerPort, podKey,
-	podIP, nodeName string, namedPortOperation NamedPortOperation) error {
+// addPodIPsToSets adds each of the pod's IPs to the sets.
+func (c *PodController) addPodIPsToSets(sets []*ipsets.IPSetMetadata, podKey string, podIPs []string, nodeName string) error {
+	for _, podIP := range podIPs {
+		if err := c.dp.AddToSets(sets, dataplane.NewPodMetadata(podKey, podIP, nodeName)); err != nil {
+			return err //nolint:wrapcheck // callers wrap the error
+		}
+	}
+	return nil
+}
+
+// removePodIPsFromSets removes each of the pod's IPs from the sets.
+func (c *PodController) removePodIPsFromSets(sets []*ipsets.IPSetMetadata, podKey string, podIPs []string, nodeName string) error {
+	for _, podIP := range podIPs {
+		if err := c.dp.RemoveFromSets(sets, dataplane.NewPodMetadata(podKey, podIP, nodeName)); err != nil {
+			return err //nolint:wrapcheck // callers wrap the error
+		}
+	}
+	return nil
+}
+
+// manageNamedPortIpsets helps with adding or deleting Pod namedPort IPsets for each of the pod's IPs.
+func (c *PodController) manageNamedPortIpsets(portList []corev1.ContainerPort, podKey string,
+	podIPs []string, nodeName string, namedPortOperation NamedPortOperation) error {
 	if util.IsWindowsDP() {
 		// NOTE: if we support namedport operations, need to be careful of implications of including the node name in the pod metadata below
 		// since we say the node name is "" in cleanUpDeletedPod
@@ -607,18 +660,20 @@ func (c *PodController) manageNamedPortIpsets(portList []corev1.ContainerPort, p
 			protocol = fmt.Sprintf("%s:", port.Protocol)
 		}
 
-		namedPortIpsetEntry := fmt.Sprintf("%s,%s%d", podIP, protocol, port.ContainerPort)
-
-		// nodename in NewPodMetadata is nil so UpdatePod is ignored
-		podMetadata := dataplane.NewPodMetadata(podKey, namedPortIpsetEntry, nodeName)
-		switch namedPortOperation {
-		case deleteNamedPort:
-			if err := c.dp.RemoveFromSets([]*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(port.Name, ipsets.NamedPorts)}, podMetadata); err != nil {
-				return fmt.Errorf("failed to remove from set when deleting named port with err %w", err)
-			}
-		case addNamedPort:
-			if err := c.dp.AddToSets([]*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(port.Name, ipsets.NamedPorts)}, podMetadata); err != nil {
-				return fmt.Errorf("failed to add to set when deleting named port with err %w", err)
+		for _, podIP := range podIPs {
+			namedPortIpsetEntry := fmt.Sprintf("%s,%s%d", podIP, protocol, port.ContainerPort)
+
+			// nodename in NewPodMetadata is nil so UpdatePod is ignored
+			podMetadata := dataplane.NewPodMetadata(podKey, namedPortIpsetEntry, nodeName)
+			switch namedPortOperation {
+			case deleteNamedPort:
+				if err := c.dp.RemoveFromSets([]*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(port.Name, ipsets.NamedPorts)}, podMetadata); err != nil {
+					return fmt.Errorf("failed to remove from set when deleting named port with err %w", err)
+				}
+			case addNamedPort:
+				if err := c.dp.AddToSets([]*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(port.Name, ipsets.NamedPorts)}, podMetadata); err != nil {
+					return fmt.Errorf("failed to add to set when deleting named port with err %w", err)
+				}
 			}
 		}
 	}
@@ -381,6 +381,55 @@ func TestAddPod(t *testing.T) {
 	checkNpmPodWithInput("TestAddPod", f, podObj)
 }
 
+func TestAddDualStackPod(t *testing.T) {
+	labels := map[string]string{
+		"app": "test-pod",
+	}
+	podObj := createPod("test-pod", "test-namespace", "0", "1.2.3.4", labels, NonHostNetwork, corev1.PodRunning)
+	podObj.Status.PodIPs = []corev1.PodIP{{IP: "1.2.3.4"}, {IP: "fd00::4"}}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f := newFixture(t, dp)
+	f.podLister = append(f.podLister, podObj)
+	f.kubeobjects = append(f.kubeobjects, podObj)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	f.newPodController(stopCh)
+
+	mockIPSets := []*ipsets.IPSetMetadata{
+		ipsets.NewIPSetMetadata("test-namespace", ipsets.Namespace),
+		ipsets.NewIPSetMetadata("app", ipsets.KeyLabelOfPod),
+		ipsets.NewIPSetMetadata("app:test-pod", ipsets.KeyValueLabelOfPod),
+	}
+	podMetadata1 := dataplane.NewPodMetadata("test-namespace/test-pod", "1.2.3.4", "")
+	podMetadata2 := dataplane.NewPodMetadata("test-namespace/test-pod", "fd00::4", "")
+
+	dp.EXPECT().AddToLists([]*ipsets.IPSetMetadata{kubeAllNamespaces}, mockIPSets[:1]).Return(nil).Times(1)
+	dp.EXPECT().AddToSets(mockIPSets[:1], podMetadata1).Return(nil).Times(1)
+	dp.EXPECT().AddToSets(mockIPSets[:1], podMetadata2).Return(nil).Times(1)
+	dp.EXPECT().AddToSets(mockIPSets[1:], podMetadata1).Return(nil).Times(1)
+	dp.EXPECT().AddToSets(mockIPSets[1:], podMetadata2).Return(nil).Times(1)
+	if !util.IsWindowsDP() {
+		namedPortSet := []*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata("app:test-pod", ipsets.NamedPorts)}
+		dp.EXPECT().AddToSets(namedPortSet, dataplane.NewPodMetadata("test-namespace/test-pod", "1.2.3.4,8080", "")).Return(nil).Times(1)
+		dp.EXPECT().AddToSets(namedPortSet, dataplane.NewPodMetadata("test-namespace/test-pod", "fd00::4,8080", "")).Return(nil).Times(1)
+	}
+	dp.EXPECT().ApplyDataPlane().Return(nil).Times(1)
+
+	addPod(t, f, podObj)
+	testCases := []expectedValues{
+		{1, 1, 0, podPromVals{1, 1, 0, 0, 0, 0, 0}},
+	}
+	// sleep in case rate limiter adds back to workqueue
+	time.Sleep(sleepDurationForRateLimiter)
+	checkPodTestResult("TestAddDualStackPod", f, testCases)
+	checkNpmPodWithInput("TestAddDualStackPod", f, podObj)
+	require.Equal(t, []string{"1.2.3.4", "fd00::4"}, f.podController.podMap["test-namespace/test-pod"].PodIPs)
+}
+
 func TestAddHostNetworkPod(t *testing.T) {
 	labels := map[string]string{
 		"app": "test-pod",
@@ -1034,11 +1083,50 @@ func TestPodMapMarshalJSON(t *testing.T) {
 	npMapRaw, err := f.podController.MarshalJSON()
 	assert.NoError(t, err)
 
-	expect := []byte(`{"test-namespace/test-pod":{"Name":"test-pod","Namespace":"test-namespace","PodIP":"1.2.3.4","Labels":{},"ContainerPorts":[],"Phase":"Running"}}`)
+	expect := []byte(`{"test-namespace/test-pod":{"Name":"test-pod","Namespace":"test-namespace","PodIP":"1.2.3.4","PodIPs":["1.2.3.4"],"Labels":{},"ContainerPorts":[],"Phase":"Running"}}`)
 	fmt.Printf("%s\n", string(npMapRaw))
 	assert.ElementsMatch(t, expect, npMapRaw)
 }
 
+func TestPodKeyByIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f := newFixture(t, dp)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	f.newPodController(stopCh)
+
+	pod := createPod("test-pod", "test-namespace", "0", "1.2.3.4", map[string]string{}, NonHostNetwork, corev1.PodRunning)
+	pod.Status.PodIPs = []corev1.PodIP{{IP: "1.2.3.4"}, {IP: "fd00::4"}}
+	podKey, err := cache.MetaNamespaceKeyFunc(pod)
+	assert.NoError(t, err)
+	f.podController.cachePod(podKey, common.NewNpmPod(pod))
+
+	for _, ip := range []string{"1.2.3.4", "fd00::4"} {
+		actualKey, ok := f.podController.PodKeyByIP(ip)
+		assert.True(t, ok)
+		assert.Equal(t, podKey, actualKey)
+	}
+
+	_, ok := f.podController.PodKeyByIP("1.2.3.5")
+	assert.False(t, ok)
+
+	// a new pod reuses the IPv4 address before the old pod is deleted
+	newPod := createPod("new-pod", "test-namespace", "0", "1.2.3.4", map[string]string{}, NonHostNetwork, corev1.PodRunning)
+	newPodKey, err := cache.MetaNamespaceKeyFunc(newPod)
+	assert.NoError(t, err)
+	f.podController.cachePod(newPodKey, common.NewNpmPod(newPod))
+	f.podController.uncachePod(podKey)
+
+	actualKey, ok := f.podController.PodKeyByIP("1.2.3.4")
+	assert.True(t, ok)
+	assert.Equal(t, newPodKey, actualKey)
+	_, ok = f.podController.PodKeyByIP("fd00::4")
+	assert.False(t, ok)
+}
+
 func TestHasValidPodIP(t *testing.T) {
 	podObj := &corev1.Pod{
 		Status: corev1.PodStatus{
@@ -1059,6 +1147,23 @@ func TestHasValidPodIP(t *testing.T) {
 	require.False(t, hasValidPodIP(podObj))
 }
 
+func TestGetPodIPs(t *testing.T) {
+	podObj := &corev1.Pod{
+		Status: corev1.PodStatus{
+			PodIP:  "1.2.3.4",
+			PodIPs: []corev1.PodIP{{IP: "1.2.3.4"}, {IP: "fd00::4"}, {IP: "invalid"}},
+		},
+	}
+	require.Equal(t, []string{"1.2.3.4", "fd00::4"}, common.GetPodIPs(podObj))
+
+	// falls back to the primary IP
+	podObj.Status.PodIPs = nil
+	require.Equal(t, []string{"1.2.3.4"}, common.GetPodIPs(podObj))
+
+	podObj.Status.PodIP = ""
+	require.Empty(t, common.GetPodIPs(podObj))
+}
+
 func TestIsCompletePod(t *testing.T) {
 	var zeroGracePeriod int64
 	var defaultGracePeriod int64 = 30
@@ -1242,3 +1347,13 @@ func TestNPMPodNoUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestNPMPodNoUpdateWithNewIPv6IP(t *testing.T) {
+	corev1Pod := createPod("test-pod", "test-namespace", "0", "1.2.3.4", nil, NonHostNetwork, corev1.PodRunning)
+	npmPod := common.NewNpmPod(corev1Pod)
+	npmPod.AppendContainerPorts(corev1Pod)
+	require.True(t, npmPod.NoUpdate(corev1Pod))
+
+	corev1Pod.Status.PodIPs = []corev1.PodIP{{IP: "1.2.3.4"}, {IP: "fd00::4"}}
+	require.False(t, npmPod.NoUpdate(corev1Pod))
+}
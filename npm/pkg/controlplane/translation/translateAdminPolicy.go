@@ -0,0 +1,248 @@
+package translation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	policyv1alpha1 "sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+const (
+	// AdminNetworkPolicyKind prefixes the policy key of an AdminNetworkPolicy.
+	// Kinds are capitalized, so a policy key can't be the same as the key of a NetworkPolicy.
+	AdminNetworkPolicyKind = "AdminNetworkPolicy"
+	// BaselineAdminNetworkPolicyKind prefixes the policy key of a BaselineAdminNetworkPolicy.
+	BaselineAdminNetworkPolicyKind = "BaselineAdminNetworkPolicy"
+)
+
+var (
+	// ErrUnsupportedAdminNetworkPolicy is returned when an AdminNetworkPolicy or BaselineAdminNetworkPolicy is translated in windows.
+	ErrUnsupportedAdminNetworkPolicy = errors.New("unsupported admin network policy used on windows")
+	// ErrUnsupportedSameLabels is returned when a peer of an admin network policy has sameLabels or notSameLabels.
+	ErrUnsupportedSameLabels = errors.New("unsupported sameLabels or notSameLabels in admin network policy peer")
+	// ErrUnsupportedSubjectNamespaceSelector is returned when the namespace selector of a subject would need an OR condition,
+	// which a pod selector list can't express (e.g. a matchExpression with multiple values).
+	ErrUnsupportedSubjectNamespaceSelector = errors.New("unsupported namespace selector with multiple values in admin network policy subject")
+	errUnknownAdminPolicyAction            = errors.New("unknown admin network policy rule action")
+)
+
+// adminRule has the fields shared by the ingress and egress rules of AdminNetworkPolicies and BaselineAdminNetworkPolicies.
+type adminRule struct {
+	target policies.Verdict
+	peers  []policyv1alpha1.AdminNetworkPolicyPeer
+	ports  *[]policyv1alpha1.AdminNetworkPolicyPort
+}
+
+// AdminNetworkPolicyKey returns the policy key of the AdminNetworkPolicy with the name.
+func AdminNetworkPolicyKey(name string) string {
+	return fmt.Sprintf("%s/%s", AdminNetworkPolicyKind, name)
+}
+
+// BaselineAdminNetworkPolicyKey returns the policy key of the BaselineAdminNetworkPolicy with the name.
+func BaselineAdminNetworkPolicyKey(name string) string {
+	return fmt.Sprintf("%s/%s", BaselineAdminNetworkPolicyKind, name)
+}
+
+// TranslateAdminNetworkPolicy translates an AdminNetworkPolicy object to an NPMNetworkPolicy object in the admin tier.
+func TranslateAdminNetworkPolicy(anp *policyv1alpha1.AdminNetworkPolicy) (*policies.NPMNetworkPolicy, error) {
+	if util.IsWindowsDP() {
+		return nil, ErrUnsupportedAdminNetworkPolicy
+	}
+
+	npmNetPol := policies.NewNPMAdminNetworkPolicy(AdminNetworkPolicyKind, anp.Name, policies.AdminTier, anp.Spec.Priority)
+	if err := adminSubject(npmNetPol, &anp.Spec.Subject); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range anp.Spec.Ingress {
+		target, err := adminTarget(rule.Action)
+		if err != nil {
+			return nil, err
+		}
+		if err := translateAdminRule(npmNetPol, policies.Ingress, policies.SrcMatch, adminRule{target, rule.From, rule.Ports}); err != nil {
+			return nil, err
+		}
+	}
+	for _, rule := range anp.Spec.Egress {
+		target, err := adminTarget(rule.Action)
+		if err != nil {
+			return nil, err
+		}
+		if err := translateAdminRule(npmNetPol, policies.Egress, policies.DstMatch, adminRule{target, rule.To, rule.Ports}); err != nil {
+			return nil, err
+		}
+	}
+	return npmNetPol, nil
+}
+
+// TranslateBaselineAdminNetworkPolicy translates a BaselineAdminNetworkPolicy object to an NPMNetworkPolicy object in the baseline admin tier.
+func TranslateBaselineAdminNetworkPolicy(banp *policyv1alpha1.BaselineAdminNetworkPolicy) (*policies.NPMNetworkPolicy, error) {
+	if util.IsWindowsDP() {
+		return nil, ErrUnsupportedAdminNetworkPolicy
+	}
+
+	npmNetPol := policies.NewNPMAdminNetworkPolicy(BaselineAdminNetworkPolicyKind, banp.Name, policies.BaselineAdminTier, 0)
+	if err := adminSubject(npmNetPol, &banp.Spec.Subject); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range banp.Spec.Ingress {
+		target, err := adminTarget(policyv1alpha1.AdminNetworkPolicyRuleAction(rule.Action))
+		if err != nil {
+			return nil, err
+		}
+		if err := translateAdminRule(npmNetPol, policies.Ingress, policies.SrcMatch, adminRule{target, rule.From, rule.Ports}); err != nil {
+			return nil, err
+		}
+	}
+	for _, rule := range banp.Spec.Egress {
+		target, err := adminTarget(policyv1alpha1.AdminNetworkPolicyRuleAction(rule.Action))
+		if err != nil {
+			return nil, err
+		}
+		if err := translateAdminRule(npmNetPol, policies.Egress, policies.DstMatch, adminRule{target, rule.To, rule.Ports}); err != nil {
+			return nil, err
+		}
+	}
+	return npmNetPol, nil
+}
+
+// adminTarget returns the ACL target for the action of a rule.
+// BaselineAdminNetworkPolicy actions are a subset of AdminNetworkPolicy actions.
+func adminTarget(action policyv1alpha1.AdminNetworkPolicyRuleAction) (policies.Verdict, error) {
+	switch action {
+	case policyv1alpha1.AdminNetworkPolicyRuleActionAllow:
+		return policies.Allowed, nil
+	case policyv1alpha1.AdminNetworkPolicyRuleActionDeny:
+		return policies.Dropped, nil
+	case policyv1alpha1.AdminNetworkPolicyRuleActionPass:
+		return policies.Passed, nil
+	default:
+		return "", fmt.Errorf("%w: %s", errUnknownAdminPolicyAction, action)
+	}
+}
+
+// adminSubject translates the subject of an admin network policy to the pod selector IPSets of npmNetPol.
+// A subject selects either namespaces or pods in namespaces.
+func adminSubject(npmNetPol *policies.NPMNetworkPolicy, subject *policyv1alpha1.AdminNetworkPolicySubject) error {
+	nsSelector := subject.Namespaces
+	if subject.Pods != nil {
+		nsSelector = &subject.Pods.NamespaceSelector
+	}
+	if nsSelector == nil {
+		// all namespaces
+		nsSelector = &metav1.LabelSelector{}
+	}
+
+	flattenNSSelector, err := flattenNameSpaceSelector(nsSelector)
+	if err != nil {
+		return err
+	}
+	if len(flattenNSSelector) != 1 {
+		return ErrUnsupportedSubjectNamespaceSelector
+	}
+	nsSelectorIPSets, nsSelectorList := nameSpaceSelector(policies.EitherMatch, &flattenNSSelector[0])
+	npmNetPol.PodSelectorIPSets = append(npmNetPol.PodSelectorIPSets, nsSelectorIPSets...)
+	npmNetPol.PodSelectorList = append(npmNetPol.PodSelectorList, nsSelectorList...)
+
+	if subject.Pods != nil {
+		psResult, err := podSelector(npmNetPol.PolicyKey, policies.EitherMatch, &subject.Pods.PodSelector)
+		if err != nil {
+			return err
+		}
+		npmNetPol.PodSelectorIPSets = append(npmNetPol.PodSelectorIPSets, psResult.psSets...)
+		npmNetPol.ChildPodSelectorIPSets = append(npmNetPol.ChildPodSelectorIPSets, psResult.childPSSets...)
+		npmNetPol.PodSelectorList = append(npmNetPol.PodSelectorList, psResult.psList...)
+	}
+	return nil
+}
+
+// translateAdminRule adds ACLs for the rule to npmNetPol. Unlike a NetworkPolicy, there's no default drop ACL
+// since a flow which no rule matches is decided by the next policy.
+func translateAdminRule(npmNetPol *policies.NPMNetworkPolicy, direction policies.Direction, matchType policies.MatchType, rule adminRule) error {
+	for _, peer := range rule.peers {
+		var namespaces *policyv1alpha1.NamespacedPeer
+		var podSelectorList []policies.SetInfo
+		switch {
+		case peer.Namespaces != nil:
+			namespaces = peer.Namespaces
+		case peer.Pods != nil:
+			namespaces = &peer.Pods.Namespaces
+			psResult, err := podSelector(npmNetPol.PolicyKey, matchType, &peer.Pods.PodSelector)
+			if err != nil {
+				return err
+			}
+			npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, psResult.psSets...)
+			npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, psResult.childPSSets...)
+			podSelectorList = psResult.psList
+		default:
+			continue
+		}
+
+		if len(namespaces.SameLabels) > 0 || len(namespaces.NotSameLabels) > 0 {
+			return ErrUnsupportedSameLabels
+		}
+		nsSelector := namespaces.NamespaceSelector
+		if nsSelector == nil {
+			nsSelector = &metav1.LabelSelector{}
+		}
+
+		// Before translating NamespaceSelector, flattenNameSpaceSelector function call should be called
+		// to handle multiple values in matchExpressions spec.
+		flattenNSSelector, err := flattenNameSpaceSelector(nsSelector)
+		if err != nil {
+			return err
+		}
+		for i := range flattenNSSelector {
+			nsSelectorIPSets, nsSelectorList := nameSpaceSelector(matchType, &flattenNSSelector[i])
+			npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, nsSelectorIPSets...)
+			nsSelectorList = append(nsSelectorList, podSelectorList...)
+			adminPeerAndPortRule(npmNetPol, direction, rule, nsSelectorList)
+		}
+	}
+	return nil
+}
+
+// adminPeerAndPortRule adds an ACL for the peer and each port of the rule (or one ACL if there are no ports).
+func adminPeerAndPortRule(npmNetPol *policies.NPMNetworkPolicy, direction policies.Direction, rule adminRule, setInfo []policies.SetInfo) {
+	if rule.ports == nil || len(*rule.ports) == 0 {
+		acl := policies.NewACLPolicy(rule.target, direction)
+		acl.AddSetInfo(setInfo)
+		npmNetPol.ACLs = append(npmNetPol.ACLs, acl)
+		return
+	}
+
+	for i := range *rule.ports {
+		acl := policies.NewACLPolicy(rule.target, direction)
+		acl.AddSetInfo(setInfo)
+		npmNetPol.RuleIPSets = adminPortRule(npmNetPol.RuleIPSets, acl, &(*rule.ports)[i])
+		npmNetPol.ACLs = append(npmNetPol.ACLs, acl)
+	}
+}
+
+// adminPortRule sets the protocol and destination port(s) of the ACL.
+// A named port is matched with a NamedPorts IPSet, which also has the protocol.
+func adminPortRule(ruleIPSets []*ipsets.TranslatedIPSet, acl *policies.ACLPolicy, port *policyv1alpha1.AdminNetworkPolicyPort) []*ipsets.TranslatedIPSet {
+	switch {
+	case port.PortNumber != nil:
+		acl.Protocol = policies.TCP
+		if port.PortNumber.Protocol != "" {
+			acl.Protocol = policies.Protocol(port.PortNumber.Protocol)
+		}
+		acl.DstPorts = policies.Ports{Port: port.PortNumber.Port}
+	case port.PortRange != nil:
+		acl.Protocol = policies.TCP
+		if port.PortRange.Protocol != "" {
+			acl.Protocol = policies.Protocol(port.PortRange.Protocol)
+		}
+		acl.DstPorts = policies.Ports{Port: port.PortRange.Start, EndPort: port.PortRange.End}
+	case port.NamedPort != nil:
+		acl.AddSetInfo([]policies.SetInfo{policies.NewSetInfo(*port.NamedPort, ipsets.NamedPorts, included, policies.DstDstMatch)})
+		ruleIPSets = append(ruleIPSets, ipsets.NewTranslatedIPSet(*port.NamedPort, ipsets.NamedPorts))
+	}
+	return ruleIPSets
+}
@@ -0,0 +1,283 @@
+package translation
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	policyv1alpha1 "sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+func TestTranslateAdminNetworkPolicy(t *testing.T) {
+	namedPort := namedPortStr
+	tests := []struct {
+		name      string
+		spec      policyv1alpha1.AdminNetworkPolicySpec
+		npmNetPol *policies.NPMNetworkPolicy
+		wantErr   error
+	}{
+		{
+			name: "namespace subject with allow, deny, and pass rules",
+			spec: policyv1alpha1.AdminNetworkPolicySpec{
+				Priority: 10,
+				Subject: policyv1alpha1.AdminNetworkPolicySubject{
+					Namespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+				},
+				Ingress: []policyv1alpha1.AdminNetworkPolicyIngressRule{
+					{
+						Action: policyv1alpha1.AdminNetworkPolicyRuleActionAllow,
+						From: []policyv1alpha1.AdminNetworkPolicyPeer{
+							{Namespaces: &policyv1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}}}},
+						},
+					},
+					{
+						Action: policyv1alpha1.AdminNetworkPolicyRuleActionDeny,
+						From: []policyv1alpha1.AdminNetworkPolicyPeer{
+							{Namespaces: &policyv1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{}}},
+						},
+					},
+				},
+				Egress: []policyv1alpha1.AdminNetworkPolicyEgressRule{
+					{
+						Action: policyv1alpha1.AdminNetworkPolicyRuleActionPass,
+						To: []policyv1alpha1.AdminNetworkPolicyPeer{
+							{Namespaces: &policyv1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}}}},
+						},
+					},
+				},
+			},
+			npmNetPol: &policies.NPMNetworkPolicy{
+				PolicyKey:   "AdminNetworkPolicy/anp",
+				ACLPolicyID: "",
+				Tier:        policies.AdminTier,
+				Priority:    10,
+				PodSelectorIPSets: []*ipsets.TranslatedIPSet{
+					ipsets.NewTranslatedIPSet("team:a", ipsets.KeyValueLabelOfNamespace),
+				},
+				PodSelectorList: []policies.SetInfo{
+					policies.NewSetInfo("team:a", ipsets.KeyValueLabelOfNamespace, included, policies.EitherMatch),
+				},
+				RuleIPSets: []*ipsets.TranslatedIPSet{
+					ipsets.NewTranslatedIPSet("team:b", ipsets.KeyValueLabelOfNamespace),
+					ipsets.NewTranslatedIPSet(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace),
+					ipsets.NewTranslatedIPSet("team:b", ipsets.KeyValueLabelOfNamespace),
+				},
+				ACLs: []*policies.ACLPolicy{
+					{
+						Target:    policies.Allowed,
+						Direction: policies.Ingress,
+						SrcList: []policies.SetInfo{
+							policies.NewSetInfo("team:b", ipsets.KeyValueLabelOfNamespace, included, policies.SrcMatch),
+						},
+					},
+					{
+						Target:    policies.Dropped,
+						Direction: policies.Ingress,
+						SrcList: []policies.SetInfo{
+							policies.NewSetInfo(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace, included, policies.SrcMatch),
+						},
+					},
+					{
+						Target:    policies.Passed,
+						Direction: policies.Egress,
+						DstList: []policies.SetInfo{
+							policies.NewSetInfo("team:b", ipsets.KeyValueLabelOfNamespace, included, policies.DstMatch),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "pod subject and pod peer with ports",
+			spec: policyv1alpha1.AdminNetworkPolicySpec{
+				Priority: 20,
+				Subject: policyv1alpha1.AdminNetworkPolicySubject{
+					Pods: &policyv1alpha1.NamespacedPodSubject{
+						PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+					},
+				},
+				Ingress: []policyv1alpha1.AdminNetworkPolicyIngressRule{
+					{
+						Action: policyv1alpha1.AdminNetworkPolicyRuleActionAllow,
+						From: []policyv1alpha1.AdminNetworkPolicyPeer{
+							{
+								Pods: &policyv1alpha1.NamespacedPodPeer{
+									Namespaces:  policyv1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}}},
+									PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "client"}},
+								},
+							},
+						},
+						Ports: &[]policyv1alpha1.AdminNetworkPolicyPort{
+							{PortNumber: &policyv1alpha1.Port{Protocol: v1.ProtocolUDP, Port: 53}},
+							{PortRange: &policyv1alpha1.PortRange{Start: 8080, End: 8090}},
+							{NamedPort: &namedPort},
+						},
+					},
+				},
+			},
+			npmNetPol: &policies.NPMNetworkPolicy{
+				PolicyKey:   "AdminNetworkPolicy/anp",
+				ACLPolicyID: "",
+				Tier:        policies.AdminTier,
+				Priority:    20,
+				PodSelectorIPSets: []*ipsets.TranslatedIPSet{
+					ipsets.NewTranslatedIPSet(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace),
+					ipsets.NewTranslatedIPSet("app:web", ipsets.KeyValueLabelOfPod),
+				},
+				PodSelectorList: []policies.SetInfo{
+					policies.NewSetInfo(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace, included, policies.EitherMatch),
+					policies.NewSetInfo("app:web", ipsets.KeyValueLabelOfPod, included, policies.EitherMatch),
+				},
+				RuleIPSets: []*ipsets.TranslatedIPSet{
+					ipsets.NewTranslatedIPSet("app:client", ipsets.KeyValueLabelOfPod),
+					ipsets.NewTranslatedIPSet("team:b", ipsets.KeyValueLabelOfNamespace),
+					ipsets.NewTranslatedIPSet(namedPortStr, ipsets.NamedPorts),
+				},
+				ACLs: []*policies.ACLPolicy{
+					{
+						Target:    policies.Allowed,
+						Direction: policies.Ingress,
+						SrcList: []policies.SetInfo{
+							policies.NewSetInfo("team:b", ipsets.KeyValueLabelOfNamespace, included, policies.SrcMatch),
+							policies.NewSetInfo("app:client", ipsets.KeyValueLabelOfPod, included, policies.SrcMatch),
+						},
+						DstPorts: policies.Ports{Port: 53},
+						Protocol: policies.UDP,
+					},
+					{
+						Target:    policies.Allowed,
+						Direction: policies.Ingress,
+						SrcList: []policies.SetInfo{
+							policies.NewSetInfo("team:b", ipsets.KeyValueLabelOfNamespace, included, policies.SrcMatch),
+							policies.NewSetInfo("app:client", ipsets.KeyValueLabelOfPod, included, policies.SrcMatch),
+						},
+						DstPorts: policies.Ports{Port: 8080, EndPort: 8090},
+						Protocol: policies.TCP,
+					},
+					{
+						Target:    policies.Allowed,
+						Direction: policies.Ingress,
+						SrcList: []policies.SetInfo{
+							policies.NewSetInfo("team:b", ipsets.KeyValueLabelOfNamespace, included, policies.SrcMatch),
+							policies.NewSetInfo("app:client", ipsets.KeyValueLabelOfPod, included, policies.SrcMatch),
+						},
+						DstList: []policies.SetInfo{
+							policies.NewSetInfo(namedPortStr, ipsets.NamedPorts, included, policies.DstDstMatch),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "peer with sameLabels",
+			spec: policyv1alpha1.AdminNetworkPolicySpec{
+				Subject: policyv1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+				Ingress: []policyv1alpha1.AdminNetworkPolicyIngressRule{
+					{
+						Action: policyv1alpha1.AdminNetworkPolicyRuleActionAllow,
+						From: []policyv1alpha1.AdminNetworkPolicyPeer{
+							{Namespaces: &policyv1alpha1.NamespacedPeer{SameLabels: []string{"tenant"}}},
+						},
+					},
+				},
+			},
+			wantErr: ErrUnsupportedSameLabels,
+		},
+		{
+			name: "subject with multiple namespace values",
+			spec: policyv1alpha1.AdminNetworkPolicySpec{
+				Subject: policyv1alpha1.AdminNetworkPolicySubject{
+					Namespaces: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: "team", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+						},
+					},
+				},
+			},
+			wantErr: ErrUnsupportedSubjectNamespaceSelector,
+		},
+		{
+			name: "unknown action",
+			spec: policyv1alpha1.AdminNetworkPolicySpec{
+				Subject: policyv1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+				Egress: []policyv1alpha1.AdminNetworkPolicyEgressRule{
+					{Action: "Log"},
+				},
+			},
+			wantErr: errUnknownAdminPolicyAction,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			anp := &policyv1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "anp"},
+				Spec:       tt.spec,
+			}
+			npmNetPol, err := TranslateAdminNetworkPolicy(anp)
+			if util.IsWindowsDP() {
+				require.ErrorIs(t, err, ErrUnsupportedAdminNetworkPolicy)
+				return
+			}
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.npmNetPol, npmNetPol)
+		})
+	}
+}
+
+func TestTranslateBaselineAdminNetworkPolicy(t *testing.T) {
+	banp := &policyv1alpha1.BaselineAdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: policyv1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: policyv1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+			Ingress: []policyv1alpha1.BaselineAdminNetworkPolicyIngressRule{
+				{
+					Action: policyv1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+					From: []policyv1alpha1.AdminNetworkPolicyPeer{
+						{Namespaces: &policyv1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{}}},
+					},
+				},
+			},
+		},
+	}
+	npmNetPol, err := TranslateBaselineAdminNetworkPolicy(banp)
+	if util.IsWindowsDP() {
+		require.ErrorIs(t, err, ErrUnsupportedAdminNetworkPolicy)
+		return
+	}
+	require.NoError(t, err)
+
+	expected := &policies.NPMNetworkPolicy{
+		PolicyKey:   BaselineAdminNetworkPolicyKey("default"),
+		ACLPolicyID: "",
+		Tier:        policies.BaselineAdminTier,
+		PodSelectorIPSets: []*ipsets.TranslatedIPSet{
+			ipsets.NewTranslatedIPSet(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace),
+		},
+		PodSelectorList: []policies.SetInfo{
+			policies.NewSetInfo(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace, included, policies.EitherMatch),
+		},
+		RuleIPSets: []*ipsets.TranslatedIPSet{
+			ipsets.NewTranslatedIPSet(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace),
+		},
+		ACLs: []*policies.ACLPolicy{
+			{
+				Target:    policies.Dropped,
+				Direction: policies.Ingress,
+				SrcList: []policies.SetInfo{
+					policies.NewSetInfo(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace, included, policies.SrcMatch),
+				},
+			},
+		},
+	}
+	require.Equal(t, expected, npmNetPol)
+}
@@ -32,10 +32,16 @@ var (
 	ErrInvalidMatchExpressionValues = errors.New(
 		"matchExpression label values must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character",
 	)
-	// ErrUnsupportedIPAddress is returned when an unsupported IP address, such as IPV6, is used
+	// ErrUnsupportedIPAddress is returned when an unsupported IP address, such as IPV6 in Windows, is used
 	ErrUnsupportedIPAddress = errors.New("unsupported IP address")
 )
 
+// splitCIDRsForAllIPs has the halves of the CIDRs which contain all IPs, since ipset doesn't allow a prefix length of 0.
+var splitCIDRsForAllIPs = map[string][]string{
+	"0.0.0.0/0": {"0.0.0.0/1", "128.0.0.0/1"},
+	"::/0":      {"::/1", "8000::/1"},
+}
+
 type podSelectorResult struct {
 	psSets      []*ipsets.TranslatedIPSet
 	childPSSets []*ipsets.TranslatedIPSet
@@ -183,13 +189,13 @@ func ipBlockIPSet(policyName, ns string, direction policies.Direction, ipBlockSe
 	// A solution is split 0.0.0.0/0 in half which convert to 0.0.0.0/1 and 128.0.0.0/1.
 	// splitCIDRSet is used to handle case where IPBlock has "0.0.0.0/0" in CIDR and "0.0.0.0/1" or "128.0.0.0/1"  in Except.
 	// splitCIDRSet has two entries ("0.0.0.0/1" and "128.0.0.0/1") as key.
+	// IPv6's "::/0" is split the same way into "::/1" and "8000::/1".
 	splitCIDRLen := 2
 	splitCIDRSet := make(map[string]int, splitCIDRLen)
-	if ipBlockRule.CIDR == "0.0.0.0/0" {
-		// two cidrs (0.0.0.0/1 and 128.0.0.0/1) for 0.0.0.0/0 + except.
+	if splitCIDRs, ok := splitCIDRsForAllIPs[ipBlockRule.CIDR]; ok {
+		// two cidrs (e.g. 0.0.0.0/1 and 128.0.0.0/1) for 0.0.0.0/0 + except.
 		members = make([]string, lenOfDeDupExcepts+splitCIDRLen)
 		// in case of "0.0.0.0/0", "0.0.0.0/1" or "0.0.0.0/1 nomatch" comes eariler than "128.0.0.0/1" or "128.0.0.0/1 nomatch".
-		splitCIDRs := []string{"0.0.0.0/1", "128.0.0.0/1"}
 		for _, cidr := range splitCIDRs {
 			members[indexOfMembers] = cidr
 			splitCIDRSet[cidr] = indexOfMembers
@@ -227,7 +233,8 @@ func ipBlockRule(policyName, ns string, direction policies.Direction, matchType
 		return nil, policies.SetInfo{}, nil
 	}
 
-	if !util.IsIPV4(ipBlockRule.CIDR) {
+	// IPv6 CIDRs are only programmed on dual-stack Linux nodes, and otherwise are ignored by the dataplane
+	if !util.IsIPV4(ipBlockRule.CIDR) && (util.IsWindowsDP() || !util.IsIPV6(ipBlockRule.CIDR)) {
 		return nil, policies.SetInfo{}, ErrUnsupportedIPAddress
 	}
 
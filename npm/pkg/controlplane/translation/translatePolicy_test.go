@@ -542,11 +542,32 @@ func TestIPBlockRule(t *testing.T) {
 			skipWindows:     true,
 		},
 		{
-			name:        "invalid ipv6",
+			name:        "ipv6",
 			ipBlockInfo: createIPBlockInfo("test", defaultNS, policies.Ingress, policies.SrcMatch, 0, 0),
 			ipBlockRule: &networkingv1.IPBlock{
 				CIDR: "2002::1234:abcd:ffff:c0a8:101/64",
 			},
+			translatedIPSet: ipsets.NewTranslatedIPSet("test-in-ns-default-0-0IN", ipsets.CIDRBlocks, []string{"2002::1234:abcd:ffff:c0a8:101/64"}...),
+			setInfo:         policies.NewSetInfo("test-in-ns-default-0-0IN", ipsets.CIDRBlocks, included, policies.SrcMatch),
+			skipWindows:     true,
+		},
+		{
+			name:        "all ipv6 with except",
+			ipBlockInfo: createIPBlockInfo("test", defaultNS, policies.Ingress, policies.SrcMatch, 0, 0),
+			ipBlockRule: &networkingv1.IPBlock{
+				CIDR:   "::/0",
+				Except: []string{"8000::/1", "fd00::/8"},
+			},
+			translatedIPSet: ipsets.NewTranslatedIPSet("test-in-ns-default-0-0IN", ipsets.CIDRBlocks, []string{"::/1", "8000::/1 nomatch", "fd00::/8 nomatch"}...),
+			setInfo:         policies.NewSetInfo("test-in-ns-default-0-0IN", ipsets.CIDRBlocks, included, policies.SrcMatch),
+			skipWindows:     true,
+		},
+		{
+			name:        "invalid ipv6",
+			ipBlockInfo: createIPBlockInfo("test", defaultNS, policies.Ingress, policies.SrcMatch, 0, 0),
+			ipBlockRule: &networkingv1.IPBlock{
+				CIDR: "2002::1234:abcd:ffff:c0a8:101/129",
+			},
 			translatedIPSet: nil,
 			setInfo:         policies.SetInfo{},
 			wantErr:         true,
@@ -17,6 +17,8 @@ import (
 
 const (
 	reconcileDuration = time.Duration(5 * time.Minute)
+	// policyTrafficDuration is how often the ACL counters are exported when PolicyManagerCfg.Counters is true
+	policyTrafficDuration = time.Duration(1 * time.Minute)
 
 	contextBackground      = "BACKGROUND"
 	contextApplyDP         = "APPLY-DP"
@@ -166,6 +168,23 @@ func (dp *DataPlane) RunPeriodicTasks() {
 		}
 	}()
 
+	if dp.Counters {
+		go func() {
+			ticker := time.NewTicker(policyTrafficDuration)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-dp.stopChannel:
+					return
+				case <-ticker.C:
+					// in Windows, does nothing
+					dp.policyMgr.RecordPolicyTraffic()
+				}
+			}
+		}()
+	}
+
 	if dp.netPolInBackground {
 		go func() {
 			ticker := time.NewTicker(dp.NetPolInterval)
@@ -239,6 +258,11 @@ func (dp *DataPlane) DeleteIPSet(setMetadata *ipsets.IPSetMetadata, forceDelete
 // AddToSets takes in a list of IPSet names along with IP member
 // and then updates it local cache
 func (dp *DataPlane) AddToSets(setNames []*ipsets.IPSetMetadata, podMetadata *PodMetadata) error {
+	if dp.ipsetMgr.IgnoresMember(podMetadata.PodIP) {
+		// there are no sets or policies for this IP family
+		return nil
+	}
+
 	err := dp.ipsetMgr.AddToSets(setNames, podMetadata.PodIP, podMetadata.PodKey)
 	if err != nil {
 		return fmt.Errorf("[DataPlane] error while adding to set: %w", err)
@@ -261,6 +285,11 @@ func (dp *DataPlane) AddToSets(setNames []*ipsets.IPSetMetadata, podMetadata *Po
 // RemoveFromSets takes in list of setnames from which a given IP member should be
 // removed and will update the local cache
 func (dp *DataPlane) RemoveFromSets(setNames []*ipsets.IPSetMetadata, podMetadata *PodMetadata) error {
+	if dp.ipsetMgr.IgnoresMember(podMetadata.PodIP) {
+		// there are no sets or policies for this IP family
+		return nil
+	}
+
 	err := dp.ipsetMgr.RemoveFromSets(setNames, podMetadata.PodIP, podMetadata.PodKey)
 	if err != nil {
 		return fmt.Errorf("[DataPlane] error while removing from set: %w", err)
@@ -632,6 +661,12 @@ func (dp *DataPlane) UpdatePolicy(policy *policies.NPMNetworkPolicy) error {
 	return nil
 }
 
+// PolicyKeyByHash returns the key of the policy whose util.Hash() is hash.
+// It's used by policy audit, which is why it isn't part of GenericDataplane.
+func (dp *DataPlane) PolicyKeyByHash(hash string) (string, bool) {
+	return dp.policyMgr.PolicyKeyByHash(hash)
+}
+
 func (dp *DataPlane) GetAllIPSets() map[string]string {
 	return dp.ipsetMgr.GetAllIPSets()
 }
@@ -160,9 +160,13 @@ func TestAddToSet(t *testing.T) {
 	require.NoError(t, err)
 
 	v6PodMetadata := NewPodMetadata("testns/a", "2001:db8:0:0:0:0:2:1", nodeName)
-	// Test IPV6 addess it should error out
+	// IPV6 addresses are ignored without dual-stack
 	err = dp.AddToSets(setsTocreate, v6PodMetadata)
-	require.Error(t, err)
+	require.NoError(t, err)
+	for _, v := range setsTocreate {
+		_, ok := dp.ipsetMgr.GetIPSet(v.GetPrefixName()).IPPodKey[v6PodMetadata.PodIP]
+		require.False(t, ok)
+	}
 
 	for _, v := range setsTocreate {
 		dp.DeleteIPSet(v, util.SoftDelete)
@@ -178,7 +182,7 @@ func TestAddToSet(t *testing.T) {
 	require.NoError(t, err)
 
 	err = dp.RemoveFromSets(setsTocreate, v6PodMetadata)
-	require.Error(t, err)
+	require.NoError(t, err)
 
 	for _, v := range setsTocreate {
 		dp.DeleteIPSet(v, util.SoftDelete)
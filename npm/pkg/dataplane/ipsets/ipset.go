@@ -3,6 +3,7 @@ package ipsets
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/npm/metrics"
@@ -25,6 +26,27 @@ const (
 	UnknownKind SetKind = "unknown"
 )
 
+// IPv6SetSuffix names the inet6 twin of a kernel set on a dual-stack Linux node.
+// A set can't mix IP families, so IPv4 members are in <hashed name> and IPv6 members are in <hashed name>-6.
+const IPv6SetSuffix = "-6"
+
+// IPv6SetName returns the name of the inet6 twin of the kernel set with the given hashed name.
+func IPv6SetName(hashedName string) string {
+	return hashedName + IPv6SetSuffix
+}
+
+// IsIPv6Member returns whether a hash set member like fd00::1, fd00::/64 nomatch, or fd00::1,tcp:80 is IPv6.
+func IsIPv6Member(member string) bool {
+	return util.IsIPV6(memberIP(member))
+}
+
+// memberIP returns the IP or CIDR of a hash set member, without the port or nomatch option.
+func memberIP(member string) string {
+	ipDetails := strings.Split(member, ",")
+	ipField := strings.Split(ipDetails[0], " ")
+	return ipField[0]
+}
+
 // NewIPSetMetadata is used for controllers to send in skeleton ipsets to DP
 func NewIPSetMetadata(name string, setType SetType) *IPSetMetadata {
 	set := &IPSetMetadata{
@@ -2,7 +2,6 @@ package ipsets
 
 import (
 	"fmt"
-	"strings"
 	"sync"
 
 	"github.com/Azure/azure-container-networking/common"
@@ -62,6 +61,11 @@ type IPSetManagerCfg struct {
 	// This is necessary for HNS (Windows); otherwise, an allow ACL with a list condition
 	// allows all IPs if the list has no members.
 	AddEmptySetToLists bool
+	// Nftables only affects Linux. It programs ipsets as nftables sets instead of with ipset.
+	Nftables bool
+	// DualStack only affects Linux. It programs IPv6 members into an inet6 twin of each set.
+	// Otherwise, IPv6 members are ignored.
+	DualStack bool
 }
 
 func NewIPSetManager(iMgrCfg *IPSetManagerCfg, ioShim *common.IOShim) *IPSetManager {
@@ -244,6 +248,11 @@ func (iMgr *IPSetManager) AddToSets(addToSets []*IPSetMetadata, ip, podKey strin
 		return npmerrors.Errorf(npmerrors.AppendIPSet, true, msg)
 	}
 
+	if iMgr.IgnoresMember(ip) {
+		klog.Infof("[IPSetManager] ignoring add of IPv6 member %s since dual-stack is disabled", ip)
+		return nil
+	}
+
 	iMgr.Lock()
 	defer iMgr.Unlock()
 
@@ -280,6 +289,10 @@ func (iMgr *IPSetManager) RemoveFromSets(removeFromSets []*IPSetMetadata, ip, po
 		return npmerrors.Errorf(npmerrors.AppendIPSet, true, msg)
 	}
 
+	if iMgr.IgnoresMember(ip) {
+		return nil
+	}
+
 	iMgr.Lock()
 	defer iMgr.Unlock()
 
@@ -583,6 +596,12 @@ func (iMgr *IPSetManager) clearDirtyCache() {
 	iMgr.dirtyCache.reset()
 }
 
+// IgnoresMember returns true for IPv6 members unless the IPSetManager programs dual-stack sets.
+// Dual-stack is only supported in Linux.
+func (iMgr *IPSetManager) IgnoresMember(member string) bool {
+	return (!iMgr.iMgrCfg.DualStack || util.IsWindowsDP()) && IsIPv6Member(member)
+}
+
 // validateIPSetMemberIP helps valid if a member added to an HashSet has valid IP or CIDR
 func validateIPSetMemberIP(ip string) bool {
 	// possible formats
@@ -592,9 +611,8 @@ func validateIPSetMemberIP(ip string) bool {
 	// 192.168.0.0/24
 	// 192.168.0.0/24,tcp:25227
 	// 192.168.0.0/24 nomatch
+	// fd00::1, fd00::1,tcp:25227, etc. for IPv6
 	// always guaranteed to have ip, not guaranteed to have port + protocol
-	ipDetails := strings.Split(ip, ",")
-	ipField := strings.Split(ipDetails[0], " ")
-
-	return util.IsIPV4(ipField[0])
+	ipField := memberIP(ip)
+	return util.IsIPV4(ipField) || util.IsIPV6(ipField)
 }
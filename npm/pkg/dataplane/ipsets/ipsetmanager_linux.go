@@ -39,6 +39,8 @@ const (
 	ipsetIPPortHashFlag = "hash:ip,port"
 	ipsetMaxelemName    = "maxelem"
 	ipsetMaxelemNum     = "4294967295"
+	ipsetFamilyName     = "family"
+	ipsetInet6Family    = "inet6"
 
 	// constants for parsing ipset save
 	createStringWithSpace = "create "
@@ -97,6 +99,14 @@ var (
 		If a flush fails, we could update the num entries for that set, but that would be a lot of overhead.
 */
 func (iMgr *IPSetManager) resetIPSets() error {
+	if iMgr.iMgrCfg.Nftables {
+		return iMgr.resetNftSets()
+	}
+	return iMgr.resetKernelIPSets()
+}
+
+// resetKernelIPSets flushes and destroys the NPM ipsets in the kernel as described above.
+func (iMgr *IPSetManager) resetKernelIPSets() error {
 	if success := iMgr.resetWithoutRestore(); success {
 		return nil
 	}
@@ -403,8 +413,23 @@ example where:
 		-F set4
 		-X set5
 		-X set4
+
+In dual-stack mode, each set has an IPv6 twin named <set>-6, which is created, flushed, and destroyed alongside the set.
+Hash sets send IPv6 members to their twin, and lists add the twins of their members to their own twin:
+		-N set1 --exist nethash
+		-N set1-6 --exist nethash family inet6
+		-N list2 --exist setlist
+		-N list2-6 --exist setlist
+		-A set1 10.0.0.1
+		-A set1-6 fd00::1
+		-A list2 set1
+		-A list2-6 set1-6
 */
 func (iMgr *IPSetManager) applyIPSets() error {
+	if iMgr.iMgrCfg.Nftables {
+		return iMgr.applyNftSets()
+	}
+
 	creator := iMgr.fileCreatorForApply(maxTryCount)
 	restoreError := creator.RunCommandWithFile(ipsetCommand, ipsetRestoreFlag)
 	if restoreError != nil {
@@ -703,6 +728,9 @@ func (iMgr *IPSetManager) flushSetForApply(creator *ioutil.FileCreator, prefixed
 	sectionID := sectionID(destroySectionPrefix, prefixedName)
 	hashedName := util.GetHashedName(prefixedName)
 	creator.AddLine(sectionID, errorHandlers, ipsetFlushFlag, hashedName) // flush set
+	if iMgr.iMgrCfg.DualStack {
+		creator.AddLine(sectionID, errorHandlers, ipsetFlushFlag, IPv6SetName(hashedName)) // flush IPv6 twin
+	}
 }
 
 func (iMgr *IPSetManager) destroySetForApply(creator *ioutil.FileCreator, prefixedName string) {
@@ -726,6 +754,9 @@ func (iMgr *IPSetManager) destroySetForApply(creator *ioutil.FileCreator, prefix
 	sectionID := sectionID(destroySectionPrefix, prefixedName)
 	hashedName := util.GetHashedName(prefixedName)
 	creator.AddLine(sectionID, errorHandlers, ipsetDestroyFlag, hashedName) // destroy set
+	if iMgr.iMgrCfg.DualStack {
+		creator.AddLine(sectionID, errorHandlers, ipsetDestroyFlag, IPv6SetName(hashedName)) // destroy IPv6 twin
+	}
 }
 
 func (iMgr *IPSetManager) createSetForApply(creator *ioutil.FileCreator, set *IPSet) {
@@ -741,6 +772,15 @@ func (iMgr *IPSetManager) createSetForApply(creator *ioutil.FileCreator, set *IP
 		specs = append(specs, ipsetMaxelemName, ipsetMaxelemNum)
 	}
 
+	// the IPv6 twin of a list holds the IPv6 twins of the list's members, so only hash sets need a family
+	ipv6Specs := []string{ipsetCreateFlag, IPv6SetName(set.HashedName), ipsetExistFlag, methodFlag}
+	if set.Kind == HashSet {
+		ipv6Specs = append(ipv6Specs, ipsetFamilyName, ipsetInet6Family)
+	}
+	if set.Type == CIDRBlocks {
+		ipv6Specs = append(ipv6Specs, ipsetMaxelemName, ipsetMaxelemNum)
+	}
+
 	prefixedName := set.Name // to appease golint complaints about function literal
 	errorHandlers := []*ioutil.LineErrorHandler{
 		{
@@ -762,6 +802,9 @@ func (iMgr *IPSetManager) createSetForApply(creator *ioutil.FileCreator, set *IP
 	}
 	sectionID := sectionID(addOrUpdateSectionPrefix, prefixedName)
 	creator.AddLine(sectionID, errorHandlers, specs...) // create set
+	if iMgr.iMgrCfg.DualStack {
+		creator.AddLine(sectionID, errorHandlers, ipv6Specs...) // create IPv6 twin
+	}
 }
 
 func (iMgr *IPSetManager) deleteMemberForApply(creator *ioutil.FileCreator, set *IPSet, sectionID, member string) {
@@ -774,7 +817,10 @@ func (iMgr *IPSetManager) deleteMemberForApply(creator *ioutil.FileCreator, set
 			},
 		},
 	}
-	creator.AddLine(sectionID, errorHandlers, ipsetDeleteFlag, set.HashedName, member) // delete member
+	creator.AddLine(sectionID, errorHandlers, ipsetDeleteFlag, kernelSetNameForMember(set, member), member) // delete member
+	if set.Kind == ListSet && iMgr.iMgrCfg.DualStack {
+		creator.AddLine(sectionID, errorHandlers, ipsetDeleteFlag, IPv6SetName(set.HashedName), IPv6SetName(member)) // delete IPv6 twin member
+	}
 }
 
 func (iMgr *IPSetManager) addMemberForApply(creator *ioutil.FileCreator, set *IPSet, sectionID, member string) {
@@ -808,7 +854,19 @@ func (iMgr *IPSetManager) addMemberForApply(creator *ioutil.FileCreator, set *IP
 			},
 		}
 	}
-	creator.AddLine(sectionID, errorHandlers, ipsetAddFlag, set.HashedName, member) // add member
+	creator.AddLine(sectionID, errorHandlers, ipsetAddFlag, kernelSetNameForMember(set, member), member) // add member
+	if set.Kind == ListSet && iMgr.iMgrCfg.DualStack {
+		creator.AddLine(sectionID, errorHandlers, ipsetAddFlag, IPv6SetName(set.HashedName), IPv6SetName(member)) // add IPv6 twin member
+	}
+}
+
+// kernelSetNameForMember returns the name of the set in the kernel which holds the member.
+// IPv6 members of a hash set belong to its IPv6 twin.
+func kernelSetNameForMember(set *IPSet, member string) string {
+	if set.Kind == HashSet && IsIPv6Member(member) {
+		return IPv6SetName(set.HashedName)
+	}
+	return set.HashedName
 }
 
 func sectionID(prefix, prefixedName string) string {
@@ -31,6 +31,12 @@ var (
 	resetIPSetsListOutputString = strings.Join(resetIPSetsNames, "\n") + "\n"
 	resetIPSetsListOutput       = []byte(resetIPSetsListOutputString)
 	otherIPSetsListOutput       = "azure-npm-123456\n"
+
+	dualStackCfg = &IPSetManagerCfg{
+		IPSetMode:   ApplyAllIPSets,
+		NetworkName: "azure",
+		DualStack:   true,
+	}
 )
 
 // TODO test that a reconcile list is updated for all the TestFailure UTs
@@ -992,6 +998,65 @@ func TestDeleteMembers(t *testing.T) {
 	require.False(t, wasFileAltered, "file should not be altered")
 }
 
+func TestDualStackApply(t *testing.T) {
+	calls := []testutils.TestCmd{fakeRestoreSuccessCommand}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	iMgr := NewIPSetManager(dualStackCfg, ioshim)
+
+	iMgr.CreateIPSets([]*IPSetMetadata{TestKVPodSet.Metadata}) // create so we can delete
+	iMgr.clearDirtyCache()
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "fd00::1", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNamedportSet.Metadata}, "fd00::1,tcp:8080", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestCIDRSet.Metadata}, "fd00::/64", ""))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestCIDRSet.Metadata}, "fd00::/80 nomatch", ""))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{TestKeyNSList.Metadata}, []*IPSetMetadata{TestNSSet.Metadata}))
+	iMgr.DeleteIPSet(TestKVPodSet.PrefixName, util.SoftDelete)
+
+	// each set has an IPv6 twin, and IPv6 members go to the twin
+	expectedLines := []string{
+		fmt.Sprintf("-N %s --exist nethash", TestNSSet.HashedName),
+		fmt.Sprintf("-N %s-6 --exist nethash family inet6", TestNSSet.HashedName),
+		fmt.Sprintf("-N %s --exist hash:ip,port", TestNamedportSet.HashedName),
+		fmt.Sprintf("-N %s-6 --exist hash:ip,port family inet6", TestNamedportSet.HashedName),
+		fmt.Sprintf("-N %s --exist nethash maxelem 4294967295", TestCIDRSet.HashedName),
+		fmt.Sprintf("-N %s-6 --exist nethash family inet6 maxelem 4294967295", TestCIDRSet.HashedName),
+		fmt.Sprintf("-N %s --exist setlist", TestKeyNSList.HashedName),
+		fmt.Sprintf("-N %s-6 --exist setlist", TestKeyNSList.HashedName),
+		fmt.Sprintf("-A %s 10.0.0.1", TestNSSet.HashedName),
+		fmt.Sprintf("-A %s-6 fd00::1", TestNSSet.HashedName),
+		fmt.Sprintf("-A %s-6 fd00::1,tcp:8080", TestNamedportSet.HashedName),
+		fmt.Sprintf("-A %s-6 fd00::/64", TestCIDRSet.HashedName),
+		fmt.Sprintf("-A %s-6 fd00::/80 nomatch", TestCIDRSet.HashedName),
+		fmt.Sprintf("-A %s %s", TestKeyNSList.HashedName, TestNSSet.HashedName),
+		fmt.Sprintf("-A %s-6 %s-6", TestKeyNSList.HashedName, TestNSSet.HashedName),
+		fmt.Sprintf("-F %s", TestKVPodSet.HashedName),
+		fmt.Sprintf("-F %s-6", TestKVPodSet.HashedName),
+		fmt.Sprintf("-X %s", TestKVPodSet.HashedName),
+		fmt.Sprintf("-X %s-6", TestKVPodSet.HashedName),
+		"",
+	}
+	sortedExpectedLines := testAndSortRestoreFileLines(t, expectedLines)
+	creator := iMgr.fileCreatorForApply(len(calls))
+	actualLines := testAndSortRestoreFileString(t, creator.ToString())
+	dptestutils.AssertEqualLines(t, sortedExpectedLines, actualLines)
+	wasFileAltered, err := creator.RunCommandOnceWithFile("ipset", "restore")
+	require.NoError(t, err, "ipset restore should be successful")
+	require.False(t, wasFileAltered, "file should not be altered")
+}
+
+func TestAddToSetsIgnoresIPv6WithoutDualStack(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	iMgr := NewIPSetManager(applyAlwaysCfg, ioshim)
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "fd00::1", "a"))
+	require.NoError(t, iMgr.RemoveFromSets([]*IPSetMetadata{TestNSSet.Metadata}, "fd00::1", "a"))
+	require.False(t, iMgr.exists(TestNSSet.PrefixName))
+}
+
 func TestUpdateWithIdenticalSaveFile(t *testing.T) {
 	calls := []testutils.TestCmd{fakeRestoreSuccessCommand}
 	ioshim := common.NewMockIOShim(calls)
@@ -686,7 +686,7 @@ func TestAddToSets(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name: "add IPv6",
+			name: "add IPv6 is ignored without dual-stack",
 			args: args{
 				cfg:               applyAlwaysCfg,
 				toCreateMetadatas: []*IPSetMetadata{namespaceSet},
@@ -701,7 +701,7 @@ func TestAddToSets(t *testing.T) {
 				toDeleteCache:    nil,
 				setsForKernel:    nil,
 			},
-			wantErr: true,
+			wantErr: false,
 		},
 		{
 			name: "add cidr",
@@ -1509,7 +1509,7 @@ func TestValidateIPSetMemberIP(t *testing.T) {
 		{
 			name:    "ipv6",
 			ipblock: "2345:0425:2CA1:0000:0000:0567:5673:23b5/24",
-			want:    false,
+			want:    true,
 		},
 		{
 			name:    "tcp",
@@ -1539,11 +1539,26 @@ func TestValidateIPSetMemberIP(t *testing.T) {
 		{
 			name:    "ipv6 tcp",
 			ipblock: "2345:0425:2CA1:0000:0000:0567:5673:23b5/24,tcp:25227",
-			want:    false,
+			want:    true,
 		},
 		{
 			name:    "ipv6 nomatch",
 			ipblock: "2345:0425:2CA1:0000:0000:0567:5673:23b5 nomatch",
+			want:    true,
+		},
+		{
+			name:    "ipv6 ip",
+			ipblock: "fd00::1",
+			want:    true,
+		},
+		{
+			name:    "ipv4-mapped ipv6",
+			ipblock: "::ffff:10.0.0.1",
+			want:    false,
+		},
+		{
+			name:    "invalid ipv6 cidr",
+			ipblock: "fd00::/129",
 			want:    false,
 		},
 		{
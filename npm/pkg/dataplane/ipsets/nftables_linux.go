@@ -0,0 +1,234 @@
+package ipsets
+
+// This file contains code for the nftables implementation of applying ipsets.
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/util"
+	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
+	"github.com/Azure/azure-container-networking/npm/util/ioutil"
+)
+
+const (
+	nftIPv4AddrType = "ipv4_addr"
+	nftIPv6AddrType = "ipv6_addr"
+	// a named port key type is the address type followed by this suffix
+	nftNamedPortKeySuffix = " . inet_proto . inet_service"
+)
+
+/*
+In nftables mode, each ipset is a named set in the NPM table, and changes to all of them are applied in one atomic nft transaction.
+
+nftables sets can't contain other sets, so a list is a set of the union of the IPs of its member sets.
+A list is rewritten whenever it or one of its members is dirty.
+
+CIDR sets can't express ipset's nomatch members either, so those are kept in a companion set named <hashed name>-except,
+which policies match with a negation alongside the CIDR set.
+Named port members like 10.0.0.1,tcp:80 are concatenations of the IP, protocol, and port.
+
+In dual-stack mode, IPv6 members are kept in an ipv6_addr twin of each set named <hashed name>-6, just like with ipset.
+
+example nft file where set1 is created or updated, list2 has set1 as a member, and set3 is deleted:
+
+	add table inet azure-npm
+	add set inet azure-npm set1 { type ipv4_addr ; }
+	flush set inet azure-npm set1
+	add element inet azure-npm set1 { 10.0.0.1, 10.0.0.2 }
+	add set inet azure-npm list2 { type ipv4_addr ; }
+	flush set inet azure-npm list2
+	add element inet azure-npm list2 { 10.0.0.1, 10.0.0.2, 10.0.0.3 }
+	delete set inet azure-npm set3
+
+Each dirty set is flushed and rewritten rather than diffed since the transaction is atomic, so there is never a moment where it is empty.
+*/
+func (iMgr *IPSetManager) applyNftSets() error {
+	creator := iMgr.fileCreatorForNftApply(maxTryCount)
+	if err := creator.RunCommandWithFile(util.Nft, util.NftFileFlag, util.NftStdin); err != nil {
+		return npmerrors.SimpleErrorWrapper("nft failed when applying ipsets", err)
+	}
+	return nil
+}
+
+// resetNftSets destroys the kernel ipsets left from when NPM last ran without nftables, after PolicyManager.Bootup has deleted the iptables
+// chains which referenced them. The nft sets live in the NPM table, which PolicyManager.Bootup recreates, so they need no reset.
+// Failures are only logged since the kernel ipsets aren't used in nftables mode.
+func (iMgr *IPSetManager) resetNftSets() error {
+	if err := iMgr.resetKernelIPSets(); err != nil {
+		metrics.SendErrorLogAndMetric(util.IpsmID, "failed to destroy kernel ipsets left from iptables mode. err: %s", err.Error())
+	}
+	return nil
+}
+
+func (iMgr *IPSetManager) fileCreatorForNftApply(maxTryCount int) *ioutil.FileCreator {
+	// a failed nft transaction changes nothing, so retry the whole file instead of skipping lines
+	creator := ioutil.NewFileCreator(iMgr.ioShim, maxTryCount)
+	creator.AddLine("", nil, util.NftAdd, util.NftTableObj, util.NftFamily, util.NftTable)
+
+	setsToAddOrUpdate := iMgr.dirtyCache.setsToAddOrUpdate()
+	setsToDelete := iMgr.dirtyCache.setsToDelete()
+	for _, prefixedName := range sortedNames(setsToAddOrUpdate) {
+		set := iMgr.setMap[prefixedName]
+		if set.Kind == HashSet {
+			iMgr.writeNftHashSet(creator, set)
+		}
+	}
+	for _, list := range iMgr.dirtyNftLists(setsToAddOrUpdate, setsToDelete) {
+		ipv4IPs, ipv6IPs := splitByFamily(listIPs(list))
+		writeNftSet(creator, list.HashedName, nftIPv4AddrType, false, ipv4IPs)
+		if iMgr.iMgrCfg.DualStack {
+			writeNftSet(creator, IPv6SetName(list.HashedName), nftIPv6AddrType, false, ipv6IPs)
+		}
+	}
+	for _, prefixedName := range sortedNames(setsToDelete) {
+		hashedName := util.GetHashedName(prefixedName)
+		deleteNftSet(creator, prefixedName, hashedName)
+		if iMgr.iMgrCfg.DualStack {
+			deleteNftSet(creator, prefixedName, IPv6SetName(hashedName))
+		}
+	}
+	return creator
+}
+
+// dirtyNftLists returns the lists in the kernel which are dirty or have a dirty member, sorted by name.
+// Lists don't track which lists they're members of, so this looks through all sets.
+func (iMgr *IPSetManager) dirtyNftLists(setsToAddOrUpdate, setsToDelete map[string]struct{}) []*IPSet {
+	lists := make([]*IPSet, 0)
+	for prefixedName, set := range iMgr.setMap {
+		if set.Kind != ListSet {
+			continue
+		}
+		if _, ok := setsToDelete[prefixedName]; ok {
+			continue
+		}
+		if _, ok := setsToAddOrUpdate[prefixedName]; ok {
+			lists = append(lists, set)
+			continue
+		}
+		if iMgr.iMgrCfg.IPSetMode == ApplyOnNeed && !set.shouldBeInKernel() {
+			continue
+		}
+		for memberName := range set.MemberIPSets {
+			if _, ok := setsToAddOrUpdate[memberName]; ok {
+				lists = append(lists, set)
+				break
+			}
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].Name < lists[j].Name
+	})
+	return lists
+}
+
+func (iMgr *IPSetManager) writeNftHashSet(creator *ioutil.FileCreator, set *IPSet) {
+	members := make([]string, 0, len(set.IPPodKey))
+	for member := range set.IPPodKey {
+		members = append(members, member)
+	}
+	ipv4Members, ipv6Members := splitByFamily(members)
+	writeNftHashSetForFamily(creator, set, set.HashedName, nftIPv4AddrType, ipv4Members)
+	if iMgr.iMgrCfg.DualStack {
+		writeNftHashSetForFamily(creator, set, IPv6SetName(set.HashedName), nftIPv6AddrType, ipv6Members)
+	}
+}
+
+func writeNftHashSetForFamily(creator *ioutil.FileCreator, set *IPSet, name, addrType string, members []string) {
+	switch set.Type {
+	case CIDRBlocks:
+		cidrs := make([]string, 0, len(members))
+		excepts := make([]string, 0)
+		for _, member := range members {
+			cidr, isExcept := strings.CutSuffix(member, " "+util.IpsetNomatch)
+			if isExcept {
+				excepts = append(excepts, cidr)
+			} else {
+				cidrs = append(cidrs, cidr)
+			}
+		}
+		writeNftSet(creator, name, addrType, true, cidrs)
+		writeNftSet(creator, name+util.NftExceptSetSuffix, addrType, true, excepts)
+	case NamedPorts:
+		elements := make([]string, 0, len(members))
+		for _, member := range members {
+			elements = append(elements, nftNamedPortElement(member))
+		}
+		writeNftSet(creator, name, addrType+nftNamedPortKeySuffix, false, elements)
+	default:
+		writeNftSet(creator, name, addrType, false, members)
+	}
+}
+
+func writeNftSet(creator *ioutil.FileCreator, hashedName, keyType string, interval bool, elements []string) {
+	spec := "{ type " + keyType + " ; }"
+	if interval {
+		// auto-merge allows overlapping CIDRs, which ipset's hash:net allows too
+		spec = "{ type " + keyType + " ; flags interval ; auto-merge ; }"
+	}
+	creator.AddLine("", nil, append(nftSetSpecs(util.NftAdd, hashedName), spec)...)
+	creator.AddLine("", nil, nftSetSpecs(util.NftFlush, hashedName)...)
+	if len(elements) == 0 {
+		// nft doesn't accept an empty element list
+		return
+	}
+	sort.Strings(elements)
+	creator.AddLine("", nil, util.NftAdd, util.NftElementObj, util.NftFamily, util.NftTable, hashedName, "{ "+strings.Join(elements, ", ")+" }")
+}
+
+func deleteNftSet(creator *ioutil.FileCreator, prefixedName, name string) {
+	creator.AddLine("", nil, nftSetSpecs(util.NftDelete, name)...)
+	if strings.HasPrefix(prefixedName, util.CIDRPrefix) {
+		creator.AddLine("", nil, nftSetSpecs(util.NftDelete, name+util.NftExceptSetSuffix)...)
+	}
+}
+
+func nftSetSpecs(operation, hashedName string) []string {
+	return []string{operation, util.NftSetObj, util.NftFamily, util.NftTable, hashedName}
+}
+
+// nftNamedPortElement converts a member like 10.0.0.1,tcp:80 to 10.0.0.1 . tcp . 80
+func nftNamedPortElement(member string) string {
+	ip, protocolAndPort, _ := strings.Cut(member, ",")
+	protocol, port, _ := strings.Cut(protocolAndPort, util.IpsetLabelDelimter)
+	return ip + " . " + protocol + " . " + port
+}
+
+// listIPs returns the union of the IPs of the members of the list.
+func listIPs(list *IPSet) []string {
+	ips := make(map[string]struct{})
+	for _, member := range list.MemberIPSets {
+		for ip := range member.IPPodKey {
+			ips[ip] = struct{}{}
+		}
+	}
+	result := make([]string, 0, len(ips))
+	for ip := range ips {
+		result = append(result, ip)
+	}
+	return result
+}
+
+// splitByFamily separates IPv4 and IPv6 members.
+func splitByFamily(members []string) (ipv4Members, ipv6Members []string) {
+	ipv4Members = make([]string, 0, len(members))
+	ipv6Members = make([]string, 0)
+	for _, member := range members {
+		if IsIPv6Member(member) {
+			ipv6Members = append(ipv6Members, member)
+		} else {
+			ipv4Members = append(ipv4Members, member)
+		}
+	}
+	return ipv4Members, ipv6Members
+}
+
+func sortedNames(names map[string]struct{}) []string {
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
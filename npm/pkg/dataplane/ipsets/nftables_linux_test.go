@@ -0,0 +1,205 @@
+package ipsets
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
+	"github.com/Azure/azure-container-networking/npm/util"
+	testutils "github.com/Azure/azure-container-networking/test/utils"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	nftCfg = &IPSetManagerCfg{
+		IPSetMode:   ApplyAllIPSets,
+		NetworkName: "azure",
+		Nftables:    true,
+	}
+
+	nftDualStackCfg = &IPSetManagerCfg{
+		IPSetMode:   ApplyAllIPSets,
+		NetworkName: "azure",
+		Nftables:    true,
+		DualStack:   true,
+	}
+
+	nftCommand        = testutils.TestCmd{Cmd: []string{"nft", "-f", "-"}}
+	nftFailureCommand = testutils.TestCmd{Cmd: []string{"nft", "-f", "-"}, Stdout: "Error: Could not process rule", ExitCode: 1}
+)
+
+func TestFileCreatorForNftApply(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	iMgr := NewIPSetManager(nftCfg, ioshim)
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestCIDRSet.Metadata}, "10.0.0.0/8", ""))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestCIDRSet.Metadata}, "10.1.0.0/16 nomatch", ""))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNamedportSet.Metadata}, "10.0.0.1,tcp:8080", "a/pod1"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a/pod1"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestKVPodSet.Metadata}, "10.0.0.2", "a/pod2"))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{TestKeyNSList.Metadata}, []*IPSetMetadata{TestNSSet.Metadata, TestKVPodSet.Metadata}))
+
+	creator := iMgr.fileCreatorForNftApply(maxTryCount)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	// hash sets are sorted by prefixed name
+	expectedLines := []string{
+		"add table inet azure-npm",
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; flags interval ; auto-merge ; }", TestCIDRSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestCIDRSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s { 10.0.0.0/8 }", TestCIDRSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s-except { type ipv4_addr ; flags interval ; auto-merge ; }", TestCIDRSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s-except", TestCIDRSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s-except { 10.1.0.0/16 }", TestCIDRSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr . inet_proto . inet_service ; }", TestNamedportSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestNamedportSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s { 10.0.0.1 . tcp . 8080 }", TestNamedportSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; }", TestNSSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestNSSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s { 10.0.0.1 }", TestNSSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; }", TestKVPodSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestKVPodSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s { 10.0.0.2 }", TestKVPodSet.HashedName),
+		// the list has the IPs of its members
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; }", TestKeyNSList.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestKeyNSList.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s { 10.0.0.1, 10.0.0.2 }", TestKeyNSList.HashedName),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestFileCreatorForNftApplyWithDirtyListMember(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	iMgr := NewIPSetManager(nftCfg, ioshim)
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a/pod1"))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{TestKeyNSList.Metadata}, []*IPSetMetadata{TestNSSet.Metadata}))
+	iMgr.CreateIPSets([]*IPSetMetadata{TestKVPodSet.Metadata})
+	iMgr.clearDirtyCache()
+
+	// the list isn't dirty itself, but has to be rewritten with the new member IP
+	require.NoError(t, iMgr.RemoveFromSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a/pod1"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.3", "a/pod3"))
+	iMgr.DeleteIPSet(TestKVPodSet.PrefixName, util.SoftDelete)
+
+	creator := iMgr.fileCreatorForNftApply(maxTryCount)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"add table inet azure-npm",
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; }", TestNSSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestNSSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s { 10.0.0.3 }", TestNSSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; }", TestKeyNSList.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestKeyNSList.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s { 10.0.0.3 }", TestKeyNSList.HashedName),
+		fmt.Sprintf("delete set inet azure-npm %s", TestKVPodSet.HashedName),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestFileCreatorForNftApplyDualStack(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	iMgr := NewIPSetManager(nftDualStackCfg, ioshim)
+
+	iMgr.CreateIPSets([]*IPSetMetadata{TestKVPodSet.Metadata})
+	iMgr.clearDirtyCache()
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestCIDRSet.Metadata}, "fd00::/64", ""))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestCIDRSet.Metadata}, "fd00::/80 nomatch", ""))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNamedportSet.Metadata}, "fd00::1,tcp:8080", "a/pod1"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a/pod1"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "fd00::1", "a/pod1"))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{TestKeyNSList.Metadata}, []*IPSetMetadata{TestNSSet.Metadata}))
+	iMgr.DeleteIPSet(TestKVPodSet.PrefixName, util.SoftDelete)
+
+	creator := iMgr.fileCreatorForNftApply(maxTryCount)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	// each set is followed by its IPv6 twin
+	expectedLines := []string{
+		"add table inet azure-npm",
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; flags interval ; auto-merge ; }", TestCIDRSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestCIDRSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s-except { type ipv4_addr ; flags interval ; auto-merge ; }", TestCIDRSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s-except", TestCIDRSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s-6 { type ipv6_addr ; flags interval ; auto-merge ; }", TestCIDRSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s-6", TestCIDRSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s-6 { fd00::/64 }", TestCIDRSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s-6-except { type ipv6_addr ; flags interval ; auto-merge ; }", TestCIDRSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s-6-except", TestCIDRSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s-6-except { fd00::/80 }", TestCIDRSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr . inet_proto . inet_service ; }", TestNamedportSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestNamedportSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s-6 { type ipv6_addr . inet_proto . inet_service ; }", TestNamedportSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s-6", TestNamedportSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s-6 { fd00::1 . tcp . 8080 }", TestNamedportSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; }", TestNSSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestNSSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s { 10.0.0.1 }", TestNSSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s-6 { type ipv6_addr ; }", TestNSSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s-6", TestNSSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s-6 { fd00::1 }", TestNSSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; }", TestKeyNSList.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestKeyNSList.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s { 10.0.0.1 }", TestKeyNSList.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s-6 { type ipv6_addr ; }", TestKeyNSList.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s-6", TestKeyNSList.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s-6 { fd00::1 }", TestKeyNSList.HashedName),
+		fmt.Sprintf("delete set inet azure-npm %s", TestKVPodSet.HashedName),
+		fmt.Sprintf("delete set inet azure-npm %s-6", TestKVPodSet.HashedName),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestApplyNftSets(t *testing.T) {
+	calls := []testutils.TestCmd{nftCommand}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	iMgr := NewIPSetManager(nftCfg, ioshim)
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a/pod1"))
+	require.NoError(t, iMgr.ApplyIPSets())
+}
+
+func TestApplyNftSetsFailure(t *testing.T) {
+	// the whole transaction is retried
+	calls := make([]testutils.TestCmd, maxTryCount)
+	for i := range calls {
+		calls[i] = nftFailureCommand
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	iMgr := NewIPSetManager(nftCfg, ioshim)
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a/pod1"))
+	require.Error(t, iMgr.ApplyIPSets())
+}
+
+func TestResetNftSets(t *testing.T) {
+	// the kernel ipsets left from iptables mode are destroyed
+	calls := GetResetTestCalls()
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	iMgr := NewIPSetManager(nftCfg, ioshim)
+	require.NoError(t, iMgr.ResetIPSets())
+}
+
+func TestResetNftSetsFailure(t *testing.T) {
+	calls := []testutils.TestCmd{
+		{Cmd: []string{"ipset", "list", "--name"}, PipedToCommand: true, HasStartError: true, ExitCode: 1},
+		{Cmd: []string{"grep", "-q", "-v", "azure-npm-"}},
+		{Cmd: []string{"ipset", "list", "--name"}, PipedToCommand: true, HasStartError: true, ExitCode: 1},
+		{Cmd: []string{"grep", "azure-npm-"}},
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	iMgr := NewIPSetManager(nftCfg, ioshim)
+	// the kernel ipsets aren't used in nftables mode, so failing to destroy them doesn't fail the reset
+	require.NoError(t, iMgr.ResetIPSets())
+}
@@ -0,0 +1,151 @@
+package policies
+
+// This file contains code for evaluating AdminNetworkPolicies and BaselineAdminNetworkPolicies in iptables and nftables.
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/Azure/azure-container-networking/npm/util/ioutil"
+)
+
+/*
+When AdminNetworkPolicy is enabled, there are base chains for each tier, and the ingress and egress chains are like so:
+
+AZURE-NPM-INGRESS:
+ 1. jump to AZURE-NPM-ANP-INGRESS
+ 2. jumps to the ingress chains of NetworkPolicies
+ 3. DROP on the ingress drop mark
+ 4. jump to AZURE-NPM-BANP-INGRESS
+
+AZURE-NPM-EGRESS:
+ 1. jump to AZURE-NPM-ANP-EGRESS
+ 2. jumps to the egress chains of NetworkPolicies
+ 3. DROP on the egress drop mark
+ 4. jump to AZURE-NPM-BANP-EGRESS
+ 5. ACCEPT on the ingress allow mark
+
+A NetworkPolicy which selects a pod either allows or drops the flow, so a BaselineAdminNetworkPolicy is only evaluated when no NetworkPolicy selects the pod.
+
+The tier chains jump to the policy chains in order of priority, then policy key.
+Allow and Deny are final in both tiers: Allow works like an allow in a NetworkPolicy, and Deny drops the packet right away.
+Pass sets the pass mark of its direction and returns. Each AdminNetworkPolicy chain starts by returning if the pass mark is set,
+so the remaining AdminNetworkPolicies are skipped and the flow is decided by NetworkPolicies.
+*/
+
+var adminTierChains = []string{
+	util.IptablesAzureAdminIngressChain,
+	util.IptablesAzureAdminEgressChain,
+	util.IptablesAzureBaselineAdminIngressChain,
+	util.IptablesAzureBaselineAdminEgressChain,
+}
+
+// baseChains returns the chains that are configured at bootup.
+func (pMgr *PolicyManager) baseChains() []string {
+	if !pMgr.AdminNetworkPolicy {
+		return iptablesAzureChains
+	}
+	chains := make([]string, 0, len(iptablesAzureChains)+len(adminTierChains))
+	chains = append(chains, iptablesAzureChains...)
+	return append(chains, adminTierChains...)
+}
+
+// isAdminTierChain is true if the chain is a base chain for admin network policies and they are enabled.
+func (pMgr *PolicyManager) isAdminTierChain(chain string) bool {
+	if !pMgr.AdminNetworkPolicy {
+		return false
+	}
+	for _, tierChain := range adminTierChains {
+		if chain == tierChain {
+			return true
+		}
+	}
+	return false
+}
+
+// firstNetworkPolicyJumpLineNumber is the line in AZURE-NPM-INGRESS and AZURE-NPM-EGRESS where jumps to NetworkPolicy chains are inserted.
+func (pMgr *PolicyManager) firstNetworkPolicyJumpLineNumber() int {
+	if pMgr.AdminNetworkPolicy {
+		// after the jump to the AdminNetworkPolicy chain
+		return 2
+	}
+	return 1
+}
+
+// adminJumpLineNumber returns the line in the policy's tier chain where the jump to its chain goes.
+// The chain already has jumps for the cached policies and the added policies.
+func (pMgr *PolicyManager) adminJumpLineNumber(networkPolicy *NPMNetworkPolicy, direction UniqueDirection, addedPolicies []*NPMNetworkPolicy) int {
+	lineNumber := 1
+	countIfBefore := func(other *NPMNetworkPolicy) {
+		if other.Tier != networkPolicy.Tier || !other.hasDirection(direction) {
+			return
+		}
+		if other.sortsBefore(networkPolicy) {
+			lineNumber++
+		}
+	}
+	for _, other := range pMgr.policyMap.cache {
+		countIfBefore(other)
+	}
+	for _, other := range addedPolicies {
+		countIfBefore(other)
+	}
+	return lineNumber
+}
+
+func (networkPolicy *NPMNetworkPolicy) hasDirection(direction UniqueDirection) bool {
+	hasIngress, hasEgress := networkPolicy.hasIngressAndEgress()
+	if direction == forIngress {
+		return hasIngress
+	}
+	return hasEgress
+}
+
+// sortsBefore is true if the policy is evaluated before the other policy in the same tier.
+func (networkPolicy *NPMNetworkPolicy) sortsBefore(other *NPMNetworkPolicy) bool {
+	if networkPolicy.Priority != other.Priority {
+		return networkPolicy.Priority < other.Priority
+	}
+	return networkPolicy.PolicyKey < other.PolicyKey
+}
+
+// ingressBaseChainName returns the chain which jumps to the policy's ingress chain.
+func (networkPolicy *NPMNetworkPolicy) ingressBaseChainName() string {
+	switch networkPolicy.Tier {
+	case AdminTier:
+		return util.IptablesAzureAdminIngressChain
+	case BaselineAdminTier:
+		return util.IptablesAzureBaselineAdminIngressChain
+	default:
+		return util.IptablesAzureIngressChain
+	}
+}
+
+// egressBaseChainName returns the chain which jumps to the policy's egress chain.
+func (networkPolicy *NPMNetworkPolicy) egressBaseChainName() string {
+	switch networkPolicy.Tier {
+	case AdminTier:
+		return util.IptablesAzureAdminEgressChain
+	case BaselineAdminTier:
+		return util.IptablesAzureBaselineAdminEgressChain
+	default:
+		return util.IptablesAzureEgressChain
+	}
+}
+
+// writeReturnOnPassMarkRules starts the chains of an AdminNetworkPolicy with a rule to return if a higher priority policy passed the flow.
+func writeReturnOnPassMarkRules(creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy) {
+	hasIngress, hasEgress := networkPolicy.hasIngressAndEgress()
+	if hasIngress {
+		creator.AddLine("", nil, returnOnPassMarkSpecs(networkPolicy.ingressChainName(), util.IptablesAzureIngressPassMarkHex)...)
+	}
+	if hasEgress {
+		creator.AddLine("", nil, returnOnPassMarkSpecs(networkPolicy.egressChainName(), util.IptablesAzureEgressPassMarkHex)...)
+	}
+}
+
+func returnOnPassMarkSpecs(chainName, passMark string) []string {
+	specs := []string{util.IptablesAppendFlag, chainName, util.IptablesJumpFlag, util.IptablesReturn}
+	specs = append(specs, onMarkSpecs(passMark)...)
+	return append(specs, commentSpecs(fmt.Sprintf("RETURN-ON-PASS-MARK-%s", passMark))...)
+}
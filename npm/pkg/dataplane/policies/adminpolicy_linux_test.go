@@ -0,0 +1,328 @@
+package policies
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	adminConfig = &PolicyManagerCfg{
+		PolicyMode:           IPSetPolicyMode,
+		PlaceAzureChainFirst: util.PlaceAzureChainFirst,
+		AdminNetworkPolicy:   true,
+	}
+
+	nftAdminConfig = &PolicyManagerCfg{
+		PolicyMode:           IPSetPolicyMode,
+		PlaceAzureChainFirst: util.PlaceAzureChainFirst,
+		Nftables:             true,
+		AdminNetworkPolicy:   true,
+	}
+)
+
+// admin network policies
+var (
+	passAdminNetPol = &NPMNetworkPolicy{
+		PolicyKey:   "AdminNetworkPolicy/pass",
+		ACLPolicyID: "azure-acl-AdminNetworkPolicy-pass",
+		Tier:        AdminTier,
+		Priority:    5,
+		PodSelectorIPSets: []*ipsets.TranslatedIPSet{
+			{Metadata: ipsets.TestNSSet.Metadata},
+		},
+		PodSelectorList: []SetInfo{
+			{
+				IPSet:     ipsets.TestNSSet.Metadata,
+				Included:  true,
+				MatchType: EitherMatch,
+			},
+		},
+		ACLs: []*ACLPolicy{
+			{
+				SrcList:   []SetInfo{{ipsets.TestCIDRSet.Metadata, true, SrcMatch}},
+				Target:    Passed,
+				Direction: Ingress,
+				Protocol:  UnspecifiedProtocol,
+			},
+			{
+				DstList:   []SetInfo{{ipsets.TestCIDRSet.Metadata, true, DstMatch}},
+				Target:    Dropped,
+				Direction: Egress,
+				Protocol:  UnspecifiedProtocol,
+			},
+		},
+	}
+	baselineAdminNetPol = &NPMNetworkPolicy{
+		PolicyKey:   "BaselineAdminNetworkPolicy/default",
+		ACLPolicyID: "azure-acl-BaselineAdminNetworkPolicy-default",
+		Tier:        BaselineAdminTier,
+		ACLs: []*ACLPolicy{
+			{
+				SrcList:   []SetInfo{{ipsets.TestCIDRSet.Metadata, true, SrcMatch}},
+				Target:    Dropped,
+				Direction: Ingress,
+				Protocol:  UnspecifiedProtocol,
+			},
+		},
+	}
+)
+
+// iptables rule variables for admin network policies
+var (
+	passAdminNetPolIngressChain = passAdminNetPol.ingressChainName()
+	passAdminNetPolEgressChain  = passAdminNetPol.egressChainName()
+	baselineAdminNetPolChain    = baselineAdminNetPol.ingressChainName()
+
+	passAdminNetPolIngressJump = fmt.Sprintf("-j %s -m set --match-set %s dst -m comment --comment INGRESS-POLICY-AdminNetworkPolicy/pass-TO-ns-test-ns-set",
+		passAdminNetPolIngressChain, ipsets.TestNSSet.HashedName)
+	passAdminNetPolEgressJump = fmt.Sprintf("-j %s -m set --match-set %s src -m comment --comment EGRESS-POLICY-AdminNetworkPolicy/pass-FROM-ns-test-ns-set",
+		passAdminNetPolEgressChain, ipsets.TestNSSet.HashedName)
+	baselineAdminNetPolJump = fmt.Sprintf("-j %s -m comment --comment INGRESS-POLICY-BaselineAdminNetworkPolicy/default-TO-all", baselineAdminNetPolChain)
+)
+
+func TestCreatorForBootupWithAdminTiers(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, adminConfig)
+
+	creator := pMgr.creatorForBootup(stringsToMap([]string{"AZURE-NPM", "AZURE-NPM-ANP-INGRESS", "AZURE-NPM-INGRESS-123456"}))
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		":AZURE-NPM-INGRESS - -",
+		":AZURE-NPM-INGRESS-ALLOW-MARK - -",
+		":AZURE-NPM-EGRESS - -",
+		":AZURE-NPM-ACCEPT - -",
+		":AZURE-NPM-ANP-EGRESS - -",
+		":AZURE-NPM-BANP-INGRESS - -",
+		":AZURE-NPM-BANP-EGRESS - -",
+		"-F AZURE-NPM",
+		"-F AZURE-NPM-ANP-INGRESS",
+		"-F AZURE-NPM-INGRESS-123456",
+		"-A AZURE-NPM-INGRESS -j AZURE-NPM-ANP-INGRESS",
+		"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+		"-A AZURE-NPM-INGRESS -j AZURE-NPM-BANP-INGRESS",
+		"-A AZURE-NPM-INGRESS-ALLOW-MARK -j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+		"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
+		"-A AZURE-NPM-EGRESS -j AZURE-NPM-ANP-EGRESS",
+		"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+		"-A AZURE-NPM-EGRESS -j AZURE-NPM-BANP-EGRESS",
+		"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
+		"-A AZURE-NPM-ACCEPT -j ACCEPT",
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, sortFlushes(expectedLines), sortFlushes(actualLines))
+	// the tier chains are base chains, so they're never stale
+	assertStaleChainsContain(t, pMgr.staleChains, "AZURE-NPM-INGRESS-123456")
+}
+
+func TestCreatorForAddAdminPolicies(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, adminConfig)
+
+	policies := []*NPMNetworkPolicy{passAdminNetPol, baselineAdminNetPol, ingressNetPol}
+	creator := pMgr.creatorForNewNetworkPolicies(ipv4, chainNames(policies), policies)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		fmt.Sprintf(":%s - -", passAdminNetPolIngressChain),
+		fmt.Sprintf(":%s - -", passAdminNetPolEgressChain),
+		fmt.Sprintf(":%s - -", baselineAdminNetPolChain),
+		fmt.Sprintf(":%s - -", ingressNetPolChain),
+		"-F AZURE-NPM",
+		"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-EGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-ACCEPT",
+		// the AdminNetworkPolicy chains start by returning on the pass mark
+		fmt.Sprintf("-A %s -j RETURN -m mark --mark 0x100/0x100 -m comment --comment RETURN-ON-PASS-MARK-0x100/0x100", passAdminNetPolIngressChain),
+		fmt.Sprintf("-A %s -j RETURN -m mark --mark 0x1000/0x1000 -m comment --comment RETURN-ON-PASS-MARK-0x1000/0x1000", passAdminNetPolEgressChain),
+		fmt.Sprintf("-A %s -j MARK --set-mark 0x100/0x100 -m set --match-set %s src -m comment --comment PASS-FROM-cidr-test-cidr-set",
+			passAdminNetPolIngressChain, ipsets.TestCIDRSet.HashedName),
+		fmt.Sprintf("-A %s -j RETURN -m mark --mark 0x100/0x100 -m comment --comment RETURN-ON-PASS-MARK-0x100/0x100", passAdminNetPolIngressChain),
+		fmt.Sprintf("-A %s -j DROP -m set --match-set %s dst -m comment --comment DROP-TO-cidr-test-cidr-set",
+			passAdminNetPolEgressChain, ipsets.TestCIDRSet.HashedName),
+		fmt.Sprintf("-I AZURE-NPM-ANP-INGRESS 1 %s", passAdminNetPolIngressJump),
+		fmt.Sprintf("-I AZURE-NPM-ANP-EGRESS 1 %s", passAdminNetPolEgressJump),
+		fmt.Sprintf("-A %s -j DROP -m set --match-set %s src -m comment --comment DROP-FROM-cidr-test-cidr-set",
+			baselineAdminNetPolChain, ipsets.TestCIDRSet.HashedName),
+		fmt.Sprintf("-I AZURE-NPM-BANP-INGRESS 1 %s", baselineAdminNetPolJump),
+		fmt.Sprintf("-A %s %s", ingressNetPolChain, ingressDropRule),
+		// NetworkPolicy jumps go after the jump to the AdminNetworkPolicy chain
+		fmt.Sprintf("-I AZURE-NPM-INGRESS 2 %s", ingressNetPolJump),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestAdminJumpLineNumber(t *testing.T) {
+	pMgr := NewPolicyManager(common.NewMockIOShim(nil), adminConfig)
+	pMgr.policyMap.cache[passAdminNetPol.PolicyKey] = passAdminNetPol
+	pMgr.policyMap.cache[baselineAdminNetPol.PolicyKey] = baselineAdminNetPol
+
+	newPolicy := func(name string, priority int32) *NPMNetworkPolicy {
+		policy := NewNPMAdminNetworkPolicy("AdminNetworkPolicy", name, AdminTier, priority)
+		policy.ACLs = []*ACLPolicy{{Target: Allowed, Direction: Ingress}}
+		return policy
+	}
+
+	tests := []struct {
+		name          string
+		policy        *NPMNetworkPolicy
+		direction     UniqueDirection
+		addedPolicies []*NPMNetworkPolicy
+		expected      int
+	}{
+		{
+			name:      "higher priority than cached policy",
+			policy:    newPolicy("a", 1),
+			direction: forIngress,
+			expected:  1,
+		},
+		{
+			name:      "lower priority than cached policy",
+			policy:    newPolicy("a", 10),
+			direction: forIngress,
+			expected:  2,
+		},
+		{
+			name:      "same priority sorts by policy key",
+			policy:    newPolicy("z", 5),
+			direction: forIngress,
+			expected:  2,
+		},
+		{
+			name:          "after cached and added policies",
+			policy:        newPolicy("c", 10),
+			direction:     forIngress,
+			addedPolicies: []*NPMNetworkPolicy{newPolicy("b", 10)},
+			expected:      3,
+		},
+		{
+			name:          "ignores policies without the direction",
+			policy:        newPolicy("c", 10),
+			direction:     forEgress,
+			addedPolicies: []*NPMNetworkPolicy{newPolicy("b", 10)},
+			expected:      2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, pMgr.adminJumpLineNumber(tt.policy, tt.direction, tt.addedPolicies))
+		})
+	}
+}
+
+func TestAddAdminPolicyWhenDisabled(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, ipsetConfig)
+
+	require.Error(t, pMgr.AddPolicies([]*NPMNetworkPolicy{baselineAdminNetPol}, nil))
+	require.False(t, pMgr.PolicyExists(baselineAdminNetPol.PolicyKey))
+}
+
+func TestCreatorForNftBootupWithAdminTiers(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, nftAdminConfig)
+
+	creator := pMgr.creatorForNftBootup()
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"add table inet azure-npm",
+		"delete table inet azure-npm",
+		"add table inet azure-npm",
+		"add chain inet azure-npm FORWARD { type filter hook forward priority -1 ; policy accept ; }",
+		"add chain inet azure-npm AZURE-NPM",
+		"add chain inet azure-npm AZURE-NPM-INGRESS",
+		"add chain inet azure-npm AZURE-NPM-INGRESS-ALLOW-MARK",
+		"add chain inet azure-npm AZURE-NPM-EGRESS",
+		"add chain inet azure-npm AZURE-NPM-ACCEPT",
+		"add chain inet azure-npm AZURE-NPM-ANP-INGRESS",
+		"add chain inet azure-npm AZURE-NPM-ANP-EGRESS",
+		"add chain inet azure-npm AZURE-NPM-BANP-INGRESS",
+		"add chain inet azure-npm AZURE-NPM-BANP-EGRESS",
+		"add rule inet azure-npm FORWARD ct state new jump AZURE-NPM",
+		`add rule inet azure-npm AZURE-NPM-INGRESS-ALLOW-MARK meta mark set meta mark | 0x200 comment "SET-INGRESS-ALLOW-MARK-0x200/0x200"`,
+		"add rule inet azure-npm AZURE-NPM-INGRESS-ALLOW-MARK jump AZURE-NPM-EGRESS",
+		"add rule inet azure-npm AZURE-NPM-ACCEPT accept",
+		"flush chain inet azure-npm AZURE-NPM-INGRESS",
+		"add rule inet azure-npm AZURE-NPM-INGRESS jump AZURE-NPM-ANP-INGRESS",
+		nftIngressDropOnMarkRule,
+		"add rule inet azure-npm AZURE-NPM-INGRESS jump AZURE-NPM-BANP-INGRESS",
+		"flush chain inet azure-npm AZURE-NPM-EGRESS",
+		"add rule inet azure-npm AZURE-NPM-EGRESS jump AZURE-NPM-ANP-EGRESS",
+		"add rule inet azure-npm AZURE-NPM-EGRESS meta mark & 0x800 == 0 jump AZURE-NPM-BANP-EGRESS",
+		nftEgressMarkDecisionsRule,
+		"flush chain inet azure-npm AZURE-NPM-ANP-INGRESS",
+		"flush chain inet azure-npm AZURE-NPM-ANP-EGRESS",
+		"flush chain inet azure-npm AZURE-NPM-BANP-INGRESS",
+		"flush chain inet azure-npm AZURE-NPM-BANP-EGRESS",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestCreatorForNftAdminPolicies(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, nftAdminConfig)
+
+	activePolicies := map[string]*NPMNetworkPolicy{
+		passAdminNetPol.PolicyKey:     passAdminNetPol,
+		baselineAdminNetPol.PolicyKey: baselineAdminNetPol,
+	}
+	creator := pMgr.creatorForNftPolicies(activePolicies, []*NPMNetworkPolicy{passAdminNetPol, baselineAdminNetPol}, nil)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		fmt.Sprintf("add chain inet azure-npm %s", passAdminNetPolIngressChain),
+		fmt.Sprintf("flush chain inet azure-npm %s", passAdminNetPolIngressChain),
+		fmt.Sprintf("add chain inet azure-npm %s", passAdminNetPolEgressChain),
+		fmt.Sprintf("flush chain inet azure-npm %s", passAdminNetPolEgressChain),
+		fmt.Sprintf(`add rule inet azure-npm %s meta mark & 0x100 == 0x100 return comment "RETURN-ON-PASS-MARK-0x100/0x100"`, passAdminNetPolIngressChain),
+		fmt.Sprintf(`add rule inet azure-npm %s meta mark & 0x1000 == 0x1000 return comment "RETURN-ON-PASS-MARK-0x1000/0x1000"`, passAdminNetPolEgressChain),
+		fmt.Sprintf(`add rule inet azure-npm %s ip saddr @%s ip saddr != @%s-except meta mark set meta mark | 0x100 return comment "PASS-FROM-cidr-test-cidr-set"`,
+			passAdminNetPolIngressChain, ipsets.TestCIDRSet.HashedName, ipsets.TestCIDRSet.HashedName),
+		fmt.Sprintf(`add rule inet azure-npm %s ip daddr @%s ip daddr != @%s-except drop comment "DROP-TO-cidr-test-cidr-set"`,
+			passAdminNetPolEgressChain, ipsets.TestCIDRSet.HashedName, ipsets.TestCIDRSet.HashedName),
+		fmt.Sprintf("add chain inet azure-npm %s", baselineAdminNetPolChain),
+		fmt.Sprintf("flush chain inet azure-npm %s", baselineAdminNetPolChain),
+		fmt.Sprintf(`add rule inet azure-npm %s ip saddr @%s ip saddr != @%s-except drop comment "DROP-FROM-cidr-test-cidr-set"`,
+			baselineAdminNetPolChain, ipsets.TestCIDRSet.HashedName, ipsets.TestCIDRSet.HashedName),
+		"flush chain inet azure-npm AZURE-NPM",
+		"add rule inet azure-npm AZURE-NPM jump AZURE-NPM-INGRESS",
+		"add rule inet azure-npm AZURE-NPM jump AZURE-NPM-EGRESS",
+		"add rule inet azure-npm AZURE-NPM jump AZURE-NPM-ACCEPT",
+		"flush chain inet azure-npm AZURE-NPM-INGRESS",
+		"add rule inet azure-npm AZURE-NPM-INGRESS jump AZURE-NPM-ANP-INGRESS",
+		nftIngressDropOnMarkRule,
+		"add rule inet azure-npm AZURE-NPM-INGRESS jump AZURE-NPM-BANP-INGRESS",
+		"flush chain inet azure-npm AZURE-NPM-EGRESS",
+		"add rule inet azure-npm AZURE-NPM-EGRESS jump AZURE-NPM-ANP-EGRESS",
+		"add rule inet azure-npm AZURE-NPM-EGRESS meta mark & 0x800 == 0 jump AZURE-NPM-BANP-EGRESS",
+		nftEgressMarkDecisionsRule,
+		"flush chain inet azure-npm AZURE-NPM-ANP-INGRESS",
+		fmt.Sprintf(`add rule inet azure-npm AZURE-NPM-ANP-INGRESS ip daddr @%s jump %s comment "INGRESS-POLICY-AdminNetworkPolicy/pass-TO-ns-test-ns-set"`,
+			ipsets.TestNSSet.HashedName, passAdminNetPolIngressChain),
+		"flush chain inet azure-npm AZURE-NPM-ANP-EGRESS",
+		fmt.Sprintf(`add rule inet azure-npm AZURE-NPM-ANP-EGRESS ip saddr @%s jump %s comment "EGRESS-POLICY-AdminNetworkPolicy/pass-FROM-ns-test-ns-set"`,
+			ipsets.TestNSSet.HashedName, passAdminNetPolEgressChain),
+		"flush chain inet azure-npm AZURE-NPM-BANP-INGRESS",
+		fmt.Sprintf(`add rule inet azure-npm AZURE-NPM-BANP-INGRESS jump %s comment "INGRESS-POLICY-BaselineAdminNetworkPolicy/default-TO-all"`, baselineAdminNetPolChain),
+		"flush chain inet azure-npm AZURE-NPM-BANP-EGRESS",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
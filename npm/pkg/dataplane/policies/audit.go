@@ -0,0 +1,71 @@
+package policies
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/util"
+)
+
+const (
+	// an NFLOG prefix is at most 64 bytes including the null terminator
+	maxAuditTagLength = 63
+	// auditTagHashPrefix marks a policy key which was replaced by its hash to fit in an audit tag
+	auditTagHashPrefix = "#"
+)
+
+var errInvalidAuditTag = errors.New("invalid audit tag")
+
+// AuditTag identifies the ACL which logged an audit event.
+type AuditTag struct {
+	Direction Direction
+	Verdict   Verdict
+	// PolicyKey is empty if the key was too long for the tag. Then PolicyHash is util.Hash() of the key.
+	PolicyKey  string
+	PolicyHash string
+}
+
+// auditTag returns the log prefix of the audit events for an ACL of the policy in the direction.
+// It looks like "IN-DROP-x/test1" and has no spaces, so it doesn't need to be quoted for iptables-restore.
+func auditTag(direction Direction, verdict Verdict, policyKey string) string {
+	tag := fmt.Sprintf("%s-%s-%s", direction, verdict, policyKey)
+	if len(tag) > maxAuditTagLength {
+		tag = fmt.Sprintf("%s-%s-%s%s", direction, verdict, auditTagHashPrefix, util.Hash(policyKey))
+	}
+	return tag
+}
+
+// auditPolicyKeys returns the policy keys which audit events of the policy are counted with:
+// the policy key, and the hashed key used by audit tags which are too long.
+func auditPolicyKeys(policyKey string) []string {
+	return []string{policyKey, auditTagHashPrefix + util.Hash(policyKey)}
+}
+
+// ParseAuditTag parses the log prefix of an audit event.
+func ParseAuditTag(tag string) (*AuditTag, error) {
+	parts := strings.SplitN(tag, "-", 3)
+	if len(parts) != 3 || parts[2] == "" {
+		return nil, fmt.Errorf("%w: %s", errInvalidAuditTag, tag)
+	}
+
+	direction := Direction(parts[0])
+	if direction != Ingress && direction != Egress {
+		return nil, fmt.Errorf("%w: unknown direction in %s", errInvalidAuditTag, tag)
+	}
+	verdict := Verdict(parts[1])
+	if verdict != Allowed && verdict != Dropped && verdict != Passed {
+		return nil, fmt.Errorf("%w: unknown verdict in %s", errInvalidAuditTag, tag)
+	}
+
+	auditTag := &AuditTag{
+		Direction: direction,
+		Verdict:   verdict,
+	}
+	if hash, ok := strings.CutPrefix(parts[2], auditTagHashPrefix); ok {
+		auditTag.PolicyHash = hash
+	} else {
+		auditTag.PolicyKey = parts[2]
+	}
+	return auditTag, nil
+}
@@ -0,0 +1,162 @@
+package policies
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
+	"github.com/Azure/azure-container-networking/npm/util"
+	testutils "github.com/Azure/azure-container-networking/test/utils"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	auditConfig = &PolicyManagerCfg{
+		PolicyMode:           IPSetPolicyMode,
+		PlaceAzureChainFirst: util.PlaceAzureChainFirst,
+		Audit:                true,
+	}
+
+	nftAuditConfig = &PolicyManagerCfg{
+		PolicyMode:           IPSetPolicyMode,
+		PlaceAzureChainFirst: util.PlaceAzureChainFirst,
+		Nftables:             true,
+		Audit:                true,
+	}
+)
+
+// iptables rule variables for audited ACLs
+var (
+	ingressDropAuditRule = fmt.Sprintf(
+		"-j NFLOG --nflog-group 100 --nflog-prefix IN-DROP-x/test1 -p TCP --dport 222:333 -m set --match-set %s src -m set ! --match-set %s dst -m comment --comment %s",
+		ipsets.TestCIDRSet.HashedName,
+		ipsets.TestKeyPodSet.HashedName,
+		ingressDropComment,
+	)
+	ingressAllowAuditRule = fmt.Sprintf("-j NFLOG --nflog-group 100 --nflog-prefix IN-ALLOW-x/test1 -m set --match-set %s src -m comment --comment %s",
+		ipsets.TestCIDRSet.HashedName,
+		ingressAllowComment,
+	)
+	egressDropAuditRule = fmt.Sprintf("-j NFLOG --nflog-group 100 --nflog-prefix OUT-DROP-x/test1 -p UDP --dport 144 -m set --match-set %s dst -m comment --comment %s",
+		ipsets.TestCIDRSet.HashedName,
+		egressDropComment,
+	)
+	egressAllowAuditRule = fmt.Sprintf("-j NFLOG --nflog-group 100 --nflog-prefix OUT-ALLOW-x/test1 -m set --match-set %s dst -m comment --comment %s",
+		ipsets.TestNamedportSet.HashedName,
+		egressAllowComment,
+	)
+)
+
+func TestCreatorForAddPoliciesWithAudit(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, auditConfig)
+
+	policies := []*NPMNetworkPolicy{bothDirectionsNetPol}
+	creator := pMgr.creatorForNewNetworkPolicies(ipv4, chainNames(policies), policies)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolEgressChain),
+		"-F AZURE-NPM",
+		"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-EGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-ACCEPT",
+		// each ACL rule is preceded by an NFLOG rule with the same matches
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressDropAuditRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressDropRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressAllowAuditRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressAllowRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressDropAuditRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressDropRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressAllowAuditRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressAllowRule),
+		fmt.Sprintf("-I AZURE-NPM-INGRESS 1 %s", ingressEgressNetPolIngressJump),
+		fmt.Sprintf("-I AZURE-NPM-EGRESS 1 %s", ingressEgressNetPolEgressJump),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestCreatorForNftPoliciesWithAudit(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, nftAuditConfig)
+
+	activePolicies := map[string]*NPMNetworkPolicy{egressNetPol.PolicyKey: egressNetPol}
+	creator := pMgr.creatorForNftPolicies(activePolicies, []*NPMNetworkPolicy{egressNetPol}, nil)
+	// the log statement comes after the matches and before the action
+	expectedRule := fmt.Sprintf(`add rule inet azure-npm %s ip daddr @%s log prefix "OUT-ALLOW-z/test3" group 100 jump AZURE-NPM-ACCEPT comment %q`,
+		egressNetPolChain, ipsets.TestNamedportSet.HashedName, egressAllowComment)
+	require.Contains(t, strings.Split(creator.ToString(), "\n"), expectedRule)
+}
+
+func TestAddPolicyWithAuditMetrics(t *testing.T) {
+	tests := []struct {
+		name             string
+		cfg              *PolicyManagerCfg
+		expectedNumRules int
+	}{
+		{
+			name: "iptables without audit",
+			cfg:  ipsetConfig,
+			// 4 ACL rules and a rule per direction
+			expectedNumRules: 6,
+		},
+		{
+			name: "iptables with audit",
+			cfg:  auditConfig,
+			// plus an NFLOG rule per ACL
+			expectedNumRules: 10,
+		},
+		{
+			name:             "nftables with audit",
+			cfg:              nftAuditConfig,
+			expectedNumRules: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			metrics.ReinitializeAll()
+			calls := GetAddPolicyTestCalls(bothDirectionsNetPol)
+			if tt.cfg.Nftables {
+				calls = []testutils.TestCmd{fakeNftCommand}
+			}
+			ioshim := common.NewMockIOShim(calls)
+			defer ioshim.VerifyCalls(t, calls)
+			pMgr := NewPolicyManager(ioshim, tt.cfg)
+
+			require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{bothDirectionsNetPol}, nil))
+			promVals{tt.expectedNumRules, 1}.testPrometheusMetrics(t)
+		})
+	}
+}
+
+func TestRemovePolicyWithAuditMetrics(t *testing.T) {
+	metrics.ReinitializeAll()
+	calls := append(GetAddPolicyTestCalls(bothDirectionsNetPol), GetRemovePolicyTestCalls(bothDirectionsNetPol)...)
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, auditConfig)
+
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{bothDirectionsNetPol}, nil))
+	hashedKey := auditTagHashPrefix + util.Hash(bothDirectionsNetPol.PolicyKey)
+	metrics.IncPolicyAuditEvents(bothDirectionsNetPol.PolicyKey, "IN", "DROP")
+	metrics.IncPolicyAuditEvents(hashedKey, "OUT", "ALLOW")
+
+	require.NoError(t, pMgr.RemovePolicy(bothDirectionsNetPol.PolicyKey))
+	val, err := metrics.TotalPolicyAuditEvents(bothDirectionsNetPol.PolicyKey, "IN", "DROP")
+	require.NoError(t, err)
+	require.Equal(t, 0, val)
+	val, err = metrics.TotalPolicyAuditEvents(hashedKey, "OUT", "ALLOW")
+	require.NoError(t, err)
+	require.Equal(t, 0, val)
+}
@@ -0,0 +1,69 @@
+package policies
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditTag(t *testing.T) {
+	tests := []struct {
+		name      string
+		direction Direction
+		verdict   Verdict
+		policyKey string
+		tag       string
+		parsed    *AuditTag
+	}{
+		{
+			name:      "ingress drop",
+			direction: Ingress,
+			verdict:   Dropped,
+			policyKey: "x/test1",
+			tag:       "IN-DROP-x/test1",
+			parsed:    &AuditTag{Direction: Ingress, Verdict: Dropped, PolicyKey: "x/test1"},
+		},
+		{
+			name:      "egress pass with a dash in the key",
+			direction: Egress,
+			verdict:   Passed,
+			policyKey: "AdminNetworkPolicy/pass-dns",
+			tag:       "OUT-PASS-AdminNetworkPolicy/pass-dns",
+			parsed:    &AuditTag{Direction: Egress, Verdict: Passed, PolicyKey: "AdminNetworkPolicy/pass-dns"},
+		},
+		{
+			name:      "long key is hashed",
+			direction: Ingress,
+			verdict:   Allowed,
+			policyKey: "x/" + strings.Repeat("a", maxAuditTagLength),
+			tag:       "IN-ALLOW-#" + util.Hash("x/"+strings.Repeat("a", maxAuditTagLength)),
+			parsed: &AuditTag{
+				Direction:  Ingress,
+				Verdict:    Allowed,
+				PolicyHash: util.Hash("x/" + strings.Repeat("a", maxAuditTagLength)),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tag := auditTag(tt.direction, tt.verdict, tt.policyKey)
+			require.Equal(t, tt.tag, tag)
+			require.LessOrEqual(t, len(tag), maxAuditTagLength)
+
+			parsed, err := ParseAuditTag(tag)
+			require.NoError(t, err)
+			require.Equal(t, tt.parsed, parsed)
+		})
+	}
+}
+
+func TestParseInvalidAuditTag(t *testing.T) {
+	for _, tag := range []string{"", "IN-DROP", "IN-DROP-", "BOTH-DROP-x/test1", "IN-REJECT-x/test1"} {
+		_, err := ParseAuditTag(tag)
+		require.ErrorIs(t, err, errInvalidAuditTag, "tag: %s", tag)
+	}
+}
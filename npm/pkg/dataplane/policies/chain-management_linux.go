@@ -175,10 +175,18 @@ Like the rest of PolicyManager, minimizes the number of OS calls by consolidatin
 
 3. Add/reposition the jump from FORWARD chain to AZURE-NPM chain.
 
+4. On dual-stack nodes, do the same for ip6tables (see bootupIPv6).
+
+When AdminNetworkPolicy is enabled, the base chains include the chains for admin network policies (see adminpolicy_linux.go).
+
 TODO: could use one grep call instead of separate calls for getting jump line nums and for getting deprecated chains and old v2 policy chains
   - would use a grep pattern like so: <line num...AZURE-NPM>|<Chain AZURE-NPM>
 */
 func (pMgr *PolicyManager) bootup(_ []string) error {
+	if pMgr.Nftables {
+		return pMgr.bootupNft()
+	}
+
 	klog.Infof("booting up iptables Azure chains")
 
 	// Stop reconciling so we don't contend for iptables, and so we don't update the staleChains at the same time as reconcile()
@@ -281,7 +289,7 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 
 	// 2. cleanup old NPM chains, and configure base chains and their rules.
 	creator := pMgr.creatorForBootup(currentChains)
-	if err := restore(creator); err != nil {
+	if err := restore(ipv4, creator); err != nil {
 		return npmerrors.SimpleErrorWrapper("failed to run iptables-restore for bootup", err)
 	}
 
@@ -291,6 +299,11 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s with error: %s", baseErrString, err.Error())
 		return npmerrors.SimpleErrorWrapper(baseErrString, err) // we used to ignore this error in v1
 	}
+
+	// 4. do the same for ip6tables on dual-stack nodes
+	if pMgr.DualStack {
+		return pMgr.bootupIPv6()
+	}
 	return nil
 }
 
@@ -298,6 +311,11 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 // - creates the jump rule from FORWARD chain to AZURE-NPM chain (if it does not exist) and makes sure it's after the jumps to KUBE-FORWARD & KUBE-SERVICES chains (if they exist).
 // - cleans up stale policy chains. It can be forced to stop this process if reconcileManager.forceLock() is called.
 func (pMgr *PolicyManager) reconcile() {
+	if pMgr.Nftables {
+		// nftables has no jump to reposition, and removed policy chains are deleted right away
+		return
+	}
+
 	if err := pMgr.positionAzureChainJumpRule(); err != nil {
 		msg := fmt.Sprintf("failed to reconcile jump rule to Azure-NPM due to %s", err.Error())
 		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s", msg)
@@ -335,15 +353,18 @@ deleteLoop:
 			}
 			break deleteLoop
 		default:
-			errCode, err := pMgr.runIPTablesCommand(util.IptablesDestroyFlag, chain)
-			if err != nil && errCode != doesNotExistErrorCode {
-				// add to staleChains if it's not one of the iptablesAzureChains
-				pMgr.staleChains.add(chain)
-				currentErrString := fmt.Sprintf("failed to clean up chain %s with err [%v]", chain, err)
-				if aggregateError == nil {
-					aggregateError = npmerrors.SimpleError(currentErrString)
-				} else {
-					aggregateError = npmerrors.SimpleErrorWrapper(fmt.Sprintf("%s and had previous error", currentErrString), aggregateError)
+			// policy chains have the same names in ip6tables
+			for _, family := range pMgr.ipFamilies() {
+				errCode, err := pMgr.runIPTablesCommandForFamily(family, util.IptablesDestroyFlag, chain)
+				if err != nil && errCode != doesNotExistErrorCode {
+					// add to staleChains if it's not one of the iptablesAzureChains
+					pMgr.staleChains.add(chain)
+					currentErrString := familyErrorString(family, fmt.Sprintf("failed to clean up chain %s with err [%v]", chain, err))
+					if aggregateError == nil {
+						aggregateError = npmerrors.SimpleError(currentErrString)
+					} else {
+						aggregateError = npmerrors.SimpleErrorWrapper(fmt.Sprintf("%s and had previous error", currentErrString), aggregateError)
+					}
 				}
 			}
 		}
@@ -360,12 +381,17 @@ func (pMgr *PolicyManager) runIPTablesCommand(operationFlag string, args ...stri
 }
 
 func (pMgr *PolicyManager) ignoreErrorsAndRunIPTablesCommand(ignored []*exitErrorInfo, operationFlag string, args ...string) (int, error) {
+	return pMgr.ignoreErrorsAndRunCommand(util.Iptables, ignored, operationFlag, args...)
+}
+
+// ignoreErrorsAndRunCommand runs an iptables or ip6tables command.
+func (pMgr *PolicyManager) ignoreErrorsAndRunCommand(iptablesCommand string, ignored []*exitErrorInfo, operationFlag string, args ...string) (int, error) {
 	allArgs := []string{util.IptablesWaitFlag, util.IptablesDefaultWaitTime, operationFlag}
 	allArgs = append(allArgs, args...)
 
-	klog.Infof("Executing iptables command with args %v", allArgs)
+	klog.Infof("Executing %s command with args %v", iptablesCommand, allArgs)
 
-	command := pMgr.ioShim.Exec.Command(util.Iptables, allArgs...)
+	command := pMgr.ioShim.Exec.Command(iptablesCommand, allArgs...)
 	output, err := command.CombinedOutput()
 
 	var exitError utilexec.ExitError
@@ -375,14 +401,14 @@ func (pMgr *PolicyManager) ignoreErrorsAndRunIPTablesCommand(ignored []*exitErro
 		outputString := strings.TrimSuffix(string(output), "\n")
 		for _, info := range ignored {
 			if errCode == info.exitCode && strings.Contains(outputString, info.stdErr) {
-				klog.Infof("%s. not able to run iptables command [%s %s]. exit code: %d, output: %s", info.messageToLog, util.Iptables, allArgsString, errCode, outputString)
+				klog.Infof("%s. not able to run iptables command [%s %s]. exit code: %d, output: %s", info.messageToLog, iptablesCommand, allArgsString, errCode, outputString)
 				return errCode, nil
 			}
 		}
 		if errCode > 0 {
-			metrics.SendErrorLogAndMetric(util.IptmID, "error: There was an error running command: [%s %s] Stderr: [%v, %s]", util.Iptables, allArgsString, exitError, outputString)
+			metrics.SendErrorLogAndMetric(util.IptmID, "error: There was an error running command: [%s %s] Stderr: [%v, %s]", iptablesCommand, allArgsString, exitError, outputString)
 		}
-		return errCode, fmt.Errorf("failed to run iptables command [%s %s] Stderr: [%s]. err: [%w]", util.Iptables, allArgsString, outputString, exitError)
+		return errCode, fmt.Errorf("failed to run iptables command [%s %s] Stderr: [%s]. err: [%w]", iptablesCommand, allArgsString, outputString, exitError)
 	}
 	return 0, nil
 }
@@ -390,8 +416,18 @@ func (pMgr *PolicyManager) ignoreErrorsAndRunIPTablesCommand(ignored []*exitErro
 // Writes the restore file for bootup, and marks the following as stale: deprecated chains and old v2 policy chains.
 // This is a separate function to help with UTs.
 func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *ioutil.FileCreator {
-	chainsToCreate := make([]string, 0, len(iptablesAzureChains))
-	for _, chain := range iptablesAzureChains {
+	pMgr.staleChains.empty()
+	creator := pMgr.creatorForBaseChains(currentChains)
+	creator.AddLine("", nil, util.IptablesRestoreCommit)
+	return creator
+}
+
+// creatorForBaseChains writes the restore file lines which flush the current chains and configure the base chains and their rules.
+// The current chains are marked as stale.
+func (pMgr *PolicyManager) creatorForBaseChains(currentChains map[string]struct{}) *ioutil.FileCreator {
+	baseChains := pMgr.baseChains()
+	chainsToCreate := make([]string, 0, len(baseChains))
+	for _, chain := range baseChains {
 		_, exists := currentChains[chain]
 		if !exists {
 			chainsToCreate = append(chainsToCreate, chain)
@@ -401,18 +437,25 @@ func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *
 	// Step 2.1 in bootup() comment: cleanup old NPM chains, and configure base chains and their rules
 	// To leave NPM deactivated, don't specify any rules for AZURE-NPM chain.
 	creator := pMgr.newCreatorWithChains(chainsToCreate)
-	pMgr.staleChains.empty()
 	for chain := range currentChains {
 		creator.AddLine("", nil, fmt.Sprintf("-F %s", chain))
 		// Step 2.2 in bootup() comment: delete deprecated chains and old v2 policy chains in the background
-		pMgr.staleChains.add(chain) // won't add base chains
+		if !pMgr.isAdminTierChain(chain) {
+			pMgr.staleChains.add(chain) // won't add base chains
+		}
 	}
 
 	// add AZURE-NPM-INGRESS chain rules
+	if pMgr.AdminNetworkPolicy {
+		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesAzureAdminIngressChain)
+	}
 	ingressDropSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesDrop}
 	ingressDropSpecs = append(ingressDropSpecs, onMarkSpecs(util.IptablesAzureIngressDropMarkHex)...)
 	ingressDropSpecs = append(ingressDropSpecs, commentSpecs(fmt.Sprintf("DROP-ON-INGRESS-DROP-MARK-%s", util.IptablesAzureIngressDropMarkHex))...)
 	creator.AddLine("", nil, ingressDropSpecs...)
+	if pMgr.AdminNetworkPolicy {
+		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesAzureBaselineAdminIngressChain)
+	}
 
 	// add AZURE-NPM-INGRESS-ALLOW-MARK chain
 	markIngressAllowSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureIngressAllowMarkChain}
@@ -422,10 +465,16 @@ func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *
 	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureIngressAllowMarkChain, util.IptablesJumpFlag, util.IptablesAzureEgressChain)
 
 	// add AZURE-NPM-EGRESS chain rules
+	if pMgr.AdminNetworkPolicy {
+		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureAdminEgressChain)
+	}
 	egressDropSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesDrop}
 	egressDropSpecs = append(egressDropSpecs, onMarkSpecs(util.IptablesAzureEgressDropMarkHex)...)
 	egressDropSpecs = append(egressDropSpecs, commentSpecs(fmt.Sprintf("DROP-ON-EGRESS-DROP-MARK-%s", util.IptablesAzureEgressDropMarkHex))...)
 	creator.AddLine("", nil, egressDropSpecs...)
+	if pMgr.AdminNetworkPolicy {
+		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureBaselineAdminEgressChain)
+	}
 
 	jumpOnIngressMatchSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureAcceptChain}
 	jumpOnIngressMatchSpecs = append(jumpOnIngressMatchSpecs, onMarkSpecs(util.IptablesAzureIngressAllowMarkHex)...)
@@ -434,7 +483,6 @@ func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *
 
 	// add AZURE-NPM-ACCEPT chain rules
 	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureAcceptChain, util.IptablesJumpFlag, util.IptablesAccept)
-	creator.AddLine("", nil, util.IptablesRestoreCommit)
 	return creator
 }
 
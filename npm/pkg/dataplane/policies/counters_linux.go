@@ -0,0 +1,245 @@
+package policies
+
+// This file contains code for reading the packet and byte counters of ACLs and exporting them per policy.
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/util"
+	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
+)
+
+const (
+	iptablesChainHeaderPrefix = "Chain "
+	iptablesCommentStart      = "/* "
+	iptablesCommentEnd        = " */"
+	nftChainHeaderPrefix      = "chain "
+)
+
+var (
+	// listCountersArgs lists every rule of the filter table with its exact counters
+	listCountersArgs = []string{
+		util.IptablesWaitFlag, util.IptablesDefaultWaitTime, util.IptablesTableFlag, util.IptablesFilterTable,
+		util.IptablesListFlag, util.IptablesVerboseFlag, util.IptablesExactFlag, util.IptablesNumericFlag,
+	}
+	listNftCountersArgs = []string{util.NftTerseFlag, util.NftList, util.NftTableObj, util.NftFamily, util.NftTable}
+
+	nftCounterRegex = regexp.MustCompile(`counter packets (\d+) bytes (\d+)`)
+	nftCommentRegex = regexp.MustCompile(`comment "(.*)"\s*$`)
+)
+
+// aclChain identifies the ACLs of a policy chain by their comments.
+type aclChain struct {
+	policyKey string
+	direction Direction
+	verdicts  map[string]Verdict
+}
+
+// ruleCounter is the counter of a rule in a chain. The target is only known for iptables.
+type ruleCounter struct {
+	chain   string
+	target  string
+	comment string
+	packets uint64
+	bytes   uint64
+}
+
+// recordPolicyTraffic exports the packet and byte counters of the ACLs of every policy as metrics.
+// The counters of a policy's ACLs with the same direction and verdict are summed.
+func (pMgr *PolicyManager) recordPolicyTraffic() {
+	traffic, err := pMgr.policyTraffic()
+	if err != nil {
+		metrics.SendErrorLogAndMetric(util.IptmID, "error: failed to read policy counters: %s", err.Error())
+		return
+	}
+	metrics.SetPolicyTraffic(traffic)
+}
+
+func (pMgr *PolicyManager) policyTraffic() (map[metrics.PolicyTrafficKey]metrics.PolicyTraffic, error) {
+	chains := pMgr.aclChains()
+
+	var counters []*ruleCounter
+	if pMgr.Nftables {
+		// the inet table has the rules of both families
+		output, err := pMgr.ioShim.Exec.Command(util.Nft, listNftCountersArgs...).CombinedOutput()
+		if err != nil {
+			return nil, npmerrors.SimpleErrorWrapper(fmt.Sprintf("failed to list nft table with output: %s", string(output)), err)
+		}
+		counters = parseNftCounters(string(output))
+	} else {
+		for _, family := range pMgr.ipFamilies() {
+			output, err := pMgr.ioShim.Exec.Command(family.iptablesCommand(), listCountersArgs...).CombinedOutput()
+			if err != nil {
+				return nil, npmerrors.SimpleErrorWrapper(fmt.Sprintf("failed to list %s rules with output: %s", family, string(output)), err)
+			}
+			counters = append(counters, parseIPTablesCounters(string(output))...)
+		}
+	}
+
+	traffic := make(map[metrics.PolicyTrafficKey]metrics.PolicyTraffic)
+	for _, counter := range counters {
+		if counter.target == util.IptablesNFLog {
+			// audit rules have the same comment as their ACL and count the same packets
+			continue
+		}
+		chain, ok := chains[counter.chain]
+		if !ok {
+			continue
+		}
+		verdict, ok := chain.verdicts[counter.comment]
+		if !ok {
+			continue
+		}
+		key := metrics.PolicyTrafficKey{
+			PolicyKey: chain.policyKey,
+			Direction: string(chain.direction),
+			Verdict:   string(verdict),
+		}
+		value := traffic[key]
+		value.Packets += counter.packets
+		value.Bytes += counter.bytes
+		traffic[key] = value
+	}
+	return traffic, nil
+}
+
+// aclChains returns the policy chains by name, with the verdicts of their ACLs by comment.
+func (pMgr *PolicyManager) aclChains() map[string]*aclChain {
+	pMgr.policyMap.RLock()
+	defer pMgr.policyMap.RUnlock()
+
+	chains := make(map[string]*aclChain)
+	for _, networkPolicy := range pMgr.policyMap.cache {
+		for _, aclPolicy := range networkPolicy.ACLs {
+			var chainName string
+			var direction Direction
+			if aclPolicy.hasIngress() {
+				chainName = networkPolicy.ingressChainName()
+				direction = Ingress
+			} else {
+				chainName = networkPolicy.egressChainName()
+				direction = Egress
+			}
+
+			chain, ok := chains[chainName]
+			if !ok {
+				chain = &aclChain{
+					policyKey: networkPolicy.PolicyKey,
+					direction: direction,
+					verdicts:  make(map[string]Verdict),
+				}
+				chains[chainName] = chain
+			}
+
+			comment := aclPolicy.comment()
+			if pMgr.Nftables {
+				comment = truncateNftComment(comment)
+			}
+			chain.verdicts[comment] = aclPolicy.Target
+		}
+	}
+	return chains
+}
+
+// parseIPTablesCounters parses the output of "iptables -L -v -x -n", which looks like:
+//
+//	Chain AZURE-NPM-INGRESS-123 (1 references)
+//	    pkts      bytes target     prot opt in     out     source               destination
+//	      10      600 MARK       tcp  --  *      *       0.0.0.0/0            0.0.0.0/0            tcp dpt:222 /* DROP-ALL-TCP-ON-PORT-222 */ MARK or 0x4000
+//
+// Lines which aren't rules are ignored.
+func parseIPTablesCounters(output string) []*ruleCounter {
+	var counters []*ruleCounter
+	var chain string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, iptablesChainHeaderPrefix) {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 {
+				chain = fields[1]
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if chain == "" || len(fields) < 3 {
+			continue
+		}
+		packets, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		bytes, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		counter := &ruleCounter{
+			chain:   chain,
+			target:  fields[2],
+			packets: packets,
+			bytes:   bytes,
+		}
+		if start := strings.Index(line, iptablesCommentStart); start >= 0 {
+			rest := line[start+len(iptablesCommentStart):]
+			if end := strings.Index(rest, iptablesCommentEnd); end >= 0 {
+				counter.comment = rest[:end]
+			}
+		}
+		counters = append(counters, counter)
+	}
+	return counters
+}
+
+/*
+parseNftCounters parses the output of "nft -t list table inet azure-npm", which looks like:
+
+	table inet azure-npm {
+		chain AZURE-NPM-INGRESS-123 {
+			tcp dport 222 counter packets 10 bytes 600 meta mark set meta mark | 0x00004000 comment "DROP-ALL-TCP-ON-PORT-222"
+		}
+	}
+
+Rules without a counter statement are ignored.
+*/
+func parseNftCounters(output string) []*ruleCounter {
+	var counters []*ruleCounter
+	var chain string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, nftChainHeaderPrefix) {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 {
+				chain = fields[1]
+			}
+			continue
+		}
+
+		match := nftCounterRegex.FindStringSubmatch(line)
+		if chain == "" || match == nil {
+			continue
+		}
+		packets, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		bytes, err := strconv.ParseUint(match[2], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		counter := &ruleCounter{
+			chain:   chain,
+			packets: packets,
+			bytes:   bytes,
+		}
+		if commentMatch := nftCommentRegex.FindStringSubmatch(line); commentMatch != nil {
+			counter.comment = commentMatch[1]
+		}
+		counters = append(counters, counter)
+	}
+	return counters
+}
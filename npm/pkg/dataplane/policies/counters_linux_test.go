@@ -0,0 +1,165 @@
+package policies
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/util"
+	testutils "github.com/Azure/azure-container-networking/test/utils"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	countersConfig = &PolicyManagerCfg{
+		PolicyMode:           IPSetPolicyMode,
+		PlaceAzureChainFirst: util.PlaceAzureChainFirst,
+		Counters:             true,
+	}
+
+	nftCountersConfig = &PolicyManagerCfg{
+		PolicyMode:           IPSetPolicyMode,
+		PlaceAzureChainFirst: util.PlaceAzureChainFirst,
+		Nftables:             true,
+		Counters:             true,
+	}
+
+	listCountersCommand    = []string{"iptables", "-w", "60", "-t", "filter", "-L", "-v", "-x", "-n"}
+	listNftCountersCommand = []string{"nft", "-t", "list", "table", "inet", "azure-npm"}
+)
+
+// iptables output for bothDirectionsNetPol with audit enabled
+var iptablesCountersOutput = fmt.Sprintf(`Chain FORWARD (policy ACCEPT 0 packets, 0 bytes)
+    pkts      bytes target     prot opt in     out     source               destination
+     100     9000 AZURE-NPM  all  --  *      *       0.0.0.0/0            0.0.0.0/0            /* azure-npm */
+
+Chain %[1]s (1 references)
+    pkts      bytes target     prot opt in     out     source               destination
+       3      180 NFLOG      tcp  --  *      *       0.0.0.0/0            0.0.0.0/0            tcp dpts:222:333 match-set %[3]s src ! match-set %[4]s dst /* %[5]s */ nflog-prefix  IN-DROP-x/test1 nflog-group 100
+       3      180 MARK       tcp  --  *      *       0.0.0.0/0            0.0.0.0/0            tcp dpts:222:333 match-set %[3]s src ! match-set %[4]s dst /* %[5]s */ MARK or 0x4000
+      10     1000 AZURE-NPM-INGRESS-ALLOW-MARK  all  --  *      *       0.0.0.0/0            0.0.0.0/0            match-set %[3]s src /* %[6]s */
+
+Chain %[2]s (1 references)
+    pkts      bytes target     prot opt in     out     source               destination
+       0        0 MARK       udp  --  *      *       0.0.0.0/0            0.0.0.0/0            udp dpt:144 match-set %[3]s dst /* %[7]s */ MARK or 0x5000
+       7      700 AZURE-NPM-ACCEPT  all  --  *      *       0.0.0.0/0            0.0.0.0/0            match-set %[8]s dst /* %[9]s */
+`,
+	bothDirectionsNetPolIngressChain, bothDirectionsNetPolEgressChain,
+	ipsets.TestCIDRSet.HashedName, ipsets.TestKeyPodSet.HashedName, ingressDropComment, ingressAllowComment,
+	egressDropComment, ipsets.TestNamedportSet.HashedName, egressAllowComment,
+)
+
+// nft output for bothDirectionsNetPol
+var nftCountersOutput = fmt.Sprintf(`table inet azure-npm {
+	chain FORWARD {
+		type filter hook forward priority filter - 1; policy accept;
+		jump AZURE-NPM comment "azure-npm"
+	}
+
+	chain %[1]s {
+		meta l4proto tcp th dport 222-333 ip saddr @%[3]s ip daddr != @%[4]s counter packets 3 bytes 180 meta mark set meta mark | 0x00004000 comment %[5]q
+		ip saddr @%[3]s counter packets 10 bytes 1000 jump AZURE-NPM-INGRESS-ALLOW-MARK comment %[6]q
+	}
+
+	chain %[2]s {
+		meta l4proto udp th dport 144 ip daddr @%[3]s counter packets 0 bytes 0 meta mark set meta mark | 0x00005000 comment %[7]q
+		ip daddr @%[8]s counter packets 7 bytes 700 jump AZURE-NPM-ACCEPT comment %[9]q
+	}
+}
+`,
+	bothDirectionsNetPolIngressChain, bothDirectionsNetPolEgressChain,
+	ipsets.TestCIDRSet.HashedName, ipsets.TestKeyPodSet.HashedName, ingressDropComment, ingressAllowComment,
+	egressDropComment, ipsets.TestNamedportSet.HashedName, egressAllowComment,
+)
+
+func TestParseIPTablesCounters(t *testing.T) {
+	counters := parseIPTablesCounters(iptablesCountersOutput)
+	require.Len(t, counters, 6)
+	require.Equal(t, &ruleCounter{chain: "FORWARD", target: "AZURE-NPM", comment: "azure-npm", packets: 100, bytes: 9000}, counters[0])
+	require.Equal(t, &ruleCounter{chain: bothDirectionsNetPolIngressChain, target: "NFLOG", comment: ingressDropComment, packets: 3, bytes: 180}, counters[1])
+	require.Equal(t, &ruleCounter{chain: bothDirectionsNetPolIngressChain, target: "MARK", comment: ingressDropComment, packets: 3, bytes: 180}, counters[2])
+	require.Equal(t, &ruleCounter{chain: bothDirectionsNetPolEgressChain, target: "AZURE-NPM-ACCEPT", comment: egressAllowComment, packets: 7, bytes: 700}, counters[5])
+}
+
+func TestParseNftCounters(t *testing.T) {
+	counters := parseNftCounters(nftCountersOutput)
+	// the jump in the FORWARD chain has no counter
+	require.Len(t, counters, 4)
+	require.Equal(t, &ruleCounter{chain: bothDirectionsNetPolIngressChain, comment: ingressDropComment, packets: 3, bytes: 180}, counters[0])
+	require.Equal(t, &ruleCounter{chain: bothDirectionsNetPolEgressChain, comment: egressAllowComment, packets: 7, bytes: 700}, counters[3])
+}
+
+func TestCreatorForNftPoliciesWithCounters(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, nftCountersConfig)
+
+	activePolicies := map[string]*NPMNetworkPolicy{egressNetPol.PolicyKey: egressNetPol}
+	creator := pMgr.creatorForNftPolicies(activePolicies, []*NPMNetworkPolicy{egressNetPol}, nil)
+	// the counter statement comes after the matches and before the action
+	expectedRule := fmt.Sprintf(`add rule inet azure-npm %s ip daddr @%s counter jump AZURE-NPM-ACCEPT comment %q`,
+		egressNetPolChain, ipsets.TestNamedportSet.HashedName, egressAllowComment)
+	require.Contains(t, strings.Split(creator.ToString(), "\n"), expectedRule)
+}
+
+func TestRecordPolicyTraffic(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   *PolicyManagerCfg
+		calls []testutils.TestCmd
+	}{
+		{
+			name:  "iptables",
+			cfg:   countersConfig,
+			calls: []testutils.TestCmd{{Cmd: listCountersCommand, Stdout: iptablesCountersOutput}},
+		},
+		{
+			name:  "nftables",
+			cfg:   nftCountersConfig,
+			calls: []testutils.TestCmd{{Cmd: listNftCountersCommand, Stdout: nftCountersOutput}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			metrics.ReinitializeAll()
+			ioshim := common.NewMockIOShim(tt.calls)
+			defer ioshim.VerifyCalls(t, tt.calls)
+			pMgr := NewPolicyManager(ioshim, tt.cfg)
+			pMgr.policyMap.cache[bothDirectionsNetPol.PolicyKey] = bothDirectionsNetPol
+
+			pMgr.RecordPolicyTraffic()
+
+			expected := map[metrics.PolicyTrafficKey]metrics.PolicyTraffic{
+				// the NFLOG rule isn't counted twice
+				{PolicyKey: "x/test1", Direction: "IN", Verdict: "DROP"}:   {Packets: 3, Bytes: 180},
+				{PolicyKey: "x/test1", Direction: "IN", Verdict: "ALLOW"}:  {Packets: 10, Bytes: 1000},
+				{PolicyKey: "x/test1", Direction: "OUT", Verdict: "DROP"}:  {Packets: 0, Bytes: 0},
+				{PolicyKey: "x/test1", Direction: "OUT", Verdict: "ALLOW"}: {Packets: 7, Bytes: 700},
+			}
+			for key, expectedTraffic := range expected {
+				traffic, ok := metrics.GetPolicyTraffic(key)
+				require.True(t, ok, "missing traffic for %+v", key)
+				require.Equal(t, expectedTraffic, traffic, "wrong traffic for %+v", key)
+			}
+		})
+	}
+}
+
+func TestRecordPolicyTrafficFailure(t *testing.T) {
+	metrics.ReinitializeAll()
+	calls := []testutils.TestCmd{{Cmd: listCountersCommand, ExitCode: 1}}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, countersConfig)
+	pMgr.policyMap.cache[bothDirectionsNetPol.PolicyKey] = bothDirectionsNetPol
+
+	pMgr.RecordPolicyTraffic()
+
+	_, ok := metrics.GetPolicyTraffic(metrics.PolicyTrafficKey{PolicyKey: "x/test1", Direction: "IN", Verdict: "ALLOW"})
+	require.False(t, ok)
+}
@@ -0,0 +1,104 @@
+package policies
+
+// This file contains code for programming IPv6 policies alongside IPv4 policies on dual-stack nodes.
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/util"
+	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
+	"github.com/Azure/azure-container-networking/npm/util/ioutil"
+	"k8s.io/klog"
+)
+
+// ipFamily is the IP family that a set of iptables/nftables rules applies to.
+type ipFamily string
+
+const (
+	ipv4 ipFamily = "IPv4"
+	ipv6 ipFamily = "IPv6"
+)
+
+// ipFamilies returns the IP families to program policies for. IPv4 always comes first.
+func (pMgr *PolicyManager) ipFamilies() []ipFamily {
+	if pMgr.DualStack {
+		return []ipFamily{ipv4, ipv6}
+	}
+	return []ipFamily{ipv4}
+}
+
+// setName returns the name of the kernel set which holds the family's members of the set with the given hashed name.
+func (family ipFamily) setName(hashedName string) string {
+	if family == ipv6 {
+		return ipsets.IPv6SetName(hashedName)
+	}
+	return hashedName
+}
+
+func (family ipFamily) iptablesCommand() string {
+	if family == ipv6 {
+		return util.Ip6tables
+	}
+	return util.Iptables
+}
+
+func (family ipFamily) iptablesRestoreCommand() string {
+	if family == ipv6 {
+		return util.Ip6tablesRestore
+	}
+	return util.IptablesRestore
+}
+
+/*
+bootupIPv6 configures the same base chains in ip6tables as bootup does in iptables.
+The policy chains have the same names in both families, but their rules in ip6tables match the IPv6 twins of the ipsets.
+
+Unlike in iptables, the jump from FORWARD chain to AZURE-NPM chain is added during bootup and isn't repositioned by reconcile.
+When AZURE-NPM chain isn't placed first, the jump is appended so that it comes after the jumps that kube-proxy prepends.
+*/
+func (pMgr *PolicyManager) bootupIPv6() error {
+	klog.Infof("booting up ip6tables Azure chains")
+
+	// 1. delete the jump to AZURE-NPM so that it isn't duplicated below
+	errCode, err := pMgr.ignoreErrorsAndRunCommand(util.Ip6tables, removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, jumpFromForwardToAzureChainArgs...)
+	if errCode == 0 {
+		klog.Infof("deleted jump rule from FORWARD chain to AZURE-NPM chain in ip6tables")
+	} else if err != nil {
+		metrics.SendErrorLogAndMetric(util.IptmID,
+			"failed to delete jump rule from FORWARD chain to AZURE-NPM chain in ip6tables for unexpected reason with exit code %d and error: %s",
+			errCode, err.Error())
+	}
+
+	currentChains, err := ioutil.AllCurrentAzureIPv6Chains(pMgr.ioShim.Exec, util.IptablesDefaultWaitTime)
+	if err != nil {
+		return npmerrors.SimpleErrorWrapper("failed to get current ip6tables chains for bootup", err)
+	}
+
+	// 2. cleanup old NPM chains, configure base chains and their rules, and add the jump to AZURE-NPM
+	if err := restore(ipv6, pMgr.creatorForIPv6Bootup(currentChains)); err != nil {
+		return npmerrors.SimpleErrorWrapper("failed to run ip6tables-restore for bootup", err)
+	}
+	return nil
+}
+
+// Writes the ip6tables-restore file for bootup, and marks old NPM chains as stale. reconcile deletes stale chains from both families.
+func (pMgr *PolicyManager) creatorForIPv6Bootup(currentChains map[string]struct{}) *ioutil.FileCreator {
+	creator := pMgr.creatorForBaseChains(currentChains)
+	if pMgr.PlaceAzureChainFirst == util.PlaceAzureChainFirst {
+		creator.AddLine("", nil, append([]string{util.IptablesInsertionFlag, util.IptablesForwardChain, "1"}, jumpToAzureChainArgs...)...)
+	} else {
+		creator.AddLine("", nil, append([]string{util.IptablesAppendFlag}, jumpFromForwardToAzureChainArgs...)...)
+	}
+	creator.AddLine("", nil, util.IptablesRestoreCommit)
+	return creator
+}
+
+func (pMgr *PolicyManager) runIPTablesCommandForFamily(family ipFamily, operationFlag string, args ...string) (int, error) {
+	return pMgr.ignoreErrorsAndRunCommand(family.iptablesCommand(), nil, operationFlag, args...)
+}
+
+func familyErrorString(family ipFamily, errString string) string {
+	return fmt.Sprintf("%s for %s", errString, family)
+}
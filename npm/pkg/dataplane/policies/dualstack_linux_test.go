@@ -0,0 +1,244 @@
+package policies
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
+	"github.com/Azure/azure-container-networking/npm/util"
+	testutils "github.com/Azure/azure-container-networking/test/utils"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	dualStackConfig = &PolicyManagerCfg{
+		PolicyMode:           IPSetPolicyMode,
+		PlaceAzureChainFirst: util.PlaceAzureChainFirst,
+		DualStack:            true,
+	}
+
+	nftDualStackConfig = &PolicyManagerCfg{
+		PolicyMode:           IPSetPolicyMode,
+		PlaceAzureChainFirst: util.PlaceAzureChainFirst,
+		Nftables:             true,
+		DualStack:            true,
+	}
+
+	fakeIP6TablesRestoreCommand = testutils.TestCmd{Cmd: []string{"ip6tables-restore", "-w", "60", "-T", "filter", "--noflush"}}
+)
+
+func TestIPFamilies(t *testing.T) {
+	require.Equal(t, []ipFamily{ipv4}, NewPolicyManager(common.NewMockIOShim(nil), ipsetConfig).ipFamilies())
+	require.Equal(t, []ipFamily{ipv4, ipv6}, NewPolicyManager(common.NewMockIOShim(nil), dualStackConfig).ipFamilies())
+}
+
+func TestCreatorForIPv6Bootup(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, dualStackConfig)
+
+	creator := pMgr.creatorForIPv6Bootup(stringsToMap([]string{"AZURE-NPM", "AZURE-NPM-INGRESS-123456"}))
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		":AZURE-NPM-INGRESS - -",
+		":AZURE-NPM-INGRESS-ALLOW-MARK - -",
+		":AZURE-NPM-EGRESS - -",
+		":AZURE-NPM-ACCEPT - -",
+		"-F AZURE-NPM",
+		"-F AZURE-NPM-INGRESS-123456",
+		"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+		"-A AZURE-NPM-INGRESS-ALLOW-MARK -j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+		"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
+		"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+		"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
+		"-A AZURE-NPM-ACCEPT -j ACCEPT",
+		"-I FORWARD 1 -j AZURE-NPM -m conntrack --ctstate NEW",
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, sortFlushes(expectedLines), sortFlushes(actualLines))
+	assertStaleChainsContain(t, pMgr.staleChains, "AZURE-NPM-INGRESS-123456")
+}
+
+func TestCreatorForIPv6BootupAfterKubeServices(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	cfg := *dualStackConfig
+	cfg.PlaceAzureChainFirst = util.PlaceAzureChainAfterKubeServices
+	pMgr := NewPolicyManager(ioshim, &cfg)
+
+	creator := pMgr.creatorForIPv6Bootup(nil)
+	require.Contains(t, creator.ToString(), "-A FORWARD -j AZURE-NPM -m conntrack --ctstate NEW\nCOMMIT\n")
+}
+
+func TestCreatorForAddPoliciesIPv6(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, dualStackConfig)
+
+	policies := []*NPMNetworkPolicy{egressNetPol}
+	creator := pMgr.creatorForNewNetworkPolicies(ipv6, chainNames(policies), policies)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		fmt.Sprintf(":%s - -", egressNetPolChain),
+		"-F AZURE-NPM",
+		"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-EGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-ACCEPT",
+		fmt.Sprintf("-A %s -j AZURE-NPM-ACCEPT -m set --match-set %s-6 dst -m comment --comment %s",
+			egressNetPolChain, ipsets.TestNamedportSet.HashedName, egressAllowComment),
+		fmt.Sprintf("-I AZURE-NPM-EGRESS 1 %s", egressNetPolJump),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestAddAndRemovePoliciesDualStack(t *testing.T) {
+	calls := []testutils.TestCmd{
+		fakeIPTablesRestoreCommand,
+		fakeIP6TablesRestoreCommand,
+		{Cmd: append([]string{"iptables", "-w", "60", "-D", util.IptablesAzureEgressChain}, egressJumpSpecs(ipv4, egressNetPol)...)},
+		fakeIPTablesRestoreCommand,
+		{Cmd: append([]string{"ip6tables", "-w", "60", "-D", util.IptablesAzureEgressChain}, egressJumpSpecs(ipv6, egressNetPol)...)},
+		fakeIP6TablesRestoreCommand,
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, dualStackConfig)
+
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{egressNetPol}, nil))
+	require.NoError(t, pMgr.RemovePolicy(egressNetPol.PolicyKey))
+	assertStaleChainsContain(t, pMgr.staleChains, egressNetPolChain)
+}
+
+func TestAddPoliciesDualStackRollsBackIPv4(t *testing.T) {
+	fakeIP6TablesRestoreFailureCommand := fakeIP6TablesRestoreCommand
+	fakeIP6TablesRestoreFailureCommand.ExitCode = 1
+	calls := []testutils.TestCmd{
+		fakeIPTablesRestoreCommand,
+		fakeIP6TablesRestoreFailureCommand,
+		fakeIP6TablesRestoreFailureCommand,
+		// the IPv4 jump rule and chain are removed so that a retry doesn't insert the jump rule twice
+		{Cmd: append([]string{"iptables", "-w", "60", "-D", util.IptablesAzureEgressChain}, egressJumpSpecs(ipv4, egressNetPol)...)},
+		fakeIPTablesRestoreCommand,
+		// the retry adds the policy to both families once
+		fakeIPTablesRestoreCommand,
+		fakeIP6TablesRestoreCommand,
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, dualStackConfig)
+
+	require.Error(t, pMgr.AddPolicies([]*NPMNetworkPolicy{egressNetPol}, nil))
+	_, ok := pMgr.GetPolicy(egressNetPol.PolicyKey)
+	require.False(t, ok)
+
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{egressNetPol}, nil))
+	_, ok = pMgr.GetPolicy(egressNetPol.PolicyKey)
+	require.True(t, ok)
+}
+
+func TestCreatorForRollingBackFirstPolicy(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, dualStackConfig)
+
+	// NPM is deactivated when rolling back the first policies
+	creator := pMgr.creatorForFlushingPolicies([]string{egressNetPolChain}, pMgr.isFirstPolicy())
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		"-F AZURE-NPM",
+		fmt.Sprintf("-F %s", egressNetPolChain),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestRemovePolicyDualStackRetry(t *testing.T) {
+	fakeIP6TablesRestoreFailureCommand := fakeIP6TablesRestoreCommand
+	fakeIP6TablesRestoreFailureCommand.ExitCode = 1
+	ipv4Delete := append([]string{"iptables", "-w", "60", "-D", util.IptablesAzureEgressChain}, egressJumpSpecs(ipv4, egressNetPol)...)
+	ipv6Delete := append([]string{"ip6tables", "-w", "60", "-D", util.IptablesAzureEgressChain}, egressJumpSpecs(ipv6, egressNetPol)...)
+	calls := []testutils.TestCmd{
+		fakeIPTablesRestoreCommand,
+		fakeIP6TablesRestoreCommand,
+		{Cmd: ipv4Delete},
+		fakeIPTablesRestoreCommand,
+		{Cmd: ipv6Delete},
+		fakeIP6TablesRestoreFailureCommand,
+		fakeIP6TablesRestoreFailureCommand,
+		// the IPv4 jump rule is already gone on retry
+		{Cmd: ipv4Delete, ExitCode: 1},
+		fakeIPTablesRestoreCommand,
+		{Cmd: ipv6Delete, ExitCode: 1},
+		fakeIP6TablesRestoreCommand,
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, dualStackConfig)
+
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{egressNetPol}, nil))
+	require.Error(t, pMgr.RemovePolicy(egressNetPol.PolicyKey))
+	require.NoError(t, pMgr.RemovePolicy(egressNetPol.PolicyKey))
+	_, ok := pMgr.GetPolicy(egressNetPol.PolicyKey)
+	require.False(t, ok)
+}
+
+func TestCleanupChainsDualStack(t *testing.T) {
+	calls := []testutils.TestCmd{
+		{Cmd: []string{"iptables", "-w", "60", "-X", "AZURE-NPM-INGRESS-123456"}},
+		{Cmd: []string{"ip6tables", "-w", "60", "-X", "AZURE-NPM-INGRESS-123456"}, ExitCode: 2},
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, dualStackConfig)
+
+	require.Error(t, pMgr.cleanupChains([]string{"AZURE-NPM-INGRESS-123456"}))
+	assertStaleChainsContain(t, pMgr.staleChains, "AZURE-NPM-INGRESS-123456")
+}
+
+func TestCreatorForNftPoliciesDualStack(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, nftDualStackConfig)
+
+	activePolicies := map[string]*NPMNetworkPolicy{egressNetPol.PolicyKey: egressNetPol}
+	creator := pMgr.creatorForNftPolicies(activePolicies, []*NPMNetworkPolicy{egressNetPol}, nil)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		fmt.Sprintf("add chain inet azure-npm %s", egressNetPolChain),
+		fmt.Sprintf("flush chain inet azure-npm %s", egressNetPolChain),
+		fmt.Sprintf("add rule inet azure-npm %s %s", egressNetPolChain, nftEgressAllowRule),
+		fmt.Sprintf("add rule inet azure-npm %s ip6 daddr @%s-6 jump AZURE-NPM-ACCEPT comment %q",
+			egressNetPolChain, ipsets.TestNamedportSet.HashedName, egressAllowComment),
+		"flush chain inet azure-npm AZURE-NPM",
+		"add rule inet azure-npm AZURE-NPM jump AZURE-NPM-INGRESS",
+		"add rule inet azure-npm AZURE-NPM jump AZURE-NPM-EGRESS",
+		"add rule inet azure-npm AZURE-NPM jump AZURE-NPM-ACCEPT",
+		"flush chain inet azure-npm AZURE-NPM-INGRESS",
+		nftIngressDropOnMarkRule,
+		"flush chain inet azure-npm AZURE-NPM-EGRESS",
+		// the policy has no pod selector sets, so its jump matches both families
+		fmt.Sprintf("add rule inet azure-npm AZURE-NPM-EGRESS jump %s comment %q", egressNetPolChain, egressNetPolJumpComment),
+		nftEgressMarkDecisionsRule,
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestNftMatchSetSpecsIPv6(t *testing.T) {
+	info := SetInfo{ipsets.TestCIDRSet.Metadata, true, SrcMatch}
+	expected := []string{
+		"ip6 saddr", "@" + ipsets.TestCIDRSet.HashedName + "-6",
+		"ip6 saddr", "!= @" + ipsets.TestCIDRSet.HashedName + "-6-except",
+	}
+	require.Equal(t, expected, info.nftMatchSetSpecs(ipv6, SrcMatch))
+}
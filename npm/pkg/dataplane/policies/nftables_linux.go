@@ -0,0 +1,491 @@
+package policies
+
+// This file contains code for the nftables implementation of booting up and adding/removing policies.
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/util"
+	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
+	"github.com/Azure/azure-container-networking/npm/util/ioutil"
+	"k8s.io/klog"
+)
+
+const (
+	// the forward chain runs right before or after iptables' FORWARD chain, which has the filter priority of 0
+	nftPriorityBeforeIptables = -1
+	nftPriorityAfterIptables  = 1
+
+	// nft rejects longer comments
+	maxNftCommentLength = 128
+)
+
+/*
+In nftables mode, NPM has its own table with the same chains as in iptables, plus a FORWARD base chain hooked into forward.
+Policy chains and the jumps to them are changed in one atomic nft transaction, so unlike iptables:
+- the jumps are rewritten with the policy chains instead of being inserted and deleted one by one
+- removed policy chains are deleted right away instead of in the background
+- there's no jump to reposition since the FORWARD chain's priority places it relative to iptables
+
+The decisions at the end of AZURE-NPM-EGRESS are made with one verdict map lookup on the mark.
+
+The table is in the inet family, so on dual-stack nodes, each rule which matches sets has an ip6 variant matching the IPv6 twins of the sets.
+Rules without sets already match both families.
+*/
+
+// bootupNft deletes NPM from iptables, and recreates the NPM table with its base chains.
+func (pMgr *PolicyManager) bootupNft() error {
+	klog.Infof("booting up nftables Azure chains")
+
+	// the iptables chains may have policies from when NPM last ran without nftables
+	for _, family := range pMgr.ipFamilies() {
+		pMgr.cleanupIPTablesForNft(family)
+	}
+
+	if err := runNft(pMgr.creatorForNftBootup()); err != nil {
+		return npmerrors.SimpleErrorWrapper("failed to run nft for bootup", err)
+	}
+	return nil
+}
+
+// cleanupIPTablesForNft deletes the jump to AZURE-NPM chain and all NPM chains in the family's iptables, so that they no longer
+// reference the kernel ipsets, which IPSetManager destroys when it resets. Failures are only logged since the chains are inactive without the jump.
+func (pMgr *PolicyManager) cleanupIPTablesForNft(family ipFamily) {
+	errCode, err := pMgr.ignoreErrorsAndRunCommand(family.iptablesCommand(), removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, jumpFromForwardToAzureChainArgs...)
+	if errCode == 0 {
+		klog.Infof("deleted jump rule from FORWARD chain to AZURE-NPM chain in %s", family.iptablesCommand())
+	} else if err != nil {
+		metrics.SendErrorLogAndMetric(util.IptmID,
+			"failed to delete jump rule from FORWARD chain to AZURE-NPM chain in %s for unexpected reason with exit code %d and error: %s",
+			family.iptablesCommand(), errCode, err.Error())
+	}
+
+	listChains := ioutil.AllCurrentAzureChains
+	if family == ipv6 {
+		listChains = ioutil.AllCurrentAzureIPv6Chains
+	}
+	currentChains, err := listChains(pMgr.ioShim.Exec, util.IptablesDefaultWaitTime)
+	if err != nil {
+		metrics.SendErrorLogAndMetric(util.IptmID, "failed to get current chains in %s to delete them: %s", family.iptablesCommand(), err.Error())
+		return
+	}
+
+	// the chains jump to each other, so flush all of them before deleting them
+	chains := make([]string, 0, len(currentChains))
+	for chain := range currentChains {
+		chains = append(chains, chain)
+	}
+	sort.Strings(chains)
+	var aggregateError error
+	for _, operationFlag := range []string{util.IptablesFlushFlag, util.IptablesDestroyFlag} {
+		for _, chain := range chains {
+			errCode, err := pMgr.runIPTablesCommandForFamily(family, operationFlag, chain)
+			if err != nil && errCode != doesNotExistErrorCode {
+				currentErrString := fmt.Sprintf("failed to run %s on chain %s with err [%v]", operationFlag, chain, err)
+				if aggregateError == nil {
+					aggregateError = npmerrors.SimpleError(currentErrString)
+				} else {
+					aggregateError = npmerrors.SimpleErrorWrapper(fmt.Sprintf("%s and had previous error", currentErrString), aggregateError)
+				}
+			}
+		}
+	}
+	if aggregateError != nil {
+		metrics.SendErrorLogAndMetric(util.IptmID, "failed to flush and delete chains in %s with error: %s", family.iptablesCommand(), aggregateError.Error())
+	}
+}
+
+func (pMgr *PolicyManager) addNftPolicies(networkPolicies []*NPMNetworkPolicy) error {
+	activePolicies := make(map[string]*NPMNetworkPolicy, len(pMgr.policyMap.cache)+len(networkPolicies))
+	for key, policy := range pMgr.policyMap.cache {
+		activePolicies[key] = policy
+	}
+	for _, policy := range networkPolicies {
+		activePolicies[policy.PolicyKey] = policy
+	}
+	creator := pMgr.creatorForNftPolicies(activePolicies, networkPolicies, nil)
+
+	timer := metrics.StartNewTimer()
+	err := runNft(creator)
+	metrics.RecordIPTablesRestoreLatency(timer, metrics.CreateOp)
+	if err != nil {
+		metrics.IncIPTablesRestoreFailures(metrics.CreateOp)
+		return fmt.Errorf("failed to run nft with updated policies. err: %w", err)
+	}
+	return nil
+}
+
+func (pMgr *PolicyManager) removeNftPolicy(networkPolicy *NPMNetworkPolicy) error {
+	activePolicies := make(map[string]*NPMNetworkPolicy, len(pMgr.policyMap.cache))
+	for key, policy := range pMgr.policyMap.cache {
+		if key != networkPolicy.PolicyKey {
+			activePolicies[key] = policy
+		}
+	}
+	creator := pMgr.creatorForNftPolicies(activePolicies, nil, networkPolicy)
+
+	timer := metrics.StartNewTimer()
+	err := runNft(creator)
+	metrics.RecordIPTablesRestoreLatency(timer, metrics.DeleteOp)
+	if err != nil {
+		metrics.IncIPTablesRestoreFailures(metrics.DeleteOp)
+		return fmt.Errorf("failed to run nft to remove policy. err: %w", err)
+	}
+	return nil
+}
+
+func runNft(creator *ioutil.FileCreator) error {
+	if err := creator.RunCommandWithFile(util.Nft, util.NftFileFlag, util.NftStdin); err != nil {
+		return fmt.Errorf("failed to run nft file. err: %w", err)
+	}
+	return nil
+}
+
+func newNftCreator(ioShim *common.IOShim) *ioutil.FileCreator {
+	// a failed nft transaction changes nothing, so retry the whole file instead of skipping lines
+	return ioutil.NewFileCreator(ioShim, maxTryCount)
+}
+
+// Writes the nft file for bootup. The table is added before it's deleted so that the delete can't fail.
+// To leave NPM deactivated, AZURE-NPM chain has no rules.
+func (pMgr *PolicyManager) creatorForNftBootup() *ioutil.FileCreator {
+	creator := newNftCreator(pMgr.ioShim)
+	creator.AddLine("", nil, util.NftAdd, util.NftTableObj, util.NftFamily, util.NftTable)
+	creator.AddLine("", nil, util.NftDelete, util.NftTableObj, util.NftFamily, util.NftTable)
+	creator.AddLine("", nil, util.NftAdd, util.NftTableObj, util.NftFamily, util.NftTable)
+
+	priority := nftPriorityAfterIptables
+	if pMgr.PlaceAzureChainFirst == util.PlaceAzureChainFirst {
+		priority = nftPriorityBeforeIptables
+	}
+	forwardChainSpec := fmt.Sprintf("{ type filter hook forward priority %d ; policy accept ; }", priority)
+	creator.AddLine("", nil, append(nftChainSpecs(util.NftAdd, util.NftForwardChain), forwardChainSpec)...)
+	for _, chain := range pMgr.baseChains() {
+		creator.AddLine("", nil, nftChainSpecs(util.NftAdd, chain)...)
+	}
+
+	// add FORWARD chain rules
+	creator.AddLine("", nil, nftRuleSpecs(util.NftForwardChain, "ct state new", util.NftJump, util.IptablesAzureChain)...)
+
+	// add AZURE-NPM-INGRESS-ALLOW-MARK chain
+	markIngressAllowComment := nftComment(fmt.Sprintf("SET-INGRESS-ALLOW-MARK-%s", util.IptablesAzureIngressAllowMarkHex))
+	creator.AddLine("", nil, nftRuleSpecs(util.IptablesAzureIngressAllowMarkChain, nftSetMark(util.IptablesAzureIngressAllowMarkHex), markIngressAllowComment)...)
+	creator.AddLine("", nil, nftRuleSpecs(util.IptablesAzureIngressAllowMarkChain, util.NftJump, util.IptablesAzureEgressChain)...)
+
+	// add AZURE-NPM-ACCEPT chain rules
+	creator.AddLine("", nil, nftRuleSpecs(util.IptablesAzureAcceptChain, util.NftAccept)...)
+
+	// add AZURE-NPM-INGRESS and AZURE-NPM-EGRESS chain rules
+	pMgr.writeNftBaseChains(creator, nil)
+	return creator
+}
+
+// Writes the nft file which adds the chains of policiesToAdd and deletes the chain of policyToRemove (if any).
+// activePolicies are all the policies which should be in effect afterwards, and the base chains are rewritten to jump to them.
+func (pMgr *PolicyManager) creatorForNftPolicies(activePolicies map[string]*NPMNetworkPolicy,
+	policiesToAdd []*NPMNetworkPolicy, policyToRemove *NPMNetworkPolicy,
+) *ioutil.FileCreator {
+	creator := newNftCreator(pMgr.ioShim)
+
+	// 1. Add the rules for the new policy chains. Flush them first in case a previous transaction left them behind.
+	for _, networkPolicy := range policiesToAdd {
+		for _, chain := range chainNames([]*NPMNetworkPolicy{networkPolicy}) {
+			creator.AddLine("", nil, nftChainSpecs(util.NftAdd, chain)...)
+			creator.AddLine("", nil, nftChainSpecs(util.NftFlush, chain)...)
+		}
+		pMgr.writeNftNetworkPolicyRules(creator, networkPolicy)
+	}
+
+	// 2. Rewrite the base chains so that they jump to the active policies, and activate or deactivate NPM.
+	sortedPolicies := make([]*NPMNetworkPolicy, 0, len(activePolicies))
+	for _, networkPolicy := range activePolicies {
+		sortedPolicies = append(sortedPolicies, networkPolicy)
+	}
+	sort.Slice(sortedPolicies, func(i, j int) bool {
+		return sortedPolicies[i].sortsBefore(sortedPolicies[j])
+	})
+
+	creator.AddLine("", nil, nftChainSpecs(util.NftFlush, util.IptablesAzureChain)...)
+	if len(sortedPolicies) > 0 {
+		creator.AddLine("", nil, nftRuleSpecs(util.IptablesAzureChain, util.NftJump, util.IptablesAzureIngressChain)...)
+		creator.AddLine("", nil, nftRuleSpecs(util.IptablesAzureChain, util.NftJump, util.IptablesAzureEgressChain)...)
+		creator.AddLine("", nil, nftRuleSpecs(util.IptablesAzureChain, util.NftJump, util.IptablesAzureAcceptChain)...)
+	}
+	pMgr.writeNftBaseChains(creator, sortedPolicies)
+
+	// 3. Delete the removed policy chains now that nothing jumps to them.
+	if policyToRemove != nil {
+		for _, chain := range chainNames([]*NPMNetworkPolicy{policyToRemove}) {
+			creator.AddLine("", nil, nftChainSpecs(util.NftFlush, chain)...)
+			creator.AddLine("", nil, nftChainSpecs(util.NftDelete, chain)...)
+		}
+	}
+	return creator
+}
+
+// writeNftBaseChains rewrites AZURE-NPM-INGRESS and AZURE-NPM-EGRESS chains with jumps to the policy chains followed by the mark decisions.
+// When AdminNetworkPolicy is enabled, it also rewrites the chains for admin network policies (see adminpolicy_linux.go).
+// The policies must be sorted.
+func (pMgr *PolicyManager) writeNftBaseChains(creator *ioutil.FileCreator, networkPolicies []*NPMNetworkPolicy) {
+	families := pMgr.ipFamilies()
+	creator.AddLine("", nil, nftChainSpecs(util.NftFlush, util.IptablesAzureIngressChain)...)
+	if pMgr.AdminNetworkPolicy {
+		creator.AddLine("", nil, nftRuleSpecs(util.IptablesAzureIngressChain, util.NftJump, util.IptablesAzureAdminIngressChain)...)
+	}
+	writeNftJumps(families, creator, util.IptablesAzureIngressChain, forIngress, NetworkPolicyTier, networkPolicies)
+	ingressDropComment := nftComment(fmt.Sprintf("DROP-ON-INGRESS-DROP-MARK-%s", util.IptablesAzureIngressDropMarkHex))
+	creator.AddLine("", nil, nftRuleSpecs(util.IptablesAzureIngressChain, nftOnMark(util.IptablesAzureIngressDropMarkHex), util.NftDrop, ingressDropComment)...)
+	if pMgr.AdminNetworkPolicy {
+		creator.AddLine("", nil, nftRuleSpecs(util.IptablesAzureIngressChain, util.NftJump, util.IptablesAzureBaselineAdminIngressChain)...)
+	}
+
+	creator.AddLine("", nil, nftChainSpecs(util.NftFlush, util.IptablesAzureEgressChain)...)
+	if pMgr.AdminNetworkPolicy {
+		creator.AddLine("", nil, nftRuleSpecs(util.IptablesAzureEgressChain, util.NftJump, util.IptablesAzureAdminEgressChain)...)
+	}
+	writeNftJumps(families, creator, util.IptablesAzureEgressChain, forEgress, NetworkPolicyTier, networkPolicies)
+	if pMgr.AdminNetworkPolicy {
+		// the mark decisions below drop on the egress drop mark first
+		egressDropMark := nftMarkValue(util.IptablesAzureEgressDropMarkHex)
+		notOnEgressDropMark := fmt.Sprintf("meta mark & 0x%x == 0", egressDropMark)
+		creator.AddLine("", nil, nftRuleSpecs(util.IptablesAzureEgressChain, notOnEgressDropMark, util.NftJump, util.IptablesAzureBaselineAdminEgressChain)...)
+	}
+	// same decisions as the DROP-ON-EGRESS-DROP-MARK and ACCEPT-ON-INGRESS-ALLOW-MARK rules in iptables, where the drop comes first
+	egressDrop := nftMarkValue(util.IptablesAzureEgressDropMarkHex)
+	ingressAllow := nftMarkValue(util.IptablesAzureIngressAllowMarkHex)
+	markDecisions := fmt.Sprintf("meta mark & 0x%x vmap { 0x%x : %s, 0x%x : %s, 0x%x : %s %s }",
+		egressDrop|ingressAllow,
+		egressDrop, util.NftDrop,
+		egressDrop|ingressAllow, util.NftDrop,
+		ingressAllow, util.NftJump, util.IptablesAzureAcceptChain,
+	)
+	creator.AddLine("", nil, nftRuleSpecs(util.IptablesAzureEgressChain, markDecisions)...)
+
+	if pMgr.AdminNetworkPolicy {
+		creator.AddLine("", nil, nftChainSpecs(util.NftFlush, util.IptablesAzureAdminIngressChain)...)
+		writeNftJumps(families, creator, util.IptablesAzureAdminIngressChain, forIngress, AdminTier, networkPolicies)
+		creator.AddLine("", nil, nftChainSpecs(util.NftFlush, util.IptablesAzureAdminEgressChain)...)
+		writeNftJumps(families, creator, util.IptablesAzureAdminEgressChain, forEgress, AdminTier, networkPolicies)
+		creator.AddLine("", nil, nftChainSpecs(util.NftFlush, util.IptablesAzureBaselineAdminIngressChain)...)
+		writeNftJumps(families, creator, util.IptablesAzureBaselineAdminIngressChain, forIngress, BaselineAdminTier, networkPolicies)
+		creator.AddLine("", nil, nftChainSpecs(util.NftFlush, util.IptablesAzureBaselineAdminEgressChain)...)
+		writeNftJumps(families, creator, util.IptablesAzureBaselineAdminEgressChain, forEgress, BaselineAdminTier, networkPolicies)
+	}
+}
+
+// writeNftJumps adds the jumps from the base chain to the chains of the policies in the tier which have the direction.
+func writeNftJumps(families []ipFamily, creator *ioutil.FileCreator, baseChain string, direction UniqueDirection, tier PolicyTier,
+	networkPolicies []*NPMNetworkPolicy,
+) {
+	for _, networkPolicy := range networkPolicies {
+		if networkPolicy.Tier != tier || !networkPolicy.hasDirection(direction) {
+			continue
+		}
+		for _, family := range nftRuleFamilies(families, len(networkPolicy.PodSelectorList) > 0) {
+			jumpSpecs := nftEgressJumpSpecs(family, networkPolicy)
+			if direction == forIngress {
+				jumpSpecs = nftIngressJumpSpecs(family, networkPolicy)
+			}
+			creator.AddLine("", nil, nftRuleSpecs(baseChain, jumpSpecs...)...)
+		}
+	}
+}
+
+func nftIngressJumpSpecs(family ipFamily, networkPolicy *NPMNetworkPolicy) []string {
+	specs := nftMatchSetSpecsForNetworkPolicy(family, networkPolicy, DstMatch)
+	return append(specs, util.NftJump, networkPolicy.ingressChainName(), nftComment(networkPolicy.commentForJumpToIngress()))
+}
+
+func nftEgressJumpSpecs(family ipFamily, networkPolicy *NPMNetworkPolicy) []string {
+	specs := nftMatchSetSpecsForNetworkPolicy(family, networkPolicy, SrcMatch)
+	return append(specs, util.NftJump, networkPolicy.egressChainName(), nftComment(networkPolicy.commentForJumpToEgress()))
+}
+
+// write rules for the policy chain(s)
+// with counters, each ACL rule counts the packets it matches, and with audit, it logs an audit event before its action
+func (pMgr *PolicyManager) writeNftNetworkPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy) {
+	if networkPolicy.Tier == AdminTier {
+		// return if a higher priority AdminNetworkPolicy passed the flow
+		hasIngress, hasEgress := networkPolicy.hasIngressAndEgress()
+		if hasIngress {
+			creator.AddLine("", nil, nftReturnOnPassMarkSpecs(networkPolicy.ingressChainName(), util.IptablesAzureIngressPassMarkHex)...)
+		}
+		if hasEgress {
+			creator.AddLine("", nil, nftReturnOnPassMarkSpecs(networkPolicy.egressChainName(), util.IptablesAzureEgressPassMarkHex)...)
+		}
+	}
+
+	for _, aclPolicy := range networkPolicy.ACLs {
+		var chainName string
+		var direction Direction
+		var actionSpecs []string
+		if aclPolicy.hasIngress() {
+			chainName = networkPolicy.ingressChainName()
+			direction = Ingress
+			switch {
+			case aclPolicy.Target == Allowed:
+				actionSpecs = []string{util.NftJump, util.IptablesAzureIngressAllowMarkChain}
+			case aclPolicy.Target == Passed:
+				actionSpecs = []string{nftSetMark(util.IptablesAzureIngressPassMarkHex), util.NftReturn}
+			case networkPolicy.Tier != NetworkPolicyTier:
+				actionSpecs = []string{util.NftDrop}
+			default:
+				actionSpecs = []string{nftSetMark(util.IptablesAzureIngressDropMarkHex)}
+			}
+		} else {
+			chainName = networkPolicy.egressChainName()
+			direction = Egress
+			switch {
+			case aclPolicy.Target == Allowed:
+				actionSpecs = []string{util.NftJump, util.IptablesAzureAcceptChain}
+			case aclPolicy.Target == Passed:
+				actionSpecs = []string{nftSetMark(util.IptablesAzureEgressPassMarkHex), util.NftReturn}
+			case networkPolicy.Tier != NetworkPolicyTier:
+				actionSpecs = []string{util.NftDrop}
+			default:
+				actionSpecs = []string{nftSetMark(util.IptablesAzureEgressDropMarkHex)}
+			}
+		}
+		hasSets := len(aclPolicy.SrcList) > 0 || len(aclPolicy.DstList) > 0
+		for _, family := range nftRuleFamilies(pMgr.ipFamilies(), hasSets) {
+			// statements must come after the matches in nft
+			specs := nftACLMatchSpecs(family, aclPolicy)
+			if pMgr.Counters {
+				specs = append(specs, util.NftCounter)
+			}
+			if pMgr.Audit {
+				specs = append(specs, nftLog(auditTag(direction, aclPolicy.Target, networkPolicy.PolicyKey)))
+			}
+			specs = append(specs, actionSpecs...)
+			specs = append(specs, nftComment(aclPolicy.comment()))
+			creator.AddLine("", nil, nftRuleSpecs(chainName, specs...)...)
+		}
+	}
+}
+
+func nftReturnOnPassMarkSpecs(chainName, passMark string) []string {
+	comment := nftComment(fmt.Sprintf("RETURN-ON-PASS-MARK-%s", passMark))
+	return nftRuleSpecs(chainName, nftOnMark(passMark), util.NftReturn, comment)
+}
+
+// nftRuleFamilies returns the families to write a rule for. A rule without sets is only written once since it matches both families.
+func nftRuleFamilies(families []ipFamily, hasSets bool) []ipFamily {
+	if !hasSets {
+		return families[:1]
+	}
+	return families
+}
+
+func nftACLMatchSpecs(family ipFamily, aclPolicy *ACLPolicy) []string {
+	specs := make([]string, 0)
+	if aclPolicy.Protocol != UnspecifiedProtocol {
+		specs = append(specs, "meta l4proto", strings.ToLower(string(aclPolicy.Protocol)))
+	}
+	if aclPolicy.DstPorts.Port != 0 || aclPolicy.DstPorts.EndPort != 0 {
+		specs = append(specs, "th dport", aclPolicy.DstPorts.toNftString())
+	}
+	for _, setInfo := range aclPolicy.SrcList {
+		specs = append(specs, setInfo.nftMatchSetSpecs(family, setInfo.MatchType)...)
+	}
+	for _, setInfo := range aclPolicy.DstList {
+		specs = append(specs, setInfo.nftMatchSetSpecs(family, setInfo.MatchType)...)
+	}
+	return specs
+}
+
+func nftMatchSetSpecsForNetworkPolicy(family ipFamily, networkPolicy *NPMNetworkPolicy, matchType MatchType) []string {
+	specs := make([]string, 0)
+	for _, setInfo := range networkPolicy.PodSelectorList {
+		specs = append(specs, setInfo.nftMatchSetSpecs(family, matchType)...)
+	}
+	return specs
+}
+
+// nftMatchSetSpecs matches the set, and for CIDR sets, also makes sure that the IP isn't in the set's except set.
+// CIDR sets are always included, so the except set never has to be negated along with the CIDR set.
+func (info SetInfo) nftMatchSetSpecs(family ipFamily, matchType MatchType) []string {
+	protocol := "ip"
+	if family == ipv6 {
+		protocol = "ip6"
+	}
+	var selector string
+	switch matchType {
+	case SrcMatch:
+		selector = protocol + " saddr"
+	case DstDstMatch:
+		selector = protocol + " daddr . meta l4proto . th dport"
+	default:
+		selector = protocol + " daddr"
+	}
+
+	hashedSetName := family.setName(info.IPSet.GetHashedName())
+	operator := ""
+	if !info.Included {
+		operator = "!= "
+	}
+	specs := []string{selector, operator + "@" + hashedSetName}
+	if info.IPSet.Type == ipsets.CIDRBlocks && info.Included {
+		specs = append(specs, selector, "!= @"+hashedSetName+util.NftExceptSetSuffix)
+	}
+	return specs
+}
+
+func (portRange *Ports) toNftString() string {
+	start := strconv.Itoa(int(portRange.Port))
+	if portRange.Port == portRange.EndPort {
+		return start
+	}
+	end := strconv.Itoa(int(portRange.EndPort))
+	return start + "-" + end
+}
+
+func nftChainSpecs(operation, chain string) []string {
+	return []string{operation, util.NftChainObj, util.NftFamily, util.NftTable, chain}
+}
+
+func nftRuleSpecs(chain string, specs ...string) []string {
+	return append([]string{util.NftAdd, util.NftRuleObj, util.NftFamily, util.NftTable, chain}, specs...)
+}
+
+func nftLog(prefix string) string {
+	return fmt.Sprintf("%s prefix %s group %d", util.NftLog, strconv.Quote(prefix), util.AuditNFLogGroup)
+}
+
+func nftComment(comment string) string {
+	return "comment " + strconv.Quote(truncateNftComment(comment))
+}
+
+func truncateNftComment(comment string) string {
+	if len(comment) > maxNftCommentLength {
+		return comment[:maxNftCommentLength]
+	}
+	return comment
+}
+
+// nftSetMark is the equivalent of setting an iptables mark like 0x200/0x200.
+func nftSetMark(markHex string) string {
+	return fmt.Sprintf("meta mark set meta mark | 0x%x", nftMarkValue(markHex))
+}
+
+// nftOnMark is the equivalent of matching an iptables mark like 0x400/0x400.
+func nftOnMark(markHex string) string {
+	mark := nftMarkValue(markHex)
+	return fmt.Sprintf("meta mark & 0x%x == 0x%x", mark, mark)
+}
+
+// nftMarkValue returns the value of an iptables mark like 0x200/0x200, where the mask is the value.
+func nftMarkValue(markHex string) uint64 {
+	value, _, _ := strings.Cut(markHex, "/")
+	mark, err := strconv.ParseUint(value, 0, 32)
+	if err != nil {
+		// the marks are constants
+		panic(fmt.Sprintf("invalid mark %s: %v", markHex, err))
+	}
+	return mark
+}
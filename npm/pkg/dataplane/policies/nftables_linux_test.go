@@ -0,0 +1,195 @@
+package policies
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
+	"github.com/Azure/azure-container-networking/npm/util"
+	testutils "github.com/Azure/azure-container-networking/test/utils"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	nftConfig = &PolicyManagerCfg{
+		PolicyMode:           IPSetPolicyMode,
+		PlaceAzureChainFirst: util.PlaceAzureChainFirst,
+		Nftables:             true,
+	}
+
+	fakeNftCommand = testutils.TestCmd{Cmd: []string{"nft", "-f", "-"}}
+
+	nftIngressDropRule = fmt.Sprintf(
+		"meta l4proto tcp th dport 222-333 ip saddr @%s ip saddr != @%s-except ip daddr != @%s meta mark set meta mark | 0x400 comment %q",
+		ipsets.TestCIDRSet.HashedName,
+		ipsets.TestCIDRSet.HashedName,
+		ipsets.TestKeyPodSet.HashedName,
+		ingressDropComment,
+	)
+	nftIngressAllowRule = fmt.Sprintf(
+		"ip saddr @%s ip saddr != @%s-except jump AZURE-NPM-INGRESS-ALLOW-MARK comment %q",
+		ipsets.TestCIDRSet.HashedName,
+		ipsets.TestCIDRSet.HashedName,
+		ingressAllowComment,
+	)
+	nftEgressDropRule = fmt.Sprintf(
+		"meta l4proto udp th dport 144 ip daddr @%s ip daddr != @%s-except meta mark set meta mark | 0x800 comment %q",
+		ipsets.TestCIDRSet.HashedName,
+		ipsets.TestCIDRSet.HashedName,
+		egressDropComment,
+	)
+	nftEgressAllowRule = fmt.Sprintf("ip daddr @%s jump AZURE-NPM-ACCEPT comment %q", ipsets.TestNamedportSet.HashedName, egressAllowComment)
+
+	nftIngressDropOnMarkRule   = `add rule inet azure-npm AZURE-NPM-INGRESS meta mark & 0x400 == 0x400 drop comment "DROP-ON-INGRESS-DROP-MARK-0x400/0x400"`
+	nftEgressMarkDecisionsRule = "add rule inet azure-npm AZURE-NPM-EGRESS meta mark & 0xa00 vmap { 0x800 : drop, 0xa00 : drop, 0x200 : jump AZURE-NPM-ACCEPT }"
+)
+
+func TestCreatorForNftBootup(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, nftConfig)
+
+	creator := pMgr.creatorForNftBootup()
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"add table inet azure-npm",
+		"delete table inet azure-npm",
+		"add table inet azure-npm",
+		"add chain inet azure-npm FORWARD { type filter hook forward priority -1 ; policy accept ; }",
+		"add chain inet azure-npm AZURE-NPM",
+		"add chain inet azure-npm AZURE-NPM-INGRESS",
+		"add chain inet azure-npm AZURE-NPM-INGRESS-ALLOW-MARK",
+		"add chain inet azure-npm AZURE-NPM-EGRESS",
+		"add chain inet azure-npm AZURE-NPM-ACCEPT",
+		"add rule inet azure-npm FORWARD ct state new jump AZURE-NPM",
+		`add rule inet azure-npm AZURE-NPM-INGRESS-ALLOW-MARK meta mark set meta mark | 0x200 comment "SET-INGRESS-ALLOW-MARK-0x200/0x200"`,
+		"add rule inet azure-npm AZURE-NPM-INGRESS-ALLOW-MARK jump AZURE-NPM-EGRESS",
+		"add rule inet azure-npm AZURE-NPM-ACCEPT accept",
+		"flush chain inet azure-npm AZURE-NPM-INGRESS",
+		nftIngressDropOnMarkRule,
+		"flush chain inet azure-npm AZURE-NPM-EGRESS",
+		nftEgressMarkDecisionsRule,
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestBootupNft(t *testing.T) {
+	calls := []testutils.TestCmd{
+		{Cmd: []string{"iptables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM", "-m", "conntrack", "--ctstate", "NEW"}, ExitCode: 2}, //nolint // AZURE-NPM chain didn't exist
+		{Cmd: listAllCommandStrings, PipedToCommand: true},
+		{Cmd: []string{"grep", "Chain AZURE-NPM"}, ExitCode: 1},
+		fakeNftCommand,
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, nftConfig)
+
+	require.NoError(t, pMgr.Bootup(nil))
+}
+
+func TestBootupNftAfterIPTables(t *testing.T) {
+	calls := []testutils.TestCmd{
+		{Cmd: []string{"iptables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM", "-m", "conntrack", "--ctstate", "NEW"}},
+		{Cmd: listAllCommandStrings, PipedToCommand: true},
+		{Cmd: []string{"grep", "Chain AZURE-NPM"}, Stdout: "Chain AZURE-NPM (0 references)\nChain AZURE-NPM-INGRESS-123456 (1 references)\n"},
+		// the chains are flushed before they are deleted since they jump to each other
+		{Cmd: []string{"iptables", "-w", "60", "-F", "AZURE-NPM"}},
+		{Cmd: []string{"iptables", "-w", "60", "-F", "AZURE-NPM-INGRESS-123456"}},
+		{Cmd: []string{"iptables", "-w", "60", "-X", "AZURE-NPM"}},
+		{Cmd: []string{"iptables", "-w", "60", "-X", "AZURE-NPM-INGRESS-123456"}, ExitCode: 2},
+		{Cmd: []string{"ip6tables", "-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM", "-m", "conntrack", "--ctstate", "NEW"}, ExitCode: 2}, //nolint // AZURE-NPM chain didn't exist
+		{Cmd: []string{"ip6tables", "-w", "60", "-t", "filter", "-n", "-L"}, PipedToCommand: true},
+		{Cmd: []string{"grep", "Chain AZURE-NPM"}, ExitCode: 1},
+		fakeNftCommand,
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, nftDualStackConfig)
+
+	// failing to delete the inactive iptables chains doesn't fail bootup
+	require.NoError(t, pMgr.Bootup(nil))
+}
+
+func TestCreatorForNftPolicies(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, nftConfig)
+
+	// 1. add the first policy, which activates NPM
+	activePolicies := map[string]*NPMNetworkPolicy{bothDirectionsNetPol.PolicyKey: bothDirectionsNetPol}
+	creator := pMgr.creatorForNftPolicies(activePolicies, []*NPMNetworkPolicy{bothDirectionsNetPol}, nil)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		fmt.Sprintf("add chain inet azure-npm %s", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf("flush chain inet azure-npm %s", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf("add chain inet azure-npm %s", bothDirectionsNetPolEgressChain),
+		fmt.Sprintf("flush chain inet azure-npm %s", bothDirectionsNetPolEgressChain),
+		fmt.Sprintf("add rule inet azure-npm %s %s", bothDirectionsNetPolIngressChain, nftIngressDropRule),
+		fmt.Sprintf("add rule inet azure-npm %s %s", bothDirectionsNetPolIngressChain, nftIngressAllowRule),
+		fmt.Sprintf("add rule inet azure-npm %s %s", bothDirectionsNetPolEgressChain, nftEgressDropRule),
+		fmt.Sprintf("add rule inet azure-npm %s %s", bothDirectionsNetPolEgressChain, nftEgressAllowRule),
+		"flush chain inet azure-npm AZURE-NPM",
+		"add rule inet azure-npm AZURE-NPM jump AZURE-NPM-INGRESS",
+		"add rule inet azure-npm AZURE-NPM jump AZURE-NPM-EGRESS",
+		"add rule inet azure-npm AZURE-NPM jump AZURE-NPM-ACCEPT",
+		"flush chain inet azure-npm AZURE-NPM-INGRESS",
+		fmt.Sprintf("add rule inet azure-npm AZURE-NPM-INGRESS ip daddr @%s jump %s comment %q",
+			ipsets.TestKeyPodSet.HashedName, bothDirectionsNetPolIngressChain, bothDirectionsNetPolIngressJumpComment),
+		nftIngressDropOnMarkRule,
+		"flush chain inet azure-npm AZURE-NPM-EGRESS",
+		fmt.Sprintf("add rule inet azure-npm AZURE-NPM-EGRESS ip saddr @%s jump %s comment %q",
+			ipsets.TestKeyPodSet.HashedName, bothDirectionsNetPolEgressChain, bothDirectionsNetPolEgressJumpComment),
+		nftEgressMarkDecisionsRule,
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+
+	// 2. remove the last policy, which deactivates NPM and deletes the policy chains after the jumps to them
+	creator = pMgr.creatorForNftPolicies(map[string]*NPMNetworkPolicy{}, nil, bothDirectionsNetPol)
+	actualLines = strings.Split(creator.ToString(), "\n")
+	expectedLines = []string{
+		"flush chain inet azure-npm AZURE-NPM",
+		"flush chain inet azure-npm AZURE-NPM-INGRESS",
+		nftIngressDropOnMarkRule,
+		"flush chain inet azure-npm AZURE-NPM-EGRESS",
+		nftEgressMarkDecisionsRule,
+		fmt.Sprintf("flush chain inet azure-npm %s", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf("delete chain inet azure-npm %s", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf("flush chain inet azure-npm %s", bothDirectionsNetPolEgressChain),
+		fmt.Sprintf("delete chain inet azure-npm %s", bothDirectionsNetPolEgressChain),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestAddAndRemoveNftPolicies(t *testing.T) {
+	calls := []testutils.TestCmd{fakeNftCommand, fakeNftCommand}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, nftConfig)
+
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{bothDirectionsNetPol, egressNetPol}, nil))
+	require.True(t, pMgr.PolicyExists(bothDirectionsNetPol.PolicyKey))
+
+	// the jumps to the remaining policy are rewritten along with the removal
+	creator := pMgr.creatorForNftPolicies(map[string]*NPMNetworkPolicy{egressNetPol.PolicyKey: egressNetPol}, nil, bothDirectionsNetPol)
+	require.Contains(t, creator.ToString(), fmt.Sprintf("add rule inet azure-npm AZURE-NPM-EGRESS jump %s comment %q", egressNetPolChain, egressNetPolJumpComment))
+
+	require.NoError(t, pMgr.RemovePolicy(bothDirectionsNetPol.PolicyKey))
+	require.False(t, pMgr.PolicyExists(bothDirectionsNetPol.PolicyKey))
+	require.True(t, pMgr.PolicyExists(egressNetPol.PolicyKey))
+}
+
+func TestNftMarks(t *testing.T) {
+	require.Equal(t, "meta mark set meta mark | 0x800", nftSetMark(util.IptablesAzureEgressDropMarkHex))
+	require.Equal(t, "meta mark & 0x400 == 0x400", nftOnMark(util.IptablesAzureIngressDropMarkHex))
+}
+
+func TestNftCommentIsTruncated(t *testing.T) {
+	comment := strings.Repeat("a", maxNftCommentLength+10)
+	require.Equal(t, fmt.Sprintf("comment %q", strings.Repeat("a", maxNftCommentLength)), nftComment(comment))
+}
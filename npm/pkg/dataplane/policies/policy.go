@@ -14,7 +14,13 @@ type NPMNetworkPolicy struct {
 	// Namespace is only used by Linux to construct an iptables comment
 	Namespace string
 	// PolicyKey is a unique combination of "namespace/name" of network policy
+	// or "kind/name" of a cluster-scoped admin network policy
 	PolicyKey string
+	// Tier decides whether the policy is evaluated before, as, or after a NetworkPolicy.
+	// Tiers other than NetworkPolicyTier are only supported in Linux
+	Tier PolicyTier
+	// Priority orders policies in AdminTier, where a lower value is evaluated first
+	Priority int32
 	// ACLPolicyID is only used in Windows. See aclPolicyID() in policy_windows.go for more info
 	ACLPolicyID string
 	// TODO get rid of PodSelectorIPSets in favor of PodSelectorList (exact same except need to add members field to SetInfo)
@@ -42,6 +48,16 @@ func NewNPMNetworkPolicy(netPolName, netPolNamespace string) *NPMNetworkPolicy {
 	}
 }
 
+// NewNPMAdminNetworkPolicy creates a cluster-scoped policy for an AdminNetworkPolicy or BaselineAdminNetworkPolicy.
+func NewNPMAdminNetworkPolicy(kind, name string, tier PolicyTier, priority int32) *NPMNetworkPolicy {
+	return &NPMNetworkPolicy{
+		PolicyKey:   fmt.Sprintf("%s/%s", kind, name),
+		Tier:        tier,
+		Priority:    priority,
+		ACLPolicyID: aclPolicyID(kind, name),
+	}
+}
+
 func (netPol *NPMNetworkPolicy) AllPodSelectorIPSets() []*ipsets.TranslatedIPSet {
 	return append(netPol.PodSelectorIPSets, netPol.ChildPodSelectorIPSets...)
 }
@@ -59,14 +75,23 @@ func (netPol *NPMNetworkPolicy) numACLRulesProducedInKernel() int {
 			hasEgress = true
 			numRules++
 		}
+		// in Linux, a pass is a rule to set the pass mark and a rule to return
+		if aclPolicy.Target == Passed {
+			numRules++
+		}
 	}
 
 	// both Windows and Linux have an extra ACL rule for ingress and an extra rule for egress
+	// in Linux, an AdminNetworkPolicy chain also starts with a rule to return if the pass mark is set
+	extraRulesPerDirection := 1
+	if netPol.Tier == AdminTier {
+		extraRulesPerDirection++
+	}
 	if hasIngress {
-		numRules++
+		numRules += extraRulesPerDirection
 	}
 	if hasEgress {
-		numRules++
+		numRules += extraRulesPerDirection
 	}
 	return numRules
 }
@@ -132,10 +157,19 @@ func NormalizePolicy(networkPolicy *NPMNetworkPolicy) {
 }
 
 func ValidatePolicy(networkPolicy *NPMNetworkPolicy) error {
+	if !networkPolicy.hasKnownTier() {
+		return npmerrors.SimpleError(fmt.Sprintf("NetPol %s has unknown tier [%s]", networkPolicy.PolicyKey, networkPolicy.Tier))
+	}
+	if util.IsWindowsDP() && networkPolicy.Tier != NetworkPolicyTier {
+		return npmerrors.SimpleError(fmt.Sprintf("NetPol %s has unsupported tier [%s] on Windows", networkPolicy.PolicyKey, networkPolicy.Tier))
+	}
 	for _, aclPolicy := range networkPolicy.ACLs {
 		if !aclPolicy.hasKnownTarget() {
 			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has unknown target [%s]", networkPolicy.PolicyKey, aclPolicy.Target))
 		}
+		if aclPolicy.Target == Passed && networkPolicy.Tier != AdminTier {
+			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has target [%s], which is only valid for an AdminNetworkPolicy", networkPolicy.PolicyKey, aclPolicy.Target))
+		}
 		if !aclPolicy.hasKnownDirection() {
 			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has unknown direction [%s]", networkPolicy.PolicyKey, aclPolicy.Direction))
 		}
@@ -173,6 +207,12 @@ func ValidatePolicy(networkPolicy *NPMNetworkPolicy) error {
 	return nil
 }
 
+func (netPol *NPMNetworkPolicy) hasKnownTier() bool {
+	return netPol.Tier == NetworkPolicyTier ||
+		netPol.Tier == AdminTier ||
+		netPol.Tier == BaselineAdminTier
+}
+
 func NewACLPolicy(target Verdict, direction Direction) *ACLPolicy {
 	acl := &ACLPolicy{
 		Target:    target,
@@ -224,7 +264,7 @@ func (aclPolicy *ACLPolicy) hasKnownProtocol() bool {
 }
 
 func (aclPolicy *ACLPolicy) hasKnownTarget() bool {
-	return aclPolicy.Target == Allowed || aclPolicy.Target == Dropped
+	return aclPolicy.Target == Allowed || aclPolicy.Target == Dropped || aclPolicy.Target == Passed
 }
 
 func (aclPolicy *ACLPolicy) satisifiesPortAndProtocolConstraints() bool {
@@ -330,6 +370,20 @@ const (
 	Allowed Verdict = "ALLOW"
 	// Dropped is denying a flow
 	Dropped Verdict = "DROP"
+	// Passed skips the remaining AdminNetworkPolicies so that the flow is decided by NetworkPolicies
+	Passed Verdict = "PASS"
+)
+
+// PolicyTier decides when a policy is evaluated relative to NetworkPolicies.
+type PolicyTier string
+
+const (
+	// NetworkPolicyTier is for a NetworkPolicy
+	NetworkPolicyTier PolicyTier = ""
+	// AdminTier is for an AdminNetworkPolicy, which is evaluated before NetworkPolicies
+	AdminTier PolicyTier = "Admin"
+	// BaselineAdminTier is for a BaselineAdminNetworkPolicy, which is evaluated for flows that no NetworkPolicy applies to
+	BaselineAdminTier PolicyTier = "BaselineAdmin"
 )
 
 // Protocol can be TCP, UDP, SCTP, or unspecified since they are currently supported in networkpolicy.
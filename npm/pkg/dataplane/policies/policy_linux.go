@@ -72,6 +72,10 @@ func (networkPolicy *NPMNetworkPolicy) commentForJump(direction UniqueDirection)
 	if len(networkPolicy.PodSelectorList) > 0 {
 		podSelectorComment = commentForInfos(networkPolicy.PodSelectorList)
 	}
+	if networkPolicy.Tier != NetworkPolicyTier {
+		// admin network policies are cluster-scoped
+		return fmt.Sprintf("%s-POLICY-%s-%s-%s", prefix, networkPolicy.PolicyKey, toFrom, podSelectorComment)
+	}
 	return fmt.Sprintf("%s-POLICY-%s-%s-%s-IN-ns-%s", prefix, networkPolicy.PolicyKey, toFrom, podSelectorComment, networkPolicy.Namespace)
 }
 
@@ -91,14 +95,14 @@ func (info SetInfo) comment() string {
 	return "!" + name
 }
 
-func (info SetInfo) matchSetSpecs(matchString string) []string {
+func (info SetInfo) matchSetSpecs(family ipFamily, matchString string) []string {
 	specs := make([]string, 0, maxLengthForMatchSetSpecs)
 	specs = append(specs, util.IptablesModuleFlag, util.IptablesSetModuleFlag)
 	if !info.Included {
 		specs = append(specs, util.IptablesNotFlag)
 	}
-	hashedSetName := info.IPSet.GetHashedName()
-	specs = append(specs, util.IptablesMatchSetFlag, hashedSetName, matchString)
+	setName := family.setName(info.IPSet.GetHashedName())
+	specs = append(specs, util.IptablesMatchSetFlag, setName, matchString)
 	return specs
 }
 
@@ -128,9 +132,12 @@ func (aclPolicy *ACLPolicy) comment() string {
 	}
 
 	builder := strings.Builder{}
-	if aclPolicy.Target == Allowed {
+	switch aclPolicy.Target {
+	case Allowed:
 		builder.WriteString("ALLOW")
-	} else {
+	case Passed:
+		builder.WriteString("PASS")
+	default:
 		builder.WriteString("DROP")
 	}
 
@@ -214,6 +221,7 @@ func (portRange *Ports) toIPTablesString() string {
 					- ingress: "ALLOW-FROM"
 					- egress: "ALLOW-TO"
 			- denied: replace "ALLOW" with "DROP"
+			- passed: replace "ALLOW" with "PASS"
 		- similar idea (think there are at most two non-namedPort ipsets e.g. ns selector and pod selector):
 			prefix
 			[-ipset1Name]
@@ -234,7 +242,7 @@ func (portRange *Ports) toIPTablesString() string {
 			-policyKey
 			-TO         (or "-FROM" if egress)
 			[-podSelectorComment]   (or "all" if there are no pod selectors)
-			-IN-ns
+			-IN-ns      (omitted for admin network policies, which are cluster-scoped)
 			-namespaceName
 
 	strings for protocol, ports, selectors:
@@ -26,6 +26,8 @@ const (
 	// it represents the number of rules unrelated to policies
 	// it's technically 3 off when there are no policies since we flush the AZURE-NPM chain then
 	numLinuxBaseACLRules = 11
+	// the jumps to the AdminNetworkPolicy and BaselineAdminNetworkPolicy chains
+	numLinuxAdminTierBaseACLRules = 4
 )
 
 type PolicyManagerCfg struct {
@@ -35,6 +37,19 @@ type PolicyManagerCfg struct {
 	PolicyMode PolicyManagerMode
 	// PlaceAzureChainFirst only affects Linux
 	PlaceAzureChainFirst bool
+	// Nftables only affects Linux. It programs policies with nftables instead of iptables.
+	Nftables bool
+	// DualStack only affects Linux. It programs IPv6 policies alongside IPv4 policies.
+	DualStack bool
+	// AdminNetworkPolicy only affects Linux. It evaluates AdminNetworkPolicies before NetworkPolicies
+	// and BaselineAdminNetworkPolicies after NetworkPolicies.
+	AdminNetworkPolicy bool
+	// Audit only affects Linux. Each ACL logs an audit event tagged with its policy key to the
+	// NFLOG group util.AuditNFLogGroup when it matches a packet.
+	Audit bool
+	// Counters only affects Linux. The packet and byte counters of ACLs are exported as metrics per policy.
+	// With nftables, each ACL rule has a counter statement.
+	Counters bool
 	// MaxBatchedACLsPerPod is the maximum number of ACLs that can be added to a Pod at once in Windows.
 	// The zero value is valid.
 	// A NetworkPolicy's ACLs are always in the same batch, and there will be at least one NetworkPolicy per batch.
@@ -97,6 +112,9 @@ func (pMgr *PolicyManager) Bootup(epIDs []string) error {
 	if !util.IsWindowsDP() {
 		// update Prometheus metrics on success
 		metrics.IncNumACLRulesBy(numLinuxBaseACLRules)
+		if pMgr.AdminNetworkPolicy {
+			metrics.IncNumACLRulesBy(numLinuxAdminTierBaseACLRules)
+		}
 	}
 
 	if util.IsWindowsDP() && pMgr.NodeIP == "" {
@@ -110,6 +128,11 @@ func (pMgr *PolicyManager) Reconcile() {
 	pMgr.reconcile()
 }
 
+// RecordPolicyTraffic reads the counters of each policy's ACLs and updates the policy traffic metrics.
+func (pMgr *PolicyManager) RecordPolicyTraffic() {
+	pMgr.recordPolicyTraffic()
+}
+
 func (pMgr *PolicyManager) PolicyExists(policyKey string) bool {
 	pMgr.policyMap.RLock()
 	defer pMgr.policyMap.RUnlock()
@@ -126,6 +149,20 @@ func (pMgr *PolicyManager) GetPolicy(policyKey string) (*NPMNetworkPolicy, bool)
 	return policy, ok
 }
 
+// PolicyKeyByHash returns the key of the cached policy whose util.Hash() is hash.
+// Audit tags have the hash instead of the policy key when the key is too long.
+func (pMgr *PolicyManager) PolicyKeyByHash(hash string) (string, bool) {
+	pMgr.policyMap.RLock()
+	defer pMgr.policyMap.RUnlock()
+
+	for policyKey := range pMgr.policyMap.cache {
+		if util.Hash(policyKey) == hash {
+			return policyKey, true
+		}
+	}
+	return "", false
+}
+
 func (pMgr *PolicyManager) AddPolicies(policies []*NPMNetworkPolicy, endpointList map[string]string) error {
 	nonEmptyPolicies := make([]*NPMNetworkPolicy, 0, len(policies))
 	for _, policy := range policies {
@@ -142,6 +179,11 @@ func (pMgr *PolicyManager) AddPolicies(policies []*NPMNetworkPolicy, endpointLis
 			metrics.SendErrorLogAndMetric(util.IptmID, "error: %s", msg)
 			return npmerrors.Errorf(npmerrors.AddPolicy, false, msg)
 		}
+		if policy.Tier != NetworkPolicyTier && !pMgr.AdminNetworkPolicy {
+			msg := fmt.Sprintf("failed to validate policy: policy %s has tier [%s], but admin network policies are disabled", policy.PolicyKey, policy.Tier)
+			metrics.SendErrorLogAndMetric(util.IptmID, "error: %s", msg)
+			return npmerrors.Errorf(npmerrors.AddPolicy, false, msg)
+		}
 	}
 
 	if len(nonEmptyPolicies) == 0 {
@@ -168,7 +210,7 @@ func (pMgr *PolicyManager) AddPolicies(policies []*NPMNetworkPolicy, endpointLis
 		if util.IsWindowsDP() {
 			metrics.IncNumACLRulesBy((1 + policy.numACLRulesProducedInKernel()) * len(endpointList))
 		} else {
-			metrics.IncNumACLRulesBy(policy.numACLRulesProducedInKernel())
+			metrics.IncNumACLRulesBy(pMgr.numLinuxACLRules(policy))
 		}
 
 		// add policy to cache
@@ -177,6 +219,16 @@ func (pMgr *PolicyManager) AddPolicies(policies []*NPMNetworkPolicy, endpointLis
 	return nil
 }
 
+// numLinuxACLRules is the number of rules for the policy in Linux.
+// With iptables, policy audit adds an NFLOG rule before each ACL rule. An nft rule logs within the ACL rule.
+func (pMgr *PolicyManager) numLinuxACLRules(policy *NPMNetworkPolicy) int {
+	numRules := policy.numACLRulesProducedInKernel()
+	if pMgr.Audit && !pMgr.Nftables {
+		numRules += len(policy.ACLs)
+	}
+	return numRules
+}
+
 func (pMgr *PolicyManager) isFirstPolicy() bool {
 	return len(pMgr.policyMap.cache) == 0
 }
@@ -215,7 +267,7 @@ func (pMgr *PolicyManager) RemovePolicy(policyKey string) error {
 		numEndpointsRemoved := numEndpointsBefore - len(policy.PodEndpoints)
 		metrics.DecNumACLRulesBy((1 + policy.numACLRulesProducedInKernel()) * numEndpointsRemoved)
 	} else {
-		metrics.DecNumACLRulesBy(policy.numACLRulesProducedInKernel())
+		metrics.DecNumACLRulesBy(pMgr.numLinuxACLRules(policy))
 	}
 
 	// remove policy from cache
@@ -42,20 +42,30 @@ Known errors that we should retry on:
 */
 
 func (pMgr *PolicyManager) addPolicies(networkPolicies []*NPMNetworkPolicy, _ map[string]string) error {
+	if pMgr.Nftables {
+		return pMgr.addNftPolicies(networkPolicies)
+	}
+
 	// 1. Add rules for the network policies and activate NPM (if necessary).
 	chainsToCreate := chainNames(networkPolicies)
-	creator := pMgr.creatorForNewNetworkPolicies(chainsToCreate, networkPolicies)
 
 	// Stop reconciling so we don't contend for iptables, and so reconcile doesn't delete chainsToCreate.
 	pMgr.reconcileManager.forceLock()
 	defer pMgr.reconcileManager.forceUnlock()
 
-	timer := metrics.StartNewTimer()
-	err := restore(creator)
-	metrics.RecordIPTablesRestoreLatency(timer, metrics.CreateOp)
-	if err != nil {
-		metrics.IncIPTablesRestoreFailures(metrics.CreateOp)
-		return fmt.Errorf("failed to restore iptables with updated policies. err: %w", err)
+	families := pMgr.ipFamilies()
+	for i, family := range families {
+		creator := pMgr.creatorForNewNetworkPolicies(family, chainsToCreate, networkPolicies)
+		timer := metrics.StartNewTimer()
+		err := restore(family, creator)
+		metrics.RecordIPTablesRestoreLatency(timer, metrics.CreateOp)
+		if err != nil {
+			metrics.IncIPTablesRestoreFailures(metrics.CreateOp)
+			// The caller will retry with all families, so undo the families which succeeded.
+			// Otherwise, the retry would insert their jump rules a second time.
+			pMgr.rollbackPolicies(families[:i], chainsToCreate, networkPolicies)
+			return fmt.Errorf("failed to restore %s with updated policies. err: %w", family.iptablesCommand(), err)
+		}
 	}
 
 	// 2. Make sure the new chains don't get deleted in the background
@@ -66,49 +76,88 @@ func (pMgr *PolicyManager) addPolicies(networkPolicies []*NPMNetworkPolicy, _ ma
 }
 
 func (pMgr *PolicyManager) removePolicy(networkPolicy *NPMNetworkPolicy, _ map[string]string) error {
+	if pMgr.Nftables {
+		if err := pMgr.removeNftPolicy(networkPolicy); err != nil {
+			return err
+		}
+		metrics.RemovePolicyAuditEvents(auditPolicyKeys(networkPolicy.PolicyKey)...)
+		return nil
+	}
+
 	chainsToDelete := chainNames([]*NPMNetworkPolicy{networkPolicy})
-	creator := pMgr.creatorForRemovingPolicies(chainsToDelete)
 
 	// Stop reconciling so we don't contend for iptables, and so we don't update the staleChains at the same time as reconcile()
 	pMgr.reconcileManager.forceLock()
 	defer pMgr.reconcileManager.forceUnlock()
 
+	// If a family fails, the families before it stay removed. Retrying is idempotent though:
+	// deleting a jump rule which doesn't exist is ignored, and flushing a chain twice is harmless.
+	for _, family := range pMgr.ipFamilies() {
+		if err := pMgr.removePoliciesForFamily(family, []*NPMNetworkPolicy{networkPolicy}, chainsToDelete, pMgr.isLastPolicy()); err != nil {
+			return err
+		}
+	}
+
+	// 3. Delete policy chains in the background.
+	for _, chain := range chainsToDelete {
+		pMgr.staleChains.add(chain)
+	}
+
+	// 4. Stop exporting the audit events of the policy.
+	metrics.RemovePolicyAuditEvents(auditPolicyKeys(networkPolicy.PolicyKey)...)
+	return nil
+}
+
+// rollbackPolicies removes the rules of newly added policies from the families which they were added to.
+// Errors are only logged since the caller is already failing.
+func (pMgr *PolicyManager) rollbackPolicies(families []ipFamily, policyChains []string, networkPolicies []*NPMNetworkPolicy) {
+	for _, family := range families {
+		// NPM was activated if these were the first policies
+		if err := pMgr.removePoliciesForFamily(family, networkPolicies, policyChains, pMgr.isFirstPolicy()); err != nil {
+			metrics.SendErrorLogAndMetric(util.IptmID, "error: failed to roll back %s after failing to add policies. err: %s", family, err.Error())
+		}
+	}
+}
+
+func (pMgr *PolicyManager) removePoliciesForFamily(family ipFamily, networkPolicies []*NPMNetworkPolicy, policyChains []string, deactivate bool) error {
 	// 1. Delete jump rules from ingress/egress chains to ingress/egress policy chains.
 	// We ought to delete these jump rules here in the foreground since if we add an NP back after deleting, iptables-restore --noflush can add duplicate jump rules.
-	deleteErr := pMgr.deleteOldJumpRulesOnRemove(networkPolicy)
-	if deleteErr != nil {
-		return fmt.Errorf("failed to delete jumps to policy chains. err: %w", deleteErr)
+	for _, networkPolicy := range networkPolicies {
+		deleteErr := pMgr.deleteOldJumpRulesOnRemove(family, networkPolicy)
+		if deleteErr != nil {
+			return fmt.Errorf("failed to delete jumps to policy chains. err: %w", deleteErr)
+		}
 	}
 
 	// 2. Flush the policy chains and deactivate NPM (if necessary).
+	creator := pMgr.creatorForFlushingPolicies(policyChains, deactivate)
 	timer := metrics.StartNewTimer()
-	restoreErr := restore(creator)
+	restoreErr := restore(family, creator)
 	metrics.RecordIPTablesRestoreLatency(timer, metrics.DeleteOp)
 	if restoreErr != nil {
 		metrics.IncIPTablesRestoreFailures(metrics.DeleteOp)
 		return fmt.Errorf("failed to flush policies. err: %w", restoreErr)
 	}
-
-	// 3. Delete policy chains in the background.
-	for _, chain := range chainsToDelete {
-		pMgr.staleChains.add(chain)
-	}
 	return nil
 }
 
-func restore(creator *ioutil.FileCreator) error {
-	err := creator.RunCommandWithFile(util.IptablesRestore, util.IptablesWaitFlag, util.IptablesDefaultWaitTime, util.IptablesRestoreTableFlag, util.IptablesFilterTable, util.IptablesRestoreNoFlushFlag)
+func restore(family ipFamily, creator *ioutil.FileCreator) error {
+	err := creator.RunCommandWithFile(family.iptablesRestoreCommand(), util.IptablesWaitFlag, util.IptablesDefaultWaitTime, util.IptablesRestoreTableFlag, util.IptablesFilterTable, util.IptablesRestoreNoFlushFlag)
 	if err != nil {
-		return fmt.Errorf("failed to restore iptables file. err: %w", err)
+		return fmt.Errorf("failed to restore %s file. err: %w", family.iptablesCommand(), err)
 	}
 	return nil
 }
 
 // NOTE: if removing multiple policies, would need to add a isLastPolicy argument instead
 func (pMgr *PolicyManager) creatorForRemovingPolicies(allChainNames []string) *ioutil.FileCreator {
+	return pMgr.creatorForFlushingPolicies(allChainNames, pMgr.isLastPolicy())
+}
+
+func (pMgr *PolicyManager) creatorForFlushingPolicies(allChainNames []string, deactivate bool) *ioutil.FileCreator {
 	creator := pMgr.newCreatorWithChains(nil)
 	// 1. Deactivate NPM (if necessary).
-	if pMgr.isLastPolicy() {
+	if deactivate {
 		creator.AddLine("", nil, util.IptablesFlushFlag, util.IptablesAzureChain)
 	}
 
@@ -151,65 +200,66 @@ func (pMgr *PolicyManager) newCreatorWithChains(chainNames []string) *ioutil.Fil
 }
 
 // will make a similar func for on update eventually
-func (pMgr *PolicyManager) deleteOldJumpRulesOnRemove(policy *NPMNetworkPolicy) error {
+func (pMgr *PolicyManager) deleteOldJumpRulesOnRemove(family ipFamily, policy *NPMNetworkPolicy) error {
 	shouldDeleteIngress, shouldDeleteEgress := policy.hasIngressAndEgress()
 	if shouldDeleteIngress {
-		if err := pMgr.deleteJumpRule(policy, true); err != nil {
+		if err := pMgr.deleteJumpRule(family, policy, true); err != nil {
 			return err
 		}
 	}
 	if shouldDeleteEgress {
-		if err := pMgr.deleteJumpRule(policy, false); err != nil {
+		if err := pMgr.deleteJumpRule(family, policy, false); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (pMgr *PolicyManager) deleteJumpRule(policy *NPMNetworkPolicy, direction UniqueDirection) error {
+func (pMgr *PolicyManager) deleteJumpRule(family ipFamily, policy *NPMNetworkPolicy, direction UniqueDirection) error {
 	var specs []string
 	var baseChainName string
 	var chainName string
 	if direction == forIngress {
-		specs = ingressJumpSpecs(policy)
-		baseChainName = util.IptablesAzureIngressChain
+		specs = ingressJumpSpecs(family, policy)
+		baseChainName = policy.ingressBaseChainName()
 		chainName = policy.ingressChainName()
 	} else {
-		specs = egressJumpSpecs(policy)
-		baseChainName = util.IptablesAzureEgressChain
+		specs = egressJumpSpecs(family, policy)
+		baseChainName = policy.egressBaseChainName()
 		chainName = policy.egressChainName()
 	}
 
 	specs = append([]string{baseChainName}, specs...)
 	timer := metrics.StartNewTimer()
-	errCode, err := pMgr.runIPTablesCommand(util.IptablesDeletionFlag, specs...)
+	errCode, err := pMgr.runIPTablesCommandForFamily(family, util.IptablesDeletionFlag, specs...)
 	metrics.RecordIPTablesDeleteLatency(timer)
 	// if this actually happens (don't think it should), could use ignoreErrorsAndRunIPTablesCommand instead with: "Bad rule (does a matching rule exist in that chain?)"
 	if err != nil && errCode != doesNotExistErrorCode && errCode != couldntLoadTargetErrorCode {
 		errorString := fmt.Sprintf("failed to delete jump from %s chain to %s chain for policy %s with exit code %d", baseChainName, chainName, policy.PolicyKey, errCode)
+		errorString = familyErrorString(family, errorString)
 		klog.Errorf("%s. err: %s", errorString, err.Error())
 		return fmt.Errorf("%s. err: %w", errorString, err)
 	}
 	return nil
 }
 
-func ingressJumpSpecs(networkPolicy *NPMNetworkPolicy) []string {
+func ingressJumpSpecs(family ipFamily, networkPolicy *NPMNetworkPolicy) []string {
 	chainName := networkPolicy.ingressChainName()
 	specs := []string{util.IptablesJumpFlag, chainName}
-	specs = append(specs, matchSetSpecsForNetworkPolicy(networkPolicy, DstMatch)...)
+	specs = append(specs, matchSetSpecsForNetworkPolicy(family, networkPolicy, DstMatch)...)
 	specs = append(specs, commentSpecs(networkPolicy.commentForJumpToIngress())...)
 	return specs
 }
 
-func egressJumpSpecs(networkPolicy *NPMNetworkPolicy) []string {
+func egressJumpSpecs(family ipFamily, networkPolicy *NPMNetworkPolicy) []string {
 	chainName := networkPolicy.egressChainName()
 	specs := []string{util.IptablesJumpFlag, chainName}
-	specs = append(specs, matchSetSpecsForNetworkPolicy(networkPolicy, SrcMatch)...)
+	specs = append(specs, matchSetSpecsForNetworkPolicy(family, networkPolicy, SrcMatch)...)
 	specs = append(specs, commentSpecs(networkPolicy.commentForJumpToEgress())...)
 	return specs
 }
 
-func (pMgr *PolicyManager) creatorForNewNetworkPolicies(policyChains []string, networkPolicies []*NPMNetworkPolicy) *ioutil.FileCreator {
+func (pMgr *PolicyManager) creatorForNewNetworkPolicies(family ipFamily, policyChains []string, networkPolicies []*NPMNetworkPolicy) *ioutil.FileCreator {
 	creator := pMgr.newCreatorWithChains(policyChains)
 
 	// 1. Activate NPM if necessary
@@ -221,64 +271,112 @@ func (pMgr *PolicyManager) creatorForNewNetworkPolicies(policyChains []string, n
 	}
 
 	// 2. Add all rules for the network policies
-	ingressJumpLineNumber := 1
-	egressJumpLineNumber := 1
+	ingressJumpLineNumber := pMgr.firstNetworkPolicyJumpLineNumber()
+	egressJumpLineNumber := pMgr.firstNetworkPolicyJumpLineNumber()
+	addedPolicies := make([]*NPMNetworkPolicy, 0, len(networkPolicies))
 	for _, networkPolicy := range networkPolicies {
 		// 2.1 add all rules for the policy chain(s)
-		writeNetworkPolicyRules(creator, networkPolicy)
+		writeNetworkPolicyRules(family, creator, networkPolicy, pMgr.Audit)
 
 		// 2.2 add jump rule(s) to the policy chain(s)
+		// jumps to admin network policy chains are ordered by priority
 		hasIngress, hasEgress := networkPolicy.hasIngressAndEgress()
 		if hasIngress {
-			ingressJumpSpecs := insertSpecs(util.IptablesAzureIngressChain, ingressJumpLineNumber, ingressJumpSpecs(networkPolicy))
+			lineNumber := ingressJumpLineNumber
+			if networkPolicy.Tier == NetworkPolicyTier {
+				ingressJumpLineNumber++
+			} else {
+				lineNumber = pMgr.adminJumpLineNumber(networkPolicy, forIngress, addedPolicies)
+			}
+			ingressJumpSpecs := insertSpecs(networkPolicy.ingressBaseChainName(), lineNumber, ingressJumpSpecs(family, networkPolicy))
 			creator.AddLine("", nil, ingressJumpSpecs...) // TODO error handler
-			ingressJumpLineNumber++
 		}
 		if hasEgress {
-			egressJumpSpecs := insertSpecs(util.IptablesAzureEgressChain, egressJumpLineNumber, egressJumpSpecs(networkPolicy))
+			lineNumber := egressJumpLineNumber
+			if networkPolicy.Tier == NetworkPolicyTier {
+				egressJumpLineNumber++
+			} else {
+				lineNumber = pMgr.adminJumpLineNumber(networkPolicy, forEgress, addedPolicies)
+			}
+			egressJumpSpecs := insertSpecs(networkPolicy.egressBaseChainName(), lineNumber, egressJumpSpecs(family, networkPolicy))
 			creator.AddLine("", nil, egressJumpSpecs...) // TODO error handler
-			egressJumpLineNumber++
 		}
+		addedPolicies = append(addedPolicies, networkPolicy)
 	}
 	creator.AddLine("", nil, util.IptablesRestoreCommit)
 	return creator
 }
 
 // write rules for the policy chain(s)
-func writeNetworkPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy) {
+// with audit, each ACL rule is preceded by an NFLOG rule with the same matches
+func writeNetworkPolicyRules(family ipFamily, creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy, audit bool) {
+	if networkPolicy.Tier == AdminTier {
+		writeReturnOnPassMarkRules(creator, networkPolicy)
+	}
+
 	for _, aclPolicy := range networkPolicy.ACLs {
 		var chainName string
+		var direction Direction
+		var passMark string
 		var actionSpecs []string
 		if aclPolicy.hasIngress() {
 			chainName = networkPolicy.ingressChainName()
-			if aclPolicy.Target == Allowed {
+			direction = Ingress
+			passMark = util.IptablesAzureIngressPassMarkHex
+			switch {
+			case aclPolicy.Target == Allowed:
 				actionSpecs = []string{util.IptablesJumpFlag, util.IptablesAzureIngressAllowMarkChain}
-			} else {
+			case aclPolicy.Target == Passed:
+				actionSpecs = setMarkSpecs(passMark)
+			case networkPolicy.Tier != NetworkPolicyTier:
+				// admin network policies drop right away instead of after all policies are evaluated
+				actionSpecs = []string{util.IptablesJumpFlag, util.IptablesDrop}
+			default:
 				actionSpecs = setMarkSpecs(util.IptablesAzureIngressDropMarkHex)
 			}
 		} else {
 			chainName = networkPolicy.egressChainName()
-			if aclPolicy.Target == Allowed {
+			direction = Egress
+			passMark = util.IptablesAzureEgressPassMarkHex
+			switch {
+			case aclPolicy.Target == Allowed:
 				actionSpecs = []string{util.IptablesJumpFlag, util.IptablesAzureAcceptChain}
-			} else {
+			case aclPolicy.Target == Passed:
+				actionSpecs = setMarkSpecs(passMark)
+			case networkPolicy.Tier != NetworkPolicyTier:
+				actionSpecs = []string{util.IptablesJumpFlag, util.IptablesDrop}
+			default:
 				actionSpecs = setMarkSpecs(util.IptablesAzureEgressDropMarkHex)
 			}
 		}
+		ruleSpecs := iptablesRuleSpecs(family, aclPolicy)
+		if audit {
+			auditLine := []string{"-A", chainName}
+			auditLine = append(auditLine, nflogSpecs(auditTag(direction, aclPolicy.Target, networkPolicy.PolicyKey))...)
+			auditLine = append(auditLine, ruleSpecs...)
+			creator.AddLine("", nil, auditLine...) // TODO add error handler
+		}
+
 		line := []string{"-A", chainName}
 		line = append(line, actionSpecs...)
-		line = append(line, iptablesRuleSpecs(aclPolicy)...)
+		line = append(line, ruleSpecs...)
 		creator.AddLine("", nil, line...) // TODO add error handler
+
+		if aclPolicy.Target == Passed {
+			// setting the mark doesn't stop the chain
+			creator.AddLine("", nil, returnOnPassMarkSpecs(chainName, passMark)...)
+		}
 	}
 }
 
-func iptablesRuleSpecs(aclPolicy *ACLPolicy) []string {
+func iptablesRuleSpecs(family ipFamily, aclPolicy *ACLPolicy) []string {
 	specs := make([]string, 0)
 	if aclPolicy.Protocol != UnspecifiedProtocol {
 		specs = append(specs, util.IptablesProtFlag, string(aclPolicy.Protocol))
 	}
 	specs = append(specs, dstPortSpecs(aclPolicy.DstPorts)...)
-	specs = append(specs, matchSetSpecsFromSetInfo(aclPolicy.SrcList)...)
-	specs = append(specs, matchSetSpecsFromSetInfo(aclPolicy.DstList)...)
+	specs = append(specs, matchSetSpecsFromSetInfo(family, aclPolicy.SrcList)...)
+	specs = append(specs, matchSetSpecsFromSetInfo(family, aclPolicy.DstList)...)
 	specs = append(specs, commentSpecs(aclPolicy.comment())...)
 	return specs
 }
@@ -290,20 +388,20 @@ func dstPortSpecs(portRange Ports) []string {
 	return []string{util.IptablesDstPortFlag, portRange.toIPTablesString()}
 }
 
-func matchSetSpecsForNetworkPolicy(networkPolicy *NPMNetworkPolicy, matchType MatchType) []string {
+func matchSetSpecsForNetworkPolicy(family ipFamily, networkPolicy *NPMNetworkPolicy, matchType MatchType) []string {
 	specs := make([]string, 0, maxLengthForMatchSetSpecs*len(networkPolicy.PodSelectorList))
 	matchString := matchType.toIPTablesString()
 	for _, setInfo := range networkPolicy.PodSelectorList {
-		specs = append(specs, setInfo.matchSetSpecs(matchString)...)
+		specs = append(specs, setInfo.matchSetSpecs(family, matchString)...)
 	}
 	return specs
 }
 
-func matchSetSpecsFromSetInfo(setInfoList []SetInfo) []string {
+func matchSetSpecsFromSetInfo(family ipFamily, setInfoList []SetInfo) []string {
 	specs := make([]string, 0, maxLengthForMatchSetSpecs*len(setInfoList))
 	for _, setInfo := range setInfoList {
 		matchString := setInfo.MatchType.toIPTablesString()
-		specs = append(specs, setInfo.matchSetSpecs(matchString)...)
+		specs = append(specs, setInfo.matchSetSpecs(family, matchString)...)
 	}
 	return specs
 }
@@ -317,6 +415,17 @@ func setMarkSpecs(mark string) []string {
 	}
 }
 
+func nflogSpecs(prefix string) []string {
+	return []string{
+		util.IptablesJumpFlag,
+		util.IptablesNFLog,
+		util.IptablesNFLogGroupFlag,
+		util.AuditNFLogGroupString,
+		util.IptablesNFLogPrefixFlag,
+		prefix,
+	}
+}
+
 func commentSpecs(comment string) []string {
 	return []string{
 		util.IptablesModuleFlag,
@@ -227,7 +227,7 @@ func TestCreatorForAddPolicies(t *testing.T) {
 
 	// 1. test with activation
 	policies := []*NPMNetworkPolicy{allTestNetworkPolicies[0]}
-	creator := pMgr.creatorForNewNetworkPolicies(chainNames(policies), policies)
+	creator := pMgr.creatorForNewNetworkPolicies(ipv4, chainNames(policies), policies)
 	actualLines := strings.Split(creator.ToString(), "\n")
 	expectedLines := []string{
 		"*filter",
@@ -254,7 +254,7 @@ func TestCreatorForAddPolicies(t *testing.T) {
 	// 2. test without activation
 	// add a policy to the cache so that we don't activate (the cache doesn't impact creatorForNewNetworkPolicies)
 	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{allTestNetworkPolicies[0]}, nil))
-	creator = pMgr.creatorForNewNetworkPolicies(chainNames(allTestNetworkPolicies), allTestNetworkPolicies)
+	creator = pMgr.creatorForNewNetworkPolicies(ipv4, chainNames(allTestNetworkPolicies), allTestNetworkPolicies)
 	actualLines = strings.Split(creator.ToString(), "\n")
 	expectedLines = []string{
 		"*filter",
@@ -183,6 +183,23 @@ func TestGetPolicy(t *testing.T) {
 	require.Equal(t, "x/test-netpol", policy.PolicyKey)
 }
 
+func TestPolicyKeyByHash(t *testing.T) {
+	testNetPol := testNetworkPolicy()
+	calls := GetAddPolicyTestCalls(testNetPol)
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, ipsetConfig)
+
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{testNetPol}, epList))
+
+	policyKey, ok := pMgr.PolicyKeyByHash(util.Hash(testNetPol.PolicyKey))
+	require.True(t, ok)
+	require.Equal(t, testNetPol.PolicyKey, policyKey)
+
+	_, ok = pMgr.PolicyKeyByHash(util.Hash("x/other-netpol"))
+	require.False(t, ok)
+}
+
 func TestRemovePolicy(t *testing.T) {
 	metrics.ReinitializeAll()
 	testNetPol := testNetworkPolicy()
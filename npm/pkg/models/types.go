@@ -10,6 +10,7 @@ import (
 	"k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	networkinginformers "k8s.io/client-go/informers/networking/v1"
+	policyinformers "sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions"
 )
 
 var (
@@ -34,6 +35,8 @@ type K8SControllersV2 struct {
 	NamespaceControllerV2 *controllersv2.NamespaceController     //nolint:structcheck // false lint error
 	NpmNamespaceCacheV2   *controllersv2.NpmNamespaceCache       //nolint:structcheck // false lint error
 	NetPolControllerV2    *controllersv2.NetworkPolicyController //nolint:structcheck // false lint error
+	// AdminNetPolControllerV2 is nil unless admin network policies are enabled
+	AdminNetPolControllerV2 *controllersv2.AdminNetworkPolicyController //nolint:structcheck // false lint error
 }
 
 // Informers are the informers for the k8s controllers
@@ -42,6 +45,8 @@ type Informers struct {
 	PodInformer     coreinformers.PodInformer                 //nolint:structcheck // false lint error
 	NsInformer      coreinformers.NamespaceInformer           //nolint:structcheck // false lint error
 	NpInformer      networkinginformers.NetworkPolicyInformer //nolint:structcheck // false lint error
+	// PolicyInformerFactory is for the policy.networking.k8s.io API group. It's nil unless admin network policies are enabled.
+	PolicyInformerFactory policyinformers.SharedInformerFactory //nolint:structcheck // false lint error
 }
 
 // AzureConfig captures the Azure specific configurations and fields
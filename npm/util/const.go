@@ -28,10 +28,11 @@ const (
 )
 
 var (
-	Iptables        = IptablesLegacy
-	Ip6tables       = Ip6tablesLegacy //nolint (avoid warning to capitalize this p)
-	IptablesSave    = IptablesSaveLegacy
-	IptablesRestore = IptablesRestoreLegacy
+	Iptables         = IptablesLegacy
+	Ip6tables        = Ip6tablesLegacy //nolint (avoid warning to capitalize this p)
+	IptablesSave     = IptablesSaveLegacy
+	IptablesRestore  = IptablesRestoreLegacy
+	Ip6tablesRestore = Ip6tablesRestoreLegacy //nolint (avoid warning to capitalize this p)
 )
 
 // iptables related constants.
@@ -41,6 +42,9 @@ const (
 
 	IptablesNft                string = "iptables-nft"
 	Ip6tablesLegacy            string = "ip6tables" //nolint (avoid warning to capitalize this p)
+	Ip6tablesNft               string = "ip6tables-nft"
+	Ip6tablesRestoreLegacy     string = "ip6tables-restore"
+	Ip6tablesRestoreNft        string = "ip6tables-nft-restore"
 	IptablesSaveNft            string = "iptables-nft-save"
 	IptablesRestoreNft         string = "iptables-nft-restore"
 	IptablesLegacy             string = "iptables"
@@ -67,6 +71,9 @@ const (
 	IptablesDrop               string = "DROP"
 	IptablesReturn             string = "RETURN"
 	IptablesMark               string = "MARK"
+	IptablesNFLog              string = "NFLOG"
+	IptablesNFLogGroupFlag     string = "--nflog-group"
+	IptablesNFLogPrefixFlag    string = "--nflog-prefix"
 	IptablesSrcFlag            string = "src"
 	IptablesDstFlag            string = "dst"
 	IptablesNamedPortFlag      string = "dst,dst"
@@ -99,6 +106,8 @@ const (
 	IptablesListFlag        string = "-L"
 	IptablesNumericFlag     string = "-n"
 	IptablesLineNumbersFlag string = "--line-numbers"
+	IptablesVerboseFlag     string = "-v"
+	IptablesExactFlag       string = "-x"
 
 	IptablesKubeServicesChain          string = "KUBE-SERVICES"
 	IptablesForwardChain               string = "FORWARD"
@@ -110,6 +119,13 @@ const (
 	IptablesAzureIngressAllowMarkChain string = "AZURE-NPM-INGRESS-ALLOW-MARK"
 	IptablesAzureEgressChain           string = "AZURE-NPM-EGRESS"
 
+	// NPM v2 chains for AdminNetworkPolicies, which are evaluated before NetworkPolicies,
+	// and BaselineAdminNetworkPolicies, which are evaluated after NetworkPolicies
+	IptablesAzureAdminIngressChain         string = "AZURE-NPM-ANP-INGRESS"
+	IptablesAzureAdminEgressChain          string = "AZURE-NPM-ANP-EGRESS"
+	IptablesAzureBaselineAdminIngressChain string = "AZURE-NPM-BANP-INGRESS"
+	IptablesAzureBaselineAdminEgressChain  string = "AZURE-NPM-BANP-EGRESS"
+
 	// Chains used in NPM v1
 	IptablesAzureIngressPortChain  string = "AZURE-NPM-INGRESS-PORT"
 	IptablesAzureIngressFromChain  string = "AZURE-NPM-INGRESS-FROM"
@@ -146,6 +162,10 @@ const (
 	IptablesAzureIngressAllowMarkHex string = "0x200/0x200"
 	IptablesAzureIngressDropMarkHex  string = "0x400/0x400"
 	IptablesAzureEgressDropMarkHex   string = "0x800/0x800"
+	// An AdminNetworkPolicy with a Pass action sets these marks so that lower priority AdminNetworkPolicies are skipped.
+	// NPM v1 uses 0x1000 and 0x2000, but NPM v1 and v2 never run together.
+	IptablesAzureIngressPassMarkHex string = "0x100/0x100"
+	IptablesAzureEgressPassMarkHex  string = "0x1000/0x1000"
 
 	// marks in NPM v1
 	IptablesAzureIngressMarkHex string = "0x2000"
@@ -209,6 +229,48 @@ const (
 	SetPolicyDelimiter string = ","
 )
 
+// nftables related constants.
+const (
+	Nft         string = "nft"
+	NftFileFlag string = "-f"
+	// NftStdin makes nft read the file from stdin.
+	NftStdin string = "-"
+	// NftTerseFlag omits the elements of sets when listing.
+	NftTerseFlag string = "-t"
+
+	NftFamily string = "inet"
+	NftTable  string = "azure-npm"
+	// NftForwardChain is the base chain hooked into forward. It plays the part of the FORWARD chain for iptables.
+	NftForwardChain string = "FORWARD"
+
+	NftAdd        string = "add"
+	NftFlush      string = "flush"
+	NftDelete     string = "delete"
+	NftList       string = "list"
+	NftTableObj   string = "table"
+	NftChainObj   string = "chain"
+	NftSetObj     string = "set"
+	NftRuleObj    string = "rule"
+	NftElementObj string = "element"
+
+	NftAccept  string = "accept"
+	NftDrop    string = "drop"
+	NftJump    string = "jump"
+	NftReturn  string = "return"
+	NftLog     string = "log"
+	NftCounter string = "counter"
+
+	// NftExceptSetSuffix names the companion set of a CIDR set which holds its nomatch members.
+	NftExceptSetSuffix string = "-except"
+)
+
+// AuditNFLogGroup is the netlink group which ACLs log audit events to when policy audit is enabled.
+// nft log statements with a group are delivered through NFLOG too.
+const (
+	AuditNFLogGroup       uint16 = 100
+	AuditNFLogGroupString string = "100"
+)
+
 const (
 	BashCommand     string = "bash"
 	BashCommandFlag string = "-c"
@@ -285,6 +347,8 @@ func DetectIptablesVersion(ioShim *common.IOShim) {
 		Iptables = IptablesNft
 		IptablesSave = IptablesSaveNft
 		IptablesRestore = IptablesRestoreNft
+		Ip6tables = Ip6tablesNft
+		Ip6tablesRestore = Ip6tablesRestoreNft
 	} else {
 		lCmd := ioShim.Exec.Command(IptablesSaveLegacy, "-t", "mangle")
 
@@ -298,6 +362,8 @@ func DetectIptablesVersion(ioShim *common.IOShim) {
 			Iptables = IptablesLegacy
 			IptablesSave = IptablesSaveLegacy
 			IptablesRestore = IptablesRestoreLegacy
+			Ip6tables = Ip6tablesLegacy
+			Ip6tablesRestore = Ip6tablesRestoreLegacy
 		} else {
 			lsavecmd := ioShim.Exec.Command(IptablesSaveNft)
 			lsaveoutput, err := lsavecmd.CombinedOutput()
@@ -321,10 +387,14 @@ func DetectIptablesVersion(ioShim *common.IOShim) {
 				Iptables = IptablesLegacy
 				IptablesSave = IptablesSaveLegacy
 				IptablesRestore = IptablesRestoreLegacy
+				Ip6tables = Ip6tablesLegacy
+				Ip6tablesRestore = Ip6tablesRestoreLegacy
 			} else {
 				Iptables = IptablesNft
 				IptablesSave = IptablesSaveNft
 				IptablesRestore = IptablesRestoreNft
+				Ip6tables = Ip6tablesNft
+				Ip6tablesRestore = Ip6tablesRestoreNft
 			}
 		}
 	}
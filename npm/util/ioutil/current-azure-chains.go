@@ -23,7 +23,16 @@ var (
 )
 
 func AllCurrentAzureChains(exec utilexec.Interface, lockWaitTimeSeconds string) (map[string]struct{}, error) {
-	iptablesListCommand := exec.Command(util.Iptables,
+	return allCurrentAzureChains(exec, util.Iptables, lockWaitTimeSeconds)
+}
+
+// AllCurrentAzureIPv6Chains is the ip6tables counterpart of AllCurrentAzureChains.
+func AllCurrentAzureIPv6Chains(exec utilexec.Interface, lockWaitTimeSeconds string) (map[string]struct{}, error) {
+	return allCurrentAzureChains(exec, util.Ip6tables, lockWaitTimeSeconds)
+}
+
+func allCurrentAzureChains(exec utilexec.Interface, iptablesCommand, lockWaitTimeSeconds string) (map[string]struct{}, error) {
+	iptablesListCommand := exec.Command(iptablesCommand,
 		util.IptablesWaitFlag, lockWaitTimeSeconds, util.IptablesTableFlag, util.IptablesFilterTable,
 		util.IptablesNumericFlag, util.IptablesListFlag,
 	)
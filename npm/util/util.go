@@ -342,6 +342,21 @@ func IsIPV4(ip string) bool {
 	return address.Is4()
 }
 
+// IsIPV6 is the IPv6 counterpart of IsIPV4. It accepts an IP or CIDR.
+func IsIPV6(ip string) bool {
+	ipOnly := strings.Split(ip, "/")
+	address, err := netip.ParseAddr(ipOnly[0])
+	if err != nil || !address.Is6() || address.Is4In6() {
+		return false
+	}
+
+	if strings.Contains(ip, "/") {
+		_, err := netip.ParsePrefix(ip)
+		return err == nil
+	}
+	return true
+}
+
 // Get preferred outbound ip of this machine
 // source: https://stackoverflow.com/questions/23558425/how-do-i-get-the-local-ip-address-in-go
 func NodeIP() (string, error) {
@@ -0,0 +1,55 @@
+package tls
+
+import (
+	"crypto/x509"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CertPoolFile is the pool of the CA certificates in a PEM bundle file.
+// The bundle is reloaded when the file changes, so that the CAs can be rotated without a restart.
+type CertPoolFile struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	pool    *x509.CertPool
+}
+
+// NewCertPoolFile loads the pool of the CA certificates in the PEM bundle at the path.
+func NewCertPoolFile(path string) (*CertPoolFile, error) {
+	f := &CertPoolFile{path: path}
+	if _, err := f.Pool(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Pool returns the pool of the CA certificates in the file, reloading it if the file changed since it was last loaded.
+// If the changed file can not be loaded, the previous pool is returned with the error.
+func (f *CertPoolFile) Pool() (*x509.CertPool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return f.pool, errors.Wrapf(err, "failed to stat CA bundle %s", f.path)
+	}
+	if f.pool != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.pool, nil
+	}
+
+	pem, err := os.ReadFile(f.path)
+	if err != nil {
+		return f.pool, errors.Wrapf(err, "failed to read CA bundle %s", f.path)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return f.pool, errors.Errorf("no certificates found in CA bundle %s", f.path)
+	}
+	f.pool, f.modTime, f.size = pool, info.ModTime(), info.Size()
+	return f.pool, nil
+}
@@ -0,0 +1,92 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// createCACertificate returns a self-signed CA certificate and its PEM encoding.
+func createCACertificate(t *testing.T, commonName string) (*x509.Certificate, []byte) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return cert, pem.EncodeToMemory(&pem.Block{Type: CertLabel, Bytes: der})
+}
+
+func verifies(cert *x509.Certificate, pool *x509.CertPool) bool {
+	_, err := cert.Verify(x509.VerifyOptions{Roots: pool})
+	return err == nil
+}
+
+func TestCertPoolFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if _, err := NewCertPoolFile(path); err == nil {
+		t.Fatal("Expected an error for a missing CA bundle")
+	}
+
+	oldCA, oldPEM := createCACertificate(t, "old-ca")
+	if err := os.WriteFile(path, oldPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewCertPoolFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load CA bundle: %v", err)
+	}
+	pool, err := f.Pool()
+	if err != nil || !verifies(oldCA, pool) {
+		t.Fatalf("Expected the old CA to be trusted, err: %v", err)
+	}
+
+	// rotate the CA.
+	newCA, newPEM := createCACertificate(t, "new-ca")
+	if err := os.WriteFile(path, newPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	pool, err = f.Pool()
+	if err != nil || !verifies(newCA, pool) || verifies(oldCA, pool) {
+		t.Fatalf("Expected only the new CA to be trusted after the rotation, err: %v", err)
+	}
+
+	// an invalid bundle keeps the previous pool.
+	if err := os.WriteFile(path, []byte("invalid"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pool, err = f.Pool()
+	if err == nil {
+		t.Fatal("Expected an error for an invalid CA bundle")
+	}
+	if !verifies(newCA, pool) {
+		t.Fatal("Expected the previous pool to be kept")
+	}
+}
@@ -13,6 +13,8 @@ type TlsSettings struct {
 	KeyVaultCertificateName            string
 	MSIResourceID                      string
 	KeyVaultCertificateRefreshInterval time.Duration
+	// ClientCAFile is a PEM bundle of the CAs that client certificates are required to be signed by. Empty disables mTLS.
+	ClientCAFile string
 }
 
 func GetTlsCertificateRetriever(settings TlsSettings) (TlsCertificateRetriever, error) {
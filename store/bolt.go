@@ -0,0 +1,360 @@
+// Copyright 2024 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/Azure/azure-container-networking/processlock"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+const (
+	// BoltExtension - Extension used for bolt database files.
+	BoltExtension = ".db"
+
+	// boltOpenTimeout bounds how long we wait for the bolt file lock held by another process.
+	boltOpenTimeout = 5 * time.Second
+)
+
+// boltBucket is the single bucket that holds all of the store's keys.
+var boltBucket = []byte("state")
+
+// boltStore is an implementation of KeyValueStore using an embedded bbolt database.
+// Every Write is committed in its own transaction, so only the changed key is
+// rewritten and a crash mid-write leaves the previous value intact.
+// The database is opened on first use and kept open until the process lock is
+// released, so that, like jsonFileStore, multiple processes can share the store
+// when serialized by the process lock.
+type boltStore struct {
+	fileName    string
+	processLock processlock.Interface
+	// jsonFileName is the JSON file store migrated in to this store on first use, if any.
+	jsonFileName string
+	migrated     bool
+	db           *bolt.DB
+	sync.Mutex
+	logger *zap.Logger
+}
+
+// NewBoltStore creates a new boltStore object, accessed as a KeyValueStore.
+func NewBoltStore(fileName string, lockclient processlock.Interface, logger *zap.Logger) (KeyValueStore, error) {
+	if fileName == "" {
+		return &boltStore{}, errors.New("need to pass in a bolt file path")
+	}
+	kvs := &boltStore{
+		fileName:    fileName,
+		processLock: lockclient,
+		logger:      logger,
+	}
+
+	return kvs, nil
+}
+
+func (kvs *boltStore) Exists() bool {
+	if _, err := os.Stat(kvs.fileName); err != nil {
+		return false
+	}
+	return true
+}
+
+// open returns the open bolt database, opening it first if needed and creating it if create is set.
+// It returns os.ErrNotExist if the database does not exist and create is not set.
+// Lock-free for internal callers.
+func (kvs *boltStore) open(create bool) (*bolt.DB, error) {
+	if kvs.db != nil {
+		return kvs.db, nil
+	}
+	if !create {
+		if _, err := os.Stat(kvs.fileName); err != nil {
+			return nil, err //nolint:wrapcheck // caller checks os.IsNotExist
+		}
+	}
+	db, err := bolt.Open(kvs.fileName, 0o644, &bolt.Options{Timeout: boltOpenTimeout}) //nolint:gomnd // file mode
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open bolt store %s", kvs.fileName)
+	}
+	kvs.db = db
+	return db, nil
+}
+
+// close closes the bolt database if it is open. Lock-free for internal callers.
+func (kvs *boltStore) close() error {
+	if kvs.db == nil {
+		return nil
+	}
+	err := kvs.db.Close()
+	kvs.db = nil
+	return errors.Wrapf(err, "failed to close bolt store %s", kvs.fileName)
+}
+
+// Read restores the value for the given key from persistent store.
+func (kvs *boltStore) Read(key string, value interface{}) error {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	if err := kvs.migrate(); err != nil {
+		return err
+	}
+
+	db, err := kvs.open(false)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrKeyNotFound
+		}
+		return err
+	}
+
+	var raw []byte
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		if b == nil {
+			return ErrStoreEmpty
+		}
+		v := b.Get([]byte(key))
+		if v == nil {
+			return ErrKeyNotFound
+		}
+		// values are only valid for the life of the transaction.
+		raw = bytes.Clone(v)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrStoreEmpty) {
+			if kvs.logger != nil {
+				kvs.logger.Info("Unable to read empty store", zap.String("fileName", kvs.fileName))
+			} else {
+				log.Printf("Unable to read store %s, was empty", kvs.fileName)
+			}
+		}
+		return err
+	}
+
+	return json.Unmarshal(raw, value)
+}
+
+// Write saves the given key value pair to persistent store.
+func (kvs *boltStore) Write(key string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	if err := kvs.migrate(); err != nil {
+		return err
+	}
+
+	return kvs.put(map[string][]byte{key: raw})
+}
+
+// put commits all of the given pairs in a single transaction.
+func (kvs *boltStore) put(pairs map[string][]byte) error {
+	db, err := kvs.open(true)
+	if err != nil {
+		return err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(boltBucket)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		for k, v := range pairs {
+			if err := b.Put([]byte(k), v); err != nil {
+				return err //nolint:wrapcheck // wrapped below
+			}
+		}
+		return nil
+	})
+	return errors.Wrapf(err, "failed to write to bolt store %s", kvs.fileName)
+}
+
+// Flush commits in-memory state to persistent store.
+// Writes are committed immediately, so there is never anything to flush.
+func (kvs *boltStore) Flush() error {
+	return nil
+}
+
+func (kvs *boltStore) lockUtil(status chan error) {
+	err := kvs.processLock.Lock()
+	status <- err
+}
+
+// Lock locks the store for exclusive access.
+func (kvs *boltStore) Lock(timeout time.Duration) error {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	afterTime := time.After(timeout)
+	status := make(chan error)
+
+	if kvs.logger != nil {
+		kvs.logger.Info("Acquiring process lock")
+	} else {
+		log.Printf("Acquiring process lock")
+	}
+
+	go kvs.lockUtil(status)
+
+	var err error
+	select {
+	case <-afterTime:
+		return ErrTimeoutLockingStore
+	case err = <-status:
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "processLock acquire error")
+	}
+
+	if kvs.logger != nil {
+		kvs.logger.Info("Acquired process lock with timeout value of", zap.Any("timeout", timeout))
+	} else {
+		log.Printf("Acquired process lock with timeout value of %v", timeout)
+	}
+
+	// migrate while holding the process lock so that concurrent processes do not race on it.
+	if err := kvs.migrate(); err != nil {
+		// the caller can't unlock a store which failed to lock, so release the process lock here.
+		if closeErr := kvs.close(); closeErr != nil {
+			log.Errorf("%v", closeErr)
+		}
+		if unlockErr := kvs.processLock.Unlock(); unlockErr != nil {
+			log.Errorf("failed to release process lock after failed migration: %v", unlockErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Unlock unlocks the store.
+// The database is closed first so that the process which locks the store next can open it.
+func (kvs *boltStore) Unlock() error {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	if err := kvs.close(); err != nil {
+		log.Errorf("%v", err)
+	}
+
+	err := kvs.processLock.Unlock()
+	if err != nil {
+		return errors.Wrap(err, "unlock error")
+	}
+
+	if kvs.logger != nil {
+		kvs.logger.Info("Released process lock")
+	} else {
+		log.Printf("Released process lock")
+	}
+
+	return nil
+}
+
+// GetModificationTime returns the modification time of the persistent store.
+func (kvs *boltStore) GetModificationTime() (time.Time, error) {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	info, err := os.Stat(kvs.fileName)
+	if err != nil {
+		if kvs.logger != nil {
+			kvs.logger.Info("os.stat() for file", zap.String("fileName", kvs.fileName), zap.Error(err))
+		} else {
+			log.Printf("os.stat() for file %v failed: %v", kvs.fileName, err)
+		}
+
+		return time.Time{}.UTC(), err
+	}
+
+	return info.ModTime().UTC(), nil
+}
+
+func (kvs *boltStore) Remove() {
+	kvs.Mutex.Lock()
+	if err := kvs.close(); err != nil {
+		log.Errorf("%v", err)
+	}
+	if err := os.Remove(kvs.fileName); err != nil {
+		log.Errorf("could not remove file %s. Error: %v", kvs.fileName, err)
+	}
+	kvs.Mutex.Unlock()
+}
+
+// migrate copies every key of the JSON file store this store replaces in to the
+// bolt database in a single transaction, then renames the JSON file so that the
+// migration happens only once. It is a no-op if the database already exists.
+// Lock-free for internal callers.
+func (kvs *boltStore) migrate() error {
+	if kvs.migrated || kvs.jsonFileName == "" {
+		return nil
+	}
+	if kvs.Exists() {
+		kvs.migrated = true
+		return nil
+	}
+
+	b, err := os.ReadFile(kvs.jsonFileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// nothing to migrate.
+			kvs.migrated = true
+			return nil
+		}
+		return errors.Wrapf(err, "failed to read json store %s", kvs.jsonFileName)
+	}
+
+	pairs := map[string][]byte{}
+	if len(b) != 0 {
+		data := map[string]json.RawMessage{}
+		if err := json.Unmarshal(b, &data); err != nil {
+			return errors.Wrapf(err, "failed to decode json store %s", kvs.jsonFileName)
+		}
+		for k, v := range data {
+			pairs[k] = v
+		}
+	}
+
+	if err := kvs.put(pairs); err != nil {
+		kvs.undoMigration()
+		return err
+	}
+
+	if err := os.Rename(kvs.jsonFileName, kvs.jsonFileName+MigratedExtension); err != nil {
+		kvs.undoMigration()
+		return errors.Wrapf(err, "failed to rename migrated json store %s", kvs.jsonFileName)
+	}
+	kvs.migrated = true
+
+	if kvs.logger != nil {
+		kvs.logger.Info("Migrated json store to bolt", zap.String("from", kvs.jsonFileName),
+			zap.String("to", kvs.fileName), zap.Int("keys", len(pairs)))
+	} else {
+		log.Printf("Migrated %d keys from json store %s to bolt store %s", len(pairs), kvs.jsonFileName, kvs.fileName)
+	}
+
+	return nil
+}
+
+// undoMigration removes the bolt database a failed migration created, so that the next
+// use of the store migrates the JSON file store again instead of finding a database.
+// Lock-free for internal callers.
+func (kvs *boltStore) undoMigration() {
+	if err := kvs.close(); err != nil {
+		log.Errorf("%v", err)
+	}
+	if err := os.Remove(kvs.fileName); err != nil && !os.IsNotExist(err) {
+		log.Errorf("could not remove partially migrated bolt store %s. Error: %v", kvs.fileName, err)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/processlock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltStoreWriteAndRead(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "test"+BoltExtension)
+	kvs, err := NewBoltStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+
+	var readValue testType1
+	require.ErrorIs(t, kvs.Read(testKey1, &readValue), ErrKeyNotFound)
+	require.False(t, kvs.Exists())
+
+	writtenValue := testType1{"test", 42}
+	anotherValue := testType1{"any", 14}
+	require.NoError(t, kvs.Write(testKey1, &writtenValue))
+	require.NoError(t, kvs.Write(testKey2, &anotherValue))
+	require.NoError(t, kvs.Flush())
+	require.True(t, kvs.Exists())
+
+	// the database stays open between operations until the store is unlocked.
+	db := kvs.(*boltStore).db
+	require.NotNil(t, db)
+	require.NoError(t, kvs.Read(testKey1, &readValue))
+	require.Same(t, db, kvs.(*boltStore).db)
+	require.NoError(t, kvs.Unlock())
+	require.Nil(t, kvs.(*boltStore).db)
+
+	// a new store on the same file sees the committed values.
+	kvs, err = NewBoltStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	require.NoError(t, kvs.Read(testKey1, &readValue))
+	require.Equal(t, writtenValue, readValue)
+	require.NoError(t, kvs.Read(testKey2, &readValue))
+	require.Equal(t, anotherValue, readValue)
+	require.ErrorIs(t, kvs.Read("missing", &readValue), ErrKeyNotFound)
+
+	modTime, err := kvs.GetModificationTime()
+	require.NoError(t, err)
+	require.False(t, modTime.IsZero())
+
+	kvs.Remove()
+	require.False(t, kvs.Exists())
+}
+
+func TestBoltStoreLock(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "test"+BoltExtension)
+
+	kvs, err := NewBoltStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	require.NoError(t, kvs.Lock(10*time.Second))
+	require.NoError(t, kvs.Unlock())
+
+	kvs, err = NewBoltStore(fileName, processlock.NewMockFileLock(true), nil)
+	require.NoError(t, err)
+	require.ErrorContains(t, kvs.Lock(10*time.Second), processlock.ErrMockFileLock.Error())
+
+	kvs, err = NewBoltStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, kvs.Lock(0), ErrTimeoutLockingStore)
+}
+
+func TestNewBoltStoreMigratesJSONFile(t *testing.T) {
+	dir := t.TempDir()
+	jsonFileName := filepath.Join(dir, "test.json")
+	require.NoError(t, os.WriteFile(jsonFileName, []byte(`{"key1":{"Field1":"test","Field2":42}}`), 0o600))
+
+	kvs, err := New(TypeBolt, jsonFileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	require.NoError(t, kvs.Lock(10*time.Second))
+
+	var readValue testType1
+	require.NoError(t, kvs.Read(testKey1, &readValue))
+	require.Equal(t, testType1{"test", 42}, readValue)
+
+	// the JSON file is moved aside so that the migration only happens once.
+	_, err = os.Stat(jsonFileName)
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(jsonFileName + MigratedExtension)
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "test"+BoltExtension))
+	require.NoError(t, err)
+	require.NoError(t, kvs.Unlock())
+
+	// a JSON file appearing later does not overwrite the bolt store.
+	require.NoError(t, os.WriteFile(jsonFileName, []byte(`{"key1":{"Field1":"stale","Field2":1}}`), 0o600))
+	kvs, err = New(TypeBolt, jsonFileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	require.NoError(t, kvs.Read(testKey1, &readValue))
+	require.Equal(t, testType1{"test", 42}, readValue)
+}
+
+// countingFileLock counts how many times it is held.
+type countingFileLock struct {
+	held int
+}
+
+func (l *countingFileLock) Lock() error {
+	l.held++
+	return nil
+}
+
+func (l *countingFileLock) Unlock() error {
+	l.held--
+	return nil
+}
+
+func TestBoltStoreLockReleasedWhenMigrationFails(t *testing.T) {
+	dir := t.TempDir()
+	jsonFileName := filepath.Join(dir, "test.json")
+	require.NoError(t, os.WriteFile(jsonFileName, []byte(`{"key1":`), 0o600))
+
+	lock := &countingFileLock{}
+	kvs, err := New(TypeBolt, jsonFileName, lock, nil)
+	require.NoError(t, err)
+	require.Error(t, kvs.Lock(10*time.Second))
+	require.Zero(t, lock.held)
+	require.Nil(t, kvs.(*boltStore).db)
+
+	// the migration is retried once the JSON file store is fixed.
+	require.NoError(t, os.WriteFile(jsonFileName, []byte(`{"key1":{"Field1":"test","Field2":42}}`), 0o600))
+	require.NoError(t, kvs.Lock(10*time.Second))
+	var readValue testType1
+	require.NoError(t, kvs.Read(testKey1, &readValue))
+	require.Equal(t, testType1{"test", 42}, readValue)
+	require.NoError(t, kvs.Unlock())
+	require.Zero(t, lock.held)
+}
+
+func TestNewUnknownStoreType(t *testing.T) {
+	_, err := New(Type("unknown"), "test.json", processlock.NewMockFileLock(false), nil)
+	require.Error(t, err)
+}
@@ -4,11 +4,14 @@
 package store
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 
@@ -27,8 +30,52 @@ const (
 	DefaultLockTimeout        = 10000 * time.Millisecond
 	DefaultLockTimeoutLinux   = 30000 * time.Millisecond
 	DefaultLockTimeoutWindows = 60000 * time.Millisecond
+
+	// JournalExtension - Extension added to the file name for the checksum journal.
+	JournalExtension = ".journal"
+
+	// DefaultSnapshotCount - number of previous versions of the file kept for recovery.
+	DefaultSnapshotCount = 3
 )
 
+// ErrChecksumMismatch is returned when the contents of the file do not match any checksum in the journal.
+var ErrChecksumMismatch = errors.New("store file checksum mismatch")
+
+// RecoveryEvent describes the restoration of a corrupted store file from a snapshot.
+type RecoveryEvent struct {
+	FileName string
+	Snapshot string
+	Reason   error
+}
+
+// RecoveryHandler is notified whenever a store file is restored from a snapshot.
+type RecoveryHandler func(RecoveryEvent)
+
+// JSONFileStoreOption configures optional behavior of the JSON file store.
+type JSONFileStoreOption func(*jsonFileStore)
+
+// WithSnapshots keeps the previous n versions of the file next to it and records their checksums in
+// a journal, so that a corrupted file is detected on Read and the last good snapshot is restored.
+// A file which still decodes, like one truncated at a line break, is detected by its checksum.
+func WithSnapshots(n int) JSONFileStoreOption {
+	return func(kvs *jsonFileStore) {
+		kvs.snapshots = n
+	}
+}
+
+// WithRecoveryHandler sets the handler notified when the file is restored from a snapshot.
+func WithRecoveryHandler(h RecoveryHandler) JSONFileStoreOption {
+	return func(kvs *jsonFileStore) {
+		kvs.onRecovery = h
+	}
+}
+
+// journal records the checksums of the most recent versions of the file, newest first.
+// It is written before the file is replaced, so the file on disk always matches one of them.
+type journal struct {
+	Checksums []string
+}
+
 // jsonFileStore is an implementation of KeyValueStore using a local JSON file.
 type jsonFileStore struct {
 	fileName    string
@@ -36,13 +83,15 @@ type jsonFileStore struct {
 	inSync      bool
 	processLock processlock.Interface
 	sync.Mutex
-	logger *zap.Logger
+	logger     *zap.Logger
+	snapshots  int
+	onRecovery RecoveryHandler
 }
 
 // NewJsonFileStore creates a new jsonFileStore object, accessed as a KeyValueStore.
 //
 //nolint:revive // ignoring name change
-func NewJsonFileStore(fileName string, lockclient processlock.Interface, logger *zap.Logger) (KeyValueStore, error) {
+func NewJsonFileStore(fileName string, lockclient processlock.Interface, logger *zap.Logger, opts ...JSONFileStoreOption) (KeyValueStore, error) {
 	if fileName == "" {
 		return &jsonFileStore{}, errors.New("need to pass in a json file path")
 	}
@@ -52,6 +101,9 @@ func NewJsonFileStore(fileName string, lockclient processlock.Interface, logger
 		data:        make(map[string]*json.RawMessage),
 		logger:      logger,
 	}
+	for _, opt := range opts {
+		opt(kvs)
+	}
 
 	return kvs, nil
 }
@@ -70,33 +122,7 @@ func (kvs *jsonFileStore) Read(key string, value interface{}) error {
 
 	// Read contents from file if memory is not in sync.
 	if !kvs.inSync {
-		// Open and parse the file if it exists.
-		file, err := os.Open(kvs.fileName)
-		if err != nil {
-			if os.IsNotExist(err) {
-				return ErrKeyNotFound
-			}
-			return err
-		}
-		defer file.Close()
-
-		b, err := io.ReadAll(file)
-		if err != nil {
-			return err
-		}
-
-		if len(b) == 0 {
-			if kvs.logger != nil {
-				kvs.logger.Info("Unable to read empty file", zap.String("fileName", kvs.fileName))
-			} else {
-				log.Printf("Unable to read file %s, was empty", kvs.fileName)
-			}
-
-			return ErrStoreEmpty
-		}
-
-		// Decode to raw JSON messages.
-		if err := json.Unmarshal(b, &kvs.data); err != nil {
+		if err := kvs.load(); err != nil {
 			return err
 		}
 
@@ -135,6 +161,98 @@ func (kvs *jsonFileStore) Flush() error {
 	return kvs.flush()
 }
 
+// load reads and decodes the file, restoring the last good snapshot if the file is corrupted.
+// Lock-free for internal callers.
+func (kvs *jsonFileStore) load() error {
+	// Open and parse the file if it exists.
+	file, err := os.Open(kvs.fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrKeyNotFound
+		}
+		return err
+	}
+	defer file.Close()
+
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	j := kvs.readJournal()
+	data := map[string]*json.RawMessage{}
+	if err = decode(b, j, &data); err == nil {
+		kvs.data = data
+		return nil
+	}
+
+	if len(b) == 0 {
+		if kvs.logger != nil {
+			kvs.logger.Info("Unable to read empty file", zap.String("fileName", kvs.fileName))
+		} else {
+			log.Printf("Unable to read file %s, was empty", kvs.fileName)
+		}
+	}
+
+	if j == nil {
+		// without a journal there are no snapshots to recover from.
+		return err
+	}
+
+	return kvs.recover(j, err)
+}
+
+// recover restores the newest snapshot that matches the journal over the corrupted file.
+// Lock-free for internal callers.
+func (kvs *jsonFileStore) recover(j *journal, reason error) error {
+	for i := 1; i <= kvs.snapshots; i++ {
+		snapshot := kvs.snapshotName(i)
+		b, err := os.ReadFile(snapshot)
+		if err != nil {
+			continue
+		}
+		data := map[string]*json.RawMessage{}
+		if err := decode(b, j, &data); err != nil {
+			continue
+		}
+		if err := replaceFile(kvs.fileName, b); err != nil {
+			return fmt.Errorf("failed to restore snapshot %s: %w", snapshot, err)
+		}
+		kvs.data = data
+
+		if kvs.logger != nil {
+			kvs.logger.Error("Restored corrupted store file from snapshot", zap.String("fileName", kvs.fileName),
+				zap.String("snapshot", snapshot), zap.Error(reason))
+		} else {
+			log.Errorf("Restored corrupted store file %s from snapshot %s: %v", kvs.fileName, snapshot, reason)
+		}
+		if kvs.onRecovery != nil {
+			kvs.onRecovery(RecoveryEvent{FileName: kvs.fileName, Snapshot: snapshot, Reason: reason})
+		}
+		return nil
+	}
+
+	if kvs.logger != nil {
+		kvs.logger.Error("Store file is corrupted and no good snapshot was found", zap.String("fileName", kvs.fileName),
+			zap.Error(reason))
+	} else {
+		log.Errorf("Store file %s is corrupted and no good snapshot was found: %v", kvs.fileName, reason)
+	}
+	return reason
+}
+
+// decode validates b against the journal, if any, and decodes it in to data.
+func decode(b []byte, j *journal, data *map[string]*json.RawMessage) error {
+	if len(b) == 0 {
+		return ErrStoreEmpty
+	}
+	if j != nil && !j.contains(checksum(b)) {
+		return ErrChecksumMismatch
+	}
+	// Decode to raw JSON messages.
+	return json.Unmarshal(b, data)
+}
+
 // Lock-free flush for internal callers.
 func (kvs *jsonFileStore) flush() error {
 	buf, err := json.MarshalIndent(&kvs.data, "", "\t")
@@ -142,7 +260,93 @@ func (kvs *jsonFileStore) flush() error {
 		return err
 	}
 
-	dir, file := filepath.Split(kvs.fileName)
+	if kvs.snapshots > 0 {
+		if err := kvs.writeJournal(buf); err != nil {
+			return err
+		}
+	}
+
+	return replaceFile(kvs.fileName, buf)
+}
+
+// writeJournal snapshots the current file and records the checksum of buf before it replaces the file.
+// Lock-free for internal callers.
+func (kvs *jsonFileStore) writeJournal(buf []byte) error {
+	j := kvs.readJournal()
+	if j == nil {
+		j = &journal{}
+	}
+
+	// only snapshot the current file if it is good, so a corrupted file never displaces a good snapshot.
+	// Before the first journaled write, the file was written without a journal and is trusted if it decodes.
+	if cur, err := os.ReadFile(kvs.fileName); err == nil && len(cur) != 0 &&
+		((len(j.Checksums) == 0 && json.Valid(cur)) || j.contains(checksum(cur))) {
+		for i := kvs.snapshots - 1; i >= 1; i-- {
+			if err := os.Rename(kvs.snapshotName(i), kvs.snapshotName(i+1)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to rotate snapshot %s: %w", kvs.snapshotName(i), err)
+			}
+		}
+		if err := replaceFile(kvs.snapshotName(1), cur); err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+		if len(j.Checksums) == 0 {
+			j.Checksums = []string{checksum(cur)}
+		}
+	}
+
+	j.Checksums = append([]string{checksum(buf)}, j.Checksums...)
+	if len(j.Checksums) > kvs.snapshots+1 {
+		j.Checksums = j.Checksums[:kvs.snapshots+1]
+	}
+	return kvs.saveJournal(j)
+}
+
+// saveJournal replaces the journal of the file with j.
+func (kvs *jsonFileStore) saveJournal(j *journal) error {
+	b, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	if err := replaceFile(kvs.fileName+JournalExtension, b); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// readJournal returns the journal of the file, or nil if there is none.
+func (kvs *jsonFileStore) readJournal() *journal {
+	b, err := os.ReadFile(kvs.fileName + JournalExtension)
+	if err != nil {
+		return nil
+	}
+	j := &journal{}
+	if err := json.Unmarshal(b, j); err != nil {
+		return nil
+	}
+	return j
+}
+
+func (kvs *jsonFileStore) snapshotName(i int) string {
+	return kvs.fileName + "." + strconv.Itoa(i)
+}
+
+func (j *journal) contains(sum string) bool {
+	for _, s := range j.Checksums {
+		if s == sum {
+			return true
+		}
+	}
+	return false
+}
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// replaceFile atomically replaces fileName with buf by writing a temp file and renaming it.
+func replaceFile(fileName string, buf []byte) (err error) {
+	dir, file := filepath.Split(fileName)
 	if dir == "" {
 		dir = "."
 	}
@@ -172,7 +376,7 @@ func (kvs *jsonFileStore) flush() error {
 	}
 
 	// atomic replace
-	if err = platform.ReplaceFile(tmpFileName, kvs.fileName); err != nil {
+	if err = platform.ReplaceFile(tmpFileName, fileName); err != nil {
 		return fmt.Errorf("rename temp file to state file failed:%v", err)
 	}
 
@@ -263,5 +467,10 @@ func (kvs *jsonFileStore) Remove() {
 	if err := os.Remove(kvs.fileName); err != nil {
 		log.Errorf("could not remove file %s. Error: %v", kvs.fileName, err)
 	}
+	// the journal and snapshots are best effort, they may not exist.
+	_ = os.Remove(kvs.fileName + JournalExtension)
+	for i := 1; i <= kvs.snapshots; i++ {
+		_ = os.Remove(kvs.snapshotName(i))
+	}
 	kvs.Mutex.Unlock()
 }
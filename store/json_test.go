@@ -5,6 +5,7 @@ package store
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -237,3 +238,76 @@ func TestFileName(t *testing.T) {
 		t.Fatalf("This should not fail for a non-empty file %v", err)
 	}
 }
+
+func TestJSONFileStoreRecoversFromSnapshot(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+	var events []RecoveryEvent
+	newStore := func() KeyValueStore {
+		kvs, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil,
+			WithSnapshots(2), WithRecoveryHandler(func(e RecoveryEvent) { events = append(events, e) }))
+		require.NoError(t, err)
+		return kvs
+	}
+
+	kvs := newStore()
+	require.NoError(t, kvs.Write(testKey1, &testType1{"first", 1}))
+	require.NoError(t, kvs.Write(testKey1, &testType1{"second", 2}))
+	require.NoError(t, kvs.Write(testKey1, &testType1{"third", 3}))
+
+	// only the configured number of snapshots are kept.
+	_, err := os.Stat(fileName + ".2")
+	require.NoError(t, err)
+	_, err = os.Stat(fileName + ".3")
+	require.True(t, os.IsNotExist(err))
+
+	// an intact file is read without recovery.
+	var readValue testType1
+	require.NoError(t, newStore().Read(testKey1, &readValue))
+	require.Equal(t, testType1{"third", 3}, readValue)
+	require.Empty(t, events)
+
+	// a half-written file fails to decode and the last good snapshot is restored.
+	require.NoError(t, os.WriteFile(fileName, []byte(`{"key1":{"Field1":"thi`), 0o600))
+	require.NoError(t, newStore().Read(testKey1, &readValue))
+	require.Equal(t, testType1{"second", 2}, readValue)
+	require.Len(t, events, 1)
+	require.Equal(t, fileName+".1", events[0].Snapshot)
+
+	// a truncated file which still decodes fails the checksum and the last good snapshot is restored.
+	require.NoError(t, os.WriteFile(fileName, []byte(`{}`), 0o600))
+	require.NoError(t, newStore().Read(testKey1, &readValue))
+	require.Equal(t, testType1{"second", 2}, readValue)
+	require.Len(t, events, 2)
+	require.ErrorIs(t, events[1].Reason, ErrChecksumMismatch)
+
+	// valid JSON that was not written by the store is also detected.
+	require.NoError(t, os.WriteFile(fileName, []byte(`{"key1":{"Field1":"other","Field2":9}}`), 0o600))
+	require.NoError(t, newStore().Read(testKey1, &readValue))
+	require.Equal(t, testType1{"second", 2}, readValue)
+	require.Len(t, events, 3)
+
+	// an empty file is recovered too.
+	require.NoError(t, os.WriteFile(fileName, nil, 0o600))
+	require.NoError(t, newStore().Read(testKey1, &readValue))
+	require.Equal(t, testType1{"second", 2}, readValue)
+
+	kvs.Remove()
+	_, err = os.Stat(fileName + JournalExtension)
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(fileName + ".1")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestJSONFileStoreCorruptedWithoutSnapshots(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+	kvs, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil, WithSnapshots(1))
+	require.NoError(t, err)
+	require.NoError(t, kvs.Write(testKey1, &testType1{"first", 1}))
+
+	// the first write has no previous version to snapshot.
+	require.NoError(t, os.WriteFile(fileName, []byte(`{"key1":`), 0o600))
+	kvs, err = NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil, WithSnapshots(1))
+	require.NoError(t, err)
+	var readValue testType1
+	require.ErrorIs(t, kvs.Read(testKey1, &readValue), ErrChecksumMismatch)
+}
@@ -5,7 +5,11 @@ package store
 
 import (
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/Azure/azure-container-networking/processlock"
+	"go.uber.org/zap"
 )
 
 // KeyValueStore represents a persistent store of (key,value) pairs.
@@ -29,3 +33,37 @@ var (
 	ErrTimeoutLockingStore            = fmt.Errorf("timed out locking store")
 	ErrNonBlockingLockIsAlreadyLocked = fmt.Errorf("attempted to perform non-blocking lock on an already locked store")
 )
+
+// Type identifies the backend used to persist a KeyValueStore.
+type Type string
+
+const (
+	// TypeJSON persists the store as a single JSON document, rewritten on every flush.
+	TypeJSON Type = "json"
+	// TypeBolt persists the store in an embedded, transactional bbolt database.
+	TypeBolt Type = "bolt"
+
+	// MigratedExtension - Extension added to a JSON store file after it has been migrated to another backend.
+	MigratedExtension = ".migrated"
+)
+
+// New creates a KeyValueStore of the given type for the JSON state file fileName.
+// An empty type selects the JSON file store. The options only apply to the JSON file store, as
+// the bolt store is already crash-safe.
+// For the bolt store the database is created next to fileName with the BoltExtension, and
+// if the database does not exist yet, an existing JSON state file is migrated in to it on first use.
+func New(storeType Type, fileName string, lockclient processlock.Interface, logger *zap.Logger, opts ...JSONFileStoreOption) (KeyValueStore, error) {
+	switch storeType {
+	case "", TypeJSON:
+		return NewJsonFileStore(fileName, lockclient, logger, opts...)
+	case TypeBolt:
+		kvs, err := NewBoltStore(strings.TrimSuffix(fileName, ".json")+BoltExtension, lockclient, logger)
+		if err != nil {
+			return kvs, err
+		}
+		kvs.(*boltStore).jsonFileName = fileName
+		return kvs, nil
+	default:
+		return nil, fmt.Errorf("unknown store type %q", storeType)
+	}
+}
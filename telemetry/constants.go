@@ -9,6 +9,7 @@ const (
 	CNIDelTimeMetricStr    = "CNIDelTimeMs"
 	CNIUpdateTimeMetricStr = "CNIUpdateTimeMs"
 	CNILockTimeoutStr      = "CNILockTimeoutError"
+	CNIStateRecoveredStr   = "CNIStateRecovered"
 
 	// Dimension Names
 	ContextStr        = "Context"
@@ -20,6 +21,8 @@ const (
 	CNIModeStr        = "CNIMode"
 	CNINetworkModeStr = "CNINetworkMode"
 	OSTypeStr         = "OSType"
+	StoreFileStr      = "StoreFile"
+	SnapshotStr       = "Snapshot"
 
 	// Values
 	SucceededStr     = "Succeeded"
@@ -0,0 +1,53 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/wireserver"
+	"github.com/Azure/azure-container-networking/test/nmagentemulator"
+)
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:8080", "address to serve the emulated wireserver on")
+	latency := flag.Duration("latency", 0, "latency added to every request")
+	programmingDelay := flag.Duration("programming-delay", 5*time.Second, "time until a put NC version is reported as programmed")
+	homeAz := flag.Uint("home-az", 1, "home AZ of the node")
+	supportedAPIs := flag.String("supported-apis", "", "comma separated NMAgent supported APIs")
+	primaryIP := flag.String("primary-ip", "10.0.0.4", "primary IP of the node")
+	subnet := flag.String("subnet", "10.0.0.0/24", "subnet of the primary interface")
+	mac := flag.String("mac", "000D3A6E5E5B", "MAC address of the primary interface")
+	flag.Parse()
+
+	config := &nmagentemulator.Config{
+		Latency:          *latency,
+		ProgrammingDelay: *programmingDelay,
+		HomeAz:           *homeAz,
+		Interfaces: wireserver.GetInterfacesResult{
+			Interface: []wireserver.Interface{{
+				MacAddress: *mac,
+				IsPrimary:  true,
+				IPSubnet: []wireserver.Subnet{{
+					Prefix:    *subnet,
+					IPAddress: []wireserver.Address{{Address: *primaryIP, IsPrimary: true}},
+				}},
+			}},
+		},
+	}
+	if *supportedAPIs != "" {
+		config.SupportedAPIs = strings.Split(*supportedAPIs, ",")
+	}
+
+	fmt.Printf("starting nmagent emulator on %s ....\n", *listen)
+	if err := http.ListenAndServe(*listen, nmagentemulator.New(config)); err != nil { //nolint:gosec // test server
+		fmt.Fprintf(os.Stderr, "nmagent emulator failed: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,388 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+// Package nmagentemulator is a stateful emulator of the NMAgent APIs that are reached through the
+// wireserver plugin path, and of the wireserver interface query, so that CNS flows can run off Azure.
+// Latency, failures, and the time NMAgent takes to program NCs are programmable.
+package nmagentemulator
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/wireserver"
+	"github.com/Azure/azure-container-networking/nmagent"
+)
+
+const (
+	pluginsPath = "/machine/plugins"
+	// ControlPath is the prefix of the emulator control API.
+	ControlPath     = "/emulator"
+	faultsPath      = ControlPath + "/faults"
+	statePath       = ControlPath + "/state"
+	interfacesQuery = "getinterfaceinfov1"
+	methodDelete    = "method/DELETE"
+)
+
+// Config is the initial configuration of the Emulator.
+type Config struct {
+	// Latency is added to every NMAgent and wireserver request.
+	Latency time.Duration
+	// ProgrammingDelay is how long after an NC is put NMAgent reports its new version as programmed.
+	ProgrammingDelay time.Duration
+	HomeAz           uint
+	SupportedAPIs    []string
+	// Interfaces are returned by the wireserver interface query.
+	Interfaces wireserver.GetInterfacesResult
+	// VirtualNetworks are returned by NMAgent network configuration requests, by VNet ID.
+	VirtualNetworks map[string]nmagent.VirtualNetwork
+}
+
+// Fault injects latency or failures into the requests it matches.
+type Fault struct {
+	// Path matches the requests whose NMAgent path contains it, such as "networkContainers" or "GetHomeAz",
+	// or "getinterfaceinfov1" for the wireserver interface query. Every request matches if it is empty.
+	Path string `json:"path,omitempty"`
+	// Method matches the request method, or any if it is empty. NMAgent PUTs arrive as POSTs through wireserver.
+	Method string `json:"method,omitempty"`
+	// Latency is added to the matching requests, in nanoseconds in JSON.
+	Latency time.Duration `json:"latency,omitempty"`
+	// Status fails the matching requests with the HTTP status code, if it is set.
+	Status int `json:"status,omitempty"`
+	// Wireserver fails the requests at wireserver instead of at NMAgent.
+	Wireserver bool `json:"wireserver,omitempty"`
+	// Count is the number of requests the Fault applies to before it is removed, or unlimited if it is 0.
+	Count int `json:"count,omitempty"`
+}
+
+func (f *Fault) matches(method, path string) bool {
+	return (f.Method == "" || strings.EqualFold(f.Method, method)) && strings.Contains(path, f.Path)
+}
+
+// NetworkContainer is the state of an NC put to the Emulator.
+type NetworkContainer struct {
+	ID             string    `json:"id"`
+	PrimaryAddress string    `json:"primaryAddress"`
+	AuthToken      string    `json:"authToken"`
+	Version        uint64    `json:"version"`
+	GoalVersion    uint64    `json:"goalVersion"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	// Request is the last put request.
+	Request json.RawMessage `json:"request"`
+}
+
+// State is a snapshot of the Emulator state.
+type State struct {
+	JoinedNetworks    []string           `json:"joinedNetworks"`
+	NetworkContainers []NetworkContainer `json:"networkContainers"`
+	Faults            []Fault            `json:"faults"`
+}
+
+// Emulator is an http.Handler emulating NMAgent and wireserver.
+type Emulator struct {
+	sync.Mutex
+	config   Config
+	networks map[string]struct{}
+	ncs      map[string]*NetworkContainer
+	faults   []*Fault
+	mux      *http.ServeMux
+	now      func() time.Time
+}
+
+// New creates an Emulator with the configuration.
+func New(config *Config) *Emulator {
+	e := &Emulator{
+		config:   *config,
+		networks: map[string]struct{}{},
+		ncs:      map[string]*NetworkContainer{},
+		mux:      http.NewServeMux(),
+		now:      time.Now,
+	}
+	e.mux.HandleFunc(pluginsPath, e.handlePlugins)
+	e.mux.HandleFunc(pluginsPath+"/", e.handlePlugins)
+	e.mux.HandleFunc(faultsPath, e.handleFaults)
+	e.mux.HandleFunc(statePath, e.handleState)
+	return e
+}
+
+func (e *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mux.ServeHTTP(w, r)
+}
+
+// AddFault injects the Fault into the following requests.
+func (e *Emulator) AddFault(f Fault) { //nolint:gocritic // faults are copied in
+	e.Lock()
+	defer e.Unlock()
+	e.faults = append(e.faults, &f)
+}
+
+// ClearFaults removes all Faults.
+func (e *Emulator) ClearFaults() {
+	e.Lock()
+	defer e.Unlock()
+	e.faults = nil
+}
+
+// SetNCVersion programs the version of the NC, as if NMAgent had been updated out of band.
+func (e *Emulator) SetNCVersion(ncID string, version uint64) bool {
+	e.Lock()
+	defer e.Unlock()
+	nc, found := e.ncs[ncID]
+	if !found {
+		return false
+	}
+	nc.Version, nc.GoalVersion = version, version
+	return true
+}
+
+// State returns a snapshot of the Emulator state.
+func (e *Emulator) State() State {
+	e.Lock()
+	defer e.Unlock()
+	state := State{
+		JoinedNetworks:    []string{},
+		NetworkContainers: []NetworkContainer{},
+		Faults:            []Fault{},
+	}
+	for vnetID := range e.networks {
+		state.JoinedNetworks = append(state.JoinedNetworks, vnetID)
+	}
+	sort.Strings(state.JoinedNetworks)
+	for _, nc := range e.ncs {
+		e.progressUntransacted(nc)
+		state.NetworkContainers = append(state.NetworkContainers, *nc)
+	}
+	sort.Slice(state.NetworkContainers, func(i, j int) bool {
+		return state.NetworkContainers[i].ID < state.NetworkContainers[j].ID
+	})
+	for _, f := range e.faults {
+		state.Faults = append(state.Faults, *f)
+	}
+	return state
+}
+
+// applyFaults sleeps for the latency of the request, and returns the Fault that fails it, if any.
+func (e *Emulator) applyFaults(method, path string) *Fault {
+	e.Lock()
+	latency := e.config.Latency
+	var failure *Fault
+	faults := e.faults[:0]
+	for _, f := range e.faults {
+		if f.matches(method, path) {
+			latency += f.Latency
+			if failure == nil && f.Status != 0 {
+				failure = f
+			}
+			if f.Count > 0 {
+				f.Count--
+				if f.Count == 0 {
+					continue
+				}
+			}
+		}
+		faults = append(faults, f)
+	}
+	e.faults = faults
+	e.Unlock()
+	time.Sleep(latency)
+	return failure
+}
+
+// progressUntransacted programs the goal version of the NC once the ProgrammingDelay has passed.
+func (e *Emulator) progressUntransacted(nc *NetworkContainer) {
+	if nc.Version != nc.GoalVersion && e.now().Sub(nc.UpdatedAt) >= e.config.ProgrammingDelay {
+		nc.Version = nc.GoalVersion
+	}
+}
+
+// handlePlugins serves the wireserver plugin path. NMAgent paths arrive in the type query parameter,
+// without the leading slash and with the separators of their own query replaced by slashes.
+func (e *Emulator) handlePlugins(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("comp") != "nmagent" {
+		http.Error(w, "unknown component", http.StatusNotFound)
+		return
+	}
+	path := q.Get("type")
+	if f := e.applyFaults(r.Method, path); f != nil {
+		if f.Wireserver || path == interfacesQuery {
+			http.Error(w, "injected wireserver fault", f.Status)
+			return
+		}
+		writeNMAgent(w, f.Status, nil)
+		return
+	}
+
+	if path == interfacesQuery {
+		w.Header().Set("Content-Type", "application/xml")
+		_ = xml.NewEncoder(w).Encode(struct {
+			XMLName xml.Name `xml:"Interfaces"`
+			*wireserver.GetInterfacesResult
+		}{GetInterfacesResult: &e.config.Interfaces})
+		return
+	}
+
+	status, body := e.nmagent(r.Method, path, r.Body)
+	if b, ok := body.([]byte); ok {
+		// non-JSON responses are passed through wireserver as they are.
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write(b)
+		return
+	}
+	writeNMAgent(w, status, body)
+}
+
+// writeNMAgent writes an NMAgent response the way wireserver does: with the NMAgent status code embedded in the
+// JSON body next to the fields of the response, and a 200 status.
+func writeNMAgent(w http.ResponseWriter, status int, body any) {
+	fields := map[string]json.RawMessage{}
+	if body != nil {
+		b, _ := json.Marshal(body)
+		_ = json.Unmarshal(b, &fields)
+	}
+	fields["httpStatusCode"], _ = json.Marshal(strconv.Itoa(status))
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(fields)
+}
+
+// nmagent serves the NMAgent request, and returns its status and body. A []byte body is XML.
+func (e *Emulator) nmagent(method, path string, body io.Reader) (int, any) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	e.Lock()
+	defer e.Unlock()
+	switch {
+	case path == "GetSupportedApis":
+		b, _ := xml.Marshal(nmagent.SupportedAPIsResponseXML{SupportedApis: e.config.SupportedAPIs})
+		return http.StatusOK, b
+	case strings.HasPrefix(path, "GetHomeAz/"):
+		return http.StatusOK, nmagent.AzResponse{HomeAz: e.config.HomeAz}
+	case path == "NetworkManagement/interfaces/api-version/2":
+		list := nmagent.NCVersionList{Containers: []nmagent.NCVersion{}}
+		for _, nc := range e.ncs {
+			e.progressUntransacted(nc)
+			list.Containers = append(list.Containers, nmagent.NCVersion{NetworkContainerID: nc.ID, Version: strconv.FormatUint(nc.Version, 10)})
+		}
+		sort.Slice(list.Containers, func(i, j int) bool {
+			return list.Containers[i].NetworkContainerID < list.Containers[j].NetworkContainerID
+		})
+		return http.StatusOK, list
+	case len(segments) >= 3 && segments[0] == "NetworkManagement" && segments[1] == "joinedVirtualNetworks":
+		return e.network(method, segments[2], strings.HasSuffix(path, methodDelete))
+	case len(segments) >= 7 && segments[0] == "NetworkManagement" && segments[1] == "interfaces" && segments[3] == "networkContainers":
+		return e.networkContainer(method, segments, strings.HasSuffix(path, methodDelete), body)
+	default:
+		return http.StatusNotFound, map[string]string{"error": fmt.Sprintf("unknown NMAgent path %q", path)}
+	}
+}
+
+func (e *Emulator) network(method, vnetID string, deleteNetwork bool) (int, any) {
+	switch {
+	case deleteNetwork:
+		delete(e.networks, vnetID)
+		return http.StatusOK, nil
+	case method == http.MethodPost:
+		e.networks[vnetID] = struct{}{}
+		return http.StatusOK, nil
+	default:
+		if _, joined := e.networks[vnetID]; !joined {
+			return http.StatusNotFound, nil
+		}
+		return http.StatusOK, e.config.VirtualNetworks[vnetID]
+	}
+}
+
+// networkContainer serves interfaces/{primary}/networkContainers/{nc}/[version/]authenticationToken/{token}/...
+func (e *Emulator) networkContainer(method string, segments []string, deleteNC bool, body io.Reader) (int, any) {
+	primaryAddress, ncID := segments[2], segments[4]
+	if segments[5] == "version" {
+		if len(segments) < 8 { //nolint:gomnd // the version path has an auth token after the version
+			return http.StatusBadRequest, map[string]string{"error": "missing authentication token"}
+		}
+		nc, found := e.ncs[ncID]
+		if !found || nc.AuthToken != segments[7] {
+			return http.StatusNotFound, nil
+		}
+		e.progressUntransacted(nc)
+		return http.StatusOK, nmagent.NCVersion{NetworkContainerID: ncID, Version: strconv.FormatUint(nc.Version, 10)}
+	}
+	authToken := segments[6]
+	if deleteNC {
+		delete(e.ncs, ncID)
+		return http.StatusOK, nil
+	}
+	if method != http.MethodPost {
+		return http.StatusMethodNotAllowed, nil
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return http.StatusBadRequest, nil
+	}
+	var req nmagent.PutNetworkContainerRequest
+	if err := json.Unmarshal(b, &req); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+	nc, found := e.ncs[ncID]
+	if !found {
+		nc = &NetworkContainer{ID: ncID}
+		e.ncs[ncID] = nc
+	} else {
+		e.progressUntransacted(nc)
+	}
+	nc.PrimaryAddress, nc.AuthToken = primaryAddress, authToken
+	nc.GoalVersion = req.Version
+	nc.UpdatedAt = e.now()
+	nc.Request = b
+	e.progressUntransacted(nc)
+	return http.StatusOK, nil
+}
+
+// handleFaults adds a Fault with POST, removes all Faults with DELETE, and lists them with GET.
+func (e *Emulator) handleFaults(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var f Fault
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		e.AddFault(f)
+	case http.MethodDelete:
+		e.ClearFaults()
+	case http.MethodGet:
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(e.State().Faults)
+}
+
+// handleState returns the State with GET. POST to ?nc=<id>&version=<version> programs the version of the NC.
+func (e *Emulator) handleState(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		version, err := strconv.ParseUint(r.URL.Query().Get("version"), 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !e.SetNCVersion(r.URL.Query().Get("nc"), version) {
+			http.Error(w, "nc not found", http.StatusNotFound)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(e.State())
+}
@@ -0,0 +1,153 @@
+package nmagentemulator
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/wireserver"
+	"github.com/Azure/azure-container-networking/nmagent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Printf(format string, args ...any) { l.t.Logf(format, args...) }
+
+func newTestEmulator(t *testing.T, config *Config) (*Emulator, *nmagent.Client, string) {
+	t.Helper()
+	e := New(config)
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+	p, err := strconv.ParseUint(port, 10, 16)
+	require.NoError(t, err)
+	client, err := nmagent.NewClient(nmagent.Config{Host: host, Port: uint16(p)})
+	require.NoError(t, err)
+	return e, client, srv.Listener.Addr().String()
+}
+
+func testPutNC(version uint64) *nmagent.PutNetworkContainerRequest {
+	return &nmagent.PutNetworkContainerRequest{
+		ID:                  "nc",
+		VNetID:              "vnet",
+		Version:             version,
+		SubnetName:          "subnet",
+		IPv4Addrs:           []string{"10.1.0.4"},
+		AuthenticationToken: "token",
+		PrimaryAddress:      "10.0.0.4",
+	}
+}
+
+func TestNetworkContainerVersionProgression(t *testing.T) {
+	e, client, _ := newTestEmulator(t, &Config{ProgrammingDelay: time.Hour})
+	now := time.Now()
+	e.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.NoError(t, client.PutNetworkContainer(ctx, testPutNC(1)))
+	versionReq := nmagent.NCVersionRequest{AuthToken: "token", NetworkContainerID: "nc", PrimaryAddress: "10.0.0.4"}
+	version, err := client.GetNCVersion(ctx, versionReq)
+	require.NoError(t, err)
+	assert.Equal(t, "0", version.Version)
+
+	// the put version is programmed after the programming delay.
+	now = now.Add(time.Hour)
+	version, err = client.GetNCVersion(ctx, versionReq)
+	require.NoError(t, err)
+	assert.Equal(t, "1", version.Version)
+
+	require.NoError(t, client.PutNetworkContainer(ctx, testPutNC(2)))
+	list, err := client.GetNCVersionList(ctx)
+	require.NoError(t, err)
+	require.Len(t, list.Containers, 1)
+	assert.Equal(t, "1", list.Containers[0].Version)
+
+	require.True(t, e.SetNCVersion("nc", 3))
+	list, err = client.GetNCVersionList(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "3", list.Containers[0].Version)
+
+	require.NoError(t, client.DeleteNetworkContainer(ctx, nmagent.DeleteContainerRequest{NCID: "nc", PrimaryAddress: "10.0.0.4", AuthenticationToken: "token"}))
+	assert.Empty(t, e.State().NetworkContainers)
+}
+
+func TestNetworksAndNodeQueries(t *testing.T) {
+	interfaces := wireserver.GetInterfacesResult{Interface: []wireserver.Interface{{
+		MacAddress: "000D3A6E5E5B",
+		IsPrimary:  true,
+		IPSubnet: []wireserver.Subnet{{
+			Prefix:    "10.0.0.0/24",
+			IPAddress: []wireserver.Address{{Address: "10.0.0.4", IsPrimary: true}},
+		}},
+	}}}
+	e, client, hostPort := newTestEmulator(t, &Config{
+		HomeAz:          2,
+		SupportedAPIs:   []string{"NetworkManagementDNCSupport"},
+		Interfaces:      interfaces,
+		VirtualNetworks: map[string]nmagent.VirtualNetwork{"vnet": {CNetSpace: "10.1.0.0/16"}},
+	})
+	ctx := context.Background()
+
+	az, err := client.GetHomeAz(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), az.HomeAz)
+	apis, err := client.SupportedAPIs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"NetworkManagementDNCSupport"}, apis)
+
+	require.NoError(t, client.JoinNetwork(ctx, nmagent.JoinNetworkRequest{NetworkID: "vnet"}))
+	assert.Equal(t, []string{"vnet"}, e.State().JoinedNetworks)
+	vnet, err := client.GetNetworkConfiguration(ctx, nmagent.GetNetworkConfigRequest{VNetID: "vnet"})
+	require.NoError(t, err)
+	assert.Equal(t, "10.1.0.0/16", vnet.CNetSpace)
+	require.NoError(t, client.DeleteNetwork(ctx, nmagent.DeleteNetworkRequest{NetworkID: "vnet"}))
+	assert.Empty(t, e.State().JoinedNetworks)
+
+	ws := &wireserver.Client{HostPort: hostPort, HTTPClient: http.DefaultClient, Logger: testLogger{t}}
+	res, err := ws.GetInterfaces(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, interfaces, *res)
+}
+
+func TestFaults(t *testing.T) {
+	e, client, _ := newTestEmulator(t, &Config{})
+	ctx := context.Background()
+
+	e.AddFault(Fault{Path: "GetHomeAz", Status: http.StatusBadRequest, Count: 1})
+	_, err := client.GetHomeAz(ctx)
+	var nmaErr nmagent.Error
+	require.ErrorAs(t, err, &nmaErr)
+	assert.Equal(t, http.StatusBadRequest, nmaErr.StatusCode())
+	assert.Equal(t, "nmagent", nmaErr.Source)
+
+	// the fault is removed after its count.
+	_, err = client.GetHomeAz(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, e.State().Faults)
+
+	e.AddFault(Fault{Path: "GetHomeAz", Method: http.MethodGet, Status: http.StatusForbidden, Wireserver: true})
+	_, err = client.GetHomeAz(ctx)
+	require.ErrorAs(t, err, &nmaErr)
+	assert.Equal(t, http.StatusForbidden, nmaErr.StatusCode())
+	assert.Equal(t, "wireserver", nmaErr.Source)
+
+	e.ClearFaults()
+	e.AddFault(Fault{Latency: 50 * time.Millisecond})
+	start := time.Now()
+	_, err = client.GetHomeAz(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestNetworkContainerVersionWithoutToken(t *testing.T) {
+	e := New(&Config{})
+	status, _ := e.nmagent(http.MethodGet, "NetworkManagement/interfaces/10.0.0.4/networkContainers/nc/version/authenticationToken", nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+}